@@ -9,13 +9,14 @@ import (
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "1.0.0"
@@ -28,6 +29,8 @@ func main() {
 	cacheDir := flag.String("cache-dir", "", "Cache directory for downloaded files (default: no caching)")
 	forceDownload := flag.Bool("force-download", false, "Force re-download even if cached file exists")
 	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
 	flag.Parse()
 
 	if *showVersion {
@@ -35,8 +38,13 @@ func main() {
 		return
 	}
 
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	if *dbPath == "" {
-		log.Fatal("ERROR: --db is required")
+		logging.Fatal("--db is required")
 	}
 
 	var xmlReader io.Reader
@@ -44,13 +52,13 @@ func main() {
 
 	if *xmlFile != "" {
 		// Use provided XML file
-		log.Printf("INFO: Reading from %s", *xmlFile)
+		slog.Info("Reading", "file", *xmlFile)
 
 		// Check if it's a zip file
 		if strings.HasSuffix(*xmlFile, ".zip") {
 			zipReader, err := zip.OpenReader(*xmlFile)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open zip file: %v", err)
+				logging.Fatal("Failed to open zip file", "err", err)
 			}
 			defer zipReader.Close()
 
@@ -67,20 +75,20 @@ func main() {
 			}
 
 			if xmlZipFile == nil {
-				log.Fatalf("ERROR: No XML file found in zip archive")
+				logging.Fatal("No XML file found in zip archive")
 			}
 
-			log.Printf("INFO: Found %s in zip", xmlZipFile.Name)
+			slog.Info("Found XML in zip", "file", xmlZipFile.Name)
 			rc, err := xmlZipFile.Open()
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open XML from zip: %v", err)
+				logging.Fatal("Failed to open XML from zip", "err", err)
 			}
 			defer rc.Close()
 
 			// Extract to temp file for reading
 			tmpFile, err := os.CreateTemp("", "arin_db_*.xml")
 			if err != nil {
-				log.Fatalf("ERROR: Failed to create temp file: %v", err)
+				logging.Fatal("Failed to create temp file", "err", err)
 			}
 			tmpPath := tmpFile.Name()
 			cleanup = func() { os.Remove(tmpPath) }
@@ -88,13 +96,13 @@ func main() {
 
 			_, err = io.Copy(tmpFile, rc)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to extract XML: %v", err)
+				logging.Fatal("Failed to extract XML", "err", err)
 			}
 			tmpFile.Close()
 
 			f, err := os.Open(tmpPath)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open extracted XML: %v", err)
+				logging.Fatal("Failed to open extracted XML", "err", err)
 			}
 			defer f.Close()
 			xmlReader = f
@@ -102,7 +110,7 @@ func main() {
 			// Plain XML or gzipped
 			f, err := os.Open(*xmlFile)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open file: %v", err)
+				logging.Fatal("Failed to open file", "err", err)
 			}
 			defer f.Close()
 
@@ -110,7 +118,7 @@ func main() {
 			if strings.HasSuffix(*xmlFile, ".gz") {
 				gr, err := gzip.NewReader(f)
 				if err != nil {
-					log.Fatalf("ERROR: Failed to create gzip reader: %v", err)
+					logging.Fatal("Failed to create gzip reader", "err", err)
 				}
 				defer gr.Close()
 				xmlReader = gr
@@ -126,60 +134,60 @@ func main() {
 		if *cacheDir != "" && !*forceDownload {
 			cachedFile := filepath.Join(*cacheDir, "arin_db.zip")
 			if stat, err := os.Stat(cachedFile); err == nil {
-				log.Printf("INFO: Using cached file: %s (%.1f MB, downloaded %s)",
-					cachedFile, float64(stat.Size())/1024/1024, stat.ModTime().Format("2006-01-02 15:04"))
+				slog.Info("Using cached file", "file", cachedFile,
+					"mb", fmt.Sprintf("%.1f", float64(stat.Size())/1024/1024), "downloaded", stat.ModTime().Format("2006-01-02 15:04"))
 				downloadPath = cachedFile
 			}
 		}
 
 		// Download if not cached
 		if downloadPath == "" {
-			log.Printf("INFO: Downloading ARIN bulk data...")
+			slog.Info("Downloading ARIN bulk data")
 			url := fmt.Sprintf("%s?apikey=%s", *downloadURL, *apiKey)
 
 			resp, err := http.Get(url)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to download: %v", err)
+				logging.Fatal("Failed to download", "err", err)
 			}
 			defer resp.Body.Close()
 
 			if resp.StatusCode != 200 {
-				log.Fatalf("ERROR: Download failed with status %d", resp.StatusCode)
+				logging.Fatal("Download failed", "status", resp.StatusCode)
 			}
 
 			// Determine download path
 			if *cacheDir != "" {
 				// Save to cache directory
 				if err := os.MkdirAll(*cacheDir, 0755); err != nil {
-					log.Fatalf("ERROR: Failed to create cache directory: %v", err)
+					logging.Fatal("Failed to create cache directory", "err", err)
 				}
 				downloadPath = filepath.Join(*cacheDir, "arin_db.zip")
-				log.Printf("INFO: Downloading to cache: %s", downloadPath)
+				slog.Info("Downloading to cache", "file", downloadPath)
 			} else {
 				// Save to temp file
 				tmpFile, err := os.CreateTemp("", "arin_db_*.zip")
 				if err != nil {
-					log.Fatalf("ERROR: Failed to create temp file: %v", err)
+					logging.Fatal("Failed to create temp file", "err", err)
 				}
 				downloadPath = tmpFile.Name()
 				tmpFile.Close()
 				cleanup = func() { os.Remove(downloadPath) }
 				defer cleanup()
-				log.Printf("INFO: Downloading to temp file: %s", downloadPath)
+				slog.Info("Downloading to temp file", "file", downloadPath)
 			}
 
 			// Download file
 			outFile, err := os.Create(downloadPath)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to create output file: %v", err)
+				logging.Fatal("Failed to create output file", "err", err)
 			}
 
 			written, err := io.Copy(outFile, resp.Body)
 			outFile.Close()
 			if err != nil {
-				log.Fatalf("ERROR: Failed to save download: %v", err)
+				logging.Fatal("Failed to save download", "err", err)
 			}
-			log.Printf("INFO: Downloaded %.1f MB", float64(written)/1024/1024)
+			slog.Info("Downloaded", "mb", fmt.Sprintf("%.1f", float64(written)/1024/1024))
 		}
 
 		// Check if it's a zip file
@@ -187,7 +195,7 @@ func main() {
 		if err == nil {
 			// It's a zip file - extract the XML
 			defer zipReader.Close()
-			log.Printf("INFO: Extracting XML from zip...")
+			slog.Info("Extracting XML from zip")
 
 			var xmlFile *zip.File
 			for _, f := range zipReader.File {
@@ -202,20 +210,20 @@ func main() {
 			}
 
 			if xmlFile == nil {
-				log.Fatalf("ERROR: No XML file found in zip archive")
+				logging.Fatal("No XML file found in zip archive")
 			}
 
-			log.Printf("INFO: Found %s in zip", xmlFile.Name)
+			slog.Info("Found XML in zip", "file", xmlFile.Name)
 			rc, err := xmlFile.Open()
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open XML from zip: %v", err)
+				logging.Fatal("Failed to open XML from zip", "err", err)
 			}
 			defer rc.Close()
 
 			// Extract to temp file
 			xmlTmpFile, err := os.CreateTemp("", "arin_db_*.xml")
 			if err != nil {
-				log.Fatalf("ERROR: Failed to create temp XML file: %v", err)
+				logging.Fatal("Failed to create temp XML file", "err", err)
 			}
 			xmlTmpPath := xmlTmpFile.Name()
 			if cleanup != nil {
@@ -231,14 +239,14 @@ func main() {
 
 			_, err = io.Copy(xmlTmpFile, rc)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to extract XML: %v", err)
+				logging.Fatal("Failed to extract XML", "err", err)
 			}
 			xmlTmpFile.Close()
 
 			// Open extracted XML
 			f, err := os.Open(xmlTmpPath)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open extracted XML: %v", err)
+				logging.Fatal("Failed to open extracted XML", "err", err)
 			}
 			defer f.Close()
 			xmlReader = f
@@ -246,42 +254,40 @@ func main() {
 			// Not a zip, assume it's plain XML or gzipped
 			f, err := os.Open(downloadPath)
 			if err != nil {
-				log.Fatalf("ERROR: Failed to open file: %v", err)
+				logging.Fatal("Failed to open file", "err", err)
 			}
 			defer f.Close()
 			xmlReader = f
 		}
 
 	} else {
-		log.Fatal("ERROR: Either --xml or --apikey must be provided")
+		logging.Fatal("Either --xml or --apikey must be provided")
 	}
 
 	// Remove existing database
 	if err := os.RemoveAll(*dbPath); err != nil && !os.IsNotExist(err) {
-		log.Fatalf("ERROR: Failed to remove existing database: %v", err)
+		logging.Fatal("Failed to remove existing database", "err", err)
 	}
 
 	// Create parent directory if needed
 	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
-		log.Fatalf("ERROR: Failed to create directory: %v", err)
+		logging.Fatal("Failed to create directory", "err", err)
 	}
 
 	// Build database using streaming (low memory usage)
 	db, err := arinbulk.BuildDatabaseStreaming(*dbPath, xmlReader)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to build database: %v", err)
+		logging.Fatal("Failed to build database", "err", err)
 	}
 	defer db.Close()
 
-	log.Printf("INFO: Database built successfully at %s", *dbPath)
+	slog.Info("Database built successfully", "path", *dbPath)
 
 	// Show stats
 	netCount, orgCount, err := db.Stats()
 	if err != nil {
-		log.Printf("WARN: Failed to get stats: %v", err)
+		slog.Warn("Failed to get stats", "err", err)
 	} else {
-		log.Printf("INFO: Statistics:")
-		log.Printf("INFO:   Networks: %d", netCount)
-		log.Printf("INFO:   Organizations: %d", orgCount)
+		slog.Info("Statistics", "networks", netCount, "orgs", orgCount)
 	}
 }
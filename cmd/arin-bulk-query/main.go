@@ -7,11 +7,11 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"net/netip"
 	"os"
 
 	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "1.0.0"
@@ -20,6 +20,8 @@ func main() {
 	dbPath := flag.String("db", "./arinbulk.ldb", "Path to ARIN bulk LevelDB database")
 	jsonOutput := flag.Bool("json", false, "Output in JSON format")
 	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
 	flag.Parse()
 
 	if *showVersion {
@@ -33,10 +35,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Open database
 	db, err := arinbulk.OpenDatabase(*dbPath)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer db.Close()
 
@@ -44,7 +51,7 @@ func main() {
 	ipStr := flag.Args()[0]
 	ip, err := netip.ParseAddr(ipStr)
 	if err != nil {
-		log.Fatalf("ERROR: Invalid IP address: %v", err)
+		logging.Fatal("Invalid IP address", "err", err)
 	}
 
 	// Lookup
@@ -58,7 +65,7 @@ func main() {
 			}
 			return
 		}
-		log.Fatalf("ERROR: Lookup failed: %v", err)
+		logging.Fatal("Lookup failed", "err", err)
 	}
 
 	// Output result
@@ -94,7 +101,7 @@ func outputJSON(match *arinbulk.Match) {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(result); err != nil {
-		log.Fatalf("ERROR: Failed to encode JSON: %v", err)
+		logging.Fatal("Failed to encode JSON", "err", err)
 	}
 }
 
@@ -0,0 +1,356 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/sources/maxmind"
+	"github.com/wingedpig/iporg/pkg/testutil"
+	"github.com/wingedpig/iporg/pkg/util/httprecord"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// Three networks: a RIPE allocation with a customer assignment inside it, an
+// ARIN allocation with a reassignment, and an APNIC prefix only RDAP knows
+var (
+	fixtureASNs = []testutil.ASNBlock{
+		{Prefix: "81.0.0.0/22", ASN: 64500, Org: "EXAMPLE-TRANSIT"},
+		{Prefix: "23.1.0.0/22", ASN: 64501, Org: "EXAMPLE-CLOUD"},
+		{Prefix: "1.2.3.0/24", ASN: 64502, Org: "EXAMPLE-APNIC"},
+	}
+	fixtureCities = []testutil.CityBlock{
+		{Prefix: "81.0.0.0/22", Country: "GB", Region: "England", City: "London", Lat: 51.5, Lon: -0.12},
+		{Prefix: "81.0.2.0/24", Country: "NL", Region: "North Holland", City: "Amsterdam", Lat: 52.37, Lon: 4.89},
+		{Prefix: "23.1.0.0/22", Country: "US", Region: "Virginia", City: "Ashburn", Lat: 39.04, Lon: -77.49},
+		{Prefix: "23.1.3.0/24", Country: "US", Region: "California", City: "Los Angeles", Lat: 34.05, Lon: -118.24},
+		{Prefix: "1.2.3.0/24", Country: "AU", Region: "New South Wales", City: "Sydney", Lat: -33.87, Lon: 151.21},
+	}
+	// The second row spans two CIDRs (81.0.1.0/24 and 81.0.2.0/23)
+	fixtureIPtoASN = []testutil.IPtoASNRow{
+		{Start: "81.0.0.0", End: "81.0.0.255", ASN: 64500, Country: "GB", Registry: "ripencc", Name: "EXAMPLE-TRANSIT"},
+		{Start: "81.0.1.0", End: "81.0.3.255", ASN: 64500, Country: "GB", Registry: "ripencc", Name: "EXAMPLE-TRANSIT"},
+		{Start: "23.1.0.0", End: "23.1.3.255", ASN: 64501, Country: "US", Registry: "arin", Name: "EXAMPLE-CLOUD"},
+		{Start: "1.2.3.0", End: "1.2.3.255", ASN: 64502, Country: "AU", Registry: "apnic", Name: "EXAMPLE-APNIC"},
+	}
+	fixtureAnnounced = map[int][]string{
+		64500: {"81.0.0.0/22"},
+		64501: {"23.1.0.0/22"},
+		64502: {"1.2.3.0/24"},
+	}
+	fixtureRIPE = []testutil.Registration{
+		{Range: "81.0.0.0 - 81.0.3.255", OrgID: "ORG-ET1-RIPE", OrgName: "Example Transit Ltd", Status: "ALLOCATED PA", Country: "GB", NetName: "ET-NET"},
+		{Range: "81.0.2.0/24", OrgID: "ORG-CO1-RIPE", OrgName: "Customer One B.V.", Status: "ASSIGNED PA", Country: "NL", NetName: "CUSTOMER-ONE"},
+	}
+	fixtureARIN = []testutil.Registration{
+		{Range: "23.1.0.0/22", OrgID: "EXCL", OrgName: "Example Cloud Inc.", Status: "DA", Country: "US", NetName: "EXAMPLE-CLOUD"},
+		{Range: "23.1.2.0/24", OrgID: "RCL-1", OrgName: "Reassigned Customer LLC", Status: "S", Country: "US", NetName: "RCL-NET"},
+	}
+	fixtureRDAP = []testutil.RDAPNetwork{
+		{CIDR: "1.2.3.0/24", Handle: "APNIC-1", Name: "EXAMPLE-AP", OrgName: "Example APNIC Pty Ltd", Country: "AU", Status: "active", Port43: "whois.apnic.net"},
+		{CIDR: "23.1.0.0/22", Handle: "NET-23-1-0-0-1", Name: "EXAMPLE-CLOUD", OrgName: "Example Cloud Inc.", Country: "US", Status: "active", Port43: "whois.arin.net"},
+		{CIDR: "23.1.2.0/24", Handle: "NET-23-1-2-0-1", Name: "RCL-NET", OrgName: "Reassigned Customer LLC", Role: "customer", Country: "US", Status: "active", Port43: "whois.arin.net"},
+	}
+	fixtureLookups = []string{"81.0.0.1", "81.0.0.200", "81.0.2.77", "81.0.3.1", "23.1.0.1", "23.1.2.9", "23.1.3.200", "1.2.3.4", "9.9.9.9"}
+)
+
+func TestBuildGolden(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *model.BuildConfig, dir string, ripestat *testutil.Server)
+	}{
+		{
+			// Mode A with prefixes from iptoasn and both bulk databases
+			name: "mode_a",
+			configure: func(cfg *model.BuildConfig, dir string, ripestat *testutil.Server) {
+				cfg.IPtoASNDBPath = filepath.Join(dir, "iptoasn")
+				cfg.RIPEBulkDBPath = filepath.Join(dir, "ripebulk")
+				cfg.ARINBulkDBPath = filepath.Join(dir, "arinbulk")
+			},
+		},
+		{
+			// Mode A with an override for a customer /25 inside an announced /24
+			name: "mode_a_overrides",
+			configure: func(cfg *model.BuildConfig, dir string, ripestat *testutil.Server) {
+				cfg.IPtoASNDBPath = filepath.Join(dir, "iptoasn")
+				cfg.RIPEBulkDBPath = filepath.Join(dir, "ripebulk")
+				cfg.OverridesFile = filepath.Join(dir, "overrides.json")
+				doc := `{"overrides":[{"cidr":"81.0.0.128/25","org_name":"Our Customer Ltd"}]}`
+				if err := os.WriteFile(cfg.OverridesFile, []byte(doc), 0644); err != nil {
+					t.Fatalf("Failed to write overrides: %v", err)
+				}
+			},
+		},
+		{
+			// Mode B with prefixes from RIPEstat; ARIN space goes to RDAP
+			name: "mode_b",
+			configure: func(cfg *model.BuildConfig, dir string, ripestat *testutil.Server) {
+				cfg.RIPEBaseURL = ripestat.URL
+				cfg.RIPEBulkDBPath = filepath.Join(dir, "ripebulk")
+				cfg.SplitByMaxMind = true
+				cfg.MinPrefixV4 = 24
+			},
+		},
+		{
+			// Mode C: records follow the RIPE and ARIN sub-allocations
+			name: "mode_c",
+			configure: func(cfg *model.BuildConfig, dir string, ripestat *testutil.Server) {
+				cfg.RIPEBaseURL = ripestat.URL
+				cfg.RIPEBulkDBPath = filepath.Join(dir, "ripebulk")
+				cfg.ARINBulkDBPath = filepath.Join(dir, "arinbulk")
+				cfg.SplitByRegistry = true
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "iporg-build-test-*")
+			if err != nil {
+				t.Fatalf("Failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			writeFixtures(t, dir)
+			ripestat := testutil.NewRIPEstat(t, fixtureAnnounced)
+			cfg := fixtureConfig(t, dir)
+			tt.configure(cfg, dir, ripestat)
+
+			if err := NewBuilder(cfg).Build(context.Background()); err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+			checkGolden(t, filepath.Join("testdata", tt.name+".golden"), dumpDatabase(t, cfg.DBPath))
+		})
+	}
+}
+
+func TestBuildRefusedKeepsMetadata(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtures(t, dir)
+	cfg := fixtureConfig(t, dir)
+	cfg.IPtoASNDBPath = filepath.Join(dir, "iptoasn")
+	cfg.RIPEBulkDBPath = filepath.Join(dir, "ripebulk")
+	if err := NewBuilder(cfg).Build(context.Background()); err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	builtAt := readBuiltAt(t, cfg.DBPath)
+	ranges := dumpDatabase(t, cfg.DBPath)
+
+	// An interrupted rebuild leaves both the records and the metadata
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	cfg.MinPrefixV4 = 24
+	b := NewBuilder(cfg)
+	b.buildTime = builtAt.Add(time.Hour)
+	if err := b.Build(canceled); err == nil {
+		t.Fatalf("interrupted Build succeeded")
+	}
+	if got := readBuiltAt(t, cfg.DBPath); !got.Equal(builtAt) {
+		t.Errorf("built_at: got %v, want %v", got, builtAt)
+	}
+	if got := dumpDatabase(t, cfg.DBPath); got != ranges {
+		t.Errorf("records changed by an interrupted build:\n%s", got)
+	}
+}
+
+// fixtureConfig returns a build config for the fixtures in dir, with RDAP
+// served by a test server
+func fixtureConfig(t *testing.T, dir string) *model.BuildConfig {
+	t.Helper()
+	rdapServer := testutil.NewRDAP(t, fixtureRDAP)
+	return &model.BuildConfig{
+		ASNFile:          filepath.Join(dir, "asns.txt"),
+		MMDBASNPath:      filepath.Join(dir, "asn.mmdb"),
+		MMDBCityPath:     filepath.Join(dir, "city.mmdb"),
+		DBPath:           filepath.Join(dir, "iporgdb"),
+		Workers:          4,
+		CacheTTL:         time.Hour,
+		NegativeTTL:      time.Hour,
+		IPv4Only:         true,
+		MinPrefixV4:      20,
+		MinPrefixV6:      32,
+		RIPEBaseURL:      "http://ripestat.invalid",
+		RDAPBootstrapURL: rdapServer.URL,
+		UserAgent:        "iporg-build-test",
+	}
+}
+
+// readBuiltAt returns the built_at recorded in the database at path
+func readBuiltAt(t *testing.T, path string) time.Time {
+	t.Helper()
+	db, err := iporgdb.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open built database: %v", err)
+	}
+	defer db.Close()
+	builtAt, err := db.GetBuiltAt()
+	if err != nil {
+		t.Fatalf("GetBuiltAt failed: %v", err)
+	}
+	return builtAt
+}
+
+// writeFixtures writes the synthetic inputs shared by all builds into dir
+func writeFixtures(t *testing.T, dir string) {
+	t.Helper()
+	steps := []struct {
+		what string
+		fn   func() error
+	}{
+		{"ASN MMDB", func() error { return testutil.WriteASNMMDB(filepath.Join(dir, "asn.mmdb"), fixtureASNs) }},
+		{"City MMDB", func() error { return testutil.WriteCityMMDB(filepath.Join(dir, "city.mmdb"), fixtureCities) }},
+		{"iptoasn", func() error { return testutil.BuildIPtoASN(filepath.Join(dir, "iptoasn"), fixtureIPtoASN) }},
+		{"RIPE bulk", func() error { return testutil.WriteRIPEBulk(filepath.Join(dir, "ripebulk"), fixtureRIPE) }},
+		{"ARIN bulk", func() error { return testutil.WriteARINBulk(filepath.Join(dir, "arinbulk"), fixtureARIN) }},
+		{"ASN list", func() error {
+			return os.WriteFile(filepath.Join(dir, "asns.txt"), []byte("AS64500\n64501\n64502\n"), 0644)
+		}},
+	}
+	for _, s := range steps {
+		if err := s.fn(); err != nil {
+			t.Fatalf("Failed to write %s fixture: %v", s.what, err)
+		}
+	}
+}
+
+// dumpDatabase renders every IPv4 range and the fixture lookups as text
+func dumpDatabase(t *testing.T, path string) string {
+	t.Helper()
+	db, err := iporgdb.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open built database: %v", err)
+	}
+	defer db.Close()
+
+	var sb strings.Builder
+	sb.WriteString("# ranges\n")
+	err = db.IterateRanges(true, func(rec *model.Record) error {
+		sb.WriteString(formatRecord(rec))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("IterateRanges failed: %v", err)
+	}
+
+	sb.WriteString("# lookups\n")
+	for _, ip := range fixtureLookups {
+		rec, err := db.GetByIP(netip.MustParseAddr(ip))
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %v\n", ip, err)
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s", ip, formatRecord(rec))
+	}
+	return sb.String()
+}
+
+// formatRecord renders the fields of a record a build controls
+func formatRecord(rec *model.Record) string {
+	return fmt.Sprintf("%s-%s prefix=%s AS%d asn_name=%q org=%q role=%s status=%q rir=%s geo=%s/%s/%s\n",
+		rec.Start, rec.End, rec.Prefix, rec.ASN, rec.ASNName, rec.OrgName, rec.SourceRole,
+		rec.StatusLabel, rec.RIR, rec.Country, rec.Region, rec.City)
+}
+
+// checkGolden compares got with the golden file, rewriting it with -update
+func checkGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("Failed to update %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run with -update to accept)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestCheckReplace(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		append   bool
+		errors   int
+		prefixes int
+		wantErr  bool
+	}{
+		{"no errors", context.Background(), false, 0, 100, false},
+		{"errors within limit", context.Background(), false, 5, 100, false},
+		{"too many errors", context.Background(), false, 6, 100, true},
+		{"interrupted", canceled, false, 0, 100, true},
+		{"append", canceled, true, 100, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(&model.BuildConfig{Append: tt.append, MaxErrorRate: 0.05})
+			b.stats.Errors = tt.errors
+			if err := b.checkReplace(tt.ctx, tt.prefixes); (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A replay that missed the archive is refused even when appending
+	replayer, err := httprecord.NewReplayer(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewReplayer failed: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://rdap.example/ip/192.0.2.1", nil)
+	if _, err := replayer.RoundTrip(req); err == nil {
+		t.Fatalf("RoundTrip of an unrecorded request succeeded")
+	}
+	b := NewBuilder(&model.BuildConfig{Append: true})
+	b.replayer = replayer
+	if err := b.checkReplace(context.Background(), 100); err == nil {
+		t.Errorf("replay misses: got nil, want error")
+	}
+}
+
+func TestSplitByRegistry(t *testing.T) {
+	ripe := enrich.NewCoverage(netip.MustParsePrefix("10.0.0.0/22"))
+	ripe.Add(netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.3.255"), nil)
+	ripe.Add(netip.MustParseAddr("10.0.0.128"), netip.MustParseAddr("10.0.0.255"), nil)
+	ripe.Add(netip.MustParseAddr("10.0.2.0"), netip.MustParseAddr("10.0.2.63"), nil)
+	arin := enrich.NewCoverage(netip.MustParsePrefix("10.0.0.0/22"))
+	arin.Add(netip.MustParseAddr("10.0.2.0"), netip.MustParseAddr("10.0.2.127"), nil)
+	coverage := map[string]*enrich.Coverage{enrich.SourceRIPEBulk: ripe, enrich.SourceARINBulk: arin}
+
+	// Out of order, as blocks from another source might be
+	blocks := []maxmind.NetworkBlock{
+		{Prefix: netip.MustParsePrefix("10.0.2.0/23"), City: "Leeds"},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/23"), City: "London"},
+	}
+	var got []string
+	for _, piece := range splitByRegistry(blocks, coverage) {
+		got = append(got, piece.Prefix.String()+" "+piece.City)
+	}
+	want := []string{
+		"10.0.0.0/25 London", "10.0.0.128/25 London", "10.0.1.0/24 London",
+		"10.0.2.0/26 Leeds", "10.0.2.64/26 Leeds", "10.0.2.128/25 Leeds", "10.0.3.0/24 Leeds",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
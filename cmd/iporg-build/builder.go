@@ -7,23 +7,27 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/enrich"
 	"github.com/wingedpig/iporg/pkg/iporgdb"
 	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/metrics"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
 	"github.com/wingedpig/iporg/pkg/ripebulk"
 	"github.com/wingedpig/iporg/pkg/sources/maxmind"
 	"github.com/wingedpig/iporg/pkg/sources/rdap"
 	"github.com/wingedpig/iporg/pkg/sources/ripe"
+	"github.com/wingedpig/iporg/pkg/util/httprecord"
 )
 
 // Builder orchestrates the database build process
@@ -35,9 +39,17 @@ type Builder struct {
 	maxmind      *maxmind.Readers
 	ripeClient   *ripe.Client
 	rdapClient   *rdap.CachedClient
-	ripeBulkDB   *ripebulk.Database // Optional: RIPE bulk database for RIPE region
-	arinBulkDB   *arinbulk.Database // Optional: ARIN bulk database for ARIN region
-	iptoasnStore *iptoasn.Store     // Optional: iptoasn database for prefix lookups
+	cacheDB      *iporgdb.DB          // Optional: separate RDAP cache store
+	replayer     *httprecord.Replayer // Set when replaying HTTP traffic
+	ripeBulkDB   *ripebulk.Database   // Optional: RIPE bulk database for RIPE region
+	arinBulkDB   *arinbulk.Database   // Optional: ARIN bulk database for ARIN region
+	iptoasnStore *iptoasn.Store       // Optional: iptoasn database for prefix lookups
+	overrides    *overrides.Set       // Optional: user-supplied corrections
+	pipeline     *enrich.Pipeline
+	metrics      *metrics.Build      // Optional: Prometheus build metrics
+	loader       *iporgdb.BulkLoader // Collects records during a load phase
+	buildTime    time.Time           // Recorded as built_at and LastChecked
+	mu           sync.Mutex          // Guards the non-atomic stats counters
 	stats        BuildStats
 }
 
@@ -48,43 +60,55 @@ type BuildStats struct {
 	PrefixesFetched   int
 	PrefixesProcessed int
 	RecordsWritten    int
-	RecordsUpdated    int
 	RecordsSkipped    int
-	RDAPCacheHits     int
-	RDAPCacheMisses   int
+	RecordsTrimmed    int
+	Conflicts         int
 	RIPEBulkHits      int
 	ARINBulkHits      int
+	OverridesApplied  int
+	RegisteredFilled  int
 	Errors            int
-	// Timing breakdowns - use atomic int64 for nanosecond counts
-	TimeMaxMindASNNanos   int64 // Accessed with atomic operations
-	TimeMaxMindGeoNanos   int64
-	TimeRIPEBulkNanos     int64
-	TimeARINBulkNanos     int64
-	TimeRDAPNanos         int64
-	TimeDBWriteNanos      int64
-	CallsMaxMindASN       int64
-	CallsMaxMindGeo       int64
-	CallsRIPEBulk         int64
-	CallsARINBulk         int64
-	CallsRDAP             int64
-	CallsDBWrite          int64
+	// Time spent per source, keyed by source name (and timingDBWrite)
+	Timings map[string]*sourceTiming
 }
 
 // NewBuilder creates a new database builder
-func NewBuilder(cfg *model.BuildConfig, minPrefixV4, minPrefixV6 int) *Builder {
+func NewBuilder(cfg *model.BuildConfig) *Builder {
 	return &Builder{
 		cfg:         cfg,
-		minPrefixV4: minPrefixV4,
-		minPrefixV6: minPrefixV6,
+		minPrefixV4: cfg.MinPrefixV4,
+		minPrefixV6: cfg.MinPrefixV6,
+		buildTime:   buildTimestamp(),
 		stats: BuildStats{
 			StartTime: time.Now(),
+			Timings:   newTimings(),
 		},
 	}
 }
 
+// timingDBWrite is the Timings key for database writes
+const timingDBWrite = "db_write"
+
+// timingLabels lists the Timings entries in summary order
+var timingLabels = []struct{ key, label string }{
+	{enrich.SourceMaxMind, "MaxMind lookups"},
+	{enrich.SourceRIPEBulk, "RIPE bulk lookups"},
+	{enrich.SourceARINBulk, "ARIN bulk lookups"},
+	{enrich.SourceRDAP, "RDAP lookups"},
+	{timingDBWrite, "Database writes"},
+}
+
+func newTimings() map[string]*sourceTiming {
+	timings := make(map[string]*sourceTiming, len(timingLabels))
+	for _, t := range timingLabels {
+		timings[t.key] = &sourceTiming{}
+	}
+	return timings
+}
+
 // Build executes the complete build pipeline
 func (b *Builder) Build(ctx context.Context) error {
-	log.Println("INFO: Starting build process...")
+	slog.Info("Starting build process")
 
 	// Step 0.5: Open iptoasn database if configured (needed for --all-asns)
 	if err := b.openIPtoASN(); err != nil {
@@ -94,12 +118,21 @@ func (b *Builder) Build(ctx context.Context) error {
 		defer b.iptoasnStore.Close()
 	}
 
-	// Step 1: Load ASNs
-	asns, err := b.loadASNs()
-	if err != nil {
-		return fmt.Errorf("failed to load ASNs: %w", err)
+	// Step 0.6: Load overrides (optional)
+	if err := b.loadOverrides(); err != nil {
+		return fmt.Errorf("failed to load overrides: %w", err)
+	}
+
+	// Step 1: Load ASNs (optional when prefixes are selected another way)
+	var asns []int
+	var err error
+	if b.cfg.AllASNs || b.cfg.ASNFile != "" {
+		asns, err = b.loadASNs()
+		if err != nil {
+			return fmt.Errorf("failed to load ASNs: %w", err)
+		}
+		slog.Info("Loaded ASNs", "asns", len(asns))
 	}
-	log.Printf("INFO: Loaded %d ASNs", len(asns))
 
 	// Step 2: Open database
 	if err := b.openDatabase(); err != nil {
@@ -114,7 +147,12 @@ func (b *Builder) Build(ctx context.Context) error {
 	defer b.maxmind.Close()
 
 	// Step 4: Initialize API clients
-	b.initializeClients()
+	if err := b.initializeClients(); err != nil {
+		return fmt.Errorf("failed to initialize API clients: %w", err)
+	}
+	if b.cacheDB != nil {
+		defer b.cacheDB.Close()
+	}
 
 	// Step 4.5: Open RIPE bulk database (optional)
 	if err := b.openRIPEBulk(); err != nil {
@@ -132,31 +170,65 @@ func (b *Builder) Build(ctx context.Context) error {
 		defer b.arinBulkDB.Close()
 	}
 
-	// Step 5: Initialize/update metadata
-	if err := b.db.InitializeMetadata(version); err != nil {
-		return fmt.Errorf("failed to initialize metadata: %w", err)
+	// Step 4.7: Set up the enrichment pipeline
+	if err := b.initPipeline(); err != nil {
+		return fmt.Errorf("failed to set up enrichment pipeline: %w", err)
 	}
 
 	// Step 6: Fetch announced prefixes
 	var allPrefixes []string
 
-	if b.cfg.IPtoASNDBPath != "" {
-		log.Printf("INFO: Using iptoasn database: %s", b.cfg.IPtoASNDBPath)
-		allPrefixes, err = b.fetchAnnouncedPrefixesFromIPtoASN(ctx, asns)
-	} else {
-		log.Printf("INFO: Using RIPEstat API for prefix discovery")
-		allPrefixes, err = b.fetchAnnouncedPrefixes(ctx, asns)
+	if len(asns) > 0 {
+		if b.cfg.IPtoASNDBPath != "" {
+			slog.Info("Using iptoasn database", "path", b.cfg.IPtoASNDBPath)
+			allPrefixes, err = b.fetchAnnouncedPrefixesFromIPtoASN(ctx, asns)
+		} else {
+			slog.Info("Using RIPEstat API for prefix discovery")
+			allPrefixes, err = b.fetchAnnouncedPrefixes(ctx, asns)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to fetch announced prefixes: %w", err)
+		}
 	}
 
-	if err != nil {
-		return fmt.Errorf("failed to fetch announced prefixes: %w", err)
+	// Step 6.5: Add prefixes from --prefix-file, --country and --rir
+	if b.hasSelectors() {
+		selected, err := b.fetchSelectedPrefixes(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to select prefixes: %w", err)
+		}
+		allPrefixes = mergePrefixes(allPrefixes, selected)
 	}
-	log.Printf("INFO: Fetched %d unique prefixes", len(allPrefixes))
+	slog.Info("Fetched unique prefixes", "prefixes", len(allPrefixes))
 
-	// Step 7: Enrich and write records
+	// Step 7: Enrich records, then resolve overlaps and bulk load them
+	if err := b.beginLoad(b.cfg.Append); err != nil {
+		return fmt.Errorf("failed to start bulk load: %w", err)
+	}
 	if err := b.enrichAndWrite(ctx, allPrefixes); err != nil {
+		b.closeLoad()
 		return fmt.Errorf("failed to enrich and write records: %w", err)
 	}
+	if err := b.checkReplace(ctx, len(allPrefixes)); err != nil {
+		b.closeLoad()
+		return err
+	}
+	if err := b.commitLoad(); err != nil {
+		return fmt.Errorf("failed to load records: %w", err)
+	}
+
+	// Step 7.5: Fill registered but unannounced space (optional)
+	if b.cfg.FillRegistered {
+		if err := b.fillRegistered(ctx); err != nil {
+			return fmt.Errorf("failed to fill registered space: %w", err)
+		}
+	}
+
+	// Step 7.6: Record the build only once its records are written
+	if err := b.writeMetadata(); err != nil {
+		return err
+	}
 
 	// Step 8: Print summary
 	b.printSummary()
@@ -164,6 +236,45 @@ func (b *Builder) Build(ctx context.Context) error {
 	return nil
 }
 
+// writeMetadata stamps the database with this build's time and config
+func (b *Builder) writeMetadata() error {
+	if err := b.db.InitializeMetadata(version); err != nil {
+		return fmt.Errorf("failed to initialize metadata: %w", err)
+	}
+	if err := b.db.SetBuiltAt(b.buildTime); err != nil {
+		return fmt.Errorf("failed to set build time: %w", err)
+	}
+	if err := b.db.SetBuildConfig(b.cfg); err != nil {
+		return fmt.Errorf("failed to store build config: %w", err)
+	}
+	return nil
+}
+
+// checkReplace refuses to write the results of a replay that missed the
+// archive, and to replace the existing records with the results of a build
+// that was interrupted or had too many errors
+func (b *Builder) checkReplace(ctx context.Context, prefixes int) error {
+	if b.replayer != nil && b.replayer.Misses() > 0 {
+		return fmt.Errorf("%d HTTP requests were not in the replay archive %s; database left unchanged",
+			b.replayer.Misses(), b.cfg.HTTPReplayDir)
+	}
+	if b.cfg.Append {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("build interrupted; existing records kept: %w", err)
+	}
+
+	b.mu.Lock()
+	errors := b.stats.Errors
+	b.mu.Unlock()
+	if prefixes > 0 && float64(errors)/float64(prefixes) > b.cfg.MaxErrorRate {
+		return fmt.Errorf("%d errors for %d prefixes exceeds --max-error-rate %g; existing records kept (pass --append to add the results to them)",
+			errors, prefixes, b.cfg.MaxErrorRate)
+	}
+	return nil
+}
+
 // loadASNs loads ASNs from the input file or enumerates all ASNs from iptoasn database
 func (b *Builder) loadASNs() ([]int, error) {
 	// If --all-asns is specified, enumerate from iptoasn database
@@ -172,14 +283,14 @@ func (b *Builder) loadASNs() ([]int, error) {
 			return nil, fmt.Errorf("--all-asns requires iptoasn database, but it's not open")
 		}
 
-		log.Println("INFO: Enumerating all ASNs from iptoasn database...")
+		slog.Info("Enumerating all ASNs from iptoasn database")
 		ctx := context.Background()
 		asns, err := b.iptoasnStore.ListASNs(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list ASNs from iptoasn: %w", err)
 		}
 
-		log.Printf("INFO: Found %d ASNs in iptoasn database", len(asns))
+		slog.Info("Found ASNs in iptoasn database", "asns", len(asns))
 		return asns, nil
 	}
 
@@ -209,12 +320,12 @@ func (b *Builder) loadASNs() ([]int, error) {
 
 		asn, err := strconv.Atoi(line)
 		if err != nil {
-			log.Printf("WARN: Invalid ASN on line %d: %s", lineNum, scanner.Text())
+			slog.Warn("Invalid ASN", "line", lineNum, "text", scanner.Text())
 			continue
 		}
 
 		if asn <= 0 {
-			log.Printf("WARN: Invalid ASN on line %d: %d", lineNum, asn)
+			slog.Warn("Invalid ASN", "line", lineNum, "asn", asn)
 			continue
 		}
 
@@ -232,6 +343,45 @@ func (b *Builder) loadASNs() ([]int, error) {
 	return asns, nil
 }
 
+// loadOverrides loads the overrides file (optional)
+func (b *Builder) loadOverrides() error {
+	if b.cfg.OverridesFile == "" {
+		return nil
+	}
+
+	set, err := overrides.Load(b.cfg.OverridesFile)
+	if err != nil {
+		return err
+	}
+
+	expired := 0
+	for _, o := range set.Entries() {
+		if o.Expired(b.buildTime) {
+			slog.Warn("Override expired and will be ignored", "cidr", o.CIDR, "expires", o.Expires)
+			expired++
+		}
+	}
+
+	b.overrides = set
+	slog.Info("Loaded overrides", "count", set.Len(), "file", b.cfg.OverridesFile, "expired", expired)
+	return nil
+}
+
+// applyOverrides applies user corrections to a fully enriched record. The
+// record is split where an override covers only part of it, so the override
+// gets a range of its own; the pieces are returned in address order.
+func (b *Builder) applyOverrides(rec *model.Record) []*model.Record {
+	pieces := b.overrides.SplitRange(rec, b.buildTime)
+	for _, piece := range pieces {
+		if b.overrides.ApplyRange(piece, b.buildTime) {
+			b.mu.Lock()
+			b.stats.OverridesApplied++
+			b.mu.Unlock()
+		}
+	}
+	return pieces
+}
+
 // openDatabase opens or creates the LevelDB database
 func (b *Builder) openDatabase() error {
 	db, err := iporgdb.Open(b.cfg.DBPath)
@@ -239,7 +389,7 @@ func (b *Builder) openDatabase() error {
 		return err
 	}
 	b.db = db
-	log.Printf("INFO: Opened database at %s", b.cfg.DBPath)
+	slog.Info("Opened database", "path", b.cfg.DBPath)
 	return nil
 }
 
@@ -250,34 +400,68 @@ func (b *Builder) openMaxMind() error {
 		return err
 	}
 	b.maxmind = readers
-	log.Println("INFO: Opened MaxMind databases")
+	slog.Info("Opened MaxMind databases")
 	return nil
 }
 
 // initializeClients initializes API clients
-func (b *Builder) initializeClients() {
+func (b *Builder) initializeClients() error {
+	transport, err := httprecord.NewTransport(b.cfg.HTTPRecordDir, b.cfg.HTTPReplayDir, nil)
+	if err != nil {
+		return err
+	}
+	ripeRate, rdapRate := 10.0, b.cfg.RDAPRateLimit // 10 req/s for RIPEstat
+	if replayer, ok := transport.(*httprecord.Replayer); ok {
+		// Replayed responses come from disk and need no pacing
+		b.replayer = replayer
+		ripeRate, rdapRate = 0, 0
+		slog.Info("Replaying HTTP traffic", "dir", b.cfg.HTTPReplayDir)
+	} else if b.cfg.HTTPRecordDir != "" {
+		slog.Info("Recording HTTP traffic", "dir", b.cfg.HTTPRecordDir)
+	}
+
 	// RIPE client
-	b.ripeClient = ripe.NewClient(
+	ripeClient := ripe.NewClient(
 		b.cfg.RIPEBaseURL,
 		b.cfg.UserAgent,
-		10.0, // 10 req/s for RIPEstat
+		ripeRate,
 	)
+	ripeClient.SetTransport(transport)
 
 	// RDAP client with caching
 	rdapClient := rdap.NewClient(
 		b.cfg.RDAPBootstrapURL,
 		b.cfg.UserAgent,
-		b.cfg.RDAPRateLimit,
+		rdapRate,
 	)
-	b.rdapClient = rdap.NewCachedClient(rdapClient, b.db, b.cfg.CacheTTL)
+	rdapClient.SetTransport(transport)
+	cacheDB := b.db
+	if b.cfg.RDAPCacheDBPath != "" {
+		db, err := iporgdb.Open(b.cfg.RDAPCacheDBPath)
+		if err != nil {
+			return fmt.Errorf("failed to open RDAP cache database: %w", err)
+		}
+		b.cacheDB = db
+		cacheDB = db
+		slog.Info("Using RDAP cache", "path", b.cfg.RDAPCacheDBPath)
+	}
+	cachedClient := rdap.NewCachedClient(rdapClient, cacheDB, b.cfg.CacheTTL)
+	cachedClient.SetNegativeTTL(b.cfg.NegativeTTL)
+
+	// The metrics endpoint may read the clients concurrently
+	b.mu.Lock()
+	b.ripeClient = ripeClient
+	b.rdapClient = cachedClient
+	b.mu.Unlock()
 
-	log.Println("INFO: Initialized API clients")
+	slog.Info("Initialized API clients")
+	return nil
 }
 
 // openRIPEBulk opens the RIPE bulk database (optional)
 func (b *Builder) openRIPEBulk() error {
 	if b.cfg.RIPEBulkDBPath == "" {
-		log.Println("INFO: RIPE bulk database not configured (will use RDAP for RIPE region)")
+		slog.Info("RIPE bulk database not configured (will use RDAP for RIPE region)")
 		return nil
 	}
 
@@ -288,15 +472,15 @@ func (b *Builder) openRIPEBulk() error {
 	}
 
 	b.ripeBulkDB = db
-	log.Printf("INFO: Using 1GB cache for RIPE bulk database")
+	slog.Info("Using 1GB cache for RIPE bulk database")
 
 	// Log metadata
 	meta, err := db.GetMetadata()
 	if err != nil {
-		log.Printf("WARN: Failed to read RIPE bulk metadata: %v", err)
+		slog.Warn("Failed to read RIPE bulk metadata", "err", err)
 	} else {
-		log.Printf("INFO: Opened RIPE bulk database: %d inetnums, %d orgs (built %s)",
-			meta.InetnumCount, meta.OrgCount, meta.BuildTime.Format("2006-01-02"))
+		slog.Info("Opened RIPE bulk database", "inetnums", meta.InetnumCount, "orgs", meta.OrgCount,
+			"built", meta.BuildTime.Format("2006-01-02"))
 	}
 
 	return nil
@@ -305,7 +489,7 @@ func (b *Builder) openRIPEBulk() error {
 // openARINBulk opens the ARIN bulk database (optional)
 func (b *Builder) openARINBulk() error {
 	if b.cfg.ARINBulkDBPath == "" {
-		log.Println("INFO: ARIN bulk database not configured (will use RDAP for ARIN region)")
+		slog.Info("ARIN bulk database not configured (will use RDAP for ARIN region)")
 		return nil
 	}
 
@@ -316,15 +500,15 @@ func (b *Builder) openARINBulk() error {
 	}
 
 	b.arinBulkDB = db
-	log.Printf("INFO: Using 1GB cache for ARIN bulk database")
+	slog.Info("Using 1GB cache for ARIN bulk database")
 
 	// Log metadata
 	meta, err := db.GetMetadata()
 	if err != nil {
-		log.Printf("WARN: Failed to read ARIN bulk metadata: %v", err)
+		slog.Warn("Failed to read ARIN bulk metadata", "err", err)
 	} else {
-		log.Printf("INFO: Opened ARIN bulk database: %d networks, %d orgs (built %s)",
-			meta.NetBlockCount, meta.OrgCount, meta.BuildTime.Format("2006-01-02"))
+		slog.Info("Opened ARIN bulk database", "networks", meta.NetBlockCount, "orgs", meta.OrgCount,
+			"built", meta.BuildTime.Format("2006-01-02"))
 	}
 
 	return nil
@@ -346,10 +530,9 @@ func (b *Builder) openIPtoASN() error {
 	// Log stats
 	stats, err := store.GetStats()
 	if err != nil {
-		log.Printf("WARN: Failed to read iptoasn stats: %v", err)
+		slog.Warn("Failed to read iptoasn stats", "err", err)
 	} else {
-		log.Printf("INFO: Opened iptoasn database: %d prefixes, %d unique ASNs",
-			stats.TotalPrefixes, stats.UniqueASNs)
+		slog.Info("Opened iptoasn database", "prefixes", stats.TotalPrefixes, "asns", stats.UniqueASNs)
 	}
 
 	return nil
@@ -357,10 +540,10 @@ func (b *Builder) openIPtoASN() error {
 
 // fetchAnnouncedPrefixes fetches all announced prefixes for the given ASNs
 func (b *Builder) fetchAnnouncedPrefixes(ctx context.Context, asns []int) ([]string, error) {
-	log.Printf("INFO: Fetching announced prefixes for %d ASNs...", len(asns))
+	slog.Info("Fetching announced prefixes", "asns", len(asns))
 
 	if b.cfg.IPv4Only {
-		log.Println("INFO: IPv4-only mode enabled - skipping IPv6 prefixes")
+		slog.Info("IPv4-only mode enabled - skipping IPv6 prefixes")
 	}
 
 	asnPrefixes, err := b.ripeClient.FetchAnnouncedPrefixesForASNs(ctx, asns, b.cfg.Workers)
@@ -373,7 +556,9 @@ func (b *Builder) fetchAnnouncedPrefixes(ctx context.Context, asns []int) ([]str
 	var skippedIPv6 int
 
 	for asn, prefixes := range asnPrefixes {
+		b.mu.Lock()
 		b.stats.ASNsProcessed++
+		b.mu.Unlock()
 		var ipv4Count, ipv6Count int
 
 		for _, prefix := range prefixes {
@@ -392,16 +577,18 @@ func (b *Builder) fetchAnnouncedPrefixes(ctx context.Context, asns []int) ([]str
 			}
 		}
 
+		b.mu.Lock()
 		b.stats.PrefixesFetched += len(prefixes)
+		b.mu.Unlock()
 		if b.cfg.IPv4Only {
-			log.Printf("INFO: AS%d: %d IPv4 prefixes (%d IPv6 skipped)", asn, ipv4Count, ipv6Count)
+			slog.Info("Fetched prefixes", "asn", asn, "ipv4", ipv4Count, "ipv6_skipped", ipv6Count)
 		} else {
-			log.Printf("INFO: AS%d: %d IPv4, %d IPv6 prefixes", asn, ipv4Count, ipv6Count)
+			slog.Info("Fetched prefixes", "asn", asn, "ipv4", ipv4Count, "ipv6", ipv6Count)
 		}
 	}
 
 	if skippedIPv6 > 0 {
-		log.Printf("INFO: Skipped %d IPv6 prefixes (IPv4-only mode)", skippedIPv6)
+		slog.Info("Skipped IPv6 prefixes (IPv4-only mode)", "prefixes", skippedIPv6)
 	}
 
 	// Convert to slice
@@ -415,30 +602,32 @@ func (b *Builder) fetchAnnouncedPrefixes(ctx context.Context, asns []int) ([]str
 
 // fetchAnnouncedPrefixesFromIPtoASN fetches prefixes from iptoasn database
 func (b *Builder) fetchAnnouncedPrefixesFromIPtoASN(ctx context.Context, asns []int) ([]string, error) {
-	log.Printf("INFO: Fetching prefixes for %d ASNs from iptoasn database...", len(asns))
+	slog.Info("Fetching prefixes from iptoasn database", "asns", len(asns))
 
 	if b.iptoasnStore == nil {
 		return nil, fmt.Errorf("iptoasn database is not open")
 	}
 
 	if b.cfg.IPv4Only {
-		log.Println("INFO: IPv4-only mode enabled - skipping IPv6 prefixes")
+		slog.Info("IPv4-only mode enabled - skipping IPv6 prefixes")
 	}
 
 	if b.cfg.BulkOnly {
-		log.Println("INFO: Bulk-only mode enabled - will skip prefixes without bulk coverage during enrichment")
+		slog.Info("Bulk-only mode enabled - will skip prefixes without bulk coverage during enrichment")
 	}
 
 	// Fetch prefixes for each ASN
 	prefixSet := make(map[string]bool)
 
 	for _, asn := range asns {
+		b.mu.Lock()
 		b.stats.ASNsProcessed++
+		b.mu.Unlock()
 
 		// Get prefixes for this ASN (raw, not collapsed)
 		prefixes, err := b.iptoasnStore.ListByASN(ctx, asn, false)
 		if err == model.ErrNotFound {
-			log.Printf("WARN: AS%d not found in iptoasn database", asn)
+			slog.Warn("ASN not found in iptoasn database", "asn", asn)
 			continue
 		}
 		if err != nil {
@@ -465,11 +654,13 @@ func (b *Builder) fetchAnnouncedPrefixesFromIPtoASN(ctx context.Context, asns []
 			}
 		}
 
+		b.mu.Lock()
 		b.stats.PrefixesFetched += len(prefixes)
+		b.mu.Unlock()
 		if b.cfg.IPv4Only {
-			log.Printf("INFO: AS%d: %d IPv4 prefixes (%d IPv6 skipped)", asn, ipv4Count, ipv6Count)
+			slog.Info("Fetched prefixes", "asn", asn, "ipv4", ipv4Count, "ipv6_skipped", ipv6Count)
 		} else {
-			log.Printf("INFO: AS%d: %d IPv4, %d IPv6 prefixes", asn, ipv4Count, ipv6Count)
+			slog.Info("Fetched prefixes", "asn", asn, "ipv4", ipv4Count, "ipv6", ipv6Count)
 		}
 	}
 
@@ -479,10 +670,25 @@ func (b *Builder) fetchAnnouncedPrefixesFromIPtoASN(ctx context.Context, asns []
 		allPrefixes = append(allPrefixes, prefix)
 	}
 
-	log.Printf("INFO: Total unique prefixes from iptoasn: %d", len(allPrefixes))
+	slog.Info("Total unique prefixes from iptoasn", "prefixes", len(allPrefixes))
 	return allPrefixes, nil
 }
 
+// mergePrefixes appends the prefixes in extra that are not already in prefixes
+func mergePrefixes(prefixes, extra []string) []string {
+	seen := make(map[string]bool, len(prefixes))
+	for _, prefix := range prefixes {
+		seen[prefix] = true
+	}
+	for _, prefix := range extra {
+		if !seen[prefix] {
+			seen[prefix] = true
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
 // isIPv6Prefix checks if a CIDR prefix is IPv6
 func isIPv6Prefix(prefix string) bool {
 	return strings.Contains(prefix, ":")
@@ -490,18 +696,25 @@ func isIPv6Prefix(prefix string) bool {
 
 // enrichAndWrite enriches prefixes with org/geo data and writes to database
 func (b *Builder) enrichAndWrite(ctx context.Context, prefixes []string) error {
-	log.Printf("INFO: Enriching and writing %d prefixes...", len(prefixes))
+	slog.Info("Enriching and writing prefixes", "prefixes", len(prefixes))
 
-	// Sort prefixes by specificity (least specific first)
-	// This ensures we process larger blocks first and skip overlapping smaller ones
+	// Sort prefixes by specificity (least specific first); overlaps are
+	// resolved when the records are loaded, so this only affects progress order
 	sortedPrefixes := sortPrefixesBySpecificity(prefixes)
-	log.Printf("INFO: Sorted prefixes by specificity (least to most specific)")
+	slog.Info("Sorted prefixes by specificity (least to most specific)")
 
-	if b.cfg.SplitByMaxMind {
-		log.Printf("INFO: Mode B enabled: splitting by MaxMind city blocks")
-		return b.enrichAndWriteModeB(ctx, sortedPrefixes)
+	if b.cfg.SplitByRegistry {
+		if b.cfg.SplitByMaxMind {
+			slog.Info("Mode C enabled: splitting by registry sub-allocations and MaxMind city blocks")
+		} else {
+			slog.Info("Mode C enabled: splitting by registry sub-allocations")
+		}
+		return b.enrichAndWriteSplit(ctx, sortedPrefixes)
+	} else if b.cfg.SplitByMaxMind {
+		slog.Info("Mode B enabled: splitting by MaxMind city blocks")
+		return b.enrichAndWriteSplit(ctx, sortedPrefixes)
 	} else {
-		log.Printf("INFO: Mode A: one record per announced prefix")
+		slog.Info("Mode A: one record per announced prefix")
 		return b.enrichAndWriteModeA(ctx, sortedPrefixes)
 	}
 }
@@ -519,7 +732,7 @@ func sortPrefixesBySpecificity(prefixes []string) []string {
 	for _, p := range prefixes {
 		prefix, err := netip.ParsePrefix(p)
 		if err != nil {
-			log.Printf("WARN: Failed to parse prefix %s: %v", p, err)
+			slog.Warn("Failed to parse prefix", "prefix", p, "err", err)
 			continue
 		}
 		parsed = append(parsed, prefixWithLen{
@@ -546,144 +759,20 @@ func sortPrefixesBySpecificity(prefixes []string) []string {
 	return result
 }
 
-// tryRIPEBulkLookup attempts to fetch org info from RIPE bulk database
-// Returns nil if RIPE bulk is not configured or IP not found
-func (b *Builder) tryRIPEBulkLookup(ip netip.Addr) *model.RDAPOrg {
-	if b.ripeBulkDB == nil {
-		return nil
-	}
-
-	// IPv6 not supported in RIPE bulk yet
-	if !ip.Is4() {
-		return nil
-	}
-
-	match, err := b.ripeBulkDB.LookupIP(ip)
-	if err != nil || match == nil {
-		// Not found in RIPE region, filtered out, or error
-		return nil
-	}
-
-	// Filter out RIPE's placeholder entries for non-RIPE address space
-	// These are ranges that RIPE tracks but doesn't manage (ARIN, APNIC, etc.)
-	if isRIPEPlaceholder(match.OrgName) {
-		return nil
-	}
-
-	// Convert RIPE bulk match to RDAPOrg format
-	return &model.RDAPOrg{
-		OrgName:     match.OrgName,
-		RIR:         "RIPE",
-		SourceRole:  "ripe_bulk", // Custom source role
-		StatusLabel: match.Status,
-		Country:     match.Country, // Use RIPE's country (more accurate for RIR-managed space)
-	}
-}
-
-// tryRIPEBulkLookupPrefix attempts to fetch org info from RIPE bulk database for a prefix
-// Returns nil if RIPE bulk is not configured or prefix not found
-func (b *Builder) tryRIPEBulkLookupPrefix(prefix netip.Prefix) *model.RDAPOrg {
-	if b.ripeBulkDB == nil {
-		return nil
-	}
-
-	// IPv6 not supported in RIPE bulk yet
-	if !prefix.Addr().Is4() {
-		return nil
-	}
-
-	match, err := b.ripeBulkDB.LookupPrefix(prefix)
-	if err != nil || match == nil {
-		// Not found in RIPE region, filtered out, or error
-		return nil
-	}
-
-	// Filter out RIPE's placeholder entries for non-RIPE address space
-	if isRIPEPlaceholder(match.OrgName) {
-		return nil
-	}
-
-	// Convert RIPE bulk match to RDAPOrg format
-	return &model.RDAPOrg{
-		OrgName:     match.OrgName,
-		RIR:         "RIPE",
-		SourceRole:  "ripe_bulk",
-		StatusLabel: match.Status,
-		Country:     match.Country, // Use RIPE's country (more accurate for RIR-managed space)
-	}
-}
-
-// isRIPEPlaceholder checks if an organization name is a RIPE placeholder
-// for non-RIPE address space (ARIN, APNIC, etc.)
-func isRIPEPlaceholder(orgName string) bool {
-	placeholders := []string{
-		"NON-RIPE-NCC-MANAGED-ADDRESS-BLOCK",
-		"UNALLOCATED",
-		"RESERVED",
+// printRates prints the effective request rate per upstream host
+func (b *Builder) printRates() {
+	rates := b.upstreamRates()
+	if len(rates) == 0 {
+		return
 	}
 
-	for _, placeholder := range placeholders {
-		if orgName == placeholder {
-			return true
+	fmt.Println("Upstream rates:")
+	for _, r := range rates {
+		rate := "unlimited"
+		if r.MaxRate > 0 {
+			rate = fmt.Sprintf("%.1f/%.1f req/s", r.Rate, r.MaxRate)
 		}
-	}
-
-	return false
-}
-
-// tryARINBulkLookup attempts to fetch org info from ARIN bulk database
-// Returns nil if ARIN bulk is not configured or IP not found
-func (b *Builder) tryARINBulkLookup(ip netip.Addr) *model.RDAPOrg {
-	if b.arinBulkDB == nil {
-		return nil
-	}
-
-	// IPv6 not supported in ARIN bulk yet
-	if !ip.Is4() {
-		return nil
-	}
-
-	match, err := b.arinBulkDB.LookupIP(ip)
-	if err != nil || match == nil {
-		// Not found in ARIN region or error
-		return nil
-	}
-
-	// Convert ARIN bulk match to RDAPOrg format
-	return &model.RDAPOrg{
-		OrgName:     match.OrgName,
-		RIR:         "ARIN",
-		SourceRole:  "arin_bulk",
-		StatusLabel: match.NetType,
-		Country:     match.Country, // Use ARIN's country (more accurate for RIR-managed space)
-	}
-}
-
-// tryARINBulkLookupPrefix attempts to fetch org info from ARIN bulk database for a prefix
-// Returns nil if ARIN bulk is not configured or prefix not found
-func (b *Builder) tryARINBulkLookupPrefix(prefix netip.Prefix) *model.RDAPOrg {
-	if b.arinBulkDB == nil {
-		return nil
-	}
-
-	// IPv6 not supported in ARIN bulk yet
-	if !prefix.Addr().Is4() {
-		return nil
-	}
-
-	match, err := b.arinBulkDB.LookupPrefix(prefix)
-	if err != nil || match == nil {
-		// Not found in ARIN region or error
-		return nil
-	}
-
-	// Convert ARIN bulk match to RDAPOrg format
-	return &model.RDAPOrg{
-		OrgName:     match.OrgName,
-		RIR:         "ARIN",
-		SourceRole:  "arin_bulk",
-		StatusLabel: match.NetType,
-		Country:     match.Country, // Use ARIN's country (more accurate for RIR-managed space)
+		fmt.Printf("  %-30s %s, %d requests, %d throttled\n", r.Host, rate, r.Requests, r.Throttled)
 	}
 }
 
@@ -699,72 +788,55 @@ func (b *Builder) printSummary() {
 	fmt.Printf("Prefixes fetched:       %d\n", b.stats.PrefixesFetched)
 	fmt.Printf("Prefixes processed:     %d\n", b.stats.PrefixesProcessed)
 	fmt.Printf("Records written:        %d\n", b.stats.RecordsWritten)
-	fmt.Printf("Records updated:        %d\n", b.stats.RecordsUpdated)
+	fmt.Printf("Records trimmed:        %d\n", b.stats.RecordsTrimmed)
 	fmt.Printf("Records skipped:        %d\n", b.stats.RecordsSkipped)
+	fmt.Printf("Overlap conflicts:      %d\n", b.stats.Conflicts)
 	if b.ripeBulkDB != nil {
 		fmt.Printf("RIPE bulk hits:         %d\n", b.stats.RIPEBulkHits)
 	}
 	if b.arinBulkDB != nil {
 		fmt.Printf("ARIN bulk hits:         %d\n", b.stats.ARINBulkHits)
 	}
-	fmt.Printf("RDAP cache hits:        %d\n", b.stats.RDAPCacheHits)
-	fmt.Printf("RDAP cache misses:      %d\n", b.stats.RDAPCacheMisses)
+	if b.overrides != nil {
+		fmt.Printf("Overrides applied:      %d\n", b.stats.OverridesApplied)
+	}
+	if b.cfg.FillRegistered {
+		fmt.Printf("Registered ranges:      %d\n", b.stats.RegisteredFilled)
+	}
+	if b.rdapClient != nil {
+		cache := b.rdapClient.Stats()
+		fmt.Printf("RDAP cache hits:        %d (%d negative)\n", cache.Hits, cache.NegativeHits)
+		fmt.Printf("RDAP cache misses:      %d\n", cache.Misses)
+		if cache.Stale > 0 {
+			fmt.Printf("RDAP stale cache used:  %d\n", cache.Stale)
+		}
+	}
 	fmt.Printf("Errors:                 %d\n", b.stats.Errors)
 
+	b.printRates()
+
 	// Print timing breakdown
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println("TIMING BREAKDOWN")
 	fmt.Println(strings.Repeat("=", 60))
 
-	// Convert atomic nanoseconds to Duration for reporting
-	timeMaxMindASN := time.Duration(atomic.LoadInt64(&b.stats.TimeMaxMindASNNanos))
-	timeMaxMindGeo := time.Duration(atomic.LoadInt64(&b.stats.TimeMaxMindGeoNanos))
-	timeRIPEBulk := time.Duration(atomic.LoadInt64(&b.stats.TimeRIPEBulkNanos))
-	timeARINBulk := time.Duration(atomic.LoadInt64(&b.stats.TimeARINBulkNanos))
-	timeRDAP := time.Duration(atomic.LoadInt64(&b.stats.TimeRDAPNanos))
-	timeDBWrite := time.Duration(atomic.LoadInt64(&b.stats.TimeDBWriteNanos))
-
-	callsMaxMindASN := atomic.LoadInt64(&b.stats.CallsMaxMindASN)
-	callsMaxMindGeo := atomic.LoadInt64(&b.stats.CallsMaxMindGeo)
-	callsRIPEBulk := atomic.LoadInt64(&b.stats.CallsRIPEBulk)
-	callsARINBulk := atomic.LoadInt64(&b.stats.CallsARINBulk)
-	callsRDAP := atomic.LoadInt64(&b.stats.CallsRDAP)
-	callsDBWrite := atomic.LoadInt64(&b.stats.CallsDBWrite)
-
 	// Calculate total accumulated work time (across all parallel workers)
-	totalWork := timeMaxMindASN + timeMaxMindGeo + timeRIPEBulk + timeARINBulk + timeRDAP + timeDBWrite
+	var totalWork time.Duration
+	for _, t := range timingLabels {
+		spent, _ := b.stats.Timings[t.key].load()
+		totalWork += spent
+	}
 	parallelismFactor := totalWork.Seconds() / elapsed.Seconds()
 
-	fmt.Printf("MaxMind ASN lookups:    %s (%.1f%% of work) - %d calls, %.2fms avg\n",
-		timeMaxMindASN.Round(time.Millisecond),
-		100*timeMaxMindASN.Seconds()/totalWork.Seconds(),
-		callsMaxMindASN,
-		float64(timeMaxMindASN.Microseconds())/float64(callsMaxMindASN)/1000.0)
-	fmt.Printf("MaxMind Geo lookups:    %s (%.1f%% of work) - %d calls, %.2fms avg\n",
-		timeMaxMindGeo.Round(time.Millisecond),
-		100*timeMaxMindGeo.Seconds()/totalWork.Seconds(),
-		callsMaxMindGeo,
-		float64(timeMaxMindGeo.Microseconds())/float64(callsMaxMindGeo)/1000.0)
-	fmt.Printf("RIPE bulk lookups:      %s (%.1f%% of work) - %d calls, %.2fms avg\n",
-		timeRIPEBulk.Round(time.Millisecond),
-		100*timeRIPEBulk.Seconds()/totalWork.Seconds(),
-		callsRIPEBulk,
-		float64(timeRIPEBulk.Microseconds())/float64(callsRIPEBulk)/1000.0)
-	fmt.Printf("ARIN bulk lookups:      %s (%.1f%% of work) - %d calls, %.2fms avg\n",
-		timeARINBulk.Round(time.Millisecond),
-		100*timeARINBulk.Seconds()/totalWork.Seconds(),
-		callsARINBulk,
-		float64(timeARINBulk.Microseconds())/float64(callsARINBulk)/1000.0)
-	fmt.Printf("RDAP lookups:           %s (%.1f%% of work) - %d calls, %.2fms avg\n",
-		timeRDAP.Round(time.Millisecond),
-		100*timeRDAP.Seconds()/totalWork.Seconds(),
-		callsRDAP,
-		float64(timeRDAP.Microseconds())/float64(callsRDAP)/1000.0)
-	fmt.Printf("Database writes:        %s (%.1f%% of work) - %d calls, %.2fms avg\n",
-		timeDBWrite.Round(time.Millisecond),
-		100*timeDBWrite.Seconds()/totalWork.Seconds(),
-		callsDBWrite,
-		float64(timeDBWrite.Microseconds())/float64(callsDBWrite)/1000.0)
+	for _, t := range timingLabels {
+		spent, calls := b.stats.Timings[t.key].load()
+		fmt.Printf("%-24s%s (%.1f%% of work) - %d calls, %.2fms avg\n",
+			t.label+":",
+			spent.Round(time.Millisecond),
+			100*spent.Seconds()/totalWork.Seconds(),
+			calls,
+			float64(spent.Microseconds())/float64(calls)/1000.0)
+	}
 
 	fmt.Printf("\nTotal work time:        %s (%.1fx parallelism)\n",
 		totalWork.Round(time.Millisecond),
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/sources/rdap"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+)
+
+// cacheLine is one RDAP cache entry in an export file
+type cacheLine struct {
+	Key   string           `json:"key"`
+	Entry *rdap.CacheEntry `json:"entry"`
+}
+
+func cacheCmd() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Usage: iporg-build cache <prune|stats|export|import> [options]")
+		os.Exit(1)
+	}
+	sub := os.Args[2]
+
+	fs := flag.NewFlagSet("cache "+sub, flag.ExitOnError)
+	dbPath := fs.String("db", "./iporgdb", "Database holding the RDAP cache (the build --db or --rdap-cache-db)")
+	ttl := fs.Duration("cache-ttl", 168*time.Hour, "Cache TTL for RDAP results")
+	negativeTTL := fs.Duration("negative-cache-ttl", rdap.DefaultNegativeTTL, "Cache TTL for RDAP \"no data\" results")
+	output := fs.String("output", "", "Export file (JSONL, default: stdout)")
+	input := fs.String("input", "", "Import file (JSONL, default: stdin)")
+	var logFlags logging.Flags
+	logFlags.Register(fs)
+	fs.Parse(os.Args[3:])
+	setupLogging(&logFlags)
+
+	db, err := iporgdb.Open(*dbPath)
+	if err != nil {
+		logging.Fatal("Failed to open database", "err", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch sub {
+	case "prune":
+		removed, err := rdap.PruneCache(ctx, db, time.Now(), *ttl, *negativeTTL)
+		if err != nil {
+			logging.Fatal("Prune failed", "err", err)
+		}
+		slog.Info("Removed expired RDAP cache entries", "count", removed)
+	case "stats":
+		if err := printCacheStats(db, *ttl, *negativeTTL); err != nil {
+			logging.Fatal("Cache stats failed", "err", err)
+		}
+	case "export":
+		if err := exportCache(db, *output); err != nil {
+			logging.Fatal("Export failed", "err", err)
+		}
+	case "import":
+		if err := importCache(db, *input); err != nil {
+			logging.Fatal("Import failed", "err", err)
+		}
+	default:
+		logging.Fatal("Unknown cache command (want prune, stats, export or import)", "command", sub)
+	}
+}
+
+// printCacheStats summarizes the RDAP cache contents
+func printCacheStats(db *iporgdb.DB, ttl, negativeTTL time.Duration) error {
+	now := time.Now()
+	var total, negative, expired, invalid, ipKeys int
+	var oldest, newest time.Time
+
+	err := db.IterateCache(rdap.CacheCategory, func(key string, value []byte) error {
+		total++
+		var entry rdap.CacheEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			invalid++
+			return nil
+		}
+		if entry.Negative {
+			negative++
+		}
+		if entry.Expired(now, ttl, negativeTTL) {
+			expired++
+		}
+		if strings.HasPrefix(key, "ip:") {
+			ipKeys++
+		}
+		if oldest.IsZero() || entry.FetchedAt.Before(oldest) {
+			oldest = entry.FetchedAt
+		}
+		if entry.FetchedAt.After(newest) {
+			newest = entry.FetchedAt
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println("RDAP CACHE STATISTICS")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("Entries:                %d\n", total)
+	fmt.Printf("  Prefix keys:          %d\n", total-ipKeys)
+	fmt.Printf("  IP keys:              %d\n", ipKeys)
+	fmt.Printf("Negative entries:       %d\n", negative)
+	fmt.Printf("Expired entries:        %d (TTL %s, negative TTL %s)\n", expired, ttl, negativeTTL)
+	if invalid > 0 {
+		fmt.Printf("Undecodable entries:    %d\n", invalid)
+	}
+	if !oldest.IsZero() {
+		fmt.Printf("Oldest entry:           %s\n", oldest.Format("2006-01-02 15:04:05 MST"))
+		fmt.Printf("Newest entry:           %s\n", newest.Format("2006-01-02 15:04:05 MST"))
+	}
+	fmt.Println(strings.Repeat("=", 60))
+	return nil
+}
+
+// exportCache writes every RDAP cache entry as a JSON line
+func exportCache(db *iporgdb.DB, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	count := 0
+	err := db.IterateCache(rdap.CacheCategory, func(key string, value []byte) error {
+		var entry rdap.CacheEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			slog.Warn("Skipping undecodable cache entry", "key", key, "err", err)
+			return nil
+		}
+		count++
+		return enc.Encode(cacheLine{Key: key, Entry: &entry})
+	})
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	slog.Info("Exported RDAP cache entries", "count", count)
+	return nil
+}
+
+// importCache merges exported entries, keeping whichever copy of a key was
+// fetched most recently
+func importCache(db *iporgdb.DB, path string) error {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var imported, skipped, lineNum int
+	for scanner.Scan() {
+		lineNum++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var line cacheLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil || line.Key == "" || line.Entry == nil {
+			return fmt.Errorf("invalid cache entry on line %d", lineNum)
+		}
+
+		var existing rdap.CacheEntry
+		if err := db.GetCache(rdap.CacheCategory, line.Key, &existing); err == nil &&
+			!existing.FetchedAt.Before(line.Entry.FetchedAt) {
+			skipped++
+			continue
+		}
+		if err := db.SetCache(rdap.CacheCategory, line.Key, line.Entry); err != nil {
+			return err
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	slog.Info("Imported RDAP cache entries", "count", imported, "skipped_older", skipped)
+	return nil
+}
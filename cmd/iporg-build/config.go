@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// configFile is the on-disk layout of a build configuration file
+type configFile struct {
+	Defaults json.RawMessage            `json:"defaults"`
+	Profiles map[string]json.RawMessage `json:"profiles"`
+}
+
+// loadConfigFile applies the defaults from path and then the named profile
+// (if any) over cfg. Keys that are absent leave cfg unchanged.
+func loadConfigFile(path, profile string, cfg *model.BuildConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file configFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if len(file.Defaults) > 0 {
+		if err := applyConfig(file.Defaults, cfg); err != nil {
+			return fmt.Errorf("%s: defaults: %w", path, err)
+		}
+	}
+
+	if profile != "" {
+		raw, ok := file.Profiles[profile]
+		if !ok {
+			return fmt.Errorf("%s: unknown profile %q (available: %s)", path, profile, profileNames(file.Profiles))
+		}
+		if err := applyConfig(raw, cfg); err != nil {
+			return fmt.Errorf("%s: profile %s: %w", path, profile, err)
+		}
+		cfg.Profile = profile
+	}
+
+	return nil
+}
+
+// applyConfig decodes one config object over cfg, rejecting unknown keys
+func applyConfig(raw json.RawMessage, cfg *model.BuildConfig) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return err
+	}
+	known := configKeys()
+	for key := range keys {
+		if !known[key] {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+	}
+	return json.Unmarshal(raw, cfg)
+}
+
+// configKeys returns the JSON keys accepted in a config object
+func configKeys() map[string]bool {
+	keys := map[string]bool{"cache_ttl": true, "negative_cache_ttl": true}
+	t := reflect.TypeOf(model.BuildConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && name != "profile" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// profileNames lists the profiles in a config file
+func profileNames(profiles map[string]json.RawMessage) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
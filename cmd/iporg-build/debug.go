@@ -6,7 +6,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
 	"sort"
 
@@ -40,14 +40,14 @@ func RunDebug(ctx context.Context, ip, asnPath, cityPath, dbPath string, asn int
 		fmt.Println("--- MaxMind Lookup ---")
 		mm, err := maxmind.Open(asnPath, cityPath)
 		if err != nil {
-			log.Printf("ERROR: Failed to open MaxMind: %v", err)
+			slog.Error("Failed to open MaxMind", "err", err)
 		} else {
 			defer mm.Close()
 
 			// ASN lookup
 			asn, asnName, err := mm.ASNInfo(parsedIP)
 			if err != nil {
-				log.Printf("ERROR: MaxMind ASN lookup failed: %v", err)
+				slog.Error("MaxMind ASN lookup failed", "err", err)
 			} else {
 				fmt.Printf("ASN: AS%d (%s)\n", asn, asnName)
 			}
@@ -55,7 +55,7 @@ func RunDebug(ctx context.Context, ip, asnPath, cityPath, dbPath string, asn int
 			// Geo lookup
 			geo, err := mm.Geo(parsedIP)
 			if err != nil {
-				log.Printf("ERROR: MaxMind Geo lookup failed: %v", err)
+				slog.Error("MaxMind Geo lookup failed", "err", err)
 			} else {
 				fmt.Printf("Country: %s\n", geo.Country)
 				if geo.City != "" {
@@ -71,7 +71,7 @@ func RunDebug(ctx context.Context, ip, asnPath, cityPath, dbPath string, asn int
 	rdapClient := rdap.NewClient("https://rdap.db.ripe.net", "iporg-debug/1.0", 5.0)
 	rdapResp, err := rdapClient.QueryIP(ctx, parsedIP)
 	if err != nil {
-		log.Printf("ERROR: RDAP query failed: %v", err)
+		slog.Error("RDAP query failed", "err", err)
 	} else if rdapResp != nil {
 		fmt.Printf("Network Name: %s\n", rdapResp.Name)
 		fmt.Printf("Type: %s\n", rdapResp.Type)
@@ -135,7 +135,7 @@ func RunDebug(ctx context.Context, ip, asnPath, cityPath, dbPath string, asn int
 		client := ripe.NewClient(ripeBase, "iporg-debug/1.0", 10.0)
 		prefixes, err := client.AnnouncedPrefixes(ctx, asn)
 		if err != nil {
-			log.Printf("ERROR: Failed to fetch announced prefixes: %v", err)
+			slog.Error("Failed to fetch announced prefixes", "err", err)
 		} else {
 			fmt.Printf("Total prefixes: %d\n", len(prefixes))
 
@@ -181,7 +181,7 @@ func RunDebug(ctx context.Context, ip, asnPath, cityPath, dbPath string, asn int
 		fmt.Println("--- Database Lookup ---")
 		db, err := iporgdb.Open(dbPath)
 		if err != nil {
-			log.Printf("ERROR: Failed to open database: %v", err)
+			slog.Error("Failed to open database", "err", err)
 		} else {
 			defer db.Close()
 
@@ -210,7 +210,7 @@ func RunDebug(ctx context.Context, ip, asnPath, cityPath, dbPath string, asn int
 			fmt.Println("\nDatabase statistics:")
 			ipv4, ipv6, err := db.CountRanges()
 			if err != nil {
-				log.Printf("ERROR: Failed to count ranges: %v", err)
+				slog.Error("Failed to count ranges", "err", err)
 			} else {
 				fmt.Printf("IPv4 ranges: %d\n", ipv4)
 				fmt.Printf("IPv6 ranges: %d\n", ipv6)
@@ -245,7 +245,7 @@ func showNearbyRanges(db *iporgdb.DB, ip netip.Addr) {
 	})
 
 	if err != nil {
-		log.Printf("ERROR: Failed to iterate ranges: %v", err)
+		slog.Error("Failed to iterate ranges", "err", err)
 		return
 	}
 
@@ -6,63 +6,154 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
-	"strings"
-	"sync"
+	"slices"
 	"sync/atomic"
 	"time"
 
+	"github.com/wingedpig/iporg/pkg/enrich"
 	"github.com/wingedpig/iporg/pkg/model"
 	"github.com/wingedpig/iporg/pkg/sources/maxmind"
-	"github.com/wingedpig/iporg/pkg/sources/rdap"
 	"github.com/wingedpig/iporg/pkg/util/ipcodec"
 	"github.com/wingedpig/iporg/pkg/util/workers"
 )
 
+// initPipeline wires the configured sources into the enrichment pipeline
+func (b *Builder) initPipeline() error {
+	prec := enrich.DefaultPrecedence()
+	if len(b.cfg.Precedence) > 0 {
+		prec = prec.Merge(enrich.Precedence(b.cfg.Precedence))
+	}
+
+	sources := []enrich.Enricher{enrich.NewMaxMind(b.maxmind)}
+	if b.ripeBulkDB != nil {
+		sources = append(sources, enrich.NewRIPEBulk(b.ripeBulkDB))
+	}
+	if b.arinBulkDB != nil {
+		sources = append(sources, enrich.NewARINBulk(b.arinBulkDB))
+	}
+	// In bulk-only mode records without bulk coverage are dropped, so RDAP would never be used
+	if !b.cfg.BulkOnly {
+		sources = append(sources, enrich.NewRDAP(b.rdapClient))
+	}
+
+	pipeline, err := enrich.NewPipeline(prec, b.observe, sources...)
+	if err != nil {
+		return err
+	}
+	b.pipeline = pipeline
+	slog.Info("Source precedence", "precedence", prec)
+	return nil
+}
+
+// observe records timing and hit statistics for each source lookup
+func (b *Builder) observe(source string, q enrich.Query, elapsed time.Duration, res *enrich.Result, err error) {
+	if t := b.stats.Timings[source]; t != nil {
+		t.add(elapsed)
+	}
+	b.metrics.ObserveSource(source, elapsed, res != nil, err)
+
+	switch source {
+	case enrich.SourceRIPEBulk:
+		if res != nil {
+			b.mu.Lock()
+			b.stats.RIPEBulkHits++
+			// Log first few hits, then every 100th
+			if b.stats.RIPEBulkHits <= 5 || b.stats.RIPEBulkHits%100 == 0 {
+				slog.Debug("RIPE bulk hit", "hits", b.stats.RIPEBulkHits, "prefix", q.Prefix, "org", res.OrgName)
+			}
+			b.mu.Unlock()
+		}
+	case enrich.SourceARINBulk:
+		if res != nil {
+			b.mu.Lock()
+			b.stats.ARINBulkHits++
+			if b.stats.ARINBulkHits <= 5 || b.stats.ARINBulkHits%100 == 0 {
+				slog.Debug("ARIN bulk hit", "hits", b.stats.ARINBulkHits, "prefix", q.Prefix, "org", res.OrgName)
+			}
+			b.mu.Unlock()
+		}
+	case enrich.SourceRDAP:
+		// Cache hits and misses are counted by the cached client
+		if err != nil {
+			slog.Warn("RDAP lookup failed", "prefix", q.Prefix, "err", err)
+			b.countError()
+		}
+	}
+}
+
+// enrichRecord fills rec from the source pipeline and applies overrides.
+// It returns the records to write, which are more than one if an override
+// splits rec, or nil if the record should be skipped.
+func (b *Builder) enrichRecord(ctx context.Context, q enrich.Query, rec *model.Record) []*model.Record {
+	trace := b.pipeline.Run(ctx, q, rec)
+
+	// Skip if bulk-only mode and no bulk coverage
+	if b.cfg.BulkOnly && !trace.Matched(enrich.SourceRIPEBulk, enrich.SourceARINBulk) {
+		b.mu.Lock()
+		b.stats.RecordsSkipped++
+		b.mu.Unlock()
+		return nil
+	}
+
+	return b.applyOverrides(rec)
+}
+
+// writeRecords queues records for the bulk load
+func (b *Builder) writeRecords(recs []*model.Record) error {
+	for _, rec := range recs {
+		if err := b.writeRecord(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countError increments the error counter
+func (b *Builder) countError() {
+	b.mu.Lock()
+	b.stats.Errors++
+	b.mu.Unlock()
+}
+
 // enrichAndWriteModeA processes prefixes in Mode A (one record per prefix)
 func (b *Builder) enrichAndWriteModeA(ctx context.Context, prefixes []string) error {
-	// Create worker pool
+	// Create worker pool; Submit blocks once the queue is full
 	pool := workers.NewPool(ctx, workers.Config{
 		Workers:   b.cfg.Workers,
 		RateLimit: 0, // Rate limiting handled by individual clients
 	})
 
-	var mu sync.Mutex
 	totalPrefixes := len(prefixes)
 
 	for i, prefix := range prefixes {
 		idx := i
 		currentPrefix := prefix
 
-		pool.Submit(idx, func(ctx context.Context) error {
+		err := pool.Submit(idx, func(ctx context.Context) error {
 			// Normalize prefix
 			normalized, err := ipcodec.NormalizePrefix(currentPrefix)
 			if err != nil {
-				log.Printf("ERROR: Invalid prefix %s: %v", currentPrefix, err)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
+				slog.Error("Invalid prefix", "prefix", currentPrefix, "err", err)
+				b.countError()
 				return nil
 			}
+			parsedPrefix := netip.MustParsePrefix(normalized)
 
 			// Get start and end IPs
 			start, end, err := ipcodec.CIDRToRange(normalized)
 			if err != nil {
-				log.Printf("ERROR: Failed to parse prefix %s: %v", normalized, err)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
+				slog.Error("Failed to parse prefix", "prefix", normalized, "err", err)
+				b.countError()
 				return nil
 			}
 
 			// Get representative IP for lookups
 			repIP, err := ipcodec.RepresentativeIP(normalized)
 			if err != nil {
-				log.Printf("ERROR: Failed to get representative IP for %s: %v", normalized, err)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
+				slog.Error("Failed to get representative IP", "prefix", normalized, "err", err)
+				b.countError()
 				return nil
 			}
 
@@ -71,251 +162,85 @@ func (b *Builder) enrichAndWriteModeA(ctx context.Context, prefixes []string) er
 				Start:       start,
 				End:         end,
 				Prefix:      normalized,
-				LastChecked: time.Now(),
+				LastChecked: b.buildTime,
 				Schema:      1,
 			}
 
-			// Enrich with MaxMind ASN
-			tStart := time.Now()
-			asn, asnName, err := b.maxmind.ASNInfo(repIP)
-			atomic.AddInt64(&b.stats.TimeMaxMindASNNanos, time.Since(tStart).Nanoseconds())
-			atomic.AddInt64(&b.stats.CallsMaxMindASN, 1)
-			if err != nil {
-				log.Printf("WARN: MaxMind ASN lookup failed for %s: %v", normalized, err)
-				// Continue without ASN info
-			} else {
-				rec.ASN = asn
-				rec.ASNName = asnName
-			}
-
-			// Enrich with MaxMind Geo
-			tStart = time.Now()
-			geo, err := b.maxmind.Geo(repIP)
-			atomic.AddInt64(&b.stats.TimeMaxMindGeoNanos, time.Since(tStart).Nanoseconds())
-			atomic.AddInt64(&b.stats.CallsMaxMindGeo, 1)
-			if err != nil {
-				log.Printf("WARN: MaxMind geo lookup failed for %s: %v", normalized, err)
-			} else {
-				rec.Country = geo.Country
-				rec.Region = geo.Region
-				rec.City = geo.City
-				rec.Lat = geo.Lat
-				rec.Lon = geo.Lon
-			}
-
-			// Try bulk databases (RIPE, ARIN) first, then fall back to RDAP
-			var rdapOrg *model.RDAPOrg
-
-			// Parse prefix for bulk lookup
-			parsedPrefix, parseErr := netip.ParsePrefix(normalized)
-			if parseErr == nil {
-				// Try RIPE bulk first
-				tStart = time.Now()
-				rdapOrg = b.tryRIPEBulkLookupPrefix(parsedPrefix)
-				atomic.AddInt64(&b.stats.TimeRIPEBulkNanos, time.Since(tStart).Nanoseconds())
-				atomic.AddInt64(&b.stats.CallsRIPEBulk, 1)
-				if rdapOrg != nil {
-					mu.Lock()
-					b.stats.RIPEBulkHits++
-					// Log first few hits, then every 100th
-					if b.stats.RIPEBulkHits <= 5 || b.stats.RIPEBulkHits%100 == 0 {
-						log.Printf("INFO: RIPE bulk hit #%d for %s -> %s", b.stats.RIPEBulkHits, normalized, rdapOrg.OrgName)
-					}
-					mu.Unlock()
-				}
-
-				// Try ARIN bulk if RIPE bulk didn't find it
-				if rdapOrg == nil {
-					tStart = time.Now()
-					rdapOrg = b.tryARINBulkLookupPrefix(parsedPrefix)
-					atomic.AddInt64(&b.stats.TimeARINBulkNanos, time.Since(tStart).Nanoseconds())
-					atomic.AddInt64(&b.stats.CallsARINBulk, 1)
-					if rdapOrg != nil {
-						mu.Lock()
-						b.stats.ARINBulkHits++
-						// Log first few hits, then every 100th
-						if b.stats.ARINBulkHits <= 5 || b.stats.ARINBulkHits%100 == 0 {
-							log.Printf("INFO: ARIN bulk hit #%d for %s -> %s", b.stats.ARINBulkHits, normalized, rdapOrg.OrgName)
-						}
-						mu.Unlock()
-					}
-				}
-			}
-
-			// Skip if bulk-only mode and no bulk coverage
-			if rdapOrg == nil && b.cfg.BulkOnly {
-				mu.Lock()
-				b.stats.RecordsSkipped++
-				mu.Unlock()
-				return nil // Skip this prefix
-			}
-
-			// Fall back to RDAP if bulk databases didn't find it
-			if rdapOrg == nil {
-				var rdapErr error
-				tStart = time.Now()
-				rdapOrg, rdapErr = b.rdapClient.OrgForPrefix(ctx, normalized)
-				atomic.AddInt64(&b.stats.TimeRDAPNanos, time.Since(tStart).Nanoseconds())
-				atomic.AddInt64(&b.stats.CallsRDAP, 1)
-				if rdapErr != nil {
-					log.Printf("WARN: RDAP lookup failed for %s: %v", normalized, rdapErr)
-					// Fallback to MaxMind ASN org
-					rec.OrgName = asnName
-					rec.SourceRole = "asn_fallback"
-					rec.RIR = "UNKNOWN"
-					mu.Lock()
-					b.stats.RDAPCacheMisses++
-					b.stats.Errors++
-					mu.Unlock()
-				} else {
-					rec.OrgName = rdap.CleanOrgName(rdapOrg.OrgName)
-					rec.SourceRole = rdapOrg.SourceRole
-					rec.StatusLabel = rdapOrg.StatusLabel
-					rec.RIR = rdapOrg.RIR
-					// Prefer RIR country over MaxMind if provided
-					if rdapOrg.Country != "" {
-						rec.Country = rdapOrg.Country
-					}
-					mu.Lock()
-					b.stats.RDAPCacheHits++
-					mu.Unlock()
-				}
-			} else {
-				// Use bulk data (RIPE or ARIN)
-				rec.OrgName = rdap.CleanOrgName(rdapOrg.OrgName)
-				rec.SourceRole = rdapOrg.SourceRole
-				rec.StatusLabel = rdapOrg.StatusLabel
-				rec.RIR = rdapOrg.RIR
-				// Prefer RIR country over MaxMind (more accurate for RIR-managed space)
-				if rdapOrg.Country != "" {
-					rec.Country = rdapOrg.Country
-				}
-			}
-
-			// Ensure we have at least some org name
-			if rec.OrgName == "" {
-				rec.OrgName = asnName
-				rec.SourceRole = "asn_fallback"
+			recs := b.enrichRecord(ctx, enrich.Query{Prefix: parsedPrefix, Addr: repIP}, rec)
+			if recs == nil {
+				return nil
 			}
 
-			// Write to database
-			tStart = time.Now()
-			writeErr := b.db.PutRange(rec)
-			atomic.AddInt64(&b.stats.TimeDBWriteNanos, time.Since(tStart).Nanoseconds())
-			atomic.AddInt64(&b.stats.CallsDBWrite, 1)
-			if writeErr != nil {
-				// Check if this is just an overlap with a less specific range (expected)
-				if strings.Contains(writeErr.Error(), "is covered by less specific") {
-					mu.Lock()
-					b.stats.RecordsSkipped++
-					b.stats.PrefixesProcessed++
-					mu.Unlock()
-					return nil
-				}
-				log.Printf("ERROR: Failed to write record for %s: %v", normalized, writeErr)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
+			// Queue for the bulk load
+			if err := b.writeRecords(recs); err != nil {
+				slog.Error("Failed to write record", "prefix", normalized, "err", err)
+				b.countError()
 				return nil
 			}
 
-			mu.Lock()
-			b.stats.RecordsWritten++
+			b.metrics.Progress()
+			b.mu.Lock()
 			b.stats.PrefixesProcessed++
 			if (idx+1)%100 == 0 || idx+1 == totalPrefixes {
 				progress := float64(idx+1) / float64(totalPrefixes) * 100
-				log.Printf("INFO: Progress: %d/%d (%.1f%%) - Last: %s -> %s",
-					idx+1, totalPrefixes, progress, normalized, rec.OrgName)
+				slog.Info("Progress", "done", idx+1, "total", totalPrefixes, "percent", fmt.Sprintf("%.1f", progress),
+					"last", normalized, "org", rec.OrgName)
 			}
-			mu.Unlock()
+			b.mu.Unlock()
 
 			return nil
 		})
+		if err != nil {
+			slog.Warn("Stopped submitting prefixes", "err", err)
+			break
+		}
 	}
 
 	// Wait for all workers to complete
 	results := pool.Wait()
 
-	// Check for errors
+	// Only failed tasks are returned
 	for _, result := range results {
 		if result.Error != nil {
-			log.Printf("WARN: Worker error: %v", result.Error)
+			slog.Warn("Worker error", "err", result.Error)
 		}
 	}
 
 	return nil
 }
 
-// enrichAndWriteModeB processes prefixes in Mode B (split by MaxMind city blocks)
-func (b *Builder) enrichAndWriteModeB(ctx context.Context, prefixes []string) error {
+// enrichAndWriteSplit processes prefixes in Mode B (split by MaxMind city
+// blocks) and/or Mode C (split on registry sub-allocations)
+func (b *Builder) enrichAndWriteSplit(ctx context.Context, prefixes []string) error {
 	// Create worker pool
 	pool := workers.NewPool(ctx, workers.Config{
 		Workers:   b.cfg.Workers,
 		RateLimit: 0,
 	})
 
-	var mu sync.Mutex
 	totalPrefixes := len(prefixes)
 
 	for i, prefix := range prefixes {
 		idx := i
 		currentPrefix := prefix
 
-		pool.Submit(idx, func(ctx context.Context) error {
+		err := pool.Submit(idx, func(ctx context.Context) error {
 			// Normalize prefix
 			normalized, err := ipcodec.NormalizePrefix(currentPrefix)
 			if err != nil {
-				log.Printf("ERROR: Invalid prefix %s: %v", currentPrefix, err)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
+				slog.Error("Invalid prefix", "prefix", currentPrefix, "err", err)
+				b.countError()
 				return nil
 			}
 
 			// Parse prefix
 			parsedPrefix, err := netip.ParsePrefix(normalized)
 			if err != nil {
-				log.Printf("ERROR: Failed to parse prefix %s: %v", normalized, err)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
+				slog.Error("Failed to parse prefix", "prefix", normalized, "err", err)
+				b.countError()
 				return nil
 			}
 
-// DISABLED: 			// Fetch org data ONCE for the parent prefix before splitting
-// DISABLED: 			// This is optimization #2: avoid redundant RDAP/RIPE bulk calls per block
-// DISABLED: 			var parentOrg *model.RDAPOrg
-// DISABLED: 
-// DISABLED: 			// Try RIPE bulk first
-// DISABLED: 			tStart := time.Now()
-// DISABLED: 			parentOrg = b.tryRIPEBulkLookupPrefix(parsedPrefix)
-// DISABLED: 			atomic.AddInt64(&b.stats.TimeRIPEBulkNanos, time.Since(tStart).Nanoseconds())
-// DISABLED: 			atomic.AddInt64(&b.stats.CallsRIPEBulk, 1)
-// DISABLED: 
-// DISABLED: 			if parentOrg != nil {
-// DISABLED: 				mu.Lock()
-// DISABLED: 				b.stats.RIPEBulkHits++
-// DISABLED: 				mu.Unlock()
-// DISABLED: 			} else {
-// DISABLED: 				// Fall back to RDAP
-// DISABLED: 				tStart = time.Now()
-// DISABLED: 				parentOrg, err = b.rdapClient.OrgForPrefix(ctx, normalized)
-// DISABLED: 				atomic.AddInt64(&b.stats.TimeRDAPNanos, time.Since(tStart).Nanoseconds())
-// DISABLED: 				atomic.AddInt64(&b.stats.CallsRDAP, 1)
-// DISABLED: 
-// DISABLED: 				if err != nil {
-// DISABLED: 					log.Printf("WARN: Parent org lookup failed for %s: %v", normalized, err)
-// DISABLED: 					mu.Lock()
-// DISABLED: 					b.stats.RDAPCacheMisses++
-// DISABLED: 					mu.Unlock()
-// DISABLED: 					// Continue anyway - processBlock will handle fallback
-// DISABLED: 				} else {
-// DISABLED: 					mu.Lock()
-// DISABLED: 					b.stats.RDAPCacheHits++
-// DISABLED: 					mu.Unlock()
-// DISABLED: 				}
-// DISABLED: 			}
-
 			// Determine minimum prefix length based on IP version
 			minPrefixLen := b.minPrefixV4
 			if parsedPrefix.Addr().Is6() {
@@ -323,205 +248,151 @@ func (b *Builder) enrichAndWriteModeB(ctx context.Context, prefixes []string) er
 			}
 
 			// Split by geo
-			blocks, err := b.maxmind.SplitPrefixByGeo(parsedPrefix, minPrefixLen)
-			if err != nil {
-				log.Printf("ERROR: Failed to split prefix %s: %v", normalized, err)
-				mu.Lock()
-				b.stats.Errors++
-				mu.Unlock()
-				return nil
+			blocks := []maxmind.NetworkBlock{{Prefix: parsedPrefix}}
+			if b.cfg.SplitByMaxMind {
+				blocks, err = b.maxmind.SplitPrefixByGeo(parsedPrefix, minPrefixLen)
+				if err != nil {
+					slog.Error("Failed to split prefix", "prefix", normalized, "err", err)
+					b.countError()
+					return nil
+				}
 			}
 
-			// log.Printf("INFO: Split %s into %d blocks", normalized, len(blocks))
+			// Fetch every bulk registry object overlapping the announced prefix
+			// once; each block then takes the most specific one covering it, so
+			// sub-allocations keep their own org without a lookup per block
+			coverage := b.pipeline.Cover(ctx, parsedPrefix)
 
-			// Process each block (look up org individually for each block)
-			// Note: We don't cache parent org because large announced prefixes often
-			// contain multiple sub-allocations with different organizations.
+			// Mode C: cut blocks where registry objects start and end, so each
+			// customer assignment gets its own records
+			if b.cfg.SplitByRegistry {
+				blocks = splitByRegistry(blocks, coverage)
+			}
 			for _, block := range blocks {
-				if err := b.processBlock(ctx, &mu, block, normalized, nil); err != nil {
-					log.Printf("ERROR: Failed to process block %s: %v", block.Prefix.String(), err)
-					mu.Lock()
-					b.stats.Errors++
-					mu.Unlock()
+				if err := b.processBlock(ctx, block, normalized, coverage); err != nil {
+					slog.Error("Failed to process block", "block", block.Prefix, "err", err)
+					b.countError()
 				}
 			}
 
-			mu.Lock()
+			b.metrics.Progress()
+			b.mu.Lock()
 			b.stats.PrefixesProcessed++
 			if (idx+1)%50 == 0 || idx+1 == totalPrefixes {
 				progress := float64(idx+1) / float64(totalPrefixes) * 100
-				log.Printf("INFO: Progress: %d/%d (%.1f%%)", idx+1, totalPrefixes, progress)
+				slog.Info("Progress", "done", idx+1, "total", totalPrefixes, "percent", fmt.Sprintf("%.1f", progress))
 			}
-			mu.Unlock()
+			b.mu.Unlock()
 
 			return nil
 		})
+		if err != nil {
+			slog.Warn("Stopped submitting prefixes", "err", err)
+			break
+		}
 	}
 
 	// Wait for completion
 	results := pool.Wait()
 	for _, result := range results {
 		if result.Error != nil {
-			log.Printf("WARN: Worker error: %v", result.Error)
+			slog.Warn("Worker error", "err", result.Error)
 		}
 	}
 
 	return nil
 }
 
-// processBlock processes a single MaxMind block
-// If parentOrg is provided (non-nil), it will be used instead of fetching org data
-func (b *Builder) processBlock(ctx context.Context, mu *sync.Mutex, block maxmind.NetworkBlock, originalPrefix string, parentOrg *model.RDAPOrg) error {
-	start := block.Prefix.Addr()
-
-	// Calculate end IP from prefix
-	bits := block.Prefix.Bits()
-	hostBits := start.BitLen() - bits
-
-	startBytes := start.AsSlice()
-	endBytes := make([]byte, len(startBytes))
-	copy(endBytes, startBytes)
-
-	// Add offset for end IP
-	carry := uint64(1<<hostBits - 1)
-	for i := len(endBytes) - 1; i >= 0 && carry > 0; i-- {
-		sum := uint64(endBytes[i]) + carry
-		endBytes[i] = byte(sum & 0xFF)
-		carry = sum >> 8
+// splitByRegistry cuts blocks at the boundaries of the registry objects in
+// coverage. Pieces keep the geo of the block they came from. The blocks are
+// sorted and walked together with the boundaries in one pass.
+func splitByRegistry(blocks []maxmind.NetworkBlock, coverage map[string]*enrich.Coverage) []maxmind.NetworkBlock {
+	var bounds []netip.Addr
+	for _, cov := range coverage {
+		// Already sorted by the coverage index
+		bounds = append(bounds, cov.Boundaries()...)
 	}
-
-	end, _ := netip.AddrFromSlice(endBytes)
-
-	// Create record
-	rec := &model.Record{
-		Start:       start,
-		End:         end,
-		Prefix:      originalPrefix, // Keep original announced prefix
-		LastChecked: time.Now(),
-		Schema:      1,
-		Country:     block.Country,
-		Region:      block.Region,
-		City:        block.City,
-		Lat:         block.Lat,
-		Lon:         block.Lon,
+	if len(bounds) == 0 {
+		return blocks
 	}
-
-	// Get representative IP
-	repIP := start
-
-	// Enrich with MaxMind ASN
-	tStart := time.Now()
-	asn, asnName, err := b.maxmind.ASNInfo(repIP)
-	atomic.AddInt64(&b.stats.TimeMaxMindASNNanos, time.Since(tStart).Nanoseconds())
-	atomic.AddInt64(&b.stats.CallsMaxMindASN, 1)
-	if err == nil {
-		rec.ASN = asn
-		rec.ASNName = asnName
+	if len(coverage) > 1 {
+		slices.SortFunc(bounds, netip.Addr.Compare)
+		bounds = slices.Compact(bounds)
 	}
-
-	// Use parent org if provided, otherwise fetch fresh
-	var rdapOrg *model.RDAPOrg
-	if parentOrg != nil {
-		// Reuse parent org data (optimization #2)
-		rdapOrg = parentOrg
-	} else {
-		// Parent lookup failed, try fetching for this specific block from bulk databases
-		// Try RIPE bulk first
-		tStart = time.Now()
-		rdapOrg = b.tryRIPEBulkLookup(repIP)
-		atomic.AddInt64(&b.stats.TimeRIPEBulkNanos, time.Since(tStart).Nanoseconds())
-		atomic.AddInt64(&b.stats.CallsRIPEBulk, 1)
-		if rdapOrg != nil {
-			mu.Lock()
-			b.stats.RIPEBulkHits++
-			// Log first few hits, then every 100th
-			if b.stats.RIPEBulkHits <= 5 || b.stats.RIPEBulkHits%100 == 0 {
-				log.Printf("INFO: RIPE bulk hit #%d (Mode B block)", b.stats.RIPEBulkHits)
-			}
-			mu.Unlock()
+	slices.SortFunc(blocks, func(a, b maxmind.NetworkBlock) int { return a.Prefix.Addr().Compare(b.Prefix.Addr()) })
+
+	var pieces []maxmind.NetworkBlock
+	i := 0
+	for _, block := range blocks {
+		start, end, err := ipcodec.CIDRToRange(block.Prefix.String())
+		if err != nil {
+			pieces = append(pieces, block)
+			continue
 		}
 
-		// Try ARIN bulk if RIPE bulk didn't find it
-		if rdapOrg == nil {
-			tStart = time.Now()
-			rdapOrg = b.tryARINBulkLookup(repIP)
-			atomic.AddInt64(&b.stats.TimeARINBulkNanos, time.Since(tStart).Nanoseconds())
-			atomic.AddInt64(&b.stats.CallsARINBulk, 1)
-			if rdapOrg != nil {
-				mu.Lock()
-				b.stats.ARINBulkHits++
-				// Log first few hits, then every 100th
-				if b.stats.ARINBulkHits <= 5 || b.stats.ARINBulkHits%100 == 0 {
-					log.Printf("INFO: ARIN bulk hit #%d (Mode B block)", b.stats.ARINBulkHits)
-				}
-				mu.Unlock()
-			}
+		// Boundaries inside the block, each the first address of a new piece
+		for i < len(bounds) && !start.Less(bounds[i]) {
+			i++
 		}
-
-		// Fall back to RDAP if bulk databases didn't find it
-		if rdapOrg == nil {
-			var err error
-			tStart = time.Now()
-			rdapOrg, err = b.rdapClient.OrgForIP(ctx, repIP)
-			atomic.AddInt64(&b.stats.TimeRDAPNanos, time.Since(tStart).Nanoseconds())
-			atomic.AddInt64(&b.stats.CallsRDAP, 1)
-			if err != nil {
-				rec.OrgName = asnName
-				rec.SourceRole = "asn_fallback"
-				rec.RIR = "UNKNOWN"
-				mu.Lock()
-				b.stats.RDAPCacheMisses++
-				mu.Unlock()
-			} else {
-				mu.Lock()
-				b.stats.RDAPCacheHits++
-				mu.Unlock()
-			}
+		for ; i < len(bounds) && !end.Less(bounds[i]); i++ {
+			pieces = appendPieces(pieces, block, start, bounds[i].Prev())
+			start = bounds[i]
 		}
+		pieces = appendPieces(pieces, block, start, end)
 	}
+	return pieces
+}
 
-	// Skip if bulk-only mode and no bulk coverage
-	if rdapOrg == nil && b.cfg.BulkOnly {
-		mu.Lock()
-		b.stats.RecordsSkipped++
-		mu.Unlock()
-		return nil // Skip this block
+// appendPieces appends the CIDR blocks spanning start-end, copying geo from block
+func appendPieces(pieces []maxmind.NetworkBlock, block maxmind.NetworkBlock, start, end netip.Addr) []maxmind.NetworkBlock {
+	prefixes, err := ipcodec.RangeToPrefixes(start, end)
+	if err != nil {
+		slog.Warn("Failed to split block", "block", block.Prefix, "start", start, "end", end, "err", err)
+		return pieces
+	}
+	for _, p := range prefixes {
+		piece := block
+		piece.Prefix = p
+		pieces = append(pieces, piece)
 	}
+	return pieces
+}
 
-	if rdapOrg != nil {
-		rec.OrgName = rdap.CleanOrgName(rdapOrg.OrgName)
-		rec.SourceRole = rdapOrg.SourceRole
-		rec.StatusLabel = rdapOrg.StatusLabel
-		rec.RIR = rdapOrg.RIR
-		// Prefer RIR country over MaxMind if provided
-		if rdapOrg.Country != "" {
-			rec.Country = rdapOrg.Country
-		}
+// processBlock processes a single MaxMind block
+func (b *Builder) processBlock(ctx context.Context, block maxmind.NetworkBlock, originalPrefix string, coverage map[string]*enrich.Coverage) error {
+	start, end, err := ipcodec.CIDRToRange(block.Prefix.String())
+	if err != nil {
+		return err
 	}
 
-	if rec.OrgName == "" {
-		rec.OrgName = asnName
+	// Create record
+	rec := &model.Record{
+		Start:       start,
+		End:         end,
+		Prefix:      originalPrefix, // Keep original announced prefix
+		LastChecked: b.buildTime,
+		Schema:      1,
 	}
 
-	// Write to database
-	tStart = time.Now()
-	writeErr := b.db.PutRange(rec)
-	atomic.AddInt64(&b.stats.TimeDBWriteNanos, time.Since(tStart).Nanoseconds())
-	atomic.AddInt64(&b.stats.CallsDBWrite, 1)
-	if writeErr != nil {
-		// Check if this is just an overlap with a less specific range (expected)
-		if strings.Contains(writeErr.Error(), "is covered by less specific") {
-			mu.Lock()
-			b.stats.RecordsSkipped++
-			mu.Unlock()
-			return nil
-		}
-		return fmt.Errorf("failed to write block: %w", writeErr)
+	// Blocks are looked up by their first address
+	recs := b.enrichRecord(ctx, enrich.Query{Prefix: block.Prefix, Addr: start, Point: true, Coverage: coverage}, rec)
+	if err := b.writeRecords(recs); err != nil {
+		return fmt.Errorf("failed to write block: %w", err)
 	}
+	return nil
+}
+
+// sourceTiming accumulates time spent in one source; updated atomically
+type sourceTiming struct {
+	nanos int64
+	calls int64
+}
 
-	mu.Lock()
-	b.stats.RecordsWritten++
-	mu.Unlock()
+func (t *sourceTiming) add(d time.Duration) {
+	atomic.AddInt64(&t.nanos, d.Nanoseconds())
+	atomic.AddInt64(&t.calls, 1)
+}
 
-	return nil
+func (t *sourceTiming) load() (time.Duration, int64) {
+	return time.Duration(atomic.LoadInt64(&t.nanos)), atomic.LoadInt64(&t.calls)
 }
@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/sources/rdap"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+	"github.com/wingedpig/iporg/pkg/util/rangeset"
+)
+
+// registeredOwner is the registry data written for an unannounced range
+type registeredOwner struct {
+	OrgName     string
+	StatusLabel string
+	RIR         string
+	Country     string
+}
+
+// fillRegistered writes records for registered ranges in the bulk databases
+// that are not covered by any record already in the database
+func (b *Builder) fillRegistered(ctx context.Context) error {
+	// Everything already in the database counts as announced
+	var covered []rangeset.Range
+	err := b.db.IterateRanges(true, func(rec *model.Record) error {
+		covered = append(covered, rangeset.Range{
+			Start: ipcodec.IPv4ToInt32(rec.Start),
+			End:   ipcodec.IPv4ToInt32(rec.End),
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read announced ranges: %w", err)
+	}
+	slog.Info("Filling registered space around existing IPv4 records", "records", len(covered))
+
+	// The new records are disjoint from the existing ones, which are kept
+	if err := b.beginLoad(true); err != nil {
+		return fmt.Errorf("failed to start bulk load: %w", err)
+	}
+
+	if b.ripeBulkDB != nil {
+		filled, err := b.fillFromRIPE(ctx, rangeset.New(covered))
+		if err != nil {
+			b.closeLoad()
+			return fmt.Errorf("RIPE bulk: %w", err)
+		}
+		// ARIN data must not overlap what RIPE just filled
+		covered = append(covered, filled...)
+	}
+
+	if b.arinBulkDB != nil {
+		if _, err := b.fillFromARIN(ctx, rangeset.New(covered)); err != nil {
+			b.closeLoad()
+			return fmt.Errorf("ARIN bulk: %w", err)
+		}
+	}
+
+	if err := b.commitLoad(); err != nil {
+		return fmt.Errorf("failed to load registered ranges: %w", err)
+	}
+	slog.Info("Filled registered but unannounced ranges", "ranges", b.stats.RegisteredFilled)
+	return nil
+}
+
+// fillFromRIPE fills gaps from RIPE inetnums and returns the ranges written
+func (b *Builder) fillFromRIPE(ctx context.Context, covered *rangeset.Set) ([]rangeset.Range, error) {
+	var filled []rangeset.Range
+	flat := rangeset.NewFlattener(func(seg rangeset.Segment[ripebulk.Inetnum]) error {
+		gaps := covered.Gaps(seg.Range)
+		if len(gaps) == 0 {
+			return nil
+		}
+		match := b.ripeBulkDB.MatchFor(seg.Value)
+		if match == nil {
+			return nil
+		}
+		owner := registeredOwner{
+			OrgName:     match.OrgName,
+			StatusLabel: match.Status,
+			RIR:         "RIPE",
+			Country:     match.Country,
+		}
+		// Without an announcing ASN there is no ASN name to fall back to
+		if owner.OrgName == "" {
+			owner.OrgName = match.Netname
+		}
+		written, err := b.writeRegistered(gaps, owner)
+		filled = append(filled, written...)
+		return err
+	})
+
+	slog.Info("Walking RIPE bulk ranges")
+	err := b.ripeBulkDB.IterateRanges(func(inet ripebulk.Inetnum) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Catch-all entries describe space managed elsewhere
+		if enrich.IsRIPEPlaceholder(inet.Netname) || inet.Status == "ALLOCATED UNSPECIFIED" {
+			return nil
+		}
+		return flat.Add(inet.Start, inet.End, inet)
+	})
+	if err != nil {
+		return filled, err
+	}
+	return filled, flat.Close()
+}
+
+// fillFromARIN fills gaps from ARIN networks and returns the ranges written
+func (b *Builder) fillFromARIN(ctx context.Context, covered *rangeset.Set) ([]rangeset.Range, error) {
+	var filled []rangeset.Range
+	orgCountry := make(map[string]string)
+
+	flat := rangeset.NewFlattener(func(seg rangeset.Segment[arinbulk.NetBlock]) error {
+		gaps := covered.Gaps(seg.Range)
+		if len(gaps) == 0 {
+			return nil
+		}
+		match, err := b.arinBulkDB.MatchFor(seg.Value)
+		if err != nil {
+			return err
+		}
+		owner := registeredOwner{
+			OrgName:     match.OrgName,
+			StatusLabel: match.NetType,
+			RIR:         "ARIN",
+		}
+		if owner.OrgName == "" {
+			owner.OrgName = match.NetName
+		}
+		if orgID := seg.Value.OrgID; orgID != "" {
+			country, ok := orgCountry[orgID]
+			if !ok {
+				if org, err := b.arinBulkDB.GetOrganization(orgID); err == nil {
+					country = org.Country
+				}
+				orgCountry[orgID] = country
+			}
+			owner.Country = country
+		}
+		written, err := b.writeRegistered(gaps, owner)
+		filled = append(filled, written...)
+		return err
+	})
+
+	slog.Info("Walking ARIN bulk ranges")
+	err := b.arinBulkDB.IterateRanges(func(net arinbulk.NetBlock) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return flat.Add(net.Start, net.End, net)
+	})
+	if err != nil {
+		return filled, err
+	}
+	return filled, flat.Close()
+}
+
+// writeRegistered writes one record per CIDR in gaps and returns the ranges written
+func (b *Builder) writeRegistered(gaps []rangeset.Range, owner registeredOwner) ([]rangeset.Range, error) {
+	var written []rangeset.Range
+	for _, gap := range gaps {
+		prefixes, err := ipcodec.RangeToPrefixes(ipcodec.Int32ToIPv4(gap.Start), ipcodec.Int32ToIPv4(gap.End))
+		if err != nil {
+			return written, err
+		}
+
+		for _, prefix := range prefixes {
+			start, end, err := ipcodec.CIDRToRange(prefix.String())
+			if err != nil {
+				return written, err
+			}
+
+			rec := &model.Record{
+				Start:       start,
+				End:         end,
+				Prefix:      prefix.String(),
+				OrgName:     rdap.CleanOrgName(owner.OrgName),
+				RIR:         owner.RIR,
+				Country:     owner.Country,
+				SourceRole:  model.SourceRoleRegistered,
+				StatusLabel: owner.StatusLabel,
+				LastChecked: b.buildTime,
+				Schema:      1,
+			}
+
+			// Geo (and the country, if the registry has none) from MaxMind
+			if geo, err := b.maxmind.Geo(start); err == nil {
+				if rec.Country == "" {
+					rec.Country = geo.Country
+				}
+				rec.Region = geo.Region
+				rec.City = geo.City
+				rec.Lat = geo.Lat
+				rec.Lon = geo.Lon
+			}
+
+			if err := b.writeRecords(b.applyOverrides(rec)); err != nil {
+				slog.Error("Failed to write registered range", "prefix", prefix, "err", err)
+				b.countError()
+				continue
+			}
+			b.mu.Lock()
+			b.stats.RegisteredFilled++
+			b.mu.Unlock()
+		}
+		written = append(written, gap)
+	}
+	return written, nil
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// buildTimestamp returns the time recorded in the database: SOURCE_DATE_EPOCH
+// if set (for reproducible builds), otherwise the current time
+func buildTimestamp() time.Time {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		secs, err := strconv.ParseInt(epoch, 10, 64)
+		if err == nil {
+			return time.Unix(secs, 0).UTC()
+		}
+		slog.Warn("Ignoring invalid SOURCE_DATE_EPOCH", "epoch", epoch, "err", err)
+	}
+	return time.Now()
+}
+
+// beginLoad starts collecting records for a bulk load. With keepExisting the
+// records already in the database are resolved together with the new ones;
+// otherwise they are replaced.
+func (b *Builder) beginLoad(keepExisting bool) error {
+	loader, err := b.db.NewBulkLoader(iporgdb.BulkLoadOptions{
+		TempDir:      b.cfg.TempDir,
+		KeepExisting: keepExisting,
+		OnConflict: func(kept, dropped *model.Record) {
+			slog.Warn("Overlapping prefixes have the same length; keeping one",
+				"kept", kept.Prefix, "dropped", dropped.Prefix, "org", kept.OrgName)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	b.loader = loader
+	return nil
+}
+
+// commitLoad resolves overlaps and writes the collected records
+func (b *Builder) commitLoad() error {
+	defer b.closeLoad()
+
+	tStart := time.Now()
+	stats, err := b.loader.Commit()
+	b.stats.Timings[timingDBWrite].add(time.Since(tStart))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Loaded records", "records", stats.Added, "runs", stats.Runs, "written", stats.Written, "trimmed", stats.Trimmed,
+		"shadowed", stats.Shadowed, "duplicates", stats.Duplicates, "conflicts", stats.Conflicts, "replaced", stats.Removed)
+
+	b.mu.Lock()
+	b.stats.RecordsWritten += int(stats.Written)
+	b.stats.RecordsSkipped += int(stats.Shadowed + stats.Duplicates)
+	b.stats.RecordsTrimmed += int(stats.Trimmed)
+	b.stats.Conflicts += int(stats.Conflicts)
+	b.mu.Unlock()
+	return nil
+}
+
+// closeLoad releases the loader, discarding any records not yet committed
+func (b *Builder) closeLoad() {
+	if err := b.loader.Close(); err != nil {
+		slog.Warn("Failed to remove bulk load files", "err", err)
+	}
+	b.loader = nil
+}
+
+// writeRecord queues rec for the bulk load
+func (b *Builder) writeRecord(rec *model.Record) error {
+	tStart := time.Now()
+	err := b.loader.Add(rec)
+	b.stats.Timings[timingDBWrite].add(time.Since(tStart))
+	if err != nil {
+		return fmt.Errorf("failed to queue record: %w", err)
+	}
+	return nil
+}
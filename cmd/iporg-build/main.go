@@ -7,13 +7,17 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"time"
 
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/metrics"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/sources/rdap"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "1.0.0"
@@ -35,6 +39,8 @@ func main() {
 		statsCmd()
 	case "debug":
 		debugCmd()
+	case "cache":
+		cacheCmd()
 	case "version":
 		fmt.Printf("iporg-build version %s\n", version)
 	case "help", "-h", "--help":
@@ -46,6 +52,15 @@ func main() {
 	}
 }
 
+// setupLogging installs the logger chosen on the command line, which the
+// library packages also log to
+func setupLogging(f *logging.Flags) {
+	if _, err := f.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 func printUsage() {
 	fmt.Println(`iporg-build - Build IP organization database from free sources
 
@@ -54,27 +69,48 @@ Usage:
   iporg-build verify [options]      Verify database consistency
   iporg-build stats [options]       Show database statistics
   iporg-build debug [options]       Debug IP lookup issues
+  iporg-build cache <cmd> [options] Maintain the RDAP cache (prune, stats, export, import)
   iporg-build version                Show version
   iporg-build help                   Show this help
 
 Build Options:
   --asn-file string              Path to ASN list file (one ASN per line)
+  --prefix-file string           Path to prefix list file (one CIDR per line)
+  --country string               Select prefixes registered in these countries (e.g. GB,IE)
+  --rir string                   Select prefixes registered with these RIRs (e.g. RIPE)
   --mmdb-asn string              Path to MaxMind GeoLite2-ASN.mmdb
   --mmdb-city string             Path to MaxMind GeoLite2-City.mmdb
   --db string                    Path to LevelDB database (default: ./iporgdb)
+  --append                       Keep records from earlier builds instead of replacing them
+  --max-error-rate float         Errors per prefix above which existing records are kept (default: 0.05)
+  --tmp-dir string               Directory for bulk load spill files (default: system temp)
   --iptoasn-db string            Use iptoasn database for prefixes (default: RIPEstat API)
   --ripe-bulk-db string          Use RIPE bulk database for RIPE region (default: RDAP)
+  --arin-bulk-db string          Use ARIN bulk database for ARIN region (default: RDAP)
+  --overrides string             Overrides file (JSON) applied after enrichment
   --workers int                  Number of concurrent workers (default: 16)
   --cache-ttl duration           Cache TTL for RDAP (default: 168h)
+  --negative-cache-ttl duration  Cache TTL for RDAP "no data" results (default: 24h)
+  --rdap-cache-db string         Keep the RDAP cache in a separate database (default: --db)
   --ipv4-only                    Skip IPv6 prefixes (default: true)
   --split-by-maxmind             Enable Mode B: split by MaxMind city blocks
+  --split-by-registry            Enable Mode C: split on RIPE/ARIN bulk sub-allocations
+  --fill-registered              Add registered but unannounced ranges from bulk data
+  --precedence string            Source precedence per field (see README)
   --min-prefix-v4 int            Minimum IPv4 prefix length for Mode B (default: 20)
   --min-prefix-v6 int            Minimum IPv6 prefix length for Mode B (default: 32)
   --ripe-base string             RIPEstat base URL (default: https://stat.ripe.net)
   --rdap-bootstrap string        RDAP bootstrap URL (default: https://rdap.db.ripe.net)
   --rdap-rate-limit float        RDAP requests per second (default: 5.0)
   --user-agent string            User-Agent header (default: iporg-build/version)
+  --http-record string           Record RIPEstat and RDAP traffic to a directory
+  --http-replay string           Replay RIPEstat and RDAP traffic from a directory (offline)
+  --config string                Build configuration file (JSON); flags override it
+  --profile string               Named profile from the config file
   --pprof string                 Enable pprof HTTP server (e.g., localhost:6060)
+  --metrics string               Serve Prometheus metrics on /metrics (e.g., localhost:9090)
+  --log-level string             Log level: debug, info, warn or error (default: info)
+  --log-format string            Log format: text or json (default: text)
 
 Examples:
   # Build database for specific ASNs (using RIPEstat API)
@@ -89,11 +125,31 @@ Examples:
   iporg-build build --asn-file=asns.txt --mmdb-asn=... --mmdb-city=... \
     --split-by-maxmind --min-prefix-v4=24
 
-  # Verify database
-  iporg-build verify --db=./data/iporgdb
+  # Build with Mode C (one record per customer assignment, optionally with geo)
+  iporg-build build --asn-file=asns.txt --mmdb-asn=... --mmdb-city=... \
+    --ripe-bulk-db=./data/ripe-bulk.ldb --arin-bulk-db=./data/arin-bulk.ldb \
+    --split-by-registry --split-by-maxmind
+
+  # Build for all prefixes registered in Great Britain and Ireland
+  iporg-build build --country=GB,IE --iptoasn-db=./iptoasndb \
+    --mmdb-asn=GeoLite2-ASN.mmdb --mmdb-city=GeoLite2-City.mmdb
+
+  # Build for an explicit list of customer blocks
+  iporg-build build --prefix-file=customers.txt --mmdb-asn=... --mmdb-city=...
+
+  # Build with a named profile from a config file
+  iporg-build build --config=build.json --profile=europe-accurate
+
+  # Verify database and report overrides that no longer match any range
+  iporg-build verify --db=./data/iporgdb --overrides=overrides.json
 
   # Show statistics
-  iporg-build stats --db=./data/iporgdb`)
+  iporg-build stats --db=./data/iporgdb
+
+  # Drop expired RDAP cache entries and copy the cache to another host
+  iporg-build cache prune --db=./rdapcache
+  iporg-build cache export --db=./rdapcache --output=rdap-cache.jsonl
+  iporg-build cache import --db=./rdapcache --input=rdap-cache.jsonl`)
 }
 
 func buildCmd() {
@@ -110,110 +166,191 @@ func buildCmd() {
 
 	// Optional flags
 	fs.StringVar(&cfg.DBPath, "db", "./iporgdb", "Path to LevelDB database")
+	fs.BoolVar(&cfg.Append, "append", false, "Keep records from earlier builds instead of replacing them")
+	fs.Float64Var(&cfg.MaxErrorRate, "max-error-rate", 0.05, "Errors per prefix above which a build does not replace existing records")
+	fs.StringVar(&cfg.TempDir, "tmp-dir", "", "Directory for bulk load spill files (default: system temp dir)")
 	fs.BoolVar(&cfg.AllASNs, "all-asns", false, "Build for all ASNs from iptoasn database")
 	fs.BoolVar(&cfg.BulkOnly, "bulk-only", false, "Only process prefixes with bulk database coverage (faster)")
-	var iptoasnDB string
-	fs.StringVar(&iptoasnDB, "iptoasn-db", "", "Use iptoasn database for prefixes instead of RIPEstat API")
-	var ripeBulkDB string
-	fs.StringVar(&ripeBulkDB, "ripe-bulk-db", "", "Use RIPE bulk database for RIPE region instead of RDAP")
-	var arinBulkDB string
-	fs.StringVar(&arinBulkDB, "arin-bulk-db", "", "Use ARIN bulk database for ARIN region instead of RDAP")
+	fs.StringVar(&cfg.IPtoASNDBPath, "iptoasn-db", "", "Use iptoasn database for prefixes instead of RIPEstat API")
+	fs.StringVar(&cfg.RIPEBulkDBPath, "ripe-bulk-db", "", "Use RIPE bulk database for RIPE region instead of RDAP")
+	fs.StringVar(&cfg.ARINBulkDBPath, "arin-bulk-db", "", "Use ARIN bulk database for ARIN region instead of RDAP")
+	fs.StringVar(&cfg.OverridesFile, "overrides", "", "Path to overrides file applied after enrichment")
+	fs.StringVar(&cfg.PrefixFile, "prefix-file", "", "Path to prefix list file (one CIDR per line)")
+	var countries, rirs string
+	fs.StringVar(&countries, "country", "", "Build for prefixes registered in these countries (e.g. GB,IE)")
+	fs.StringVar(&rirs, "rir", "", "Build for prefixes registered with these RIRs (e.g. RIPE,ARIN)")
 	fs.IntVar(&cfg.Workers, "workers", 16, "Number of concurrent workers")
-	var cacheTTL string
-	fs.StringVar(&cacheTTL, "cache-ttl", "168h", "Cache TTL for RDAP")
+	fs.DurationVar(&cfg.CacheTTL, "cache-ttl", 168*time.Hour, "Cache TTL for RDAP")
+	fs.DurationVar(&cfg.NegativeTTL, "negative-cache-ttl", rdap.DefaultNegativeTTL, "Cache TTL for RDAP \"no data\" results (0 disables)")
+	fs.StringVar(&cfg.RDAPCacheDBPath, "rdap-cache-db", "", "Keep the RDAP cache in a separate database (default: the output database)")
 	fs.BoolVar(&cfg.SplitByMaxMind, "split-by-maxmind", false, "Enable Mode B: split by MaxMind city blocks")
+	fs.BoolVar(&cfg.SplitByRegistry, "split-by-registry", false, "Enable Mode C: split on RIPE/ARIN bulk sub-allocations")
 	fs.BoolVar(&cfg.IPv4Only, "ipv4-only", true, "Skip IPv6 prefixes (default: true)")
+	fs.BoolVar(&cfg.FillRegistered, "fill-registered", false, "Add registered but unannounced ranges from the bulk databases")
+	var precedence string
+	fs.StringVar(&precedence, "precedence", "", "Source precedence per field, e.g. \"country:ARIN=maxmind,rdap;org=arin_bulk,rdap,maxmind\"")
 
-	var minPrefixV4, minPrefixV6 int
-	fs.IntVar(&minPrefixV4, "min-prefix-v4", 20, "Minimum IPv4 prefix length for Mode B")
-	fs.IntVar(&minPrefixV6, "min-prefix-v6", 32, "Minimum IPv6 prefix length for Mode B")
+	fs.IntVar(&cfg.MinPrefixV4, "min-prefix-v4", 20, "Minimum IPv4 prefix length for Mode B")
+	fs.IntVar(&cfg.MinPrefixV6, "min-prefix-v6", 32, "Minimum IPv6 prefix length for Mode B")
 
 	fs.StringVar(&cfg.RIPEBaseURL, "ripe-base", "https://stat.ripe.net", "RIPEstat base URL")
 	fs.StringVar(&cfg.RDAPBootstrapURL, "rdap-bootstrap", "https://rdap.db.ripe.net", "RDAP bootstrap URL")
 	fs.Float64Var(&cfg.RDAPRateLimit, "rdap-rate-limit", 5.0, "RDAP requests per second")
 	fs.StringVar(&cfg.UserAgent, "user-agent", cfg.UserAgent, "User-Agent header")
+	fs.StringVar(&cfg.HTTPRecordDir, "http-record", "", "Record RIPEstat and RDAP traffic to this directory")
+	fs.StringVar(&cfg.HTTPReplayDir, "http-replay", "", "Replay RIPEstat and RDAP traffic from this directory (no network access)")
+
+	// Config file flags
+	var configPath, profile string
+	fs.StringVar(&configPath, "config", "", "Build configuration file (JSON)")
+	fs.StringVar(&profile, "profile", "", "Named profile from the config file")
 
 	// Profiling flag
-	var pprofAddr string
+	var pprofAddr, metricsAddr string
 	fs.StringVar(&pprofAddr, "pprof", "", "Enable pprof HTTP server on address (e.g., localhost:6060)")
+	fs.StringVar(&metricsAddr, "metrics", "", "Serve Prometheus metrics on address (e.g., localhost:9090)")
+
+	var logFlags logging.Flags
+	logFlags.Register(fs)
 
 	fs.Parse(os.Args[2:])
+	setupLogging(&logFlags)
+
+	// Apply the config file over the flag defaults, then parse again so that
+	// flags given on the command line take precedence over the file
+	if configPath != "" {
+		if err := loadConfigFile(configPath, profile, cfg); err != nil {
+			logging.Fatal("Invalid build configuration", "err", err)
+		}
+		fs.Parse(os.Args[2:])
+		slog.Info("Loaded build configuration", "path", configPath)
+		if cfg.Profile != "" {
+			slog.Info("Using profile", "profile", cfg.Profile)
+		}
+	} else if profile != "" {
+		logging.Fatal("--profile requires --config")
+	}
 
 	// Start pprof server if requested
 	if pprofAddr != "" {
 		go func() {
-			log.Printf("INFO: Starting pprof server on http://%s/debug/pprof/", pprofAddr)
+			slog.Info("Starting pprof server", "url", "http://"+pprofAddr+"/debug/pprof/")
 			if err := http.ListenAndServe(pprofAddr, nil); err != nil {
-				log.Printf("WARN: pprof server failed: %v", err)
+				slog.Warn("pprof server failed", "err", err)
 			}
 		}()
 	}
 
+	// Selector flags replace the config file's lists when given
+	if countries != "" {
+		cfg.Countries = parseSelectorList(countries)
+	}
+	if rirs != "" {
+		cfg.RIRs = parseSelectorList(rirs)
+	}
+	for i, rir := range cfg.RIRs {
+		name := normalizeRIR(rir)
+		if name == "" {
+			logging.Fatal("Unknown RIR (want RIPE, ARIN, APNIC, LACNIC or AFRINIC)", "rir", rir)
+		}
+		cfg.RIRs[i] = name
+	}
+
 	// Validate required flags
-	if !cfg.AllASNs && cfg.ASNFile == "" {
-		log.Fatal("ERROR: --asn-file is required (or use --all-asns)")
+	hasSelectors := cfg.PrefixFile != "" || len(cfg.Countries) > 0 || len(cfg.RIRs) > 0
+	if !cfg.AllASNs && cfg.ASNFile == "" && !hasSelectors && !cfg.FillRegistered {
+		logging.Fatal("--asn-file is required (or use --all-asns, --prefix-file, --country or --rir)")
+	}
+	if cfg.FillRegistered && cfg.RIPEBulkDBPath == "" && cfg.ARINBulkDBPath == "" {
+		logging.Fatal("--fill-registered requires at least one of --ripe-bulk-db or --arin-bulk-db")
+	}
+	if cfg.SplitByRegistry && cfg.RIPEBulkDBPath == "" && cfg.ARINBulkDBPath == "" {
+		logging.Fatal("--split-by-registry requires at least one of --ripe-bulk-db or --arin-bulk-db")
+	}
+	if (len(cfg.Countries) > 0 || len(cfg.RIRs) > 0) &&
+		cfg.IPtoASNDBPath == "" && cfg.RIPEBulkDBPath == "" && cfg.ARINBulkDBPath == "" {
+		logging.Fatal("--country and --rir require --iptoasn-db, --ripe-bulk-db or --arin-bulk-db")
 	}
-	if cfg.AllASNs && iptoasnDB == "" {
-		log.Fatal("ERROR: --all-asns requires --iptoasn-db")
+	if cfg.AllASNs && cfg.IPtoASNDBPath == "" {
+		logging.Fatal("--all-asns requires --iptoasn-db")
 	}
-	if cfg.BulkOnly && ripeBulkDB == "" && arinBulkDB == "" {
-		log.Fatal("ERROR: --bulk-only requires at least one of --ripe-bulk-db or --arin-bulk-db")
+	if cfg.BulkOnly && cfg.RIPEBulkDBPath == "" && cfg.ARINBulkDBPath == "" {
+		logging.Fatal("--bulk-only requires at least one of --ripe-bulk-db or --arin-bulk-db")
+	}
+	if cfg.HTTPRecordDir != "" && cfg.HTTPReplayDir != "" {
+		logging.Fatal("--http-record and --http-replay cannot be used together")
 	}
 	if cfg.MMDBASNPath == "" {
-		log.Fatal("ERROR: --mmdb-asn is required")
+		logging.Fatal("--mmdb-asn is required")
 	}
 	if cfg.MMDBCityPath == "" {
-		log.Fatal("ERROR: --mmdb-city is required")
+		logging.Fatal("--mmdb-city is required")
 	}
 
-	// Parse cache TTL
-	var err error
-	cfg.CacheTTL, err = time.ParseDuration(cacheTTL)
-	if err != nil {
-		log.Fatalf("ERROR: Invalid cache-ttl: %v", err)
+	// Validate source precedence from the config file, then merge the flag over it
+	if err := enrich.Precedence(cfg.Precedence).Validate(); err != nil {
+		logging.Fatal("Invalid precedence in config", "err", err)
+	}
+	if precedence != "" {
+		prec, err := enrich.ParsePrecedence(precedence)
+		if err != nil {
+			logging.Fatal("Invalid precedence", "err", err)
+		}
+		cfg.Precedence = enrich.Precedence(cfg.Precedence).Merge(prec)
 	}
-
-	// Set iptoasn database path
-	cfg.IPtoASNDBPath = iptoasnDB
-
-	// Set RIPE bulk database path
-	cfg.RIPEBulkDBPath = ripeBulkDB
-
-	// Set ARIN bulk database path
-	cfg.ARINBulkDBPath = arinBulkDB
 
 	// Run the build
 	ctx := context.Background()
-	builder := NewBuilder(cfg, minPrefixV4, minPrefixV6)
+	builder := NewBuilder(cfg)
+
+	// Start metrics server if requested
+	if metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		builder.registerMetrics(reg)
+		go func() {
+			slog.Info("Starting metrics server", "url", "http://"+metricsAddr+metrics.Path)
+			if err := metrics.Serve(metricsAddr, reg); err != nil {
+				slog.Warn("Metrics server failed", "err", err)
+			}
+		}()
+	}
+
 	if err := builder.Build(ctx); err != nil {
-		log.Fatalf("ERROR: Build failed: %v", err)
+		logging.Fatal("Build failed", "err", err)
 	}
 
-	log.Println("INFO: Build completed successfully")
+	slog.Info("Build completed successfully")
 }
 
 func verifyCmd() {
 	fs := flag.NewFlagSet("verify", flag.ExitOnError)
 	dbPath := fs.String("db", "./iporgdb", "Path to LevelDB database")
+	overridesFile := fs.String("overrides", "", "Overrides file to check against the database")
+	var logFlags logging.Flags
+	logFlags.Register(fs)
 	fs.Parse(os.Args[2:])
+	setupLogging(&logFlags)
 
 	ctx := context.Background()
-	if err := RunVerify(ctx, *dbPath); err != nil {
-		log.Fatalf("ERROR: Verification failed: %v", err)
+	if err := RunVerify(ctx, *dbPath, *overridesFile); err != nil {
+		logging.Fatal("Verification failed", "err", err)
 	}
 
-	log.Println("INFO: Verification completed successfully")
+	slog.Info("Verification completed successfully")
 }
 
 func statsCmd() {
 	fs := flag.NewFlagSet("stats", flag.ExitOnError)
 	dbPath := fs.String("db", "./iporgdb", "Path to LevelDB database")
 	verbose := fs.Bool("verbose", false, "Show detailed statistics")
+	var logFlags logging.Flags
+	logFlags.Register(fs)
 	fs.Parse(os.Args[2:])
+	setupLogging(&logFlags)
 
 	ctx := context.Background()
 	if err := RunStats(ctx, *dbPath, *verbose); err != nil {
-		log.Fatalf("ERROR: Stats failed: %v", err)
+		logging.Fatal("Stats failed", "err", err)
 	}
 }
 
@@ -225,7 +362,10 @@ func debugCmd() {
 	mmdbCity := fs.String("mmdb-city", "", "Path to MaxMind GeoLite2-City.mmdb")
 	dbPath := fs.String("db", "./iporgdb", "Path to LevelDB database")
 	ripeBase := fs.String("ripe-base", "https://stat.ripe.net", "RIPEstat base URL")
+	var logFlags logging.Flags
+	logFlags.Register(fs)
 	fs.Parse(os.Args[2:])
+	setupLogging(&logFlags)
 
 	if *ip == "" {
 		fmt.Fprintf(os.Stderr, "ERROR: --ip is required\n\n")
@@ -241,6 +381,6 @@ func debugCmd() {
 
 	ctx := context.Background()
 	if err := RunDebug(ctx, *ip, *mmdbASN, *mmdbCity, *dbPath, *asn, *ripeBase); err != nil {
-		log.Fatalf("ERROR: Debug failed: %v", err)
+		logging.Fatal("Debug failed", "err", err)
 	}
 }
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/wingedpig/iporg/pkg/metrics"
+	"github.com/wingedpig/iporg/pkg/sources/rdap"
+	"github.com/wingedpig/iporg/pkg/util/ratelimit"
+)
+
+// registerMetrics registers the build metrics with reg. Counters from
+// BuildStats and the RDAP client are read at scrape time.
+func (b *Builder) registerMetrics(reg prometheus.Registerer) {
+	b.metrics = metrics.NewBuild(reg)
+
+	stat := func(name, help string, field func(*BuildStats) int) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "build",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return float64(field(&b.stats))
+		})
+	}
+	reg.MustRegister(
+		stat("asns_processed_total", "ASNs whose prefixes have been fetched.",
+			func(s *BuildStats) int { return s.ASNsProcessed }),
+		stat("prefixes_fetched_total", "Announced prefixes selected for the build.",
+			func(s *BuildStats) int { return s.PrefixesFetched }),
+		stat("prefixes_processed_total", "Prefixes enriched so far.",
+			func(s *BuildStats) int { return s.PrefixesProcessed }),
+		stat("records_written_total", "Records written to the database.",
+			func(s *BuildStats) int { return s.RecordsWritten }),
+		stat("records_skipped_total", "Records skipped (bulk-only misses, shadowed or duplicate ranges).",
+			func(s *BuildStats) int { return s.RecordsSkipped }),
+		stat("errors_total", "Errors encountered during the build.",
+			func(s *BuildStats) int { return s.Errors }),
+	)
+
+	rdapStat := func(name, help string, field func(s *rdap.CacheStats) int64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: "rdap_cache",
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			b.mu.Lock()
+			client := b.rdapClient
+			b.mu.Unlock()
+			if client == nil {
+				return 0
+			}
+			s := client.Stats()
+			return float64(field(&s))
+		})
+	}
+	reg.MustRegister(
+		rdapStat("hits_total", "Fresh RDAP cache entries served, including negative ones.",
+			func(s *rdap.CacheStats) int64 { return s.Hits }),
+		rdapStat("misses_total", "RDAP lookups that missed the cache.",
+			func(s *rdap.CacheStats) int64 { return s.Misses }),
+		rdapStat("stale_total", "Expired RDAP cache entries served because RDAP rate limited the build.",
+			func(s *rdap.CacheStats) int64 { return s.Stale }),
+	)
+
+	reg.MustRegister(metrics.NewUpstreamCollector(b.upstreamRates))
+}
+
+// upstreamRates returns the rate limiter state of the RIPEstat and RDAP clients
+func (b *Builder) upstreamRates() []ratelimit.HostRate {
+	b.mu.Lock()
+	ripeClient, rdapClient := b.ripeClient, b.rdapClient
+	b.mu.Unlock()
+
+	var rates []ratelimit.HostRate
+	if ripeClient != nil {
+		rates = append(rates, ripeClient.Rates()...)
+	}
+	if rdapClient != nil {
+		rates = append(rates, rdapClient.Rates()...)
+	}
+	return rates
+}
@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// knownRIRs maps accepted RIR spellings (including iptoasn registry names)
+// to the names used in records
+var knownRIRs = map[string]string{
+	"RIPE":    "RIPE",
+	"RIPENCC": "RIPE",
+	"ARIN":    "ARIN",
+	"APNIC":   "APNIC",
+	"LACNIC":  "LACNIC",
+	"AFRINIC": "AFRINIC",
+}
+
+// normalizeRIR returns the canonical RIR name, or "" if unknown
+func normalizeRIR(name string) string {
+	return knownRIRs[strings.ToUpper(strings.TrimSpace(name))]
+}
+
+// parseSelectorList splits a comma-separated flag value into upper-case entries
+func parseSelectorList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.ToUpper(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// hasSelectors reports whether prefixes are selected other than by ASN
+func (b *Builder) hasSelectors() bool {
+	return b.cfg.PrefixFile != "" || len(b.cfg.Countries) > 0 || len(b.cfg.RIRs) > 0
+}
+
+// fetchSelectedPrefixes resolves --prefix-file, --country and --rir
+func (b *Builder) fetchSelectedPrefixes(ctx context.Context) ([]string, error) {
+	var selected []string
+
+	if b.cfg.PrefixFile != "" {
+		prefixes, err := loadPrefixFile(b.cfg.PrefixFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prefix file: %w", err)
+		}
+		slog.Info("Loaded prefixes", "prefixes", len(prefixes), "file", b.cfg.PrefixFile)
+		selected = append(selected, prefixes...)
+	}
+
+	if len(b.cfg.Countries) > 0 || len(b.cfg.RIRs) > 0 {
+		var prefixes []string
+		var err error
+		if b.iptoasnStore != nil {
+			prefixes, err = b.selectFromIPtoASN(ctx)
+		} else {
+			prefixes, err = b.selectFromBulk()
+		}
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, prefixes...)
+	}
+
+	// Deduplicate and apply the IPv4-only filter
+	seen := make(map[string]bool, len(selected))
+	result := make([]string, 0, len(selected))
+	for _, prefix := range selected {
+		if seen[prefix] || (b.cfg.IPv4Only && isIPv6Prefix(prefix)) {
+			continue
+		}
+		seen[prefix] = true
+		result = append(result, prefix)
+	}
+
+	b.mu.Lock()
+	b.stats.PrefixesFetched += len(result)
+	b.mu.Unlock()
+	return result, nil
+}
+
+// loadPrefixFile reads CIDR prefixes (or single addresses) one per line
+func loadPrefixFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var prefixes []string
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.Contains(line, "/") {
+			addr, err := netip.ParseAddr(line)
+			if err != nil {
+				slog.Warn("Invalid prefix", "line", lineNum, "text", line)
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()).String())
+			continue
+		}
+
+		normalized, err := ipcodec.NormalizePrefix(line)
+		if err != nil {
+			slog.Warn("Invalid prefix", "line", lineNum, "text", line)
+			continue
+		}
+		prefixes = append(prefixes, normalized)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("no valid prefixes found in file")
+	}
+	return prefixes, nil
+}
+
+// selectFromIPtoASN returns announced prefixes whose iptoasn country or
+// registry matches the selectors
+func (b *Builder) selectFromIPtoASN(ctx context.Context) ([]string, error) {
+	slog.Info("Selecting prefixes from iptoasn database", "countries", b.cfg.Countries, "rirs", b.cfg.RIRs)
+
+	countries := toSet(b.cfg.Countries)
+	rirs := toSet(b.cfg.RIRs)
+
+	var prefixes []string
+	err := b.iptoasnStore.WalkV4(ctx, nil, func(k []byte, p *model.CanonicalPrefix) (bool, error) {
+		if countries[strings.ToUpper(p.Country)] || rirs[normalizeRIR(p.Registry)] {
+			prefixes = append(prefixes, p.CIDR)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk iptoasn database: %w", err)
+	}
+
+	slog.Info("Selected prefixes from iptoasn database", "prefixes", len(prefixes))
+	return prefixes, nil
+}
+
+// selectFromBulk returns the least specific registered ranges in the bulk
+// databases that match the selectors
+func (b *Builder) selectFromBulk() ([]string, error) {
+	countries := toSet(b.cfg.Countries)
+	rirs := toSet(b.cfg.RIRs)
+
+	for rir := range rirs {
+		if (rir == "RIPE" && b.ripeBulkDB != nil) || (rir == "ARIN" && b.arinBulkDB != nil) {
+			continue
+		}
+		return nil, fmt.Errorf("--rir=%s requires --iptoasn-db or the matching bulk database", rir)
+	}
+	if b.ripeBulkDB == nil && b.arinBulkDB == nil {
+		return nil, fmt.Errorf("--country and --rir require --iptoasn-db, --ripe-bulk-db or --arin-bulk-db")
+	}
+
+	var ranges []selectedRange
+
+	if b.ripeBulkDB != nil {
+		all := rirs["RIPE"]
+		err := b.ripeBulkDB.IterateRanges(func(inet ripebulk.Inetnum) error {
+			if enrich.IsRIPEPlaceholder(inet.Netname) || inet.Status == "ALLOCATED UNSPECIFIED" {
+				return nil
+			}
+			if all || countries[strings.ToUpper(inet.Country)] {
+				ranges = append(ranges, selectedRange{inet.Start, inet.End})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate RIPE bulk database: %w", err)
+		}
+	}
+
+	if b.arinBulkDB != nil {
+		all := rirs["ARIN"]
+		orgCountry := make(map[string]string)
+		err := b.arinBulkDB.IterateRanges(func(net arinbulk.NetBlock) error {
+			if !all {
+				if len(countries) == 0 || net.OrgID == "" {
+					return nil
+				}
+				country, ok := orgCountry[net.OrgID]
+				if !ok {
+					if org, err := b.arinBulkDB.GetOrganization(net.OrgID); err == nil {
+						country = strings.ToUpper(org.Country)
+					}
+					orgCountry[net.OrgID] = country
+				}
+				if !countries[country] {
+					return nil
+				}
+			}
+			ranges = append(ranges, selectedRange{net.Start, net.End})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate ARIN bulk database: %w", err)
+		}
+	}
+
+	prefixes, err := topLevelPrefixes(ranges)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Selected ranges from bulk databases", "ranges", len(ranges), "prefixes", len(prefixes))
+	return prefixes, nil
+}
+
+// selectedRange is an inclusive IPv4 range from a bulk database
+type selectedRange struct {
+	start, end uint32
+}
+
+// topLevelPrefixes drops ranges nested inside other ranges and converts the
+// rest to CIDR prefixes
+func topLevelPrefixes(ranges []selectedRange) ([]string, error) {
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].start != ranges[j].start {
+			return ranges[i].start < ranges[j].start
+		}
+		return ranges[i].end > ranges[j].end
+	})
+
+	var prefixes []string
+	var covered uint32
+	hasCovered := false
+	for _, r := range ranges {
+		if hasCovered && r.end <= covered {
+			continue // Nested in a range already selected
+		}
+		cidrs, err := ipcodec.RangeToPrefixes(ipcodec.Int32ToIPv4(r.start), ipcodec.Int32ToIPv4(r.end))
+		if err != nil {
+			return nil, err
+		}
+		for _, cidr := range cidrs {
+			prefixes = append(prefixes, cidr.String())
+		}
+		covered, hasCovered = r.end, true
+	}
+	return prefixes, nil
+}
+
+// toSet converts a list to a lookup set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
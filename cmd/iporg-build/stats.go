@@ -5,8 +5,9 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"sort"
 	"strings"
 
@@ -15,7 +16,7 @@ import (
 
 // RunStats displays database statistics
 func RunStats(ctx context.Context, dbPath string, verbose bool) error {
-	log.Printf("INFO: Opening database at %s", dbPath)
+	slog.Info("Opening database", "path", dbPath)
 	db, err := iporgdb.Open(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
@@ -44,6 +45,21 @@ func RunStats(ctx context.Context, dbPath string, verbose bool) error {
 		fmt.Printf("Schema version:         %d\n", stats.SchemaVersion)
 	}
 
+	// Effective build configuration
+	cfg, err := db.GetBuildConfig()
+	if err != nil {
+		slog.Warn("Failed to get build config", "err", err)
+	} else if cfg != nil {
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format build config: %w", err)
+		}
+		if cfg.Profile != "" {
+			fmt.Printf("Build profile:          %s\n", cfg.Profile)
+		}
+		fmt.Printf("Build configuration:\n%s\n", data)
+	}
+
 	fmt.Println()
 
 	// Record counts
@@ -6,16 +6,18 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
+	"time"
 
 	"github.com/wingedpig/iporg/pkg/iporgdb"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
 )
 
 // RunVerify performs consistency checks on the database
-func RunVerify(ctx context.Context, dbPath string) error {
-	log.Printf("INFO: Opening database at %s", dbPath)
+func RunVerify(ctx context.Context, dbPath, overridesFile string) error {
+	slog.Info("Opening database", "path", dbPath)
 	db, err := iporgdb.Open(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
@@ -25,57 +27,72 @@ func RunVerify(ctx context.Context, dbPath string) error {
 	var issues int
 
 	// Check 1: Verify no overlapping ranges
-	log.Println("INFO: Checking for overlapping ranges...")
+	slog.Info("Checking for overlapping ranges")
 	overlaps, err := checkOverlaps(db)
 	if err != nil {
 		return fmt.Errorf("overlap check failed: %w", err)
 	}
 	if overlaps > 0 {
-		log.Printf("ERROR: Found %d overlapping ranges", overlaps)
+		slog.Error("Found overlapping ranges", "count", overlaps)
 		issues += overlaps
 	} else {
-		log.Println("OK: No overlapping ranges found")
+		slog.Info("No overlapping ranges found")
 	}
 
 	// Check 2: Verify all records have required fields
-	log.Println("INFO: Checking for missing required fields...")
+	slog.Info("Checking for missing required fields")
 	missing, err := checkMissingFields(db)
 	if err != nil {
 		return fmt.Errorf("missing fields check failed: %w", err)
 	}
 	if missing > 0 {
-		log.Printf("ERROR: Found %d records with missing fields", missing)
+		slog.Error("Found records with missing fields", "count", missing)
 		issues += missing
 	} else {
-		log.Println("OK: All records have required fields")
+		slog.Info("All records have required fields")
 	}
 
 	// Check 3: Verify IP range validity
-	log.Println("INFO: Checking IP range validity...")
+	slog.Info("Checking IP range validity")
 	invalid, err := checkRangeValidity(db)
 	if err != nil {
 		return fmt.Errorf("range validity check failed: %w", err)
 	}
 	if invalid > 0 {
-		log.Printf("ERROR: Found %d invalid ranges", invalid)
+		slog.Error("Found invalid ranges", "count", invalid)
 		issues += invalid
 	} else {
-		log.Println("OK: All ranges are valid")
+		slog.Info("All ranges are valid")
 	}
 
 	// Check 4: Verify metadata
-	log.Println("INFO: Checking metadata...")
+	slog.Info("Checking metadata")
 	if err := checkMetadata(db); err != nil {
-		log.Printf("WARN: Metadata issues: %v", err)
+		slog.Warn("Metadata issues", "err", err)
 	} else {
-		log.Println("OK: Metadata is valid")
+		slog.Info("Metadata is valid")
+	}
+
+	// Check 5: Verify overrides still match the data (optional)
+	if overridesFile != "" {
+		slog.Info("Checking overrides", "file", overridesFile)
+		stale, err := checkOverrides(db, overridesFile)
+		if err != nil {
+			return fmt.Errorf("overrides check failed: %w", err)
+		}
+		if stale > 0 {
+			slog.Error("Found overrides that no longer match any range", "count", stale)
+			issues += stale
+		} else {
+			slog.Info("All overrides match at least one range")
+		}
 	}
 
 	if issues > 0 {
 		return fmt.Errorf("verification found %d issues", issues)
 	}
 
-	log.Println("INFO: All verification checks passed")
+	slog.Info("All verification checks passed")
 	return nil
 }
 
@@ -89,9 +106,8 @@ func checkOverlaps(db *iporgdb.DB) (int, error) {
 		if prevRec != nil {
 			// Check if current range overlaps with previous
 			if rec.Start.Compare(prevRec.End) <= 0 {
-				log.Printf("ERROR: Overlap detected: %s (%s-%s) overlaps with %s (%s-%s)",
-					rec.Prefix, rec.Start, rec.End,
-					prevRec.Prefix, prevRec.Start, prevRec.End)
+				slog.Error("Overlap detected", "prefix", rec.Prefix, "start", rec.Start, "end", rec.End,
+					"other_prefix", prevRec.Prefix, "other_start", prevRec.Start, "other_end", prevRec.End)
 				overlaps++
 			}
 		}
@@ -107,9 +123,8 @@ func checkOverlaps(db *iporgdb.DB) (int, error) {
 	err = db.IterateRanges(false, func(rec *model.Record) error {
 		if prevRec != nil {
 			if rec.Start.Compare(prevRec.End) <= 0 {
-				log.Printf("ERROR: Overlap detected: %s (%s-%s) overlaps with %s (%s-%s)",
-					rec.Prefix, rec.Start, rec.End,
-					prevRec.Prefix, prevRec.Start, prevRec.End)
+				slog.Error("Overlap detected", "prefix", rec.Prefix, "start", rec.Start, "end", rec.End,
+					"other_prefix", prevRec.Prefix, "other_start", prevRec.Start, "other_end", prevRec.End)
 				overlaps++
 			}
 		}
@@ -129,17 +144,17 @@ func checkMissingFields(db *iporgdb.DB) (int, error) {
 
 	check := func(rec *model.Record) error {
 		if rec.OrgName == "" {
-			log.Printf("ERROR: Record %s has no org name", rec.Prefix)
+			slog.Error("Record has no org name", "prefix", rec.Prefix)
 			missing++
 		}
 		if rec.Country == "" {
-			log.Printf("WARN: Record %s has no country", rec.Prefix)
+			slog.Warn("Record has no country", "prefix", rec.Prefix)
 		}
 		if rec.ASN == 0 {
-			log.Printf("WARN: Record %s has no ASN", rec.Prefix)
+			slog.Warn("Record has no ASN", "prefix", rec.Prefix)
 		}
 		if rec.SourceRole == "" {
-			log.Printf("WARN: Record %s has no source role", rec.Prefix)
+			slog.Warn("Record has no source role", "prefix", rec.Prefix)
 		}
 		return nil
 	}
@@ -161,20 +176,19 @@ func checkRangeValidity(db *iporgdb.DB) (int, error) {
 	check := func(rec *model.Record) error {
 		// Check that start <= end
 		if rec.Start.Compare(rec.End) > 0 {
-			log.Printf("ERROR: Invalid range %s: start %s > end %s",
-				rec.Prefix, rec.Start, rec.End)
+			slog.Error("Invalid range: start after end", "prefix", rec.Prefix, "start", rec.Start, "end", rec.End)
 			invalid++
 		}
 
 		// Check that start and end have the same IP version
 		if rec.Start.Is4() != rec.End.Is4() {
-			log.Printf("ERROR: Invalid range %s: mixed IP versions", rec.Prefix)
+			slog.Error("Invalid range: mixed IP versions", "prefix", rec.Prefix)
 			invalid++
 		}
 
 		// Verify prefix is valid
 		if _, err := netip.ParsePrefix(rec.Prefix); err != nil {
-			log.Printf("ERROR: Invalid prefix %s: %v", rec.Prefix, err)
+			slog.Error("Invalid prefix", "prefix", rec.Prefix, "err", err)
 			invalid++
 		}
 
@@ -200,7 +214,7 @@ func checkMetadata(db *iporgdb.DB) error {
 	if schema == 0 {
 		return fmt.Errorf("schema version not set")
 	}
-	log.Printf("INFO: Schema version: %d", schema)
+	slog.Info("Schema version", "version", schema)
 
 	builtAt, err := db.GetBuiltAt()
 	if err != nil {
@@ -209,7 +223,7 @@ func checkMetadata(db *iporgdb.DB) error {
 	if builtAt.IsZero() {
 		return fmt.Errorf("built_at not set")
 	}
-	log.Printf("INFO: Built at: %s", builtAt.Format("2006-01-02 15:04:05"))
+	slog.Info("Built at", "time", builtAt.Format("2006-01-02 15:04:05"))
 
 	builderVer, err := db.GetBuilderVersion()
 	if err != nil {
@@ -218,7 +232,36 @@ func checkMetadata(db *iporgdb.DB) error {
 	if builderVer == "" {
 		return fmt.Errorf("builder version not set")
 	}
-	log.Printf("INFO: Builder version: %s", builderVer)
+	slog.Info("Builder version", "version", builderVer)
 
 	return nil
 }
+
+// checkOverrides reports overrides that match no stored range, and expired ones
+func checkOverrides(db *iporgdb.DB, path string) (int, error) {
+	set, err := overrides.Load(path)
+	if err != nil {
+		return 0, err
+	}
+
+	stale := 0
+	now := time.Now()
+	for _, o := range set.Entries() {
+		if o.Expired(now) {
+			slog.Warn("Override expired", "cidr", o.CIDR, "expires", o.Expires, "comment", o.Comment)
+			continue
+		}
+
+		found, err := db.HasOverlap(o.Prefix())
+		if err != nil {
+			return 0, fmt.Errorf("failed to check %s: %w", o.CIDR, err)
+		}
+		if !found {
+			slog.Error("Override matches no range in the database", "cidr", o.CIDR, "comment", o.Comment)
+			stale++
+		}
+	}
+
+	slog.Info("Checked overrides", "count", set.Len())
+	return stale, nil
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Access log formats
+const (
+	logCombined = "combined" // Apache/nginx combined (or common) format
+	logNginx    = "nginx"    // nginx "main": combined plus "$http_x_forwarded_for"
+	logJSON     = "json"     // One JSON object per line
+	logSyslog   = "syslog"   // Syslog/firewall lines (SRC=, src=, srcip= or the first address)
+)
+
+// jsonClientKeys are tried in order for the client address of a JSON log line
+var jsonClientKeys = []string{"remote_addr", "client_ip", "clientip", "client", "ip", "src_ip", "source_ip", "remote_ip"}
+
+// jsonXFFKeys are tried in order for the X-Forwarded-For chain of a JSON log line
+var jsonXFFKeys = []string{"http_x_forwarded_for", "x_forwarded_for", "X-Forwarded-For", "xff"}
+
+// syslogKeys introduce the source address in firewall logs
+var syslogKeys = []string{"SRC=", "src=", "srcip=", "src_ip=", "client="}
+
+// clientExtractor finds the client IP in one log line
+type clientExtractor struct {
+	format  string
+	column  []string       // Extra JSON field path tried first
+	trusted []netip.Prefix // Proxies skipped in X-Forwarded-For chains (nil = private ranges)
+}
+
+// newClientExtractor creates an extractor for format. trusted lists the
+// proxy ranges to skip in X-Forwarded-For chains: "" trusts private,
+// loopback and link-local addresses, "none" ignores the chains.
+func newClientExtractor(format, column, trusted string) (*clientExtractor, error) {
+	switch format {
+	case logCombined, logNginx, logJSON, logSyslog:
+	default:
+		return nil, fmt.Errorf("unknown access log format %q (want combined, nginx, json or syslog)", format)
+	}
+	e := &clientExtractor{format: format}
+	if column != "" {
+		e.column = strings.Split(column, ".")
+	}
+
+	switch trusted {
+	case "":
+	case "none":
+		e.trusted = []netip.Prefix{}
+	default:
+		for _, s := range strings.Split(trusted, ",") {
+			s = strings.TrimSpace(s)
+			p, err := netip.ParsePrefix(s)
+			if err != nil {
+				addr, aerr := netip.ParseAddr(s)
+				if aerr != nil {
+					return nil, fmt.Errorf("invalid trusted proxy %q", s)
+				}
+				p = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			e.trusted = append(e.trusted, p.Masked())
+		}
+	}
+	return e, nil
+}
+
+// extract returns the client IP of line ("" if none is found), and for JSON
+// lines whether the line is an object
+func (e *clientExtractor) extract(line []byte) (ip string, object bool) {
+	var remote, xff string
+	switch e.format {
+	case logCombined, logNginx:
+		remote, _, _ = strings.Cut(string(line), " ")
+		if e.format == logNginx {
+			if quoted := quotedFields(line); len(quoted) >= 4 {
+				xff = quoted[3]
+			}
+		}
+	case logJSON:
+		var obj map[string]any
+		if err := json.Unmarshal(line, &obj); err != nil || obj == nil {
+			return "", false
+		}
+		object = true
+		if e.column != nil {
+			remote = lookupPath(obj, e.column)
+		}
+		for _, key := range jsonClientKeys {
+			if remote != "" {
+				break
+			}
+			remote = lookupPath(obj, []string{key})
+		}
+		for _, key := range jsonXFFKeys {
+			if xff = lookupPath(obj, []string{key}); xff != "" {
+				break
+			}
+		}
+	case logSyslog:
+		remote = syslogSource(string(line))
+	}
+
+	return e.client(remote, xff), object
+}
+
+// client picks the client from the connecting address and an
+// X-Forwarded-For chain: walking the chain from the right, the first
+// address that is not a trusted proxy
+func (e *clientExtractor) client(remote, xff string) string {
+	addr, ok := parseAddrToken(remote)
+	if !ok {
+		return strings.TrimSpace(remote) // Looked up and reported as invalid
+	}
+	if xff == "" || xff == "-" || !e.isTrusted(addr) {
+		return addr.String()
+	}
+
+	hops := strings.Split(xff, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddrToken(hops[i])
+		if !ok {
+			break // Forged or garbled: trust nothing further left
+		}
+		addr = hop
+		if !e.isTrusted(hop) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+// isTrusted reports whether addr is a trusted proxy
+func (e *clientExtractor) isTrusted(addr netip.Addr) bool {
+	if e.trusted == nil {
+		return addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast()
+	}
+	for _, p := range e.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// syslogSource returns the source address of a firewall log line: the
+// value of the first SRC=-style key, or else the first address in the line
+func syslogSource(line string) string {
+	for _, key := range syslogKeys {
+		if i := strings.Index(line, key); i >= 0 && (i == 0 || line[i-1] == ' ') {
+			value, _, _ := strings.Cut(line[i+len(key):], " ")
+			return value
+		}
+	}
+	for _, tok := range strings.Fields(line) {
+		if addr, ok := parseAddrToken(tok); ok {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+// parseAddrToken parses an address that may be quoted, bracketed, carry a
+// port or be followed by punctuation
+func parseAddrToken(s string) (netip.Addr, bool) {
+	s = strings.Trim(strings.TrimSpace(s), `"',;()`)
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.Unmap(), true
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(s, "[]")); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// quotedFields returns the double-quoted fields of a log line, unescaped
+func quotedFields(line []byte) []string {
+	var fields []string
+	for {
+		start := bytes.IndexByte(line, '"')
+		if start < 0 {
+			return fields
+		}
+		line = line[start+1:]
+		var field []byte
+		i := 0
+		for ; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+			}
+			field = append(field, line[i])
+		}
+		fields = append(fields, string(field))
+		if i >= len(line) {
+			return fields
+		}
+		line = line[i+1:]
+	}
+}
+
+// lookupPath returns the string at path in a decoded JSON object
+func lookupPath(obj map[string]any, path []string) string {
+	var v any = obj
+	for _, key := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = m[key]
+	}
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
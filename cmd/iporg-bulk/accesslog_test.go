@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExtractClient(t *testing.T) {
+	tests := []struct {
+		format, trusted, line, want string
+	}{
+		{logCombined, "", `203.0.113.9 - - [10/Oct/2025:13:55:36 +0000] "GET / HTTP/1.1" 200 2326 "-" "curl/8.0"`, "203.0.113.9"},
+		{logCombined, "", `::ffff:203.0.113.9 - - [10/Oct/2025:13:55:36 +0000] "GET / HTTP/1.1" 200 0 "-" "-"`, "203.0.113.9"},
+		// nginx: the proxy is private, so the rightmost untrusted hop wins
+		{logNginx, "", `10.0.0.5 - - [10/Oct/2025:13:55:36 +0000] "GET / HTTP/1.1" 200 5 "-" "Mozilla \"x\"" "198.51.100.7, 203.0.113.9"`, "203.0.113.9"},
+		{logNginx, "", `10.0.0.5 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua" "198.51.100.7, 10.0.0.9"`, "198.51.100.7"},
+		// A public connecting address is the client whatever the header says
+		{logNginx, "", `192.0.2.1 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua" "198.51.100.7"`, "192.0.2.1"},
+		{logNginx, "none", `10.0.0.5 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua" "198.51.100.7"`, "10.0.0.5"},
+		{logNginx, "192.0.2.0/24", `192.0.2.1 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua" "198.51.100.7"`, "198.51.100.7"},
+		// A garbled hop stops the walk
+		{logNginx, "", `10.0.0.5 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua" "198.51.100.7, unknown, 10.0.0.9"`, "10.0.0.9"},
+		{logJSON, "", `{"remote_addr":"10.0.0.5","http_x_forwarded_for":"198.51.100.7","status":200}`, "198.51.100.7"},
+		{logJSON, "", `{"client":{"ip":"198.51.100.7"}}`, ""},
+		// A top-level "ip" is often the server's own address
+		{logJSON, "", `{"ip":"192.0.2.80","remote_addr":"198.51.100.7"}`, "198.51.100.7"},
+		{logSyslog, "", `Oct 10 13:55:36 fw kernel: IN=eth0 OUT= SRC=198.51.100.7 DST=192.0.2.1 PROTO=TCP`, "198.51.100.7"},
+		{logSyslog, "", `Oct 10 13:55:36 sshd[123]: Failed password for root from 198.51.100.7 port 22`, "198.51.100.7"},
+		{logSyslog, "", `Oct 10 13:55:36 fw: connection from [2001:db8::1]:443 refused`, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		e, err := newClientExtractor(tt.format, "", tt.trusted)
+		if err != nil {
+			t.Fatalf("Failed to create extractor: %v", err)
+		}
+		if got, _ := e.extract([]byte(tt.line)); got != tt.want {
+			t.Errorf("%s %q: got %q, want %q", tt.format, tt.line, got, tt.want)
+		}
+	}
+
+	// A JSON field path is tried first
+	e, err := newClientExtractor(logJSON, "client.ip", "")
+	if err != nil {
+		t.Fatalf("Failed to create extractor: %v", err)
+	}
+	if got, _ := e.extract([]byte(`{"client":{"ip":"198.51.100.7"}}`)); got != "198.51.100.7" {
+		t.Errorf("got %q, want 198.51.100.7", got)
+	}
+
+	if _, err := newClientExtractor("w3c", "", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := newClientExtractor(logNginx, "", "not-a-cidr"); err == nil {
+		t.Error("expected an error for an invalid trusted proxy")
+	}
+}
+
+func TestAnnotateLog(t *testing.T) {
+	e, err := newClientExtractor(logCombined, "", "")
+	if err != nil {
+		t.Fatalf("Failed to create extractor: %v", err)
+	}
+	input := `8.8.8.8 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua"` + "\n" + `garbage` + "\n"
+	reader := newLogReader(strings.NewReader(input), e)
+	fields, err := parseFields("asn,org_name", "")
+	if err != nil {
+		t.Fatalf("Failed to parse fields: %v", err)
+	}
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	writer, err := newRowWriter(bw, formatLog, reader, fields)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	var readErr error
+	for r := range reader.rows(&readErr) {
+		if err := writer.write(r, testResults[r.ip]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	bw.Flush()
+
+	want := `8.8.8.8 - - [t] "GET / HTTP/1.1" 200 5 "-" "ua" asn=15169 org_name="Google LLC"` + "\n" + `garbage` + "\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	if _, err := newRowWriter(bw, formatCSV, reader, fields); err == nil {
+		t.Error("expected an error writing a text log as CSV")
+	}
+}
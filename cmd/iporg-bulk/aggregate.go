@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// Report formats
+const (
+	reportTable = "table"
+	reportCSV   = "csv"
+	reportJSON  = "json"
+)
+
+// aggregateKeys maps each --aggregate value to the key and detail of a result
+var aggregateKeys = map[string]func(ip string, res *model.LookupResult) (key, detail string){
+	"org": func(ip string, res *model.LookupResult) (string, string) {
+		return res.OrgName, ""
+	},
+	"asn": func(ip string, res *model.LookupResult) (string, string) {
+		if res.ASN == 0 {
+			return "", ""
+		}
+		return "AS" + strconv.Itoa(res.ASN), res.ASNName
+	},
+	"country": func(ip string, res *model.LookupResult) (string, string) {
+		return res.Country, ""
+	},
+	"rir": func(ip string, res *model.LookupResult) (string, string) {
+		return res.RIR, ""
+	},
+	"prefix": func(ip string, res *model.LookupResult) (string, string) {
+		return res.Prefix, res.OrgName
+	},
+	"ip": func(ip string, res *model.LookupResult) (string, string) {
+		return ip, fmt.Sprintf("%s, AS%d, %s", res.OrgName, res.ASN, res.Country)
+	},
+}
+
+// aggregator counts lookups and unique IPs per key. It is only used from
+// the goroutine that emits results.
+type aggregator struct {
+	by      string
+	key     func(ip string, res *model.LookupResult) (string, string)
+	entries map[string]*aggregateEntry
+	ips     map[string]struct{} // Every IP seen
+
+	total, notFound, invalid, errors int
+}
+
+// aggregateEntry is the count for one key
+type aggregateEntry struct {
+	key    string
+	detail string
+	count  int
+	ips    map[string]struct{}
+}
+
+// newAggregator creates an aggregator grouping by one of aggregateKeys
+func newAggregator(by string) (*aggregator, error) {
+	key, ok := aggregateKeys[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown aggregate %q (want org, asn, country, rir, prefix or ip)", by)
+	}
+	return &aggregator{
+		by:      by,
+		key:     key,
+		entries: make(map[string]*aggregateEntry),
+		ips:     make(map[string]struct{}),
+	}, nil
+}
+
+// add counts one lookup of ip; res is nil when the lookup failed with err.
+// Failed lookups are counted separately, except that the ip aggregate lists
+// every address so that top talkers appear whether or not they were found.
+func (a *aggregator) add(ip string, res *model.LookupResult, err error) {
+	a.total++
+	a.ips[ip] = struct{}{}
+
+	var key, detail string
+	switch {
+	case err == nil:
+		key, detail = a.key(ip, res)
+		if key == "" {
+			key = "(unknown)"
+		}
+	case errors.Is(err, model.ErrNotFound):
+		a.notFound++
+		key, detail = ip, "(not found)"
+	case errors.Is(err, model.ErrInvalidIP):
+		a.invalid++
+		key, detail = ip, "(invalid)"
+	default:
+		a.errors++
+		key, detail = ip, "(error)"
+	}
+	if err != nil && (a.by != "ip" || ip == "") {
+		return
+	}
+
+	e := a.entries[key]
+	if e == nil {
+		e = &aggregateEntry{key: key, detail: detail, ips: make(map[string]struct{})}
+		a.entries[key] = e
+	}
+	e.count++
+	e.ips[ip] = struct{}{}
+}
+
+// sorted returns the top entries (all if top <= 0) by descending count,
+// then key
+func (a *aggregator) sorted(top int) []*aggregateEntry {
+	entries := make([]*aggregateEntry, 0, len(a.entries))
+	for _, e := range a.entries {
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(x, y *aggregateEntry) int {
+		if c := cmp.Compare(y.count, x.count); c != 0 {
+			return c
+		}
+		return strings.Compare(x.key, y.key)
+	})
+	if top > 0 && len(entries) > top {
+		entries = entries[:top]
+	}
+	return entries
+}
+
+// write writes the top entries and the summary counts in format
+func (a *aggregator) write(w io.Writer, format string, top int) error {
+	entries := a.sorted(top)
+	switch format {
+	case reportTable:
+		return a.writeTable(w, entries)
+	case reportCSV:
+		return a.writeCSV(w, entries)
+	case reportJSON:
+		return a.writeJSON(w, entries)
+	default:
+		return fmt.Errorf("unknown report format %q (want table, csv or json)", format)
+	}
+}
+
+func (a *aggregator) writeTable(w io.Writer, entries []*aggregateEntry) error {
+	fmt.Fprintf(w, "%10s  %7s  %10s  %s\n", "COUNT", "PERCENT", "UNIQUE IPS", strings.ToUpper(a.by))
+	for _, e := range entries {
+		label := e.key
+		if e.detail != "" {
+			label += "  " + e.detail
+		}
+		fmt.Fprintf(w, "%10d  %6.2f%%  %10d  %s\n", e.count, percent(e.count, a.total), len(e.ips), label)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Lookups:      %d\n", a.total)
+	fmt.Fprintf(w, "Unique IPs:   %d\n", len(a.ips))
+	fmt.Fprintf(w, "Not found:    %d (%.2f%%)\n", a.notFound, percent(a.notFound, a.total))
+	fmt.Fprintf(w, "Invalid:      %d (%.2f%%)\n", a.invalid, percent(a.invalid, a.total))
+	_, err := fmt.Fprintf(w, "Errors:       %d\n", a.errors)
+	return err
+}
+
+// writeCSV writes one row per entry; the summary counts follow as rows
+// keyed by a leading "#"
+func (a *aggregator) writeCSV(w io.Writer, entries []*aggregateEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{a.by, "detail", "count", "percent", "unique_ips"})
+	for _, e := range entries {
+		cw.Write([]string{e.key, e.detail, strconv.Itoa(e.count),
+			strconv.FormatFloat(percent(e.count, a.total), 'f', 2, 64), strconv.Itoa(len(e.ips))})
+	}
+	for _, s := range []struct {
+		name  string
+		count int
+	}{{"#total", a.total}, {"#not_found", a.notFound}, {"#invalid", a.invalid}, {"#errors", a.errors}} {
+		cw.Write([]string{s.name, "", strconv.Itoa(s.count),
+			strconv.FormatFloat(percent(s.count, a.total), 'f', 2, 64), ""})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// aggregateReport is the JSON report
+type aggregateReport struct {
+	Aggregate string               `json:"aggregate"`
+	Total     int                  `json:"total"`
+	UniqueIPs int                  `json:"unique_ips"`
+	NotFound  int                  `json:"not_found"`
+	Invalid   int                  `json:"invalid"`
+	Errors    int                  `json:"errors"`
+	Rows      []aggregateReportRow `json:"rows"`
+}
+
+// aggregateReportRow is one entry of the JSON report
+type aggregateReportRow struct {
+	Key       string  `json:"key"`
+	Detail    string  `json:"detail,omitempty"`
+	Count     int     `json:"count"`
+	Percent   float64 `json:"percent"`
+	UniqueIPs int     `json:"unique_ips"`
+}
+
+func (a *aggregator) writeJSON(w io.Writer, entries []*aggregateEntry) error {
+	report := aggregateReport{
+		Aggregate: a.by,
+		Total:     a.total,
+		UniqueIPs: len(a.ips),
+		NotFound:  a.notFound,
+		Invalid:   a.invalid,
+		Errors:    a.errors,
+		Rows:      make([]aggregateReportRow, 0, len(entries)),
+	}
+	for _, e := range entries {
+		report.Rows = append(report.Rows, aggregateReportRow{
+			Key:       e.key,
+			Detail:    e.detail,
+			Count:     e.count,
+			Percent:   percent(e.count, a.total),
+			UniqueIPs: len(e.ips),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// percent returns n as a percentage of total
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+func newTestAggregator(t *testing.T, by string) *aggregator {
+	t.Helper()
+	agg, err := newAggregator(by)
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+	google := &model.LookupResult{ASN: 15169, ASNName: "GOOGLE", OrgName: "Google LLC", Country: "US", RIR: "ARIN", Prefix: "8.8.8.0/24"}
+	cloudflare := &model.LookupResult{ASN: 13335, ASNName: "CLOUDFLARENET", OrgName: "Cloudflare", Country: "US", RIR: "ARIN", Prefix: "1.1.1.0/24"}
+	agg.add("8.8.8.8", google, nil)
+	agg.add("8.8.8.8", google, nil)
+	agg.add("8.8.4.4", google, nil)
+	agg.add("1.1.1.1", cloudflare, nil)
+	agg.add("192.0.2.1", nil, model.ErrNotFound)
+	agg.add("bad", nil, model.ErrInvalidIP)
+	agg.add("9.9.9.9", nil, errors.New("closed"))
+	return agg
+}
+
+func TestAggregateTable(t *testing.T) {
+	agg := newTestAggregator(t, "asn")
+
+	var out strings.Builder
+	if err := agg.write(&out, reportTable, 1); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "     COUNT  PERCENT  UNIQUE IPS  ASN\n" +
+		"         3   42.86%           2  AS15169  GOOGLE\n" +
+		"\n" +
+		"Lookups:      7\n" +
+		"Unique IPs:   6\n" +
+		"Not found:    1 (14.29%)\n" +
+		"Invalid:      1 (14.29%)\n" +
+		"Errors:       1\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestAggregateCSV(t *testing.T) {
+	agg := newTestAggregator(t, "country")
+
+	var out strings.Builder
+	if err := agg.write(&out, reportCSV, 0); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	want := "country,detail,count,percent,unique_ips\n" +
+		"US,,4,57.14,3\n" +
+		"#total,,7,100.00,\n" +
+		"#not_found,,1,14.29,\n" +
+		"#invalid,,1,14.29,\n" +
+		"#errors,,1,14.29,\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestAggregateJSON(t *testing.T) {
+	agg := newTestAggregator(t, "ip")
+
+	var out strings.Builder
+	if err := agg.write(&out, reportJSON, 2); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var report aggregateReport
+	if err := json.Unmarshal([]byte(out.String()), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Total != 7 || report.UniqueIPs != 6 || report.NotFound != 1 || report.Invalid != 1 || report.Errors != 1 {
+		t.Errorf("got summary %+v, want 7 total, 6 unique, 1 not found, 1 invalid, 1 error", report)
+	}
+	if len(report.Rows) != 2 || report.Rows[0].Key != "8.8.8.8" || report.Rows[0].Count != 2 {
+		t.Fatalf("got rows %+v, want 8.8.8.8 with 2 lookups first", report.Rows)
+	}
+
+	// Top talkers include addresses that were not found
+	if got := len(agg.sorted(0)); got != 6 {
+		t.Errorf("got %d rows, want 6", got)
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	if _, err := newAggregator("city"); err == nil {
+		t.Error("expected an error for an unknown aggregate")
+	}
+	agg := newTestAggregator(t, "org")
+	if err := agg.write(&strings.Builder{}, "xml", 0); err == nil {
+		t.Error("expected an error for an unknown report format")
+	}
+}
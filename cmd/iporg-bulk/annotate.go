@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// Input and output formats
+const (
+	formatIPs   = "ips" // One IP per line (input only)
+	formatCSV   = "csv"
+	formatTSV   = "tsv"
+	formatJSONL = "jsonl"
+	formatLog   = "log" // Access log lines with key=value fields appended (output only)
+)
+
+// resultFields maps LookupResult JSON names to their values, in output order
+var resultFields = []struct {
+	name  string
+	value func(*model.LookupResult) any
+}{
+	{"ip", func(r *model.LookupResult) any { return r.IP }},
+	{"asn", func(r *model.LookupResult) any { return r.ASN }},
+	{"asn_name", func(r *model.LookupResult) any { return r.ASNName }},
+	{"org_name", func(r *model.LookupResult) any { return r.OrgName }},
+	{"rir", func(r *model.LookupResult) any { return r.RIR }},
+	{"country", func(r *model.LookupResult) any { return r.Country }},
+	{"region", func(r *model.LookupResult) any { return r.Region }},
+	{"city", func(r *model.LookupResult) any { return r.City }},
+	{"lat", func(r *model.LookupResult) any { return r.Lat }},
+	{"lon", func(r *model.LookupResult) any { return r.Lon }},
+	{"prefix", func(r *model.LookupResult) any { return r.Prefix }},
+	{"source_role", func(r *model.LookupResult) any { return r.SourceRole }},
+}
+
+// field is a LookupResult field appended to each row
+type field struct {
+	name  string // Output column or key, including any prefix
+	value func(*model.LookupResult) any
+}
+
+// parseFields resolves a comma-separated list of LookupResult field names
+func parseFields(list, prefix string) ([]field, error) {
+	var fields []field
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, f := range resultFields {
+			if f.name == name {
+				fields = append(fields, field{name: prefix + name, value: f.value})
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields selected")
+	}
+	return fields, nil
+}
+
+// row is one input record and the IP found in it
+type row struct {
+	ip     string
+	cols   []string // CSV/TSV columns
+	raw    []byte   // JSONL or JSON log line
+	parsed bool     // JSONL line decoded to an object
+	line   []byte   // Text access log line
+}
+
+// rowReader reads records of one input format
+type rowReader struct {
+	format string
+	header []string // CSV/TSV column names (nil without a header)
+	column int      // CSV/TSV column holding the IP
+	path   []string // JSONL field path to the IP
+	logs   *clientExtractor
+
+	csv     *csv.Reader
+	scanner *bufio.Scanner
+}
+
+// newRowReader prepares to read format from r, reading the CSV/TSV header
+// if there is one. column names the IP column (or its 1-based index) for
+// CSV/TSV and the dotted field path for JSONL.
+func newRowReader(r io.Reader, format, column string, hasHeader bool) (*rowReader, error) {
+	rr := &rowReader{format: format}
+	switch format {
+	case formatIPs, formatJSONL:
+		rr.scanner = bufio.NewScanner(r)
+		rr.scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+		if format == formatJSONL {
+			rr.path = strings.Split(column, ".")
+		}
+		return rr, nil
+	case formatCSV, formatTSV:
+		rr.csv = csv.NewReader(r)
+		if format == formatTSV {
+			rr.csv.Comma = '\t'
+			rr.csv.LazyQuotes = true
+		}
+		rr.csv.FieldsPerRecord = -1
+		rr.csv.ReuseRecord = false
+	default:
+		return nil, fmt.Errorf("unknown input format %q (want ips, csv, tsv or jsonl)", format)
+	}
+
+	if hasHeader {
+		header, err := rr.csv.Read()
+		if err == io.EOF {
+			return nil, fmt.Errorf("input has no header row")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		rr.header = header
+	}
+
+	if i, err := strconv.Atoi(column); err == nil {
+		if i < 1 {
+			return nil, fmt.Errorf("column index %d out of range", i)
+		}
+		rr.column = i - 1
+		return rr, nil
+	}
+	for i, name := range rr.header {
+		if strings.EqualFold(strings.TrimSpace(name), column) {
+			rr.column = i
+			return rr, nil
+		}
+	}
+	if rr.header == nil {
+		return nil, fmt.Errorf("--column must be an index when the input has no header")
+	}
+	return nil, fmt.Errorf("column %q not found in header", column)
+}
+
+// newLogReader prepares to read access log lines, extracting client IPs with e
+func newLogReader(r io.Reader, e *clientExtractor) *rowReader {
+	rr := &rowReader{format: e.format, logs: e}
+	rr.scanner = bufio.NewScanner(r)
+	rr.scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return rr
+}
+
+// rows yields the input records one at a time. A read error ends the
+// sequence and is stored in *errp.
+func (rr *rowReader) rows(errp *error) iter.Seq[row] {
+	if rr.csv != nil {
+		return func(yield func(row) bool) {
+			for {
+				cols, err := rr.csv.Read()
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					*errp = err
+					return
+				}
+				r := row{cols: cols}
+				if rr.column < len(cols) {
+					r.ip = strings.TrimSpace(cols[rr.column])
+				}
+				if !yield(r) {
+					return
+				}
+			}
+		}
+	}
+
+	return func(yield func(row) bool) {
+		for rr.scanner.Scan() {
+			line := bytes.TrimSpace(rr.scanner.Bytes())
+			if len(line) == 0 || (rr.format == formatIPs && line[0] == '#') {
+				continue
+			}
+			var r row
+			switch {
+			case rr.logs != nil && rr.format == logJSON:
+				r.raw = bytes.Clone(line)
+				r.ip, r.parsed = rr.logs.extract(r.raw)
+			case rr.logs != nil:
+				r.line = bytes.Clone(line)
+				r.ip, _ = rr.logs.extract(r.line)
+			case rr.format == formatIPs:
+				r.ip = string(line)
+			default:
+				r.raw = bytes.Clone(line)
+				r.ip, r.parsed = jsonPath(r.raw, rr.path)
+			}
+			if !yield(r) {
+				return
+			}
+		}
+		*errp = rr.scanner.Err()
+	}
+}
+
+// jsonPath returns the string at path in a JSON object, and whether line
+// is an object at all
+func jsonPath(line []byte, path []string) (string, bool) {
+	var obj map[string]any
+	if err := json.Unmarshal(line, &obj); err != nil || obj == nil {
+		return "", false
+	}
+	return lookupPath(obj, path), true
+}
+
+// rowWriter writes annotated records in one output format
+type rowWriter struct {
+	format string
+	header []string // Input column names, for CSV/TSV to JSONL
+	fields []field
+
+	w   *bufio.Writer
+	csv *csv.Writer
+	buf []byte
+}
+
+// newRowWriter creates a writer of format to w appending fields. The header
+// row, if any, is written immediately.
+func newRowWriter(w *bufio.Writer, format string, in *rowReader, fields []field) (*rowWriter, error) {
+	rw := &rowWriter{format: format, header: in.header, fields: fields, w: w}
+	textLog := in.logs != nil && in.format != logJSON
+	if textLog != (format == formatLog) {
+		return nil, fmt.Errorf("%s input cannot be written as %s", in.format, format)
+	}
+	switch format {
+	case formatCSV, formatTSV:
+		if in.format == formatJSONL || in.format == logJSON {
+			return nil, fmt.Errorf("JSON input can only be written as JSONL")
+		}
+		rw.csv = csv.NewWriter(w)
+		if format == formatTSV {
+			rw.csv.Comma = '\t'
+		}
+		header := in.header
+		if in.format == formatIPs {
+			header = []string{"ip"}
+		}
+		if header != nil {
+			out := append([]string(nil), header...)
+			for _, f := range fields {
+				out = append(out, f.name)
+			}
+			if err := rw.csv.Write(out); err != nil {
+				return nil, err
+			}
+		}
+	case formatJSONL, formatLog:
+	default:
+		return nil, fmt.Errorf("unknown output format %q (want csv, tsv or jsonl)", format)
+	}
+	return rw, nil
+}
+
+// write writes r with the fields of res appended; res is nil when the
+// lookup failed, leaving the fields empty (CSV/TSV) or absent (JSONL)
+func (rw *rowWriter) write(r row, res *model.LookupResult) error {
+	if rw.csv != nil {
+		cols := r.cols
+		if cols == nil {
+			cols = []string{r.ip}
+		}
+		out := append(make([]string, 0, len(cols)+len(rw.fields)), cols...)
+		for _, f := range rw.fields {
+			s := ""
+			if res != nil {
+				s = formatValue(f.value(res))
+			}
+			out = append(out, s)
+		}
+		return rw.csv.Write(out)
+	}
+
+	buf := rw.buf[:0]
+	switch {
+	case r.line != nil:
+		buf = append(buf, r.line...)
+		if res != nil {
+			for _, f := range rw.fields {
+				buf = append(buf, ' ')
+				buf = append(buf, f.name...)
+				buf = append(buf, '=')
+				buf = appendLogValue(buf, formatValue(f.value(res)))
+			}
+		}
+	case r.raw != nil && !r.parsed:
+		// Not an object: pass the line through unchanged
+		buf = append(buf, r.raw...)
+	case r.raw != nil:
+		buf = append(buf, bytes.TrimSuffix(r.raw, []byte("}"))...)
+		buf = bytes.TrimRight(buf, " \t")
+		first := buf[len(buf)-1] == '{'
+		buf = rw.appendFields(buf, res, first)
+		buf = append(buf, '}')
+	default:
+		buf = append(buf, '{')
+		for i, col := range r.cols {
+			name := "col" + strconv.Itoa(i+1)
+			if i < len(rw.header) {
+				name = rw.header[i]
+			}
+			buf = appendJSONField(buf, name, col, i == 0)
+		}
+		buf = rw.appendFields(buf, res, len(r.cols) == 0)
+		buf = append(buf, '}')
+	}
+	buf = append(buf, '\n')
+	rw.buf = buf
+	_, err := rw.w.Write(buf)
+	return err
+}
+
+// appendFields appends the looked-up fields as JSON members
+func (rw *rowWriter) appendFields(buf []byte, res *model.LookupResult, first bool) []byte {
+	if res == nil {
+		return buf
+	}
+	for _, f := range rw.fields {
+		buf = appendJSONField(buf, f.name, f.value(res), first)
+		first = false
+	}
+	return buf
+}
+
+// flush writes any buffered CSV/TSV output to the underlying writer
+func (rw *rowWriter) flush() error {
+	if rw.csv != nil {
+		rw.csv.Flush()
+		return rw.csv.Error()
+	}
+	return nil
+}
+
+// appendJSONField appends "key":value, preceded by a comma unless first
+func appendJSONField(buf []byte, key string, value any, first bool) []byte {
+	if !first {
+		buf = append(buf, ',')
+	}
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v = []byte("null")
+	}
+	buf = append(buf, k...)
+	buf = append(buf, ':')
+	return append(buf, v...)
+}
+
+// appendLogValue appends a key=value value, quoted if it is empty or holds
+// spaces, quotes or equals signs
+func appendLogValue(buf []byte, s string) []byte {
+	if s == "" || strings.ContainsAny(s, " \t\"=") {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+// formatValue formats a field value for a CSV/TSV column
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// annotate reads input, looks each IP up in results and returns the output
+func annotate(t *testing.T, input, inFormat, outFormat, column string, results map[string]*model.LookupResult) string {
+	t.Helper()
+
+	reader, err := newRowReader(strings.NewReader(input), inFormat, column, true)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	fields, err := parseFields("asn,org_name", "")
+	if err != nil {
+		t.Fatalf("Failed to parse fields: %v", err)
+	}
+	var out bytes.Buffer
+	bw := bufio.NewWriter(&out)
+	writer, err := newRowWriter(bw, outFormat, reader, fields)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+
+	var readErr error
+	for r := range reader.rows(&readErr) {
+		if err := writer.write(r, results[r.ip]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if readErr != nil {
+		t.Fatalf("Read failed: %v", readErr)
+	}
+	if err := writer.flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	bw.Flush()
+	return out.String()
+}
+
+var testResults = map[string]*model.LookupResult{
+	"8.8.8.8": {IP: "8.8.8.8", ASN: 15169, OrgName: "Google LLC"},
+}
+
+func TestReadIPs(t *testing.T) {
+	input := "8.8.8.8\n\n# comment\n  1.1.1.1  \n2001:db8::1"
+
+	reader, err := newRowReader(strings.NewReader(input), formatIPs, "", false)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	var got []string
+	var readErr error
+	for r := range reader.rows(&readErr) {
+		got = append(got, r.ip)
+	}
+	if readErr != nil {
+		t.Fatalf("Read failed: %v", readErr)
+	}
+	want := []string{"8.8.8.8", "1.1.1.1", "2001:db8::1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestAnnotateCSV(t *testing.T) {
+	input := "time,Client IP,path\n10:00,8.8.8.8,/a\n10:01,192.0.2.1,\"/b,c\"\n"
+
+	got := annotate(t, input, formatCSV, formatCSV, "client ip", testResults)
+	want := "time,Client IP,path,asn,org_name\n10:00,8.8.8.8,/a,15169,Google LLC\n10:01,192.0.2.1,\"/b,c\",,\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// By index, converted to JSONL keyed by the header
+	got = annotate(t, input, formatCSV, formatJSONL, "2", testResults)
+	want = `{"time":"10:00","Client IP":"8.8.8.8","path":"/a","asn":15169,"org_name":"Google LLC"}` + "\n" +
+		`{"time":"10:01","Client IP":"192.0.2.1","path":"/b,c"}` + "\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAnnotateJSONL(t *testing.T) {
+	input := `{"b":1,"client":{"ip":"8.8.8.8"},"a":"x"}` + "\n" +
+		`{}` + "\n" +
+		`not json` + "\n"
+
+	got := annotate(t, input, formatJSONL, formatJSONL, "client.ip", testResults)
+	want := `{"b":1,"client":{"ip":"8.8.8.8"},"a":"x","asn":15169,"org_name":"Google LLC"}` + "\n" +
+		`{}` + "\n" +
+		`not json` + "\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAnnotateErrors(t *testing.T) {
+	if _, err := newRowReader(strings.NewReader("a,b\n"), formatCSV, "ip", true); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+	if _, err := newRowReader(strings.NewReader("a,b\n"), formatCSV, "ip", false); err == nil {
+		t.Error("expected an error for a column name without a header")
+	}
+	if _, err := parseFields("asn,bogus", ""); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	reader, err := newRowReader(strings.NewReader("{}\n"), formatJSONL, "ip", true)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	if _, err := newRowWriter(bufio.NewWriter(&bytes.Buffer{}), formatCSV, reader, nil); err == nil {
+		t.Error("expected an error writing JSONL input as CSV")
+	}
+}
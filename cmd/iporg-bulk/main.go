@@ -9,13 +9,16 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"strings"
-	"sync"
+	"slices"
+	"time"
 
 	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/metrics"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 	"github.com/wingedpig/iporg/pkg/util/workers"
 )
 
@@ -24,10 +27,28 @@ const version = "1.0.0"
 func main() {
 	// Parse flags
 	dbPath := flag.String("db", "./iporgdb", "Path to LevelDB database")
-	inputFile := flag.String("input", "", "Input file (one IP per line, default: stdin)")
-	outputFile := flag.String("output", "", "Output file (JSONL format, default: stdout)")
+	inputFile := flag.String("input", "", "Input file (default: stdin)")
+	outputFile := flag.String("output", "", "Output file (default: stdout)")
+	inputFormat := flag.String("input-format", formatIPs, "Input format: ips (one per line), csv, tsv or jsonl")
+	outputFormat := flag.String("output-format", "", "Output format: csv, tsv or jsonl (default: same as input, jsonl for ips)")
+	column := flag.String("column", "ip", "CSV/TSV column holding the IP (name or 1-based index), or JSONL field path (e.g. client.ip); with --access-log=json, tried first when given")
+	noHeader := flag.Bool("no-header", false, "CSV/TSV input has no header row")
+	fieldList := flag.String("fields", "asn,org_name,country", "Lookup result fields to append to CSV/TSV/JSONL records")
+	fieldPrefix := flag.String("field-prefix", "", "Prefix for appended column and field names (e.g. ip_)")
+	accessLog := flag.String("access-log", "", "Read access log lines instead: combined, nginx, json or syslog")
+	trustedProxies := flag.String("trusted-proxies", "", "Proxy CIDRs skipped in X-Forwarded-For chains (default: private ranges, \"none\" to ignore the chains)")
+	aggregate := flag.String("aggregate", "", "Write a report of lookups per org, asn, country, rir, prefix or ip instead of records")
+	top := flag.Int("top", 20, "Rows in the --aggregate report (0 for all)")
+	reportFormat := flag.String("report-format", reportTable, "Format of the --aggregate report: table, csv or json")
 	workerCount := flag.Int("workers", 10, "Number of concurrent workers")
+	window := flag.Int("window", 1000, "Maximum lookups in flight (bounds memory use)")
+	unordered := flag.Bool("unordered", false, "Write results as they complete instead of in input order")
+	progressEvery := flag.Duration("progress", 10*time.Second, "Progress report interval (0 to disable)")
+	overridesFile := flag.String("overrides", "", "Overrides file applied to lookup results")
+	metricsAddr := flag.String("metrics", "", "Serve Prometheus metrics on address (e.g., localhost:9090)")
 	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
 	flag.Parse()
 
 	if *showVersion {
@@ -35,26 +56,51 @@ func main() {
 		return
 	}
 
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Open database
 	db, err := iporgdb.Open(*dbPath)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer db.Close()
 
+	if *overridesFile != "" {
+		set, err := overrides.Load(*overridesFile)
+		if err != nil {
+			logging.Fatal("Failed to load overrides", "err", err)
+		}
+		db.SetOverrides(set)
+		slog.Info("Loaded overrides", "count", set.Len(), "file", *overridesFile)
+	}
+
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		db.SetObserver(metrics.NewLookup(reg).Observe)
+		go func() {
+			slog.Info("Starting metrics server", "url", "http://"+*metricsAddr+metrics.Path)
+			if err := metrics.Serve(*metricsAddr, reg); err != nil {
+				slog.Warn("Metrics server failed", "err", err)
+			}
+		}()
+	}
+
 	// Setup input
 	var input *os.File
 	if *inputFile == "" {
 		input = os.Stdin
-		log.Println("INFO: Reading from stdin (one IP per line)")
+		slog.Info("Reading from stdin", "format", *inputFormat)
 	} else {
 		f, err := os.Open(*inputFile)
 		if err != nil {
-			log.Fatalf("ERROR: Failed to open input file: %v", err)
+			logging.Fatal("Failed to open input file", "err", err)
 		}
 		defer f.Close()
 		input = f
-		log.Printf("INFO: Reading from %s", *inputFile)
+		slog.Info("Reading input", "file", *inputFile)
 	}
 
 	// Setup output
@@ -64,126 +110,150 @@ func main() {
 	} else {
 		f, err := os.Create(*outputFile)
 		if err != nil {
-			log.Fatalf("ERROR: Failed to create output file: %v", err)
+			logging.Fatal("Failed to create output file", "err", err)
 		}
 		defer f.Close()
 		output = f
-		log.Printf("INFO: Writing to %s", *outputFile)
+		slog.Info("Writing output", "file", *outputFile)
 	}
 
-	// Read all IPs
-	var ips []string
-	scanner := bufio.NewScanner(input)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	var reader *rowReader
+	if *accessLog != "" {
+		// In a JSON log an "ip" field is often the server's address, so the
+		// --column default is only used for CSV/TSV/JSONL input
+		logColumn := ""
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "column" {
+				logColumn = *column
+			}
+		})
+		extractor, err := newClientExtractor(*accessLog, logColumn, *trustedProxies)
+		if err != nil {
+			logging.Fatal("Invalid --access-log", "err", err)
+		}
+		reader = newLogReader(input, extractor)
+		if *outputFormat == "" {
+			*outputFormat = formatLog
+			if *accessLog == logJSON {
+				*outputFormat = formatJSONL
+			}
+		}
+	} else {
+		reader, err = newRowReader(input, *inputFormat, *column, !*noHeader)
+		if err != nil {
+			logging.Fatal("Invalid input", "err", err)
 		}
-		ips = append(ips, line)
-	}
-	if err := scanner.Err(); err != nil {
-		log.Fatalf("ERROR: Failed to read input: %v", err)
 	}
 
-	log.Printf("INFO: Processing %d IPs with %d workers", len(ips), *workerCount)
-
-	// Process IPs
-	ctx := context.Background()
-	pool := workers.NewPool(ctx, workers.Config{
-		Workers:   *workerCount,
-		RateLimit: 0, // No rate limit for local lookups
-	})
+	bw := bufio.NewWriterSize(output, 64*1024)
+	if *outputFormat == "" {
+		*outputFormat = *inputFormat
+		if *inputFormat == formatIPs {
+			*outputFormat = formatJSONL
+		}
+	}
 
-	type result struct {
-		index int
-		data  *model.LookupResult
-		err   error
+	// A report replaces the records. Bare IP lists written as JSONL keep the
+	// full lookup result per line; everything else is annotated with the
+	// selected fields.
+	var agg *aggregator
+	var writer *rowWriter
+	if *aggregate != "" {
+		agg, err = newAggregator(*aggregate)
+		if err != nil {
+			logging.Fatal("Invalid --aggregate", "err", err)
+		}
+		if !slices.Contains([]string{reportTable, reportCSV, reportJSON}, *reportFormat) {
+			logging.Fatal("Invalid --report-format (want table, csv or json)", "format", *reportFormat)
+		}
+	} else if reader.format != formatIPs || *outputFormat != formatJSONL {
+		fields, err := parseFields(*fieldList, *fieldPrefix)
+		if err != nil {
+			logging.Fatal("Invalid --fields", "err", err)
+		}
+		writer, err = newRowWriter(bw, *outputFormat, reader, fields)
+		if err != nil {
+			logging.Fatal("Invalid output", "err", err)
+		}
+	}
+	enc := json.NewEncoder(bw)
+	flush := func() error {
+		if writer != nil {
+			if err := writer.flush(); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
 	}
 
-	results := make([]result, len(ips))
-	var mu sync.Mutex
-	var processed, found, notFound, errors int
+	slog.Info("Processing IPs", "workers", *workerCount, "window", *window, "ordered", !*unordered)
 
-	for i, ip := range ips {
-		idx := i
-		currentIP := ip
+	// Records are read, looked up and written as a stream: at most window
+	// lookups are in flight, so memory use does not grow with the input
+	var readErr error
+	progress := newProgress(*progressEvery)
 
-		pool.Submit(idx, func(ctx context.Context) error {
-			rec, err := db.LookupString(currentIP)
-			if err != nil {
-				if err == model.ErrNotFound {
-					mu.Lock()
-					notFound++
-					mu.Unlock()
-					results[idx] = result{
-						index: idx,
-						data: &model.LookupResult{
-							IP: currentIP,
-						},
-						err: err,
-					}
-				} else {
-					mu.Lock()
-					errors++
-					mu.Unlock()
-					results[idx] = result{
-						index: idx,
-						err:   err,
-					}
+	lookup := func(ctx context.Context, r row) (*model.LookupResult, error) {
+		rec, err := db.LookupString(r.ip)
+		if err != nil {
+			return nil, err
+		}
+		return iporgdb.ToLookupResult(r.ip, rec), nil
+	}
+
+	cfg := workers.Config{Workers: *workerCount, QueueSize: max(*window-*workerCount, 1)}
+	err = workers.Stream(context.Background(), cfg, reader.rows(&readErr), !*unordered, lookup,
+		func(it workers.Item[row, *model.LookupResult]) error {
+			var out interface{}
+			switch {
+			case it.Err == model.ErrNotFound:
+				progress.notFound++
+				out = map[string]interface{}{
+					"ip":    it.In.ip,
+					"error": "not found",
+				}
+			case it.Err != nil:
+				progress.errors++
+				out = map[string]interface{}{
+					"ip":    it.In.ip,
+					"error": it.Err.Error(),
 				}
-				return nil
+			default:
+				progress.found++
+				out = it.Out
 			}
 
-			mu.Lock()
-			found++
-			mu.Unlock()
-
-			results[idx] = result{
-				index: idx,
-				data:  iporgdb.ToLookupResult(currentIP, rec),
+			var err error
+			switch {
+			case agg != nil:
+				agg.add(it.In.ip, it.Out, it.Err)
+			case writer != nil:
+				err = writer.write(it.In, it.Out)
+			default:
+				err = enc.Encode(out)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+			if progress.add() {
+				return flush()
 			}
 			return nil
 		})
+	if err != nil {
+		logging.Fatal("Processing failed", "err", err)
 	}
-
-	// Wait for completion
-	pool.Wait()
-
-	// Write results in order
-	for _, res := range results {
-		processed++
-
-		if res.err != nil {
-			if res.err == model.ErrNotFound {
-				// Write not found result
-				data := map[string]interface{}{
-					"ip":    res.data.IP,
-					"error": "not found",
-				}
-				writeJSON(output, data)
-			} else {
-				// Write error result
-				data := map[string]interface{}{
-					"error": res.err.Error(),
-				}
-				writeJSON(output, data)
-			}
-		} else {
-			// Write successful result
-			writeJSON(output, res.data)
+	if readErr != nil {
+		logging.Fatal("Failed to read input", "err", readErr)
+	}
+	if agg != nil {
+		if err := agg.write(bw, *reportFormat, *top); err != nil {
+			logging.Fatal("Failed to write report", "err", err)
 		}
 	}
-
-	// Print summary to stderr
-	if output != os.Stdout {
-		log.Printf("INFO: Processed: %d, Found: %d, Not found: %d, Errors: %d",
-			processed, found, notFound, errors)
+	if err := flush(); err != nil {
+		logging.Fatal("Failed to write output", "err", err)
 	}
-}
 
-func writeJSON(w *os.File, data interface{}) {
-	encoder := json.NewEncoder(w)
-	if err := encoder.Encode(data); err != nil {
-		log.Printf("ERROR: Failed to write JSON: %v", err)
-	}
+	progress.done()
 }
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// maxLineSize is the longest input line accepted
+const maxLineSize = 1024 * 1024
+
+// progress counts results and logs a report at a fixed interval. It is only
+// used from the goroutine that emits results.
+type progress struct {
+	every                   time.Duration
+	start, last             time.Time
+	processed               int
+	found, notFound, errors int
+}
+
+func newProgress(every time.Duration) *progress {
+	now := time.Now()
+	return &progress{every: every, start: now, last: now}
+}
+
+// add counts one result and reports whether a progress report was logged
+func (p *progress) add() bool {
+	p.processed++
+	if p.every <= 0 || p.processed%1000 != 0 {
+		return false
+	}
+	now := time.Now()
+	if now.Sub(p.last) < p.every {
+		return false
+	}
+	p.last = now
+	slog.Info("Progress", p.attrs(now)...)
+	return true
+}
+
+// done logs the final summary
+func (p *progress) done() {
+	slog.Info("Processing complete", p.attrs(time.Now())...)
+}
+
+func (p *progress) attrs(now time.Time) []any {
+	elapsed := now.Sub(p.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.processed) / elapsed.Seconds()
+	}
+	return []any{
+		"processed", p.processed,
+		"found", p.found,
+		"not_found", p.notFound,
+		"errors", p.errors,
+		"rate", int(rate),
+		"elapsed", elapsed.Round(time.Second),
+	}
+}
@@ -0,0 +1,380 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/cymru"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// DNS record types and classes
+const (
+	typeSOA  = 6
+	typeTXT  = 16
+	typeANY  = 255
+	classIN  = 1
+	classANY = 255
+)
+
+// DNS response codes
+const (
+	rcodeSuccess  = 0
+	rcodeFormErr  = 1
+	rcodeServFail = 2
+	rcodeNXDomain = 3
+	rcodeNotImp   = 4
+	rcodeRefused  = 5
+)
+
+// Header flag bits
+const (
+	flagQR = 1 << 15
+	flagAA = 1 << 10
+	flagTC = 1 << 9
+	flagRD = 1 << 8
+)
+
+const (
+	headerSize = 12
+	maxUDPSize = 512 // Answers are small, so EDNS0 is not needed
+)
+
+// errFormat reports a malformed query
+var errFormat = errors.New("malformed query")
+
+// question is the single question of a query
+type question struct {
+	labels []string // Lowercased labels of the name
+	wire   []byte   // The question as sent, echoed in the response
+	qtype  uint16
+	qclass uint16
+}
+
+// parseQuestion parses the question following the header of msg
+func parseQuestion(msg []byte) (*question, error) {
+	q := &question{}
+	off := headerSize
+	for {
+		if off >= len(msg) {
+			return nil, errFormat
+		}
+		n := int(msg[off])
+		off++
+		if n == 0 {
+			break
+		}
+		if n > 63 || off+n > len(msg) {
+			return nil, errFormat // Compression pointers are not used in questions
+		}
+		q.labels = append(q.labels, lowerASCII(string(msg[off:off+n])))
+		off += n
+	}
+	if off+4 > len(msg) {
+		return nil, errFormat
+	}
+	q.qtype = binary.BigEndian.Uint16(msg[off:])
+	q.qclass = binary.BigEndian.Uint16(msg[off+2:])
+	q.wire = msg[headerSize : off+4]
+	return q, nil
+}
+
+// lowerASCII lowercases ASCII letters only, as DNS names compare, keeping
+// every other byte so label lengths match the wire
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// record is a resource record of the response
+type record struct {
+	name  []byte // Owner name, usually a compression pointer
+	rtype uint16
+	ttl   uint32
+	data  []byte
+}
+
+// handler answers queries for one zone
+type handler struct {
+	zone     []string // Zone labels
+	resolver *cymru.Resolver
+	ttl      uint32
+	soa      []byte // SOA rdata
+	negTTL   uint32
+}
+
+// newHandler creates a handler for zone
+func newHandler(zone string, resolver *cymru.Resolver, ttl, negTTL, serial uint32) *handler {
+	zone = lowerASCII(strings.Trim(zone, "."))
+	h := &handler{
+		zone:     strings.Split(zone, "."),
+		resolver: resolver,
+		ttl:      ttl,
+		negTTL:   negTTL,
+	}
+
+	// SOA: ns.<zone> hostmaster.<zone> serial refresh retry expire minimum
+	soa := appendName(nil, append([]string{"ns"}, h.zone...))
+	soa = appendName(soa, append([]string{"hostmaster"}, h.zone...))
+	for _, v := range []uint32{serial, 3600, 600, 86400, negTTL} {
+		soa = binary.BigEndian.AppendUint32(soa, v)
+	}
+	h.soa = soa
+	return h
+}
+
+// handle answers one query message; it returns nil if msg should be dropped
+func (h *handler) handle(ctx context.Context, msg []byte, maxSize int) []byte {
+	if len(msg) < headerSize {
+		return nil
+	}
+	id := binary.BigEndian.Uint16(msg)
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&flagQR != 0 {
+		return nil // A response, not a query
+	}
+	opcode := (flags >> 11) & 0xf
+	respFlags := uint16(flagQR|flagAA) | flags&flagRD | opcode<<11
+
+	if opcode != 0 {
+		return response(id, respFlags|rcodeNotImp, nil, nil, nil)
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return response(id, respFlags|rcodeFormErr, nil, nil, nil)
+	}
+	q, err := parseQuestion(msg)
+	if err != nil {
+		return response(id, respFlags|rcodeFormErr, nil, nil, nil)
+	}
+
+	rcode, answers, authority := h.answer(ctx, q)
+	resp := response(id, respFlags|uint16(rcode), q, answers, authority)
+	if len(resp) > maxSize {
+		resp = response(id, respFlags|flagTC|uint16(rcode), q, nil, nil)
+	}
+	return resp
+}
+
+// answer resolves a question to a response code and records
+func (h *handler) answer(ctx context.Context, q *question) (rcode int, answers, authority []record) {
+	if q.qclass != classIN && q.qclass != classANY {
+		return rcodeRefused, nil, nil
+	}
+	rel, ok := h.relative(q.labels)
+	if !ok {
+		return rcodeRefused, nil, nil
+	}
+
+	// Negative answers carry the SOA for caching
+	apex := h.apexPointer(q.labels)
+	soa := []record{{name: apex, rtype: typeSOA, ttl: h.negTTL, data: h.soa}}
+
+	if len(rel) == 0 {
+		if q.qtype == typeSOA || q.qtype == typeANY {
+			return rcodeSuccess, soa, nil
+		}
+		return rcodeSuccess, nil, soa
+	}
+
+	txt, err := h.lookup(ctx, rel)
+	switch {
+	case errors.Is(err, model.ErrNotFound), errors.Is(err, model.ErrInvalidIP):
+		slog.Debug("DNS query", "name", strings.Join(q.labels, "."), "result", "nxdomain")
+		return rcodeNXDomain, nil, soa
+	case err != nil:
+		slog.Warn("DNS lookup failed", "name", strings.Join(q.labels, "."), "err", err)
+		return rcodeServFail, nil, nil
+	}
+	slog.Debug("DNS query", "name", strings.Join(q.labels, "."), "answer", txt)
+
+	if q.qtype != typeTXT && q.qtype != typeANY {
+		return rcodeSuccess, nil, soa
+	}
+	// Answers are owned by the question name at offset 12
+	return rcodeSuccess, []record{{name: []byte{0xc0, headerSize}, rtype: typeTXT, ttl: h.ttl, data: txtData(txt)}}, nil
+}
+
+// relative returns the labels of name below the zone, and whether name is
+// in the zone at all
+func (h *handler) relative(labels []string) ([]string, bool) {
+	n := len(labels) - len(h.zone)
+	if n < 0 {
+		return nil, false
+	}
+	for i, l := range h.zone {
+		if labels[n+i] != l {
+			return nil, false
+		}
+	}
+	return labels[:n], true
+}
+
+// apexPointer returns a compression pointer to the zone apex within the
+// question name
+func (h *handler) apexPointer(labels []string) []byte {
+	off := headerSize
+	for _, l := range labels[:len(labels)-len(h.zone)] {
+		off += len(l) + 1
+	}
+	return []byte{0xc0 | byte(off>>8), byte(off)}
+}
+
+// lookup answers the labels below the zone:
+//
+//	<reversed IPv4>.origin   ASN | BGP Prefix | CC | Registry | Allocated
+//	<reversed nibbles>.origin6
+//	<reversed IPv4>.org      ASN | BGP Prefix | CC | Registry | Org Name
+//	<reversed nibbles>.org6
+//	AS<n>                    ASN | CC | Registry | Allocated | AS Name
+func (h *handler) lookup(ctx context.Context, rel []string) (string, error) {
+	last := rel[len(rel)-1]
+	if len(rel) == 1 && strings.HasPrefix(last, "as") {
+		asn, ok := cymru.ParseASN(last)
+		if !ok {
+			return "", model.ErrNotFound
+		}
+		as, err := h.resolver.AS(ctx, asn)
+		if err != nil {
+			return "", err
+		}
+		return as.String(), nil
+	}
+
+	var addr netip.Addr
+	var err error
+	switch last {
+	case "origin", "org":
+		addr, err = reverseIPv4(rel[:len(rel)-1])
+	case "origin6", "org6":
+		addr, err = reverseIPv6(rel[:len(rel)-1])
+	default:
+		return "", model.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	origin, err := h.resolver.Origin(addr)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(last, "org") {
+		return origin.Org(), nil
+	}
+	return origin.String(), nil
+}
+
+// reverseIPv4 parses the labels of 4.4.8.8 as 8.8.4.4
+func reverseIPv4(labels []string) (netip.Addr, error) {
+	if len(labels) != 4 {
+		return netip.Addr{}, model.ErrInvalidIP
+	}
+	addr, err := netip.ParseAddr(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0])
+	if err != nil || !addr.Is4() {
+		return netip.Addr{}, model.ErrInvalidIP
+	}
+	return addr, nil
+}
+
+// reverseIPv6 parses reversed nibble labels as an IPv6 address. Fewer than
+// 32 nibbles name the start of a prefix; the rest are zero.
+func reverseIPv6(labels []string) (netip.Addr, error) {
+	if len(labels) == 0 || len(labels) > 32 {
+		return netip.Addr{}, model.ErrInvalidIP
+	}
+	var b [16]byte
+	for i := range labels {
+		nibble := labels[len(labels)-1-i]
+		if len(nibble) != 1 {
+			return netip.Addr{}, model.ErrInvalidIP
+		}
+		var v byte
+		switch c := nibble[0]; {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		default:
+			return netip.Addr{}, model.ErrInvalidIP
+		}
+		if i%2 == 0 {
+			b[i/2] = v << 4
+		} else {
+			b[i/2] |= v
+		}
+	}
+	return netip.AddrFrom16(b), nil
+}
+
+// response builds a response message
+func response(id, flags uint16, q *question, answers, authority []record) []byte {
+	msg := make([]byte, headerSize, 128)
+	binary.BigEndian.PutUint16(msg, id)
+	binary.BigEndian.PutUint16(msg[2:], flags)
+	if q != nil {
+		binary.BigEndian.PutUint16(msg[4:], 1)
+		msg = append(msg, q.wire...)
+	}
+	binary.BigEndian.PutUint16(msg[6:], uint16(len(answers)))
+	binary.BigEndian.PutUint16(msg[8:], uint16(len(authority)))
+	for _, rrs := range [][]record{answers, authority} {
+		for _, rr := range rrs {
+			msg = append(msg, rr.name...)
+			msg = binary.BigEndian.AppendUint16(msg, rr.rtype)
+			msg = binary.BigEndian.AppendUint16(msg, classIN)
+			msg = binary.BigEndian.AppendUint32(msg, rr.ttl)
+			msg = binary.BigEndian.AppendUint16(msg, uint16(len(rr.data)))
+			msg = append(msg, rr.data...)
+		}
+	}
+	return msg
+}
+
+// appendName appends labels as an uncompressed domain name
+func appendName(buf []byte, labels []string) []byte {
+	for _, l := range labels {
+		buf = append(buf, byte(len(l)))
+		buf = append(buf, l...)
+	}
+	return append(buf, 0)
+}
+
+// txtData encodes s as TXT rdata, split into strings of at most 255 bytes
+func txtData(s string) []byte {
+	var data []byte
+	for {
+		n := min(len(s), 255)
+		data = append(data, byte(n))
+		data = append(data, s[:n]...)
+		s = s[n:]
+		if s == "" {
+			return data
+		}
+	}
+}
+
+// validateZone checks a zone name
+func validateZone(zone string) error {
+	zone = strings.Trim(zone, ".")
+	if zone == "" {
+		return fmt.Errorf("zone is required")
+	}
+	for _, l := range strings.Split(zone, ".") {
+		if l == "" || len(l) > 63 {
+			return fmt.Errorf("invalid zone %q", zone)
+		}
+	}
+	return nil
+}
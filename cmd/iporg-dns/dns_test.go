@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/cymru"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+func newTestHandler(t *testing.T) *handler {
+	t.Helper()
+	db, err := iporgdb.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, rec := range []*model.Record{
+		{Start: netip.MustParseAddr("8.8.8.0"), End: netip.MustParseAddr("8.8.8.255"), ASN: 15169, ASNName: "GOOGLE",
+			OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.0/24", Schema: 1},
+		{Start: netip.MustParseAddr("2001:4860::"), End: netip.MustParseAddr("2001:4860:ffff:ffff:ffff:ffff:ffff:ffff"),
+			ASN: 15169, ASNName: "GOOGLE", OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "2001:4860::/32", Schema: 1},
+	} {
+		if err := db.PutRange(rec); err != nil {
+			t.Fatalf("Failed to put range: %v", err)
+		}
+	}
+	return newHandler("asn.example.net.", cymru.NewResolver(db, nil), 3600, 300, 1)
+}
+
+// query builds a query message for name
+func query(name string, qtype uint16) []byte {
+	msg := []byte{0x12, 0x34, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0}
+	msg = appendName(msg, strings.Split(strings.Trim(name, "."), "."))
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, classIN)
+}
+
+// parsed is the part of a response checked by the tests
+type parsed struct {
+	rcode     int
+	answers   int
+	authority int
+	truncated bool
+	txt       string
+}
+
+// parseResponse decodes the response to req
+func parseResponse(t *testing.T, resp []byte, req []byte) parsed {
+	t.Helper()
+	if len(resp) < len(req) {
+		t.Fatalf("Response too short: %d bytes", len(resp))
+	}
+	if resp[0] != req[0] || resp[1] != req[1] {
+		t.Fatalf("Response ID does not match")
+	}
+	flags := binary.BigEndian.Uint16(resp[2:])
+	p := parsed{
+		rcode:     int(flags & 0xf),
+		answers:   int(binary.BigEndian.Uint16(resp[6:])),
+		authority: int(binary.BigEndian.Uint16(resp[8:])),
+		truncated: flags&flagTC != 0,
+	}
+	if flags&flagQR == 0 || flags&flagAA == 0 {
+		t.Errorf("flags %#x: want QR and AA set", flags)
+	}
+	if p.answers > 0 {
+		// Question, then name pointer, type, class, TTL, rdlength, rdata
+		off := len(req) + 2
+		if rtype := binary.BigEndian.Uint16(resp[off:]); rtype == typeTXT {
+			rdata := resp[off+10:]
+			p.txt = string(rdata[1 : 1+int(rdata[0])])
+		}
+	}
+	return p
+}
+
+func TestHandle(t *testing.T) {
+	h := newTestHandler(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		qtype     uint16
+		rcode     int
+		txt       string
+		authority int
+	}{
+		{"8.8.8.8.origin.asn.example.net", typeTXT, rcodeSuccess, "15169 | 8.8.8.0/24 | US | arin | ", 0},
+		{"8.8.8.8.ORIGIN.Asn.Example.Net", typeTXT, rcodeSuccess, "15169 | 8.8.8.0/24 | US | arin | ", 0},
+		{"8.8.8.8.org.asn.example.net", typeTXT, rcodeSuccess, "15169 | 8.8.8.0/24 | US | arin | Google LLC", 0},
+		{"0.6.8.4.1.0.0.2.origin6.asn.example.net", typeTXT, rcodeSuccess, "15169 | 2001:4860::/32 | US | arin | ", 0},
+		{"8.8.8.8.origin.asn.example.net", 1, rcodeSuccess, "", 1}, // A: no data
+		{"1.2.0.192.origin.asn.example.net", typeTXT, rcodeNXDomain, "", 1},
+		{"8.8.8.origin.asn.example.net", typeTXT, rcodeNXDomain, "", 1},
+		{"AS15169.asn.example.net", typeTXT, rcodeNXDomain, "", 1}, // No iptoasn store
+		{"foo.asn.example.net", typeTXT, rcodeNXDomain, "", 1},
+		{"asn.example.net", typeSOA, rcodeSuccess, "", 0},
+		{"8.8.8.8.origin.asn.cymru.com", typeTXT, rcodeRefused, "", 0},
+	}
+	for _, tt := range tests {
+		req := query(tt.name, tt.qtype)
+		p := parseResponse(t, h.handle(ctx, req, maxUDPSize), req)
+		if p.rcode != tt.rcode || p.txt != tt.txt || p.authority != tt.authority {
+			t.Errorf("%s: got rcode %d, txt %q, %d authority; want rcode %d, txt %q, %d authority",
+				tt.name, p.rcode, p.txt, p.authority, tt.rcode, tt.txt, tt.authority)
+		}
+	}
+
+	// Non-ASCII labels keep their length, so the SOA points at the apex
+	for _, name := range []string{"\xff.origin.asn.example.net", "\xc4\xb0.ORIGIN.asn.example.net"} {
+		q, err := parseQuestion(query(name, typeTXT))
+		if err != nil {
+			t.Fatalf("%q: parseQuestion failed: %v", name, err)
+		}
+		want := headerSize + len(strings.SplitN(name, ".", 2)[0]) + 1 + len("origin") + 1
+		if got := h.apexPointer(q.labels); got[0] != 0xc0 || int(got[1]) != want {
+			t.Errorf("%q: got apex pointer %#x, want offset %d", name, got, want)
+		}
+	}
+
+	// Malformed and non-query messages
+	if resp := h.handle(ctx, []byte{1, 2, 3}, maxUDPSize); resp != nil {
+		t.Errorf("short message: got a response, want none")
+	}
+	req := query("8.8.8.8.origin.asn.example.net", typeTXT)
+	req[2] |= 0x80
+	if resp := h.handle(ctx, req, maxUDPSize); resp != nil {
+		t.Errorf("response message: got a response, want none")
+	}
+	req = query("8.8.8.8.origin.asn.example.net", typeTXT)
+	if p := parseResponse(t, h.handle(ctx, req[:len(req)-3], maxUDPSize), req[:headerSize]); p.rcode != rcodeFormErr {
+		t.Errorf("truncated question: got rcode %d, want %d", p.rcode, rcodeFormErr)
+	}
+	if p := parseResponse(t, h.handle(ctx, req, 40), req); !p.truncated || p.answers != 0 {
+		t.Errorf("oversized answer: got truncated %v with %d answers, want truncated and none", p.truncated, p.answers)
+	}
+}
+
+func TestServe(t *testing.T) {
+	h := newTestHandler(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer pc.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	go serveUDP(ctx, h, pc)
+	go serveTCP(ctx, h, ln)
+
+	req := query("8.8.8.8.origin.asn.example.net", typeTXT)
+	want := "15169 | 8.8.8.0/24 | US | arin | "
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write(req); err != nil {
+		t.Fatalf("UDP write failed: %v", err)
+	}
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("UDP read failed: %v", err)
+	}
+	if p := parseResponse(t, buf[:n], req); p.txt != want {
+		t.Errorf("UDP: got %q, want %q", p.txt, want)
+	}
+
+	tc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer tc.Close()
+	tc.SetDeadline(time.Now().Add(5 * time.Second))
+	// Two queries on one connection
+	for range 2 {
+		if _, err := tc.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(req))), req...)); err != nil {
+			t.Fatalf("TCP write failed: %v", err)
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(tc, size[:]); err != nil {
+			t.Fatalf("TCP read failed: %v", err)
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(tc, resp); err != nil {
+			t.Fatalf("TCP read failed: %v", err)
+		}
+		if p := parseResponse(t, resp, req); p.txt != want {
+			t.Errorf("TCP: got %q, want %q", p.txt, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/cymru"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/metrics"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+)
+
+const version = "1.0.0"
+
+const (
+	maxTCPConns    = 256              // Further TCP connections are closed at once
+	tcpIdleTimeout = 10 * time.Second // Idle TCP connections are closed
+)
+
+func main() {
+	dbPath := flag.String("db", "./iporgdb", "Path to LevelDB database (empty to use only --iptoasn-db)")
+	iptoasnPath := flag.String("iptoasn-db", "", "iptoasn database for AS<n> queries and addresses not in --db")
+	zone := flag.String("zone", "", "Zone to serve, e.g. asn.example.net (required)")
+	listen := flag.String("listen", ":5353", "UDP and TCP address to listen on")
+	ttl := flag.Duration("ttl", time.Hour, "TTL of answers")
+	negTTL := flag.Duration("negative-ttl", 5*time.Minute, "TTL of NXDOMAIN and empty answers")
+	workers := flag.Int("workers", runtime.NumCPU(), "Number of UDP workers")
+	metricsAddr := flag.String("metrics", "", "Serve Prometheus metrics on address (e.g., localhost:9090)")
+	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("iporg-dns version %s\n", version)
+		return
+	}
+
+	if err := validateZone(*zone); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n\n", err)
+		usage()
+		os.Exit(1)
+	}
+	if *dbPath == "" && *iptoasnPath == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: --db or --iptoasn-db is required\n")
+		os.Exit(1)
+	}
+
+	_, err := logFlags.Setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	var db *iporgdb.DB
+	if *dbPath != "" {
+		db, err = iporgdb.Open(*dbPath)
+		if err != nil {
+			logging.Fatal("Failed to open database", "err", err)
+		}
+		defer db.Close()
+	}
+	var store *iptoasn.Store
+	if *iptoasnPath != "" {
+		store, err = iptoasn.Open(*iptoasnPath)
+		if err != nil {
+			logging.Fatal("Failed to open iptoasn database", "err", err)
+		}
+		defer store.Close()
+	}
+
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		if db != nil {
+			db.SetObserver(metrics.NewLookup(reg).Observe)
+		}
+		go func() {
+			slog.Info("Starting metrics server", "url", "http://"+*metricsAddr+metrics.Path)
+			if err := metrics.Serve(*metricsAddr, reg); err != nil {
+				slog.Error("Metrics server failed", "err", err)
+			}
+		}()
+	}
+
+	h := newHandler(*zone, cymru.NewResolver(db, store), uint32(ttl.Seconds()), uint32(negTTL.Seconds()), uint32(time.Now().Unix()))
+
+	pc, err := net.ListenPacket("udp", *listen)
+	if err != nil {
+		logging.Fatal("Failed to listen on UDP", "addr", *listen, "err", err)
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logging.Fatal("Failed to listen on TCP", "addr", *listen, "err", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		pc.Close()
+		ln.Close()
+	}()
+
+	slog.Info("Serving DNS", "zone", *zone, "addr", *listen)
+	var wg sync.WaitGroup
+	for range max(*workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serveUDP(ctx, h, pc)
+		}()
+	}
+	serveTCP(ctx, h, ln)
+	wg.Wait()
+	slog.Info("Shut down")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: iporg-dns --zone=<zone> [options]\n\n")
+	fmt.Fprintf(os.Stderr, "Serves Team Cymru-style TXT records over UDP and TCP:\n")
+	fmt.Fprintf(os.Stderr, "  4.4.8.8.origin.<zone>    \"15169 | 8.8.4.0/24 | US | arin | \"\n")
+	fmt.Fprintf(os.Stderr, "  <nibbles>.origin6.<zone> the same for IPv6\n")
+	fmt.Fprintf(os.Stderr, "  4.4.8.8.org.<zone>       the registered organisation in place of the date\n")
+	fmt.Fprintf(os.Stderr, "  AS15169.<zone>           \"15169 | US | arin |  | GOOGLE\" (needs --iptoasn-db)\n\n")
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExample:\n")
+	fmt.Fprintf(os.Stderr, "  iporg-dns --zone=asn.example.net --listen=:53 --iptoasn-db=./data/iptoasndb\n")
+	fmt.Fprintf(os.Stderr, "  dig +short TXT 4.4.8.8.origin.asn.example.net @localhost\n")
+}
+
+// serveUDP answers queries on pc until it is closed
+func serveUDP(ctx context.Context, h *handler, pc net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				slog.Error("UDP read failed", "err", err)
+			}
+			return
+		}
+		if resp := h.handle(ctx, buf[:n], maxUDPSize); resp != nil {
+			if _, err := pc.WriteTo(resp, addr); err != nil {
+				slog.Debug("UDP write failed", "client", addr, "err", err)
+			}
+		}
+	}
+}
+
+// serveTCP accepts connections on ln until it is closed
+func serveTCP(ctx context.Context, h *handler, ln net.Listener) {
+	sem := make(chan struct{}, maxTCPConns)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				slog.Error("TCP accept failed", "err", err)
+			}
+			return
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			slog.Warn("Too many TCP connections", "client", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			serveConn(ctx, h, conn)
+		}()
+	}
+}
+
+// serveConn answers length-prefixed queries on one TCP connection
+func serveConn(ctx context.Context, h *handler, conn net.Conn) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var size [2]byte
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		resp := h.handle(ctx, msg, 65535)
+		if resp == nil {
+			return
+		}
+		out := binary.BigEndian.AppendUint16(make([]byte, 0, len(resp)+2), uint16(len(resp)))
+		if _, err := conn.Write(append(out, resp...)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iporgpb"
+	"github.com/wingedpig/iporg/pkg/metrics"
+	"github.com/wingedpig/iporg/pkg/service"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+)
+
+const version = "1.0.0"
+
+// shutdownTimeout bounds how long in-flight calls may run after a signal
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	dbPath := flag.String("db", "./iporgdb", "Path to LevelDB database")
+	listen := flag.String("listen", ":50051", "TCP address to listen on")
+	maxCIDRResults := flag.Int("max-cidr-results", service.DefaultMaxCIDRResults, "Maximum results of a LookupCIDR call")
+	infoTTL := flag.Duration("info-ttl", service.DefaultInfoTTL, "How long Info results are cached")
+	cacheSize := flag.Int("cache-size", 100000, "Lookup results cached in memory (0 to disable)")
+	streamWorkers := flag.Int("stream-workers", runtime.NumCPU(), "Concurrent lookups per LookupStream call")
+	enableReflection := flag.Bool("reflection", true, "Register the gRPC reflection service")
+	metricsAddr := flag.String("metrics", "", "Serve Prometheus metrics on address (e.g., localhost:9090)")
+	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("iporg-grpc version %s\n", version)
+		return
+	}
+
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	db, err := iporgdb.Open(*dbPath)
+	if err != nil {
+		logging.Fatal("Failed to open database", "err", err)
+	}
+	defer db.Close()
+	db.SetCacheSize(*cacheSize)
+
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		db.SetObserver(metrics.NewLookup(reg).Observe)
+		go func() {
+			slog.Info("Starting metrics server", "url", "http://"+*metricsAddr+metrics.Path)
+			if err := metrics.Serve(*metricsAddr, reg); err != nil {
+				slog.Error("Metrics server failed", "err", err)
+			}
+		}()
+	}
+
+	svc := service.New(db, service.Options{MaxCIDRResults: *maxCIDRResults, InfoTTL: *infoTTL})
+	gs := grpc.NewServer()
+	iporgpb.RegisterIPOrgServer(gs, newServer(svc, *streamWorkers))
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(gs, hs)
+	if *enableReflection {
+		reflection.Register(gs)
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logging.Fatal("Failed to listen", "addr", *listen, "err", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// SIGHUP reopens the database, e.g. after a rebuild is moved into place
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := svc.Reload(); err != nil {
+				slog.Error("Failed to reload database", "err", err)
+				continue
+			}
+			slog.Info("Reloaded database", "path", *dbPath)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		slog.Info("Shutting down")
+		hs.Shutdown()
+		timer := time.AfterFunc(shutdownTimeout, gs.Stop)
+		defer timer.Stop()
+		gs.GracefulStop()
+	}()
+
+	slog.Info("Serving gRPC", "addr", *listen)
+	if err := gs.Serve(ln); err != nil {
+		logging.Fatal("gRPC server failed", "err", err)
+	}
+	slog.Info("Shut down")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: iporg-grpc [options]\n\n")
+	fmt.Fprintf(os.Stderr, "Serves the iporg.v1.IPOrg gRPC service (see pkg/iporgpb/iporg.proto):\n")
+	fmt.Fprintf(os.Stderr, "  Lookup        record containing an IP address\n")
+	fmt.Fprintf(os.Stderr, "  LookupStream  bidirectional stream of lookups, answered in order\n")
+	fmt.Fprintf(os.Stderr, "  LookupCIDR    records overlapping a prefix\n")
+	fmt.Fprintf(os.Stderr, "  Info          build metadata and record counts\n")
+	fmt.Fprintf(os.Stderr, "SIGHUP reopens the database, e.g. after a rebuild is moved into place.\n\n")
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExample:\n")
+	fmt.Fprintf(os.Stderr, "  iporg-grpc --db=./data/iporgdb --listen=:50051\n")
+	fmt.Fprintf(os.Stderr, "  grpcurl -plaintext -d '{\"ip\":\"8.8.8.8\"}' localhost:50051 iporg.v1.IPOrg/Lookup\n")
+}
@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/wingedpig/iporg/pkg/iporgpb"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/service"
+	"github.com/wingedpig/iporg/pkg/util/workers"
+)
+
+// server implements the IPOrg gRPC service over a lookup service
+type server struct {
+	iporgpb.UnimplementedIPOrgServer
+	svc           *service.Service
+	streamWorkers int // Concurrent lookups per LookupStream call
+}
+
+func newServer(svc *service.Service, streamWorkers int) *server {
+	return &server{svc: svc, streamWorkers: streamWorkers}
+}
+
+// Lookup returns the record containing an IP address
+func (s *server) Lookup(ctx context.Context, req *iporgpb.LookupRequest) (*iporgpb.LookupResponse, error) {
+	res, err := s.svc.Lookup(req.GetIp())
+	if err != nil {
+		return nil, toStatus(err).Err()
+	}
+	return &iporgpb.LookupResponse{Id: req.GetId(), Result: toProto(res)}, nil
+}
+
+// LookupStream answers lookups in the order they arrive. Requests are read
+// ahead and looked up concurrently, so a client that keeps sending isn't
+// held to one lookup per round trip. Failed lookups are reported in their
+// response rather than ending the stream.
+func (s *server) LookupStream(stream iporgpb.IPOrg_LookupStreamServer) error {
+	var recvErr error
+	requests := func(yield func(*iporgpb.LookupRequest) bool) {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					recvErr = err
+				}
+				return
+			}
+			if !yield(req) {
+				return
+			}
+		}
+	}
+
+	err := workers.Stream(stream.Context(), workers.Config{Workers: s.streamWorkers}, requests, true, s.lookupOne,
+		func(it workers.Item[*iporgpb.LookupRequest, *iporgpb.LookupResponse]) error {
+			if it.Err != nil {
+				return it.Err
+			}
+			return stream.Send(it.Out)
+		})
+	if err != nil {
+		return err
+	}
+	return recvErr
+}
+
+// lookupOne answers one streamed lookup
+func (s *server) lookupOne(ctx context.Context, req *iporgpb.LookupRequest) (*iporgpb.LookupResponse, error) {
+	resp := &iporgpb.LookupResponse{Id: req.GetId()}
+	res, err := s.svc.Lookup(req.GetIp())
+	if err != nil {
+		st := toStatus(err)
+		resp.Code = uint32(st.Code())
+		resp.Error = st.Message()
+	} else {
+		resp.Result = toProto(res)
+	}
+	return resp, nil
+}
+
+// LookupCIDR returns the records overlapping a prefix
+func (s *server) LookupCIDR(ctx context.Context, req *iporgpb.LookupCIDRRequest) (*iporgpb.LookupCIDRResponse, error) {
+	results, truncated, err := s.svc.LookupCIDR(req.GetCidr(), int(req.GetLimit()))
+	if err != nil {
+		return nil, toStatus(err).Err()
+	}
+	resp := &iporgpb.LookupCIDRResponse{Results: make([]*iporgpb.LookupResult, len(results)), Truncated: truncated}
+	for i, res := range results {
+		resp.Results[i] = toProto(res)
+	}
+	return resp, nil
+}
+
+// Info returns build metadata and record counts
+func (s *server) Info(ctx context.Context, req *iporgpb.InfoRequest) (*iporgpb.InfoResponse, error) {
+	info, err := s.svc.Info(ctx)
+	if err != nil {
+		return nil, toStatus(err).Err()
+	}
+	resp := &iporgpb.InfoResponse{
+		BuilderVersion:   info.BuilderVersion,
+		SchemaVersion:    int32(info.Stats.SchemaVersion),
+		TotalRecords:     info.Stats.TotalRecords,
+		Ipv4Records:      info.Stats.IPv4Records,
+		Ipv6Records:      info.Stats.IPv6Records,
+		RecordsByRir:     info.Stats.RecordsByRIR,
+		RecordsByRole:    info.Stats.RecordsByRole,
+		RecordsByCountry: info.Stats.RecordsByCountry,
+	}
+	if !info.BuiltAt.IsZero() {
+		resp.BuiltAt = timestamppb.New(info.BuiltAt)
+	}
+	return resp, nil
+}
+
+// toProto converts a lookup result to its message
+func toProto(res *model.LookupResult) *iporgpb.LookupResult {
+	return &iporgpb.LookupResult{
+		Ip:         res.IP,
+		Asn:        uint32(res.ASN),
+		AsnName:    res.ASNName,
+		OrgName:    res.OrgName,
+		Rir:        res.RIR,
+		Country:    res.Country,
+		Region:     res.Region,
+		City:       res.City,
+		Lat:        res.Lat,
+		Lon:        res.Lon,
+		Prefix:     res.Prefix,
+		SourceRole: res.SourceRole,
+	}
+}
+
+// toStatus maps a lookup error to a gRPC status
+func toStatus(err error) *status.Status {
+	switch {
+	case errors.Is(err, model.ErrInvalidIP):
+		return status.New(codes.InvalidArgument, err.Error())
+	case errors.Is(err, model.ErrNotFound):
+		return status.New(codes.NotFound, err.Error())
+	case errors.Is(err, model.ErrDatabaseClosed):
+		return status.New(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err)
+	}
+	slog.Error("Lookup failed", "err", err)
+	return status.New(codes.Internal, err.Error())
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iporgpb"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/service"
+)
+
+// newTestClient serves a test database over an in-memory listener
+func newTestClient(t *testing.T) iporgpb.IPOrgClient {
+	t.Helper()
+	db, err := iporgdb.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.PutRange(&model.Record{
+		Start: netip.MustParseAddr("8.8.8.0"), End: netip.MustParseAddr("8.8.8.255"), ASN: 15169, ASNName: "GOOGLE",
+		OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.0/24", Schema: 1,
+	}); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+	if err := db.SetBuilderVersion("test"); err != nil {
+		t.Fatalf("SetBuilderVersion failed: %v", err)
+	}
+
+	ln := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	iporgpb.RegisterIPOrgServer(gs, newServer(service.New(db, service.Options{}), 4))
+	go gs.Serve(ln)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return iporgpb.NewIPOrgClient(conn)
+}
+
+func TestLookup(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.Lookup(ctx, &iporgpb.LookupRequest{Ip: "8.8.8.8", Id: "a"})
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	res := resp.GetResult()
+	if resp.GetId() != "a" || res.GetAsn() != 15169 || res.GetOrgName() != "Google LLC" || res.GetPrefix() != "8.8.8.0/24" {
+		t.Errorf("got %v", resp)
+	}
+
+	for ip, want := range map[string]codes.Code{"192.0.2.1": codes.NotFound, "bogus": codes.InvalidArgument} {
+		if _, err := client.Lookup(ctx, &iporgpb.LookupRequest{Ip: ip}); status.Code(err) != want {
+			t.Errorf("%s: got %v, want code %v", ip, err, want)
+		}
+	}
+}
+
+func TestLookupStream(t *testing.T) {
+	client := newTestClient(t)
+
+	stream, err := client.LookupStream(context.Background())
+	if err != nil {
+		t.Fatalf("LookupStream failed: %v", err)
+	}
+	ips := []string{"8.8.8.8", "bogus", "192.0.2.1", "8.8.8.9"}
+	go func() {
+		for i, ip := range ips {
+			stream.Send(&iporgpb.LookupRequest{Ip: ip, Id: fmt.Sprint(i)})
+		}
+		stream.CloseSend()
+	}()
+
+	wantCodes := []codes.Code{codes.OK, codes.InvalidArgument, codes.NotFound, codes.OK}
+	for i := range ips {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv %d failed: %v", i, err)
+		}
+		if resp.GetId() != fmt.Sprint(i) || codes.Code(resp.GetCode()) != wantCodes[i] || (resp.GetResult() != nil) != (wantCodes[i] == codes.OK) {
+			t.Errorf("response %d: got %v, want code %v", i, resp, wantCodes[i])
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("end of stream: got %v, want EOF", err)
+	}
+}
+
+func TestLookupStreamOrder(t *testing.T) {
+	client := newTestClient(t)
+
+	stream, err := client.LookupStream(context.Background())
+	if err != nil {
+		t.Fatalf("LookupStream failed: %v", err)
+	}
+	const n = 1000
+	go func() {
+		for i := range n {
+			stream.Send(&iporgpb.LookupRequest{Ip: fmt.Sprintf("8.8.%d.%d", 8-i%2, i%256), Id: fmt.Sprint(i)})
+		}
+		stream.CloseSend()
+	}()
+
+	// Lookups run concurrently, but responses follow the requests
+	for i := range n {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv %d failed: %v", i, err)
+		}
+		found := i%2 == 0
+		if resp.GetId() != fmt.Sprint(i) || (resp.GetResult() != nil) != found {
+			t.Fatalf("response %d: got %v, want id %d found %v", i, resp, i, found)
+		}
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("end of stream: got %v, want EOF", err)
+	}
+}
+
+func TestLookupCIDRAndInfo(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	resp, err := client.LookupCIDR(ctx, &iporgpb.LookupCIDRRequest{Cidr: "8.8.0.0/16"})
+	if err != nil {
+		t.Fatalf("LookupCIDR failed: %v", err)
+	}
+	if len(resp.GetResults()) != 1 || resp.GetTruncated() || resp.GetResults()[0].GetIp() != "8.8.8.0" {
+		t.Errorf("got %v", resp)
+	}
+	if _, err := client.LookupCIDR(ctx, &iporgpb.LookupCIDRRequest{Cidr: "10.0.0.0/8"}); status.Code(err) != codes.NotFound {
+		t.Errorf("empty prefix: got %v, want NotFound", err)
+	}
+
+	info, err := client.Info(ctx, &iporgpb.InfoRequest{})
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if info.GetBuilderVersion() != "test" || info.GetTotalRecords() != 1 || info.GetRecordsByRir()["ARIN"] != 1 {
+		t.Errorf("got %v", info)
+	}
+}
@@ -0,0 +1,489 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/sources/maxmind"
+	"github.com/wingedpig/iporg/pkg/sources/rdap"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// sourceIPtoASN names the iptoasn database, which supplies announced
+// prefixes rather than record fields
+const sourceIPtoASN = "iptoasn"
+
+// sourceDB names the iporg database being queried
+const sourceDB = "iporgdb"
+
+// explainFields are the precedence fields in the order the builder resolves them
+var explainFields = []string{enrich.FieldOrg, enrich.FieldRIR, enrich.FieldASN, enrich.FieldCountry, enrich.FieldGeo}
+
+// explainConfig names the local databases consulted by --explain
+type explainConfig struct {
+	RIPEBulkDB string
+	ARINBulkDB string
+	IPtoASNDB  string
+	MMDBASN    string
+	MMDBCity   string
+	Precedence string
+	Overrides  *overrides.Set
+}
+
+// explainer answers queries from every configured local source and shows
+// which of them the builder's precedence would use
+type explainer struct {
+	db        *iporgdb.DB // nil if there is no database
+	ripe      *ripebulk.Database
+	arin      *arinbulk.Database
+	iptoasn   *iptoasn.Store
+	maxmind   *maxmind.Readers
+	overrides *overrides.Set
+	prec      enrich.Precedence
+	rdap      *rdapStub
+	pipeline  *enrich.Pipeline
+}
+
+// newExplainer opens the configured sources; db may be nil
+func newExplainer(db *iporgdb.DB, cfg explainConfig) (*explainer, error) {
+	e := &explainer{db: db, overrides: cfg.Overrides, prec: enrich.DefaultPrecedence(), rdap: &rdapStub{}}
+	if cfg.Precedence != "" {
+		prec, err := enrich.ParsePrecedence(cfg.Precedence)
+		if err != nil {
+			return nil, err
+		}
+		e.prec = e.prec.Merge(prec)
+	}
+
+	// The builder always consults RDAP; the stub records whether it would
+	// have been reached without making the request
+	sources := []enrich.Enricher{e.rdap}
+	var err error
+	if cfg.MMDBASN != "" || cfg.MMDBCity != "" {
+		if cfg.MMDBASN == "" || cfg.MMDBCity == "" {
+			e.Close()
+			return nil, fmt.Errorf("--mmdb-asn and --mmdb-city must be given together")
+		}
+		if e.maxmind, err = maxmind.Open(cfg.MMDBASN, cfg.MMDBCity); err != nil {
+			e.Close()
+			return nil, err
+		}
+		sources = append(sources, enrich.NewMaxMind(e.maxmind))
+	}
+	if cfg.RIPEBulkDB != "" {
+		if e.ripe, err = ripebulk.OpenDatabase(cfg.RIPEBulkDB); err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to open RIPE bulk database: %w", err)
+		}
+		sources = append(sources, enrich.NewRIPEBulk(e.ripe))
+	}
+	if cfg.ARINBulkDB != "" {
+		if e.arin, err = arinbulk.OpenDatabase(cfg.ARINBulkDB); err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to open ARIN bulk database: %w", err)
+		}
+		sources = append(sources, enrich.NewARINBulk(e.arin))
+	}
+	if cfg.IPtoASNDB != "" {
+		if e.iptoasn, err = iptoasn.Open(cfg.IPtoASNDB); err != nil {
+			e.Close()
+			return nil, fmt.Errorf("failed to open iptoasn database: %w", err)
+		}
+	}
+	if e.db == nil && len(sources) == 1 && e.iptoasn == nil {
+		return nil, fmt.Errorf("--explain needs at least one database")
+	}
+
+	if e.pipeline, err = enrich.NewPipeline(e.prec, nil, sources...); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close closes the sources opened by newExplainer
+func (e *explainer) Close() {
+	if e.ripe != nil {
+		e.ripe.Close()
+	}
+	if e.arin != nil {
+		e.arin.Close()
+	}
+	if e.iptoasn != nil {
+		e.iptoasn.Close()
+	}
+	if e.maxmind != nil {
+		e.maxmind.Close()
+	}
+}
+
+// answer is what one source says about a query
+type answer struct {
+	Source   string   `json:"source"`
+	Match    bool     `json:"match"`
+	OrgName  string   `json:"org_name,omitempty"`
+	OrgID    string   `json:"org_id,omitempty"`
+	Network  string   `json:"network,omitempty"` // RIPE netname or ARIN net handle
+	Status   string   `json:"status,omitempty"`  // RIPE status or ARIN net type
+	RIR      string   `json:"rir,omitempty"`
+	Country  string   `json:"country,omitempty"`
+	ASN      int      `json:"asn,omitempty"`
+	ASNName  string   `json:"asn_name,omitempty"`
+	Range    string   `json:"range,omitempty"`
+	Selected []string `json:"selected,omitempty"` // Fields the builder would take from this source
+	Note     string   `json:"note,omitempty"`
+}
+
+// selection is the source the precedence picks for one field
+type selection struct {
+	Field  string `json:"field"`
+	Source string `json:"source,omitempty"` // Empty when no source has a value
+	Value  string `json:"value,omitempty"`
+}
+
+// explanation describes how one query is answered by each source
+type explanation struct {
+	Query      string              `json:"query"`
+	Prefix     string              `json:"prefix"`    // Prefix the sources were asked about
+	Announced  bool                `json:"announced"` // Prefix came from iptoasn
+	Sources    []answer            `json:"sources"`
+	Selected   []selection         `json:"selected"`
+	Overridden bool                `json:"overridden,omitempty"`
+	Result     *model.LookupResult `json:"result,omitempty"` // Record the builder would write
+	Precedence string              `json:"precedence"`
+}
+
+// explain looks up an IP or CIDR in every source. Like the builder in its
+// default mode, an IP is looked up as the announced prefix holding it when
+// iptoasn knows one; otherwise the address itself is looked up.
+func (e *explainer) explain(ctx context.Context, query string) (*explanation, error) {
+	var prefix netip.Prefix
+	var addr netip.Addr
+	if strings.Contains(query, "/") {
+		p, err := netip.ParsePrefix(query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", model.ErrInvalidIP, err)
+		}
+		prefix = p.Masked()
+		addr = prefix.Addr()
+	} else {
+		a, err := netip.ParseAddr(query)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", model.ErrInvalidIP, err)
+		}
+		addr = a.Unmap()
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	x := &explanation{Query: query, Precedence: e.prec.String()}
+	var announced *model.CanonicalPrefix
+	if e.iptoasn != nil {
+		a := answer{Source: sourceIPtoASN}
+		p, err := e.iptoasn.LookupIP(addr)
+		switch {
+		case err == nil:
+			announced = p
+			a.Match = true
+			a.ASN, a.ASNName = p.ASN, p.ASName
+			a.Country, a.RIR, a.Range = p.Country, strings.ToUpper(p.Registry), p.CIDR
+			a.Note = "announced prefix"
+		case errors.Is(err, model.ErrNotFound):
+			a.Note = "not announced"
+		default:
+			a.Note = err.Error()
+		}
+		x.Sources = append(x.Sources, a)
+	}
+	if announced != nil && prefix.IsSingleIP() {
+		if p, err := netip.ParsePrefix(announced.CIDR); err == nil {
+			prefix = p.Masked()
+			x.Announced = true
+		}
+	}
+	x.Prefix = prefix.String()
+
+	q := enrich.Query{Prefix: prefix, Addr: addr, Point: prefix.IsSingleIP()}
+	if !q.Point {
+		q.Addr, _ = ipcodec.RepresentativeIP(prefix.String())
+	}
+
+	if e.db != nil {
+		x.Sources = append(x.Sources, e.stored(addr))
+	}
+	if e.ripe != nil {
+		x.Sources = append(x.Sources, e.ripeAnswer(q))
+	}
+	if e.arin != nil {
+		x.Sources = append(x.Sources, e.arinAnswer(q))
+	}
+	x.Sources = append(x.Sources, answer{Source: enrich.SourceRDAP})
+	if e.maxmind != nil {
+		x.Sources = append(x.Sources, e.maxmindAnswer(q.Addr))
+	}
+
+	// Resolve the record the way the builder does
+	start, end, err := ipcodec.CIDRToRange(prefix.String())
+	if err != nil {
+		return nil, err
+	}
+	rec := &model.Record{Start: start, End: end, Prefix: prefix.String(), Schema: 1}
+	e.rdap.queried = false
+	trace := e.pipeline.Run(ctx, q, rec)
+	for _, field := range explainFields {
+		s := selection{Field: field, Source: trace.Chosen[field]}
+		if s.Source != "" {
+			s.Value = fieldValue(field, rec)
+		}
+		x.Selected = append(x.Selected, s)
+	}
+	if e.overrides != nil {
+		x.Overridden = e.overrides.ApplyRange(rec, time.Now())
+	}
+	if len(trace.Chosen) > 0 || x.Overridden {
+		x.Result = iporgdb.ToLookupResult(query, rec)
+	}
+
+	for i := range x.Sources {
+		a := &x.Sources[i]
+		for _, s := range x.Selected {
+			if s.Source == a.Source {
+				a.Selected = append(a.Selected, s.Field)
+			}
+		}
+		if err := trace.Errors[a.Source]; err != nil && a.Note == "" {
+			a.Note = err.Error()
+		}
+		if a.Source == enrich.SourceRDAP {
+			a.Note = "live source, not queried; the builder would not reach it"
+			if e.rdap.queried {
+				a.Note = "live source, not queried; the builder would query it and may prefer its answer"
+			}
+		}
+	}
+	return x, nil
+}
+
+// stored returns the record already in the iporg database
+func (e *explainer) stored(addr netip.Addr) answer {
+	a := answer{Source: sourceDB}
+	rec, err := e.db.GetByIP(addr)
+	if err != nil {
+		if !errors.Is(err, model.ErrNotFound) {
+			a.Note = err.Error()
+		}
+		return a
+	}
+	a.Match = true
+	a.OrgName, a.Status, a.RIR, a.Country = rec.OrgName, rec.StatusLabel, rec.RIR, rec.Country
+	a.ASN, a.ASNName = rec.ASN, rec.ASNName
+	a.Range = rec.Start.String() + " - " + rec.End.String()
+	a.Note = "source role " + rec.SourceRole
+	return a
+}
+
+// ripeAnswer looks q up in the RIPE bulk database
+func (e *explainer) ripeAnswer(q enrich.Query) answer {
+	a := answer{Source: enrich.SourceRIPEBulk}
+	if !q.Addr.Is4() {
+		a.Note = "IPv6 not supported"
+		return a
+	}
+	var match *ripebulk.Match
+	var err error
+	if q.Point {
+		match, err = e.ripe.LookupIP(q.Addr)
+	} else {
+		match, err = e.ripe.LookupPrefix(q.Prefix)
+	}
+	if err != nil {
+		if !errors.Is(err, ripebulk.ErrNotFound) {
+			a.Note = err.Error()
+		}
+		return a
+	}
+	a.Match = true
+	a.OrgName, a.OrgID, a.Network = rdap.CleanOrgName(match.OrgName), match.OrgID, match.Netname
+	a.Status, a.RIR, a.Country = match.Status, "RIPE", match.Country
+	a.Range = match.Start.String() + " - " + match.End.String()
+	if enrich.IsRIPEPlaceholder(match.OrgName) {
+		a.Note = "placeholder for non-RIPE space, ignored"
+	}
+	return a
+}
+
+// arinAnswer looks q up in the ARIN bulk database
+func (e *explainer) arinAnswer(q enrich.Query) answer {
+	a := answer{Source: enrich.SourceARINBulk}
+	if !q.Addr.Is4() {
+		a.Note = "IPv6 not supported"
+		return a
+	}
+	var match *arinbulk.Match
+	var err error
+	if q.Point {
+		match, err = e.arin.LookupIP(q.Addr)
+	} else {
+		match, err = e.arin.LookupPrefix(q.Prefix)
+	}
+	if err != nil {
+		if !errors.Is(err, arinbulk.ErrNotFound) {
+			a.Note = err.Error()
+		}
+		return a
+	}
+	a.Match = true
+	a.OrgName, a.OrgID, a.Network = rdap.CleanOrgName(match.OrgName), match.OrgID, match.NetHandle
+	a.Status, a.RIR, a.Country = match.NetType, "ARIN", match.Country
+	a.Range = match.Start.String() + " - " + match.End.String()
+	return a
+}
+
+// maxmindAnswer looks addr up in the MaxMind databases
+func (e *explainer) maxmindAnswer(addr netip.Addr) answer {
+	a := answer{Source: enrich.SourceMaxMind}
+	asn, asnName, asnErr := e.maxmind.ASNInfo(addr)
+	if asnErr == nil && asn != 0 {
+		a.Match = true
+		a.ASN, a.ASNName, a.OrgName = asn, asnName, asnName
+	}
+	if geo, err := e.maxmind.Geo(addr); err == nil && geo.Country != "" {
+		a.Match = true
+		a.Country = geo.Country
+	}
+	return a
+}
+
+// fieldValue formats the value a field was resolved to
+func fieldValue(field string, rec *model.Record) string {
+	switch field {
+	case enrich.FieldOrg:
+		return rec.OrgName
+	case enrich.FieldRIR:
+		if rec.StatusLabel != "" {
+			return rec.RIR + " (" + rec.StatusLabel + ")"
+		}
+		return rec.RIR
+	case enrich.FieldASN:
+		return "AS" + strconv.Itoa(rec.ASN) + " " + rec.ASNName
+	case enrich.FieldCountry:
+		return rec.Country
+	case enrich.FieldGeo:
+		var parts []string
+		for _, s := range []string{rec.City, rec.Region} {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+		if rec.Lat != 0 || rec.Lon != 0 {
+			parts = append(parts, fmt.Sprintf("%.4f, %.4f", rec.Lat, rec.Lon))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+// rdapStub stands in for RDAP, recording whether the builder would have
+// queried it
+type rdapStub struct {
+	queried bool
+}
+
+func (s *rdapStub) Name() string { return enrich.SourceRDAP }
+func (s *rdapStub) Local() bool  { return false }
+
+func (s *rdapStub) Enrich(ctx context.Context, q enrich.Query) (*enrich.Result, error) {
+	s.queried = true
+	return nil, nil
+}
+
+// writeExplanation writes x as JSON or as a side-by-side text table
+func writeExplanation(w io.Writer, format string, x *explanation, first bool) error {
+	if format == formatJSON {
+		data, err := json.Marshal(x)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	if !first {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Query:   %s\n", x.Query)
+	switch {
+	case x.Announced:
+		fmt.Fprintf(w, "Prefix:  %s (announced, per iptoasn)\n", x.Prefix)
+	case x.Prefix != x.Query:
+		fmt.Fprintf(w, "Prefix:  %s\n", x.Prefix)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tORG\tORG ID\tNETWORK\tSTATUS\tCOUNTRY\tASN\tRANGE\tNOTE")
+	for _, a := range x.Sources {
+		note := a.Note
+		if !a.Match && note == "" {
+			note = "no match"
+		}
+		if len(a.Selected) > 0 {
+			sel := "selected: " + strings.Join(a.Selected, ",")
+			if note != "" {
+				sel += "; " + note
+			}
+			note = sel
+		}
+		asn := ""
+		if a.ASN != 0 {
+			asn = "AS" + strconv.Itoa(a.ASN)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", a.Source, dash(a.OrgName), dash(a.OrgID),
+			dash(a.Network), dash(a.Status), dash(a.Country), dash(asn), dash(a.Range), note)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nBuilder precedence would select:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, s := range x.Selected {
+		if s.Source == "" {
+			fmt.Fprintf(tw, "  %s\t(none)\t\n", s.Field)
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", s.Field, s.Source, s.Value)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if x.Overridden {
+		fmt.Fprintln(w, "  (an override then replaces some of these fields)")
+	}
+	return nil
+}
+
+// dash returns s, or "-" if it is empty
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/testutil"
+)
+
+func newTestExplainer(t *testing.T) *explainer {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := explainConfig{
+		RIPEBulkDB: filepath.Join(dir, "ripe"),
+		ARINBulkDB: filepath.Join(dir, "arin"),
+		IPtoASNDB:  filepath.Join(dir, "iptoasn"),
+		MMDBASN:    filepath.Join(dir, "asn.mmdb"),
+		MMDBCity:   filepath.Join(dir, "city.mmdb"),
+	}
+	if err := testutil.WriteRIPEBulk(cfg.RIPEBulkDB, []testutil.Registration{
+		{Range: "8.0.0.0/8", OrgID: "ORG-IANA1-RIPE", OrgName: "NON-RIPE-NCC-MANAGED-ADDRESS-BLOCK", Status: "ALLOCATED UNSPECIFIED", Country: "EU"},
+		{Range: "31.90.0.0/16", OrgID: "ORG-EE1-RIPE", OrgName: "EE Limited", Status: "ALLOCATED PA", Country: "GB", NetName: "EE-NET"},
+	}); err != nil {
+		t.Fatalf("Failed to write RIPE bulk: %v", err)
+	}
+	if err := testutil.WriteARINBulk(cfg.ARINBulkDB, []testutil.Registration{
+		{Range: "8.8.8.0/24", OrgID: "GOGL", OrgName: "Google LLC", Status: "Direct Allocation", Country: "US", NetName: "LVLT-GOGL-8-8-8"},
+	}); err != nil {
+		t.Fatalf("Failed to write ARIN bulk: %v", err)
+	}
+	if err := testutil.BuildIPtoASN(cfg.IPtoASNDB, []testutil.IPtoASNRow{
+		{Start: "8.8.8.0", End: "8.8.8.255", ASN: 15169, Country: "US", Registry: "arin", Name: "GOOGLE"},
+	}); err != nil {
+		t.Fatalf("Failed to build iptoasn: %v", err)
+	}
+	if err := testutil.WriteASNMMDB(cfg.MMDBASN, []testutil.ASNBlock{
+		{Prefix: "8.8.8.0/24", ASN: 15169, Org: "GOOGLE"},
+		{Prefix: "31.90.0.0/16", ASN: 12576, Org: "EE Limited"},
+	}); err != nil {
+		t.Fatalf("Failed to write ASN mmdb: %v", err)
+	}
+	if err := testutil.WriteCityMMDB(cfg.MMDBCity, []testutil.CityBlock{
+		{Prefix: "8.8.8.0/24", Country: "US", City: "Mountain View"},
+		{Prefix: "31.90.0.0/16", Country: "GB", City: "London"},
+	}); err != nil {
+		t.Fatalf("Failed to write City mmdb: %v", err)
+	}
+
+	e, err := newExplainer(nil, cfg)
+	if err != nil {
+		t.Fatalf("Failed to open sources: %v", err)
+	}
+	t.Cleanup(e.Close)
+	return e
+}
+
+// source returns the answer of one source
+func source(t *testing.T, x *explanation, name string) answer {
+	t.Helper()
+	for _, a := range x.Sources {
+		if a.Source == name {
+			return a
+		}
+	}
+	t.Fatalf("No answer from %s", name)
+	return answer{}
+}
+
+// chosen returns the source selected for a field
+func chosen(x *explanation, field string) string {
+	for _, s := range x.Selected {
+		if s.Field == field {
+			return s.Source
+		}
+	}
+	return ""
+}
+
+func TestExplain(t *testing.T) {
+	e := newTestExplainer(t)
+
+	x, err := e.explain(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if !x.Announced || x.Prefix != "8.8.8.0/24" {
+		t.Errorf("prefix: got %s (announced %v), want announced 8.8.8.0/24", x.Prefix, x.Announced)
+	}
+	if a := source(t, x, enrich.SourceRIPEBulk); !strings.Contains(a.Note, "placeholder") {
+		t.Errorf("ripe_bulk note: got %q, want placeholder", a.Note)
+	}
+	if a := source(t, x, enrich.SourceARINBulk); !a.Match || a.OrgID != "GOGL" || a.Network != "NET-TEST-1" {
+		t.Errorf("arin_bulk: got %+v, want GOGL/NET-TEST-1", a)
+	}
+	if a := source(t, x, sourceIPtoASN); a.ASN != 15169 || a.Range != "8.8.8.0/24" {
+		t.Errorf("iptoasn: got AS%d %s, want AS15169 8.8.8.0/24", a.ASN, a.Range)
+	}
+	for field, want := range map[string]string{
+		enrich.FieldOrg:     enrich.SourceARINBulk,
+		enrich.FieldRIR:     enrich.SourceARINBulk,
+		enrich.FieldASN:     enrich.SourceMaxMind,
+		enrich.FieldCountry: enrich.SourceMaxMind, // ARIN bulk matches carry no country
+		enrich.FieldGeo:     enrich.SourceMaxMind,
+	} {
+		if got := chosen(x, field); got != want {
+			t.Errorf("%s: got %s, want %s", field, got, want)
+		}
+	}
+	if x.Result == nil || x.Result.OrgName != "Google LLC" || x.Result.City != "Mountain View" {
+		t.Errorf("result: got %+v, want Google LLC in Mountain View", x.Result)
+	}
+	if a := source(t, x, enrich.SourceRDAP); !strings.Contains(a.Note, "would not reach") {
+		t.Errorf("rdap note: got %q, want not reached", a.Note)
+	}
+
+	var out strings.Builder
+	if err := writeExplanation(&out, formatText, x, true); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "selected: org,rir") {
+		t.Errorf("text output does not mark the selected source:\n%s", out.String())
+	}
+}
+
+func TestExplainUnannounced(t *testing.T) {
+	e := newTestExplainer(t)
+
+	// Not announced: the address itself is looked up
+	x, err := e.explain(context.Background(), "31.90.1.1")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if x.Announced || x.Prefix != "31.90.1.1/32" {
+		t.Errorf("prefix: got %s (announced %v), want 31.90.1.1/32", x.Prefix, x.Announced)
+	}
+	if got := chosen(x, enrich.FieldOrg); got != enrich.SourceRIPEBulk {
+		t.Errorf("org: got %s, want %s", got, enrich.SourceRIPEBulk)
+	}
+
+	// Nothing local: the builder would fall through to RDAP
+	x, err = e.explain(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if x.Result != nil {
+		t.Errorf("result: got %+v, want none", x.Result)
+	}
+	if a := source(t, x, enrich.SourceRDAP); !strings.Contains(a.Note, "would query it") {
+		t.Errorf("rdap note: got %q, want queried", a.Note)
+	}
+
+	if _, err := e.explain(context.Background(), "bad"); err == nil {
+		t.Error("Expected an error for an invalid query")
+	}
+}
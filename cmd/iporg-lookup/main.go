@@ -4,23 +4,52 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"iter"
+	"log/slog"
+	"net/netip"
 	"os"
+	"strings"
 
 	"github.com/wingedpig/iporg/pkg/iporgdb"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "1.0.0"
 
+// Exit codes
+const (
+	exitOK       = 0 // Every query was found
+	exitNotFound = 1 // At least one query was not found
+	exitError    = 2 // Invalid input, a failed lookup or a usage error
+)
+
 func main() {
 	// Parse flags
 	dbPath := flag.String("db", "./iporgdb", "Path to LevelDB database")
-	jsonOutput := flag.Bool("json", true, "Output as JSON")
+	format := flag.String("format", "", "Output format: json, table, csv or text (default: text on a terminal, json otherwise)")
+	jsonOutput := flag.Bool("json", false, "Output as JSON (deprecated: use --format)")
+	fieldList := flag.String("fields", "", "Comma-separated fields to output (default: all; ip,asn,org_name,country,prefix for table)")
+	raw := flag.Bool("raw", false, "Show the stored record, including range, status label and last checked time")
+	overridesFile := flag.String("overrides", "", "Overrides file applied to the lookup result")
+	explain := flag.Bool("explain", false, "Compare the answers of every configured local database and show which the builder would use")
+	var ecfg explainConfig
+	flag.StringVar(&ecfg.RIPEBulkDB, "ripe-bulk-db", "", "RIPE bulk database for --explain")
+	flag.StringVar(&ecfg.ARINBulkDB, "arin-bulk-db", "", "ARIN bulk database for --explain")
+	flag.StringVar(&ecfg.IPtoASNDB, "iptoasn-db", "", "iptoasn database for --explain (announced prefixes)")
+	flag.StringVar(&ecfg.MMDBASN, "mmdb-asn", "", "MaxMind GeoLite2-ASN.mmdb for --explain")
+	flag.StringVar(&ecfg.MMDBCity, "mmdb-city", "", "MaxMind GeoLite2-City.mmdb for --explain")
+	flag.StringVar(&ecfg.Precedence, "precedence", "", "Source precedence for --explain, as for iporg-build")
 	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
+	flag.Usage = usage
 	flag.Parse()
 
 	if *showVersion {
@@ -28,70 +57,220 @@ func main() {
 		return
 	}
 
-	// Get IP address from args
 	if flag.NArg() < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: iporg-lookup [options] <ip-address>\n\n")
-		fmt.Fprintf(os.Stderr, "Options:\n")
-		flag.PrintDefaults()
-		fmt.Fprintf(os.Stderr, "\nExamples:\n")
-		fmt.Fprintf(os.Stderr, "  iporg-lookup 8.8.8.8\n")
-		fmt.Fprintf(os.Stderr, "  iporg-lookup --db=/data/iporgdb 2001:4860:4860::8888\n")
-		os.Exit(1)
+		usage()
+		os.Exit(exitError)
 	}
 
-	ipStr := flag.Arg(0)
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(exitError)
+	}
 
-	// Open database
-	db, err := iporgdb.Open(*dbPath)
-	if err != nil {
-		log.Fatalf("ERROR: Failed to open database: %v", err)
+	// --json is honoured when given explicitly
+	if *format == "" {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "json" {
+				*format = formatText
+				if *jsonOutput {
+					*format = formatJSON
+				}
+			}
+		})
+	}
+	if *format == "" {
+		*format = formatJSON
+		if isTerminal(os.Stdout) {
+			*format = formatText
+		}
 	}
-	defer db.Close()
 
-	// Lookup IP
-	rec, err := db.LookupString(ipStr)
+	if *explain {
+		if *format != formatJSON && *format != formatText {
+			fmt.Fprintf(os.Stderr, "ERROR: --explain supports json and text output\n")
+			os.Exit(exitError)
+		}
+		if *fieldList != "" || *raw {
+			fmt.Fprintf(os.Stderr, "ERROR: --fields and --raw do not apply to --explain\n")
+			os.Exit(exitError)
+		}
+	}
+
+	cols, err := selectColumns(*fieldList, *format, *raw)
 	if err != nil {
-		if err == model.ErrNotFound {
-			if *jsonOutput {
-				fmt.Printf("{\"error\":\"IP not found in database\",\"ip\":\"%s\"}\n", ipStr)
-			} else {
-				fmt.Printf("IP %s not found in database\n", ipStr)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(exitError)
+	}
+	single := flag.NArg() == 1 && flag.Arg(0) != "-" && !strings.Contains(flag.Arg(0), "/")
+	out := bufio.NewWriter(os.Stdout)
+	p, err := newPrinter(out, *format, cols, *fieldList != "", *raw, single)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	var set *overrides.Set
+	if *overridesFile != "" {
+		set, err = overrides.Load(*overridesFile)
+		if err != nil {
+			fatal("Failed to load overrides", "err", err)
+		}
+	}
+
+	// Open database; --explain works without one
+	var db *iporgdb.DB
+	if _, statErr := os.Stat(*dbPath); !*explain || statErr == nil {
+		db, err = iporgdb.Open(*dbPath)
+		if err != nil {
+			fatal("Failed to open database", "err", err)
+		}
+		defer db.Close()
+		if set != nil {
+			db.SetOverrides(set)
+		}
+	}
+
+	if *explain {
+		ecfg.Overrides = set
+		e, err := newExplainer(db, ecfg)
+		if err != nil {
+			fatal("Failed to open sources", "err", err)
+		}
+		code := runExplain(e, out, *format)
+		e.Close()
+		if err := out.Flush(); err != nil {
+			fatal("Failed to write output", "err", err)
+		}
+		os.Exit(code)
+	}
+
+	code := exitOK
+	for query, err := range queries(flag.Args(), os.Stdin) {
+		if err != nil {
+			fatal("Failed to read stdin", "err", err)
+		}
+		for _, r := range lookup(db, query) {
+			switch {
+			case r.err == model.ErrNotFound:
+				code = max(code, exitNotFound)
+			case r.err != nil:
+				code = exitError
+			}
+			if err := p.print(r); err != nil {
+				fatal("Failed to write output", "err", err)
 			}
-			os.Exit(1)
 		}
-		log.Fatalf("ERROR: Lookup failed: %v", err)
 	}
+	if err := p.flush(); err != nil {
+		fatal("Failed to write output", "err", err)
+	}
+	if err := out.Flush(); err != nil {
+		fatal("Failed to write output", "err", err)
+	}
+	os.Exit(code)
+}
 
-	// Convert to result
-	result := iporgdb.ToLookupResult(ipStr, rec)
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: iporg-lookup [options] <ip-or-cidr|-> ...\n\n")
+	fmt.Fprintf(os.Stderr, "Looks up IP addresses, or every stored range overlapping a CIDR. \"-\" reads\n")
+	fmt.Fprintf(os.Stderr, "queries from stdin, one per line.\n\n")
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExit status: 0 if every query was found, 1 if any was not found,\n")
+	fmt.Fprintf(os.Stderr, "2 on invalid input or errors.\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  iporg-lookup 8.8.8.8\n")
+	fmt.Fprintf(os.Stderr, "  iporg-lookup --db=/data/iporgdb 2001:4860:4860::8888\n")
+	fmt.Fprintf(os.Stderr, "  iporg-lookup --format=table 8.8.8.8 1.1.1.1 31.90.0.0/16\n")
+	fmt.Fprintf(os.Stderr, "  cut -d' ' -f1 access.log | iporg-lookup --format=csv --fields=ip,org_name -\n")
+	fmt.Fprintf(os.Stderr, "  iporg-lookup --explain --ripe-bulk-db=./ripe-bulk --arin-bulk-db=./arin-bulk 31.90.1.1\n")
+}
 
-	// Output
-	if *jsonOutput {
-		data, err := json.MarshalIndent(result, "", "  ")
+// runExplain explains every query and returns the exit code
+func runExplain(e *explainer, w io.Writer, format string) int {
+	code := exitOK
+	first := true
+	for query, err := range queries(flag.Args(), os.Stdin) {
+		if err != nil {
+			fatal("Failed to read stdin", "err", err)
+		}
+		x, err := e.explain(context.Background(), query)
 		if err != nil {
-			log.Fatalf("ERROR: Failed to marshal JSON: %v", err)
+			slog.Error("Explain failed", "query", query, "err", err)
+			code = exitError
+			continue
+		}
+		if x.Result == nil {
+			code = max(code, exitNotFound)
 		}
-		fmt.Println(string(data))
-	} else {
-		printHumanReadable(ipStr, result)
+		if err := writeExplanation(w, format, x, first); err != nil {
+			fatal("Failed to write output", "err", err)
+		}
+		first = false
 	}
+	return code
+}
+
+// fatal logs an error and exits with exitError, which unlike
+// logging.Fatal's status cannot be mistaken for a not-found result
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(exitError)
 }
 
-func printHumanReadable(ip string, result *model.LookupResult) {
-	fmt.Printf("IP Address:         %s\n", ip)
-	fmt.Printf("Organization:       %s\n", result.OrgName)
-	fmt.Printf("ASN:                AS%d (%s)\n", result.ASN, result.ASNName)
-	fmt.Printf("Prefix:             %s\n", result.Prefix)
-	fmt.Printf("RIR:                %s\n", result.RIR)
-	fmt.Printf("Country:            %s\n", result.Country)
-	if result.Region != "" {
-		fmt.Printf("Region:             %s\n", result.Region)
+// queries yields the query arguments, expanding "-" to the lines of stdin
+func queries(args []string, stdin io.Reader) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for _, arg := range args {
+			if arg != "-" {
+				if !yield(arg, nil) {
+					return
+				}
+				continue
+			}
+			scanner := bufio.NewScanner(stdin)
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				if !yield(line, nil) {
+					return
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				yield("", err)
+				return
+			}
+		}
 	}
-	if result.City != "" {
-		fmt.Printf("City:               %s\n", result.City)
+}
+
+// lookup answers one query: an IP address, or a CIDR matched against every
+// stored range it overlaps
+func lookup(db *iporgdb.DB, query string) []result {
+	if !strings.Contains(query, "/") {
+		rec, err := db.LookupString(query)
+		return []result{{query: query, rec: rec, err: err}}
 	}
-	if result.Lat != 0 || result.Lon != 0 {
-		fmt.Printf("Location:           %.4f, %.4f\n", result.Lat, result.Lon)
+
+	prefix, err := netip.ParsePrefix(query)
+	if err != nil {
+		return []result{{query: query, err: fmt.Errorf("%w: %v", model.ErrInvalidIP, err)}}
+	}
+	recs, _, err := db.LookupPrefix(prefix, 0)
+	if err != nil {
+		return []result{{query: query, err: err}}
 	}
-	fmt.Printf("Source:             %s\n", result.SourceRole)
+	results := make([]result, len(recs))
+	for i, rec := range recs {
+		results[i] = result{query: query, rec: rec}
+	}
+	return results
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+func openTestDB(t *testing.T) *iporgdb.DB {
+	t.Helper()
+	db, err := iporgdb.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, rec := range []*model.Record{
+		{Start: netip.MustParseAddr("8.8.8.0"), End: netip.MustParseAddr("8.8.8.255"), ASN: 15169, ASNName: "GOOGLE",
+			OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.0/24", SourceRole: "registrant",
+			StatusLabel: "DIRECT ALLOCATION", LastChecked: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Schema: 1},
+		{Start: netip.MustParseAddr("8.8.9.0"), End: netip.MustParseAddr("8.8.9.255"), ASN: 15169, ASNName: "GOOGLE",
+			OrgName: "Google Cloud", RIR: "ARIN", Country: "US", Prefix: "8.8.9.0/24", SourceRole: "customer", Schema: 1},
+	} {
+		if err := db.PutRange(rec); err != nil {
+			t.Fatalf("Failed to put range: %v", err)
+		}
+	}
+	return db
+}
+
+// run looks up every query and returns the printed output
+func run(t *testing.T, db *iporgdb.DB, format, fields string, raw bool, args ...string) string {
+	t.Helper()
+	cols, err := selectColumns(fields, format, raw)
+	if err != nil {
+		t.Fatalf("Failed to select columns: %v", err)
+	}
+	var out strings.Builder
+	p, err := newPrinter(&out, format, cols, fields != "", raw, false)
+	if err != nil {
+		t.Fatalf("Failed to create printer: %v", err)
+	}
+	for query, err := range queries(args, strings.NewReader("")) {
+		if err != nil {
+			t.Fatalf("Failed to read queries: %v", err)
+		}
+		for _, r := range lookup(db, query) {
+			if err := p.print(r); err != nil {
+				t.Fatalf("Print failed: %v", err)
+			}
+		}
+	}
+	if err := p.flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	return out.String()
+}
+
+func TestQueries(t *testing.T) {
+	var got []string
+	for q, err := range queries([]string{"8.8.8.8", "-", "1.1.1.1"}, strings.NewReader("9.9.9.9\n\n# skip\n10.0.0.0/8\n")) {
+		if err != nil {
+			t.Fatalf("Failed to read queries: %v", err)
+		}
+		got = append(got, q)
+	}
+	if want := "8.8.8.8,9.9.9.9,10.0.0.0/8,1.1.1.1"; strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestLookupFormats(t *testing.T) {
+	db := openTestDB(t)
+
+	got := run(t, db, formatTable, "", false, "8.8.8.8", "8.8.9.0/24", "192.0.2.1")
+	want := "IP          ASN    ORG_NAME      COUNTRY  PREFIX\n" +
+		"8.8.8.8     15169  Google LLC    US       8.8.8.0/24\n" +
+		"8.8.9.0/24  15169  Google Cloud  US       8.8.9.0/24\n" +
+		"192.0.2.1   (IP not found in database)\n"
+	if got != want {
+		t.Errorf("table: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = run(t, db, formatCSV, "ip,org_name", false, "8.8.8.0/23", "bad")
+	want = "ip,org_name,error\n" +
+		"8.8.8.0/23,Google LLC,\n" +
+		"8.8.8.0/23,Google Cloud,\n" +
+		"bad,,\"invalid IP address: invalid IP address: ParseAddr(\"\"bad\"\"): unable to parse IP\"\n"
+	if got != want {
+		t.Errorf("csv: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = run(t, db, formatJSON, "", false, "8.8.8.8", "192.0.2.1")
+	want = `{"ip":"8.8.8.8","asn":15169,"asn_name":"GOOGLE","org_name":"Google LLC","rir":"ARIN","country":"US","prefix":"8.8.8.0/24","source_role":"registrant"}` + "\n" +
+		`{"error":"IP not found in database","ip":"192.0.2.1"}` + "\n"
+	if got != want {
+		t.Errorf("json: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = run(t, db, formatJSON, "ip,status_label,last_checked", true, "8.8.8.8")
+	want = `{"ip":"8.8.8.8","status_label":"DIRECT ALLOCATION","last_checked":"2025-01-02T03:04:05Z"}` + "\n"
+	if got != want {
+		t.Errorf("raw json: got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got = run(t, db, formatText, "", true, "8.8.8.8")
+	for _, line := range []string{"Organization:       Google LLC", "Range:              8.8.8.0 - 8.8.8.255", "Status:             DIRECT ALLOCATION"} {
+		if !strings.Contains(got, line) {
+			t.Errorf("text: missing %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	if _, err := selectColumns("ip,status_label", formatCSV, false); err == nil {
+		t.Error("expected an error for a raw field without --raw")
+	}
+	if _, err := selectColumns("ip,bogus", formatCSV, true); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if _, err := newPrinter(&strings.Builder{}, "xml", nil, false, false, false); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
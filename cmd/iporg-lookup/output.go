@@ -0,0 +1,305 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// Output formats
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatCSV   = "csv"
+	formatText  = "text"
+)
+
+// result is the answer to one query; a CIDR query has one result per
+// stored range it overlaps
+type result struct {
+	query string
+	rec   *model.Record // nil when err is set
+	err   error
+}
+
+// column is an output field computed from a result
+type column struct {
+	name  string
+	label string // Heading in text output
+	raw   bool   // Only with --raw
+	value func(query string, rec *model.Record) any
+}
+
+// columns lists every field, in output order
+var columns = []column{
+	{"ip", "IP Address", false, func(q string, r *model.Record) any { return q }},
+	{"start", "Range Start", true, func(q string, r *model.Record) any { return r.Start.String() }},
+	{"end", "Range End", true, func(q string, r *model.Record) any { return r.End.String() }},
+	{"asn", "ASN", false, func(q string, r *model.Record) any { return r.ASN }},
+	{"asn_name", "ASN Name", false, func(q string, r *model.Record) any { return r.ASNName }},
+	{"org_name", "Organization", false, func(q string, r *model.Record) any { return r.OrgName }},
+	{"rir", "RIR", false, func(q string, r *model.Record) any { return r.RIR }},
+	{"country", "Country", false, func(q string, r *model.Record) any { return r.Country }},
+	{"region", "Region", false, func(q string, r *model.Record) any { return r.Region }},
+	{"city", "City", false, func(q string, r *model.Record) any { return r.City }},
+	{"lat", "Latitude", false, func(q string, r *model.Record) any { return r.Lat }},
+	{"lon", "Longitude", false, func(q string, r *model.Record) any { return r.Lon }},
+	{"prefix", "Prefix", false, func(q string, r *model.Record) any { return r.Prefix }},
+	{"source_role", "Source", false, func(q string, r *model.Record) any { return r.SourceRole }},
+	{"status_label", "Status", true, func(q string, r *model.Record) any { return r.StatusLabel }},
+	{"last_checked", "Last Checked", true, func(q string, r *model.Record) any { return r.LastChecked.UTC().Format(time.RFC3339) }},
+	{"schema", "Schema", true, func(q string, r *model.Record) any { return r.Schema }},
+}
+
+// tableFields are the default table columns
+var tableFields = []string{"ip", "asn", "org_name", "country", "prefix"}
+
+// selectColumns resolves a comma-separated field list. An empty list
+// selects every field (the table defaults for table output); raw fields
+// are only available with raw set.
+func selectColumns(list, format string, raw bool) ([]column, error) {
+	var names []string
+	switch {
+	case list != "":
+		names = strings.Split(list, ",")
+	case format == formatTable:
+		names = tableFields
+		if raw {
+			names = append(names[:len(names):len(names)], "start", "end", "status_label", "last_checked")
+		}
+	default:
+		var cols []column
+		for _, c := range columns {
+			if raw || !c.raw {
+				cols = append(cols, c)
+			}
+		}
+		return cols, nil
+	}
+
+	var cols []column
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, c := range columns {
+			if c.name == name && (raw || !c.raw) {
+				cols = append(cols, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+	return cols, nil
+}
+
+// printer writes results in one output format
+type printer struct {
+	format  string
+	cols    []column
+	fields  bool // Fields were selected explicitly
+	raw     bool
+	indent  bool // Indent JSON (single lookups)
+	results int
+
+	w   io.Writer
+	tw  *tabwriter.Writer
+	csv *csv.Writer
+}
+
+// newPrinter creates a printer and writes any header
+func newPrinter(w io.Writer, format string, cols []column, fields, raw, indent bool) (*printer, error) {
+	p := &printer{format: format, cols: cols, fields: fields, raw: raw, indent: indent, w: w}
+	switch format {
+	case formatJSON, formatText:
+	case formatTable:
+		p.tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		names := make([]string, len(cols))
+		for i, c := range cols {
+			names[i] = strings.ToUpper(c.name)
+		}
+		fmt.Fprintln(p.tw, strings.Join(names, "\t"))
+	case formatCSV:
+		p.csv = csv.NewWriter(w)
+		names := make([]string, 0, len(cols)+1)
+		for _, c := range cols {
+			names = append(names, c.name)
+		}
+		p.csv.Write(append(names, "error"))
+	default:
+		return nil, fmt.Errorf("unknown format %q (want json, table, csv or text)", format)
+	}
+	return p, nil
+}
+
+// print writes one result
+func (p *printer) print(r result) error {
+	p.results++
+	switch p.format {
+	case formatJSON:
+		return p.printJSON(r)
+	case formatTable:
+		if r.err != nil {
+			// The last cell of a line does not widen its column
+			_, err := fmt.Fprintf(p.tw, "%s\t(%s)\n", r.query, errorText(r.err))
+			return err
+		}
+		vals := make([]string, len(p.cols))
+		for i, c := range p.cols {
+			vals[i] = formatValue(c.value(r.query, r.rec))
+		}
+		_, err := fmt.Fprintln(p.tw, strings.Join(vals, "\t"))
+		return err
+	case formatCSV:
+		vals := make([]string, 0, len(p.cols)+1)
+		for i, c := range p.cols {
+			switch {
+			case r.err == nil:
+				vals = append(vals, formatValue(c.value(r.query, r.rec)))
+			case i == 0:
+				vals = append(vals, r.query)
+			default:
+				vals = append(vals, "")
+			}
+		}
+		errText := ""
+		if r.err != nil {
+			errText = errorText(r.err)
+		}
+		return p.csv.Write(append(vals, errText))
+	default:
+		return p.printText(r)
+	}
+}
+
+// printJSON writes a result as a JSON object: the LookupResult unless
+// fields were selected or --raw given
+func (p *printer) printJSON(r result) error {
+	var v any
+	switch {
+	case r.err != nil:
+		v = map[string]string{"ip": r.query, "error": errorText(r.err)}
+	case !p.fields && !p.raw:
+		v = iporgdb.ToLookupResult(r.query, r.rec)
+	default:
+		obj := make([]byte, 0, 256)
+		obj = append(obj, '{')
+		for i, c := range p.cols {
+			if i > 0 {
+				obj = append(obj, ',')
+			}
+			k, _ := json.Marshal(c.name)
+			val, err := json.Marshal(c.value(r.query, r.rec))
+			if err != nil {
+				return err
+			}
+			obj = append(append(append(obj, k...), ':'), val...)
+		}
+		v = json.RawMessage(append(obj, '}'))
+	}
+
+	var data []byte
+	var err error
+	if p.indent && r.err == nil {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.w, string(data))
+	return err
+}
+
+// printText writes a result as labelled lines, separated from the
+// previous result by a blank line
+func (p *printer) printText(r result) error {
+	if p.results > 1 {
+		fmt.Fprintln(p.w)
+	}
+	if r.err != nil {
+		_, err := fmt.Fprintf(p.w, "%s: %s\n", r.query, errorText(r.err))
+		return err
+	}
+	if !p.fields {
+		printHumanReadable(p.w, r.query, r.rec, p.raw)
+		return nil
+	}
+	for _, c := range p.cols {
+		fmt.Fprintf(p.w, "%-20s%s\n", c.label+":", formatValue(c.value(r.query, r.rec)))
+	}
+	return nil
+}
+
+// flush writes out buffered table and CSV output
+func (p *printer) flush() error {
+	switch {
+	case p.tw != nil:
+		return p.tw.Flush()
+	case p.csv != nil:
+		p.csv.Flush()
+		return p.csv.Error()
+	}
+	return nil
+}
+
+func printHumanReadable(w io.Writer, ip string, rec *model.Record, raw bool) {
+	fmt.Fprintf(w, "IP Address:         %s\n", ip)
+	fmt.Fprintf(w, "Organization:       %s\n", rec.OrgName)
+	fmt.Fprintf(w, "ASN:                AS%d (%s)\n", rec.ASN, rec.ASNName)
+	fmt.Fprintf(w, "Prefix:             %s\n", rec.Prefix)
+	fmt.Fprintf(w, "RIR:                %s\n", rec.RIR)
+	fmt.Fprintf(w, "Country:            %s\n", rec.Country)
+	if rec.Region != "" {
+		fmt.Fprintf(w, "Region:             %s\n", rec.Region)
+	}
+	if rec.City != "" {
+		fmt.Fprintf(w, "City:               %s\n", rec.City)
+	}
+	if rec.Lat != 0 || rec.Lon != 0 {
+		fmt.Fprintf(w, "Location:           %.4f, %.4f\n", rec.Lat, rec.Lon)
+	}
+	fmt.Fprintf(w, "Source:             %s\n", rec.SourceRole)
+	if raw {
+		fmt.Fprintf(w, "Range:              %s - %s\n", rec.Start, rec.End)
+		fmt.Fprintf(w, "Status:             %s\n", rec.StatusLabel)
+		fmt.Fprintf(w, "Last Checked:       %s\n", rec.LastChecked.UTC().Format(time.RFC3339))
+		fmt.Fprintf(w, "Schema:             %d\n", rec.Schema)
+	}
+}
+
+// errorText describes a lookup error for output
+func errorText(err error) string {
+	if err == model.ErrNotFound {
+		return "IP not found in database"
+	}
+	return err.Error()
+}
+
+// formatValue formats a field value for table, CSV and text output
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
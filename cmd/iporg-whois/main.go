@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/cymru"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/metrics"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+)
+
+const version = "1.0.0"
+
+func main() {
+	dbPath := flag.String("db", "./iporgdb", "Path to LevelDB database (empty to use only --iptoasn-db)")
+	iptoasnPath := flag.String("iptoasn-db", "", "iptoasn database for AS<n> queries and addresses not in --db")
+	ripeBulkPath := flag.String("ripe-bulk-db", "", "RIPE bulk database for registry detail and unannounced space")
+	arinBulkPath := flag.String("arin-bulk-db", "", "ARIN bulk database for registry detail and unannounced space")
+	listen := flag.String("listen", ":4343", "TCP address to listen on")
+	var l limits
+	flag.IntVar(&l.maxConns, "max-conns", 256, "Maximum concurrent connections")
+	flag.IntVar(&l.maxPerIP, "max-conns-per-ip", 8, "Maximum concurrent connections per client address (0 for no limit)")
+	flag.DurationVar(&l.idleTimeout, "timeout", 30*time.Second, "Time allowed for each request line to arrive or be written")
+	flag.DurationVar(&l.maxDuration, "max-duration", 10*time.Minute, "Maximum lifetime of a connection")
+	flag.IntVar(&l.maxQueries, "max-queries", 100000, "Maximum queries per connection (0 for no limit)")
+	metricsAddr := flag.String("metrics", "", "Serve Prometheus metrics on address (e.g., localhost:9090)")
+	showVersion := flag.Bool("version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(flag.CommandLine)
+	flag.Usage = usage
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("iporg-whois version %s\n", version)
+		return
+	}
+
+	if *dbPath == "" && *iptoasnPath == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: --db or --iptoasn-db is required\n")
+		os.Exit(1)
+	}
+	if l.maxConns < 1 || l.idleTimeout <= 0 || l.maxDuration <= 0 {
+		fmt.Fprintf(os.Stderr, "ERROR: --max-conns, --timeout and --max-duration must be positive\n")
+		os.Exit(1)
+	}
+
+	_, err := logFlags.Setup()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	var db *iporgdb.DB
+	if *dbPath != "" {
+		db, err = iporgdb.Open(*dbPath)
+		if err != nil {
+			logging.Fatal("Failed to open database", "err", err)
+		}
+		defer db.Close()
+	}
+	var store *iptoasn.Store
+	if *iptoasnPath != "" {
+		store, err = iptoasn.Open(*iptoasnPath)
+		if err != nil {
+			logging.Fatal("Failed to open iptoasn database", "err", err)
+		}
+		defer store.Close()
+	}
+	var ripe *ripebulk.Database
+	if *ripeBulkPath != "" {
+		ripe, err = ripebulk.OpenDatabase(*ripeBulkPath)
+		if err != nil {
+			logging.Fatal("Failed to open RIPE bulk database", "err", err)
+		}
+		defer ripe.Close()
+	}
+	var arin *arinbulk.Database
+	if *arinBulkPath != "" {
+		arin, err = arinbulk.OpenDatabase(*arinBulkPath)
+		if err != nil {
+			logging.Fatal("Failed to open ARIN bulk database", "err", err)
+		}
+		defer arin.Close()
+	}
+
+	if *metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		if db != nil {
+			db.SetObserver(metrics.NewLookup(reg).Observe)
+		}
+		go func() {
+			slog.Info("Starting metrics server", "url", "http://"+*metricsAddr+metrics.Path)
+			if err := metrics.Serve(*metricsAddr, reg); err != nil {
+				slog.Error("Metrics server failed", "err", err)
+			}
+		}()
+	}
+
+	resolver := cymru.NewResolver(db, store)
+	resolver.SetRegistries(ripe, arin)
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logging.Fatal("Failed to listen", "addr", *listen, "err", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	slog.Info("Serving whois", "addr", *listen)
+	newServer(resolver, l).serve(ctx, ln)
+	slog.Info("Shut down")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: iporg-whois [options]\n\n")
+	fmt.Fprintf(os.Stderr, "Serves whois queries in the Team Cymru formats:\n")
+	fmt.Fprintf(os.Stderr, "  8.8.8.8                  AS | IP | AS Name\n")
+	fmt.Fprintf(os.Stderr, "  -v 8.8.8.8               AS | IP | BGP Prefix | CC | Registry | Allocated | AS Name\n")
+	fmt.Fprintf(os.Stderr, "  AS15169                  AS | AS Name (needs --iptoasn-db)\n")
+	fmt.Fprintf(os.Stderr, "  begin ... end            bulk mode, one query or option per line\n\n")
+	fmt.Fprintf(os.Stderr, "Single-query flags: -v verbose, -p prefix, -c country, -r registry,\n")
+	fmt.Fprintf(os.Stderr, "-a allocation date, -n AS name, -f no header. Bulk options: verbose,\n")
+	fmt.Fprintf(os.Stderr, "[no]header, [no]prefix, [no]countrycode, [no]registry, [no]allocdate, [no]asname.\n\n")
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nExample:\n")
+	fmt.Fprintf(os.Stderr, "  iporg-whois --listen=:43 --iptoasn-db=./data/iptoasndb --ripe-bulk-db=./data/ripe-bulk.ldb\n")
+	fmt.Fprintf(os.Stderr, "  whois -h localhost \" -v 8.8.8.8\"\n")
+	fmt.Fprintf(os.Stderr, "  printf 'begin\\nverbose\\n8.8.8.8\\n1.1.1.1\\nend\\n' | nc localhost 43\n")
+}
@@ -0,0 +1,433 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/cymru"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// maxLineSize is the longest request line accepted
+const maxLineSize = 4096
+
+// columns selects the output columns, as the Team Cymru options do. AS and
+// IP are always shown.
+type columns struct {
+	header    bool
+	prefix    bool
+	country   bool
+	registry  bool
+	allocated bool
+	asname    bool
+}
+
+// defaultColumns is the Cymru default: AS | IP | AS Name with a header
+func defaultColumns() columns {
+	return columns{header: true, asname: true}
+}
+
+// verbose selects every column
+func (c *columns) verbose() {
+	*c = columns{header: c.header, prefix: true, country: true, registry: true, allocated: true, asname: true}
+}
+
+// option applies a bulk-mode keyword and reports whether word was one
+func (c *columns) option(word string) bool {
+	on := !strings.HasPrefix(word, "no")
+	switch strings.TrimPrefix(word, "no") {
+	case "verbose":
+		if on {
+			c.verbose()
+		}
+	case "header":
+		c.header = on
+	case "prefix":
+		c.prefix = on
+	case "countrycode":
+		c.country = on
+	case "registry":
+		c.registry = on
+	case "allocdate":
+		c.allocated = on
+	case "asname":
+		c.asname = on
+	default:
+		return false
+	}
+	return true
+}
+
+// flags applies single-query flags such as "-v" or "-pc"
+func (c *columns) flags(s string) error {
+	for _, f := range strings.TrimPrefix(s, "-") {
+		switch f {
+		case 'v':
+			c.verbose()
+		case 'p':
+			c.prefix = true
+		case 'c':
+			c.country = true
+		case 'r':
+			c.registry = true
+		case 'a':
+			c.allocated = true
+		case 'n':
+			c.asname = true
+		case 'f':
+			c.header = false
+		default:
+			return fmt.Errorf("unknown option -%c", f)
+		}
+	}
+	return nil
+}
+
+// cell is one padded output column
+type cell struct {
+	value string
+	width int
+}
+
+// row joins cells with " | ", padding all but the last
+func row(cells []cell) string {
+	var sb strings.Builder
+	for i, c := range cells {
+		if i > 0 {
+			sb.WriteString(" | ")
+		}
+		sb.WriteString(c.value)
+		if i < len(cells)-1 {
+			for n := len(c.value); n < c.width; n++ {
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// originCells returns the cells of an IP answer; o is nil for no match
+func (c columns) originCells(ip string, o *cymru.Origin) []cell {
+	if o == nil {
+		o = &cymru.Origin{}
+	}
+	prefix, asname := o.Prefix, o.ASName
+	if o.ASN == 0 {
+		prefix, asname = "NA", "NA"
+	}
+	cells := []cell{{cymru.ASNString(o.ASN), 7}, {ip, 16}}
+	if c.prefix {
+		cells = append(cells, cell{prefix, 19})
+	}
+	if c.country {
+		cells = append(cells, cell{o.Country, 2})
+	}
+	if c.registry {
+		cells = append(cells, cell{o.Registry, 8})
+	}
+	if c.allocated {
+		cells = append(cells, cell{o.Allocated, 10})
+	}
+	if c.asname {
+		cells = append(cells, cell{asname, 0})
+	}
+	return cells
+}
+
+// originHeader returns the header of IP answers
+func (c columns) originHeader() []cell {
+	cells := []cell{{"AS", 7}, {"IP", 16}}
+	if c.prefix {
+		cells = append(cells, cell{"BGP Prefix", 19})
+	}
+	if c.country {
+		cells = append(cells, cell{"CC", 2})
+	}
+	if c.registry {
+		cells = append(cells, cell{"Registry", 8})
+	}
+	if c.allocated {
+		cells = append(cells, cell{"Allocated", 10})
+	}
+	if c.asname {
+		cells = append(cells, cell{"AS Name", 0})
+	}
+	return cells
+}
+
+// asCells returns the cells of an AS answer; a is nil for no match
+func (c columns) asCells(asn int, a *cymru.AS) []cell {
+	if a == nil {
+		a = &cymru.AS{ASN: asn, Name: "NA"}
+	}
+	cells := []cell{{cymru.ASNString(a.ASN), 7}}
+	if c.country {
+		cells = append(cells, cell{a.Country, 2})
+	}
+	if c.registry {
+		cells = append(cells, cell{a.Registry, 8})
+	}
+	if c.allocated {
+		cells = append(cells, cell{a.Allocated, 10})
+	}
+	return append(cells, cell{a.Name, 0})
+}
+
+// asHeader returns the header of AS answers
+func (c columns) asHeader() []cell {
+	cells := []cell{{"AS", 7}}
+	if c.country {
+		cells = append(cells, cell{"CC", 2})
+	}
+	if c.registry {
+		cells = append(cells, cell{"Registry", 8})
+	}
+	if c.allocated {
+		cells = append(cells, cell{"Allocated", 10})
+	}
+	return append(cells, cell{"AS Name", 0})
+}
+
+// limits bounds the connections and work of the server
+type limits struct {
+	maxConns    int           // Concurrent connections
+	maxPerIP    int           // Concurrent connections per client address
+	idleTimeout time.Duration // Time allowed for each line to arrive or be written
+	maxDuration time.Duration // Lifetime of a connection
+	maxQueries  int           // Queries per connection
+}
+
+// server answers whois queries
+type server struct {
+	resolver *cymru.Resolver
+	limits   limits
+
+	mu     sync.Mutex
+	active int
+	perIP  map[netip.Addr]int
+}
+
+func newServer(resolver *cymru.Resolver, l limits) *server {
+	return &server{resolver: resolver, limits: l, perIP: make(map[netip.Addr]int)}
+}
+
+// serve accepts connections on ln until it is closed
+func (s *server) serve(ctx context.Context, ln net.Listener) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				slog.Error("Accept failed", "err", err)
+			}
+			return
+		}
+		client := clientAddr(conn)
+		if !s.acquire(client) {
+			slog.Warn("Connection limit reached", "client", client)
+			go reject(conn)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Free the slot before the client sees the connection close
+			defer conn.Close()
+			defer s.release(client)
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+// acquire reserves a connection slot for client
+func (s *server) acquire(client netip.Addr) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active >= s.limits.maxConns || (s.limits.maxPerIP > 0 && s.perIP[client] >= s.limits.maxPerIP) {
+		return false
+	}
+	s.active++
+	s.perIP[client]++
+	return true
+}
+
+// release frees the slot reserved by acquire
+func (s *server) release(client netip.Addr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.perIP[client]--; s.perIP[client] <= 0 {
+		delete(s.perIP, client)
+	}
+}
+
+// reject tells the client of conn it is over the connection limit. The
+// request is drained first, as closing with it unread would reset the
+// connection and lose the message.
+func reject(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	io.WriteString(conn, "Error: too many connections\n")
+	if tc, ok := conn.(*net.TCPConn); ok {
+		tc.CloseWrite()
+	}
+	io.CopyN(io.Discard, conn, maxLineSize)
+}
+
+// clientAddr returns the address of the client of conn
+func clientAddr(conn net.Conn) netip.Addr {
+	if ap, err := netip.ParseAddrPort(conn.RemoteAddr().String()); err == nil {
+		return ap.Addr().Unmap()
+	}
+	return netip.Addr{}
+}
+
+// handle serves one connection: a single query line, or a bulk batch
+// between "begin" and "end"
+func (s *server) handle(ctx context.Context, conn net.Conn) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	deadline := time.Now().Add(s.limits.maxDuration)
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 1024), maxLineSize)
+	next := func() (string, bool) {
+		d := time.Now().Add(s.limits.idleTimeout)
+		if d.After(deadline) {
+			d = deadline
+		}
+		conn.SetDeadline(d)
+		if !scanner.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(scanner.Text()), true
+	}
+
+	w := bufio.NewWriter(conn)
+	defer w.Flush()
+
+	line, ok := next()
+	if !ok {
+		return
+	}
+	if strings.EqualFold(line, "begin") {
+		s.bulk(ctx, w, next)
+	} else {
+		s.single(ctx, w, line)
+	}
+	if err := scanner.Err(); err != nil {
+		slog.Debug("Read failed", "client", conn.RemoteAddr(), "err", err)
+	}
+}
+
+// session writes the answers of one connection
+type session struct {
+	s       *server
+	w       *bufio.Writer
+	cols    columns
+	header  bool // A header has been written
+	queries int
+}
+
+// single answers a one-line request: optional flags, then queries
+func (s *server) single(ctx context.Context, w *bufio.Writer, line string) {
+	ss := &session{s: s, w: w, cols: defaultColumns()}
+	for _, tok := range strings.Fields(line) {
+		if strings.HasPrefix(tok, "-") {
+			if err := ss.cols.flags(tok); err != nil {
+				fmt.Fprintf(w, "Error: %v\n", err)
+				return
+			}
+			continue
+		}
+		if !ss.query(ctx, tok, 1) {
+			return
+		}
+	}
+}
+
+// bulk answers the lines of a begin/end batch
+func (s *server) bulk(ctx context.Context, w *bufio.Writer, next func() (string, bool)) {
+	ss := &session{s: s, w: w, cols: defaultColumns()}
+	fmt.Fprintf(w, "Bulk mode; iporg-whois [%s]\n", time.Now().UTC().Format("2006-01-02 15:04:05 -0700"))
+	for lineNum := 2; ; lineNum++ {
+		line, ok := next()
+		if !ok {
+			return
+		}
+		word := strings.ToLower(line)
+		switch {
+		case word == "end":
+			return
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case ss.cols.option(word):
+			continue
+		}
+		// Anything after the query (such as a comment) is ignored
+		tok, _, _ := strings.Cut(line, " ")
+		if !ss.query(ctx, tok, lineNum) {
+			return
+		}
+	}
+}
+
+// query answers one IP or AS query, returning false once the connection's
+// query limit is reached
+func (ss *session) query(ctx context.Context, q string, lineNum int) bool {
+	ss.queries++
+	if ss.s.limits.maxQueries > 0 && ss.queries > ss.s.limits.maxQueries {
+		fmt.Fprintf(ss.w, "Error: query limit of %d reached\n", ss.s.limits.maxQueries)
+		return false
+	}
+
+	if asn, ok := cymru.ParseASN(q); ok && strings.HasPrefix(strings.ToUpper(q), "AS") {
+		as, err := ss.s.resolver.AS(ctx, asn)
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			slog.Warn("AS lookup failed", "asn", asn, "err", err)
+		}
+		ss.writeHeader(ss.cols.asHeader())
+		ss.writeRow(ss.cols.asCells(asn, as))
+		return true
+	}
+
+	addr, err := netip.ParseAddr(q)
+	if err != nil {
+		fmt.Fprintf(ss.w, "Error: no ASN or IP match on line %d.\n", lineNum)
+		return true
+	}
+	o, err := ss.s.resolver.Origin(addr)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		slog.Warn("Lookup failed", "ip", q, "err", err)
+	}
+	ss.writeHeader(ss.cols.originHeader())
+	ss.writeRow(ss.cols.originCells(addr.String(), o))
+	return true
+}
+
+// writeHeader writes the header before the first answer, if enabled
+func (ss *session) writeHeader(cells []cell) {
+	if ss.header || !ss.cols.header {
+		return
+	}
+	ss.header = true
+	ss.writeRow(cells)
+}
+
+func (ss *session) writeRow(cells []cell) {
+	ss.w.WriteString(strings.TrimRight(row(cells), " "))
+	ss.w.WriteByte('\n')
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/cymru"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// startServer serves a test database on a loopback listener
+func startServer(t *testing.T, l limits) string {
+	t.Helper()
+	db, err := iporgdb.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.PutRange(&model.Record{
+		Start: netip.MustParseAddr("8.8.8.0"), End: netip.MustParseAddr("8.8.8.255"), ASN: 15169, ASNName: "GOOGLE",
+		OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.0/24", Schema: 1,
+	}); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newServer(cymru.NewResolver(db, nil), l).serve(ctx, ln)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		ln.Close()
+		<-done
+	})
+	return ln.Addr().String()
+}
+
+func testLimits() limits {
+	return limits{maxConns: 4, maxPerIP: 2, idleTimeout: 5 * time.Second, maxDuration: time.Minute, maxQueries: 3}
+}
+
+// ask sends req and returns the whole response
+func ask(t *testing.T, addr, req string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, req); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	resp, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	return string(resp)
+}
+
+func TestSingle(t *testing.T) {
+	addr := startServer(t, testLimits())
+
+	tests := []struct {
+		req  string
+		want string
+	}{
+		{"8.8.8.8\n", "AS      | IP               | AS Name\n15169   | 8.8.8.8          | GOOGLE\n"},
+		{" -v 8.8.8.8\r\n", "AS      | IP               | BGP Prefix          | CC | Registry | Allocated  | AS Name\n" +
+			"15169   | 8.8.8.8          | 8.8.8.0/24          | US | arin     |            | GOOGLE\n"},
+		{"-fp 8.8.8.8\n", "15169   | 8.8.8.8          | 8.8.8.0/24          | GOOGLE\n"},
+		{"-f 192.0.2.1\n", "NA      | 192.0.2.1        | NA\n"},
+		{"-f AS15169\n", "15169   | NA\n"}, // No iptoasn store
+		{"AS15169\n", "AS      | AS Name\n15169   | NA\n"},
+		{"-v AS15169\n", "AS      | CC | Registry | Allocated  | AS Name\n15169   |    |          |            | NA\n"},
+		{"example.com\n", "Error: no ASN or IP match on line 1.\n"},
+		{"-x 8.8.8.8\n", "Error: unknown option -x\n"},
+	}
+	for _, tt := range tests {
+		if got := ask(t, addr, tt.req); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.req, got, tt.want)
+		}
+	}
+}
+
+func TestBulk(t *testing.T) {
+	addr := startServer(t, testLimits())
+
+	got := ask(t, addr, "begin\nverbose\nnoallocdate\n8.8.8.8 comment\nbogus\n\n192.0.2.1\nend\n8.8.4.4\n")
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	want := []string{
+		"AS      | IP               | BGP Prefix          | CC | Registry | AS Name",
+		"15169   | 8.8.8.8          | 8.8.8.0/24          | US | arin     | GOOGLE",
+		"Error: no ASN or IP match on line 5.",
+		"NA      | 192.0.2.1        | NA                  |    |          | NA",
+	}
+	if len(lines) != len(want)+1 || !strings.HasPrefix(lines[0], "Bulk mode; iporg-whois [") {
+		t.Fatalf("got %q", got)
+	}
+	for i, w := range want {
+		if lines[i+1] != w {
+			t.Errorf("line %d: got %q, want %q", i+2, lines[i+1], w)
+		}
+	}
+
+	// The query limit ends the batch
+	got = ask(t, addr, "begin\nnoheader\n8.8.8.8\n8.8.8.8\n8.8.8.8\n8.8.8.8\nend\n")
+	if !strings.HasSuffix(got, "GOOGLE\nError: query limit of 3 reached\n") || strings.Count(got, "GOOGLE") != 3 {
+		t.Errorf("query limit: got %q", got)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	l := testLimits()
+	l.idleTimeout = 200 * time.Millisecond
+	addr := startServer(t, l)
+
+	// Idle connections hold their slots until they time out
+	var conns []net.Conn
+	for range l.maxPerIP {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("Failed to dial: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	// Wait for the server to accept them
+	time.Sleep(50 * time.Millisecond)
+	if got, want := ask(t, addr, "8.8.8.8\n"), "Error: too many connections\n"; got != want {
+		t.Errorf("over the limit: got %q, want %q", got, want)
+	}
+
+	for _, conn := range conns {
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if resp, _ := io.ReadAll(conn); len(resp) != 0 {
+			t.Errorf("idle connection: got %q, want nothing", resp)
+		}
+	}
+	if got := ask(t, addr, "-f 8.8.8.8\n"); got != "15169   | 8.8.8.8          | GOOGLE\n" {
+		t.Errorf("after timeout: got %q", got)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
@@ -39,7 +39,7 @@ func (b *Builder) Build(ctx context.Context) error {
 	}
 	defer reader.Close()
 
-	log.Printf("Parsing TSV from %s...", meta.CachePath)
+	slog.Info("Parsing TSV", "path", meta.CachePath)
 
 	// 2. Parse TSV
 	parser := iptoasn.NewParser(reader)
@@ -48,10 +48,10 @@ func (b *Builder) Build(ctx context.Context) error {
 		return fmt.Errorf("failed to parse TSV: %w", err)
 	}
 
-	log.Printf("Parsed %d rows", len(rows))
+	slog.Info("Parsed rows", "rows", len(rows))
 
 	// 3. Convert to canonical prefixes
-	log.Printf("Converting to canonical prefixes...")
+	slog.Info("Converting to canonical prefixes")
 	var prefixes []*model.CanonicalPrefix
 	for _, row := range rows {
 		// Skip if no CIDR
@@ -69,33 +69,33 @@ func (b *Builder) Build(ctx context.Context) error {
 		prefixes = append(prefixes, cp)
 	}
 
-	log.Printf("Generated %d canonical prefixes", len(prefixes))
+	slog.Info("Generated canonical prefixes", "prefixes", len(prefixes))
 
 	// 4. Deduplicate
-	log.Printf("Deduplicating...")
+	slog.Info("Deduplicating")
 	aggregator := iptoasn.NewAggregator()
 	prefixes = aggregator.Deduplicate(prefixes)
-	log.Printf("After deduplication: %d prefixes", len(prefixes))
+	slog.Info("Deduplicated prefixes", "prefixes", len(prefixes))
 
 	// 5. Sort by start IP
-	log.Printf("Sorting by start IP...")
+	slog.Info("Sorting by start IP")
 	aggregator.SortByStartIP(prefixes)
 
 	// 6. Collapse per ASN (if enabled)
 	var collapsedByASN map[int][]*model.CanonicalPrefix
 	if b.cfg.collapse {
-		log.Printf("Collapsing adjacent prefixes per ASN...")
+		slog.Info("Collapsing adjacent prefixes per ASN")
 		collapsedByASN = aggregator.CollapseByASN(prefixes)
 
 		totalCollapsed := 0
 		for _, collapsed := range collapsedByASN {
 			totalCollapsed += len(collapsed)
 		}
-		log.Printf("After collapse: %d prefixes (saved %d)", totalCollapsed, len(prefixes)-totalCollapsed)
+		slog.Info("Collapsed prefixes", "prefixes", totalCollapsed, "saved", len(prefixes)-totalCollapsed)
 	}
 
 	// 7. Open/create database
-	log.Printf("Opening database at %s...", b.cfg.dbPath)
+	slog.Info("Opening database", "path", b.cfg.dbPath)
 	store, err := iptoasn.Open(b.cfg.dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
@@ -103,31 +103,31 @@ func (b *Builder) Build(ctx context.Context) error {
 	defer store.Close()
 
 	// 8. Write to database
-	log.Printf("Writing to database...")
+	slog.Info("Writing to database")
 	if err := store.WriteBatch(prefixes, collapsedByASN); err != nil {
 		return fmt.Errorf("failed to write batch: %w", err)
 	}
 
 	// 9. Calculate statistics
-	log.Printf("Calculating statistics...")
+	slog.Info("Calculating statistics")
 	stats := b.calculateStats(prefixes, collapsedByASN, meta)
 
 	// 10. Write metadata and stats
 	if err := store.SetMetadata("source_url", b.cfg.sourceURL); err != nil {
-		log.Printf("Warning: failed to set source_url metadata: %v", err)
+		slog.Warn("Failed to set source_url metadata", "err", err)
 	}
 	if err := store.SetMetadata("built_at", time.Now().Format(time.RFC3339)); err != nil {
-		log.Printf("Warning: failed to set built_at metadata: %v", err)
+		slog.Warn("Failed to set built_at metadata", "err", err)
 	}
 	if err := store.SetMetadata("version", version); err != nil {
-		log.Printf("Warning: failed to set version metadata: %v", err)
+		slog.Warn("Failed to set version metadata", "err", err)
 	}
 	if err := store.SetStats(stats); err != nil {
-		log.Printf("Warning: failed to set stats: %v", err)
+		slog.Warn("Failed to set stats", "err", err)
 	}
 
 	duration := time.Since(startTime)
-	log.Printf("Build completed in %s", duration)
+	slog.Info("Build completed", "duration", duration)
 
 	// Print summary
 	fmt.Printf("\nBuild Summary:\n")
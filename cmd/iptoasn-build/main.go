@@ -7,12 +7,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	"github.com/wingedpig/iporg/pkg/iptoasn"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/httprecord"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "0.1.0"
@@ -57,6 +59,10 @@ Global options:
   --skip-download       Skip download, use cached file
   --collapse            Collapse adjacent prefixes per ASN (default: true)
   --workers=<n>         Concurrent workers (default: 4)
+  --http-record=<dir>   Record the download to a directory
+  --http-replay=<dir>   Replay the download from a directory (offline)
+  --log-level=<level>   Log level: debug, info, warn or error (default: info)
+  --log-format=<fmt>    Log format: text or json (default: text)
   --version             Show version
 
 Examples:
@@ -81,6 +87,8 @@ type Config struct {
 	skipDownload bool
 	collapse     bool
 	workers      int
+	httpRecord   string
+	httpReplay   string
 	showVersion  bool
 }
 
@@ -94,7 +102,11 @@ func parseFlags(args []string) *Config {
 	fs.BoolVar(&cfg.skipDownload, "skip-download", false, "Skip download, use cached file")
 	fs.BoolVar(&cfg.collapse, "collapse", true, "Collapse adjacent prefixes per ASN")
 	fs.IntVar(&cfg.workers, "workers", 4, "Concurrent workers")
+	fs.StringVar(&cfg.httpRecord, "http-record", "", "Record the download to this directory")
+	fs.StringVar(&cfg.httpReplay, "http-replay", "", "Replay the download from this directory (no network access)")
 	fs.BoolVar(&cfg.showVersion, "version", false, "Show version")
+	var logFlags logging.Flags
+	logFlags.Register(fs)
 
 	fs.Parse(args)
 
@@ -103,25 +115,38 @@ func parseFlags(args []string) *Config {
 		os.Exit(0)
 	}
 
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	return cfg
 }
 
+// newFetcher creates a fetcher, recording or replaying HTTP traffic if requested
+func newFetcher(cfg *Config) *iptoasn.Fetcher {
+	fetcher := iptoasn.NewFetcher(cfg.sourceURL, cfg.cacheDir)
+	transport, err := httprecord.NewTransport(cfg.httpRecord, cfg.httpReplay, nil)
+	if err != nil {
+		logging.Fatal("Invalid HTTP archive options", "err", err)
+	}
+	fetcher.SetTransport(transport)
+	return fetcher
+}
+
 func runFetch() {
 	cfg := parseFlags(os.Args[2:])
 
-	fetcher := iptoasn.NewFetcher(cfg.sourceURL, cfg.cacheDir)
+	fetcher := newFetcher(cfg)
 
-	log.Printf("Fetching from %s...", cfg.sourceURL)
+	slog.Info("Fetching", "url", cfg.sourceURL)
 	meta, err := fetcher.Fetch(context.Background())
 	if err != nil {
-		log.Fatalf("Fetch failed: %v", err)
+		logging.Fatal("Fetch failed", "err", err)
 	}
 
-	log.Printf("Fetch complete:")
-	log.Printf("  Cache path: %s", meta.CachePath)
-	log.Printf("  ETag: %s", meta.ETag)
-	log.Printf("  Last-Modified: %s", meta.LastModified)
-	log.Printf("  Fetched at: %s", meta.FetchedAt)
+	slog.Info("Fetch complete", "path", meta.CachePath, "etag", meta.ETag,
+		"last_modified", meta.LastModified, "fetched_at", meta.FetchedAt)
 }
 
 func runBuild() {
@@ -129,12 +154,12 @@ func runBuild() {
 
 	builder := NewBuilder(cfg)
 
-	log.Printf("Building database at %s...", cfg.dbPath)
+	slog.Info("Building database", "path", cfg.dbPath)
 	if err := builder.Build(context.Background()); err != nil {
-		log.Fatalf("Build failed: %v", err)
+		logging.Fatal("Build failed", "err", err)
 	}
 
-	log.Printf("Build complete!")
+	slog.Info("Build complete")
 }
 
 func runAll() {
@@ -142,23 +167,23 @@ func runAll() {
 
 	// Fetch
 	if !cfg.skipDownload {
-		fetcher := iptoasn.NewFetcher(cfg.sourceURL, cfg.cacheDir)
-		log.Printf("Fetching from %s...", cfg.sourceURL)
+		fetcher := newFetcher(cfg)
+		slog.Info("Fetching", "url", cfg.sourceURL)
 		meta, err := fetcher.Fetch(context.Background())
 		if err != nil {
-			log.Fatalf("Fetch failed: %v", err)
+			logging.Fatal("Fetch failed", "err", err)
 		}
-		log.Printf("Downloaded to %s", meta.CachePath)
+		slog.Info("Downloaded", "path", meta.CachePath)
 	}
 
 	// Build
 	builder := NewBuilder(cfg)
-	log.Printf("Building database at %s...", cfg.dbPath)
+	slog.Info("Building database", "path", cfg.dbPath)
 	if err := builder.Build(context.Background()); err != nil {
-		log.Fatalf("Build failed: %v", err)
+		logging.Fatal("Build failed", "err", err)
 	}
 
-	log.Printf("Build complete!")
+	slog.Info("Build complete")
 }
 
 func runStats() {
@@ -166,17 +191,17 @@ func runStats() {
 
 	store, err := iptoasn.Open(cfg.dbPath)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer store.Close()
 
 	stats, err := store.GetStats()
 	if err == model.ErrNotFound {
-		log.Printf("No statistics found in database")
+		slog.Info("No statistics found in database")
 		return
 	}
 	if err != nil {
-		log.Fatalf("Failed to get stats: %v", err)
+		logging.Fatal("Failed to get stats", "err", err)
 	}
 
 	fmt.Printf("IPToASN Database Statistics\n")
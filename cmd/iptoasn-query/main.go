@@ -8,12 +8,12 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
 
 	"github.com/wingedpig/iporg/pkg/iptoasn"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "0.1.0"
@@ -55,6 +55,8 @@ Options:
   --json              Output as JSON (default: true)
   --limit=<n>         Limit number of results
   --offset-key=<key>  Resume walk from this key (for walk command)
+  --log-level=<level> Log level: debug, info, warn or error (default: info)
+  --log-format=<fmt>  Log format: text or json (default: text)
   --version           Show version
 
 Examples:
@@ -89,8 +91,14 @@ func parseFlags(args []string) *Config {
 	fs.BoolVar(&cfg.json, "json", true, "Output as JSON")
 	fs.IntVar(&cfg.limit, "limit", 0, "Limit number of results (0 = no limit)")
 	fs.StringVar(&cfg.offsetKey, "offset-key", "", "Resume walk from this key")
+	var logFlags logging.Flags
+	logFlags.Register(fs)
 
 	fs.Parse(args)
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
 	return cfg
 }
 
@@ -104,7 +112,7 @@ func runASN() {
 	asnStr := os.Args[2]
 	asn, err := strconv.Atoi(asnStr)
 	if err != nil {
-		log.Fatalf("Invalid ASN number: %s", asnStr)
+		logging.Fatal("Invalid ASN number", "asn", asnStr)
 	}
 
 	cfg := parseFlags(os.Args[3:])
@@ -112,7 +120,7 @@ func runASN() {
 	// Open database
 	store, err := iptoasn.Open(cfg.dbPath)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer store.Close()
 
@@ -123,13 +131,13 @@ func runASN() {
 		os.Exit(1)
 	}
 	if err != nil {
-		log.Fatalf("Failed to get ASN index: %v", err)
+		logging.Fatal("Failed to get ASN index", "err", err)
 	}
 
 	// List prefixes
 	prefixes, err := store.ListByASN(context.Background(), asn, cfg.collapsed)
 	if err != nil {
-		log.Fatalf("Failed to list prefixes: %v", err)
+		logging.Fatal("Failed to list prefixes", "err", err)
 	}
 
 	// Apply limit if specified
@@ -149,7 +157,7 @@ func runASN() {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		if err := enc.Encode(output); err != nil {
-			log.Fatalf("Failed to encode JSON: %v", err)
+			logging.Fatal("Failed to encode JSON", "err", err)
 		}
 	} else {
 		fmt.Printf("AS%d (%d prefixes total, showing %d%s):\n",
@@ -167,7 +175,7 @@ func runWalk() {
 	// Open database
 	store, err := iptoasn.Open(cfg.dbPath)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer store.Close()
 
@@ -191,7 +199,7 @@ func runWalk() {
 	})
 
 	if err != nil {
-		log.Fatalf("Walk failed: %v", err)
+		logging.Fatal("Walk failed", "err", err)
 	}
 
 	// Output
@@ -203,7 +211,7 @@ func runWalk() {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		if err := enc.Encode(output); err != nil {
-			log.Fatalf("Failed to encode JSON: %v", err)
+			logging.Fatal("Failed to encode JSON", "err", err)
 		}
 	} else {
 		fmt.Printf("Walked %d prefixes:\n", count)
@@ -219,14 +227,14 @@ func runListASNs() {
 	// Open database
 	store, err := iptoasn.Open(cfg.dbPath)
 	if err != nil {
-		log.Fatalf("Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer store.Close()
 
 	// List ASNs
 	asns, err := store.ListASNs(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to list ASNs: %v", err)
+		logging.Fatal("Failed to list ASNs", "err", err)
 	}
 
 	// Apply limit if specified
@@ -243,7 +251,7 @@ func runListASNs() {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		if err := enc.Encode(output); err != nil {
-			log.Fatalf("Failed to encode JSON: %v", err)
+			logging.Fatal("Failed to encode JSON", "err", err)
 		}
 	} else {
 		fmt.Printf("Found %d ASNs:\n", len(asns))
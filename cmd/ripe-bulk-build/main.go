@@ -7,13 +7,15 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/util/httprecord"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "0.1.0"
@@ -24,8 +26,12 @@ func main() {
 		cacheDir    = flag.String("cache", "cache/ripe", "Path to cache directory for RIPE dumps")
 		baseURL     = flag.String("url", ripebulk.DefaultBaseURL, "RIPE FTP base URL")
 		skipFetch   = flag.Bool("skip-fetch", false, "Skip fetching, use cached files only")
+		httpRecord  = flag.String("http-record", "", "Record the dump downloads to this directory")
+		httpReplay  = flag.String("http-replay", "", "Replay the dump downloads from this directory (no network access)")
 		showVersion = flag.Bool("version", false, "Show version and exit")
+		logFlags    logging.Flags
 	)
+	logFlags.Register(flag.CommandLine)
 
 	flag.Parse()
 
@@ -34,8 +40,12 @@ func main() {
 		os.Exit(0)
 	}
 
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	log.Printf("INFO: RIPE Bulk Database Builder v%s", version)
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	slog.Info("RIPE Bulk Database Builder", "version", version)
 
 	startTime := time.Now()
 
@@ -44,107 +54,111 @@ func main() {
 
 	// Fetch RIPE dumps
 	var inetnumPath, orgPath string
-	var err error
 
 	if *skipFetch {
-		log.Printf("INFO: Skipping fetch, using cached files")
+		slog.Info("Skipping fetch, using cached files")
 		inetnumPath = filepath.Join(*cacheDir, ripebulk.InetnumFile)
 		orgPath = filepath.Join(*cacheDir, ripebulk.OrganisationFile)
 
 		// Verify cache files exist
 		if _, err := os.Stat(inetnumPath); err != nil {
-			log.Fatalf("ERROR: Cached inetnum file not found: %s", inetnumPath)
+			logging.Fatal("Cached inetnum file not found", "path", inetnumPath)
 		}
 		if _, err := os.Stat(orgPath); err != nil {
-			log.Fatalf("ERROR: Cached organisation file not found: %s", orgPath)
+			logging.Fatal("Cached organisation file not found", "path", orgPath)
 		}
 	} else {
 		fetcher := ripebulk.NewFetcher(*baseURL, *cacheDir)
+		transport, err := httprecord.NewTransport(*httpRecord, *httpReplay, nil)
+		if err != nil {
+			logging.Fatal("Invalid HTTP archive options", "err", err)
+		}
+		fetcher.SetTransport(transport)
 		inetnumPath, orgPath, err = fetcher.FetchAll(ctx)
 		if err != nil {
-			log.Fatalf("ERROR: Failed to fetch RIPE dumps: %v", err)
+			logging.Fatal("Failed to fetch RIPE dumps", "err", err)
 		}
 	}
 
 	// Parse organisations first
-	log.Printf("INFO: Parsing organisations from %s", filepath.Base(orgPath))
+	slog.Info("Parsing organisations", "file", filepath.Base(orgPath))
 	orgFile, err := ripebulk.OpenGzipFile(orgPath)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open organisation file: %v", err)
+		logging.Fatal("Failed to open organisation file", "err", err)
 	}
 
 	orgs, err := ripebulk.ParseOrganisations(orgFile)
 	orgFile.Close()
 	if err != nil {
-		log.Fatalf("ERROR: Failed to parse organisations: %v", err)
+		logging.Fatal("Failed to parse organisations", "err", err)
 	}
-	log.Printf("INFO: Parsed %d organisations", len(orgs))
+	slog.Info("Parsed organisations", "orgs", len(orgs))
 
 	// Parse inetnums
-	log.Printf("INFO: Parsing inetnums from %s", filepath.Base(inetnumPath))
+	slog.Info("Parsing inetnums", "file", filepath.Base(inetnumPath))
 	inetnumFile, err := ripebulk.OpenGzipFile(inetnumPath)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open inetnum file: %v", err)
+		logging.Fatal("Failed to open inetnum file", "err", err)
 	}
 
 	inetnums, err := ripebulk.ParseInetnums(inetnumFile)
 	inetnumFile.Close()
 	if err != nil {
-		log.Fatalf("ERROR: Failed to parse inetnums: %v", err)
+		logging.Fatal("Failed to parse inetnums", "err", err)
 	}
-	log.Printf("INFO: Parsed %d inetnums", len(inetnums))
+	slog.Info("Parsed inetnums", "inetnums", len(inetnums))
 
 	// Remove existing database if present
 	if _, err := os.Stat(*dbPath); err == nil {
-		log.Printf("INFO: Removing existing database at %s", *dbPath)
+		slog.Info("Removing existing database", "path", *dbPath)
 		if err := os.RemoveAll(*dbPath); err != nil {
-			log.Fatalf("ERROR: Failed to remove existing database: %v", err)
+			logging.Fatal("Failed to remove existing database", "err", err)
 		}
 	}
 
 	// Create database directory
 	if err := os.MkdirAll(filepath.Dir(*dbPath), 0755); err != nil {
-		log.Fatalf("ERROR: Failed to create database directory: %v", err)
+		logging.Fatal("Failed to create database directory", "err", err)
 	}
 
 	// Build database
 	db, err := ripebulk.BuildDatabase(*dbPath, inetnums, orgs)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to build database: %v", err)
+		logging.Fatal("Failed to build database", "err", err)
 	}
 	defer db.Close()
 
 	// Verify database
-	log.Printf("INFO: Verifying database...")
+	slog.Info("Verifying database")
 	meta, err := db.GetMetadata()
 	if err != nil {
-		log.Fatalf("ERROR: Failed to read metadata: %v", err)
+		logging.Fatal("Failed to read metadata", "err", err)
 	}
 
-	log.Printf("INFO: ============================================")
-	log.Printf("INFO: Database build complete!")
-	log.Printf("INFO: Path:              %s", *dbPath)
-	log.Printf("INFO: Schema version:    %d", meta.SchemaVersion)
-	log.Printf("INFO: Build time:        %s", meta.BuildTime.Format(time.RFC3339))
-	log.Printf("INFO: Inetnum count:     %d", meta.InetnumCount)
-	log.Printf("INFO: Organisation count: %d", meta.OrgCount)
-	log.Printf("INFO: Source URL:        %s", meta.SourceURL)
-	log.Printf("INFO: Elapsed time:      %s", time.Since(startTime))
-	log.Printf("INFO: ============================================")
+	slog.Info("Database build complete",
+		"path", *dbPath,
+		"schema_version", meta.SchemaVersion,
+		"build_time", meta.BuildTime.Format(time.RFC3339),
+		"inetnums", meta.InetnumCount,
+		"orgs", meta.OrgCount,
+		"source_url", meta.SourceURL,
+		"duration", time.Since(startTime))
 
 	// Sanity check: lookup test
-	log.Printf("INFO: Running sanity check...")
+	slog.Info("Running sanity check")
 	testIP := "31.90.1.1" // Known EE/BT range in RIPE
 	match, err := db.LookupIP(mustParseIP(testIP))
 	if err != nil {
-		log.Printf("WARN: Sanity check lookup failed (may be expected): %v", err)
+		slog.Warn("Sanity check lookup failed (may be expected)", "err", err)
 	} else {
-		log.Printf("INFO: Sanity check successful:")
-		log.Printf("INFO:   IP: %s", testIP)
-		log.Printf("INFO:   Range: %s - %s", match.Start, match.End)
-		log.Printf("INFO:   Org: %s (%s)", match.OrgName, match.OrgID)
-		log.Printf("INFO:   Status: %s", match.Status)
-		log.Printf("INFO:   Country: %s", match.Country)
+		slog.Info("Sanity check successful",
+			"ip", testIP,
+			"start", match.Start,
+			"end", match.End,
+			"org", match.OrgName,
+			"org_id", match.OrgID,
+			"status", match.Status,
+			"country", match.Country)
 	}
 }
 
@@ -7,11 +7,11 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"net/netip"
 	"os"
 
 	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const version = "0.1.0"
@@ -21,7 +21,9 @@ func main() {
 		dbPath      = flag.String("db", "data/ripe-bulk.ldb", "Path to RIPE bulk LevelDB database")
 		jsonOutput  = flag.Bool("json", false, "Output in JSON format")
 		showVersion = flag.Bool("version", false, "Show version and exit")
+		logFlags    logging.Flags
 	)
+	logFlags.Register(flag.CommandLine)
 
 	flag.Parse()
 
@@ -36,12 +38,17 @@ func main() {
 		os.Exit(1)
 	}
 
+	if _, err := logFlags.Setup(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
 	query := flag.Arg(0)
 
 	// Open database
 	db, err := ripebulk.OpenDatabase(*dbPath)
 	if err != nil {
-		log.Fatalf("ERROR: Failed to open database: %v", err)
+		logging.Fatal("Failed to open database", "err", err)
 	}
 	defer db.Close()
 
@@ -52,18 +59,18 @@ func main() {
 	if prefix, err := netip.ParsePrefix(query); err == nil {
 		match, err = db.LookupPrefix(prefix)
 		if err != nil {
-			log.Fatalf("ERROR: Lookup failed: %v", err)
+			logging.Fatal("Lookup failed", "err", err)
 		}
 	} else {
 		// Try parsing as IP
 		ip, err := netip.ParseAddr(query)
 		if err != nil {
-			log.Fatalf("ERROR: Invalid IP or prefix: %s", query)
+			logging.Fatal("Invalid IP or prefix", "query", query)
 		}
 
 		match, err = db.LookupIP(ip)
 		if err != nil {
-			log.Fatalf("ERROR: Lookup failed: %v", err)
+			logging.Fatal("Lookup failed", "err", err)
 		}
 	}
 
@@ -100,7 +107,7 @@ func outputJSON(match *ripebulk.Match) {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
 	if err := enc.Encode(result); err != nil {
-		log.Fatalf("ERROR: Failed to encode JSON: %v", err)
+		logging.Fatal("Failed to encode JSON", "err", err)
 	}
 }
 
@@ -62,7 +62,7 @@ func main() {
 		idx := i
 		currentIP := ip
 
-		pool.Submit(idx, func(ctx context.Context) error {
+		err := pool.Submit(idx, func(ctx context.Context) error {
 			rec, err := db.LookupString(currentIP)
 			mu.Lock()
 			results[idx] = result{
@@ -74,9 +74,15 @@ func main() {
 			mu.Unlock()
 			return nil
 		})
+		if err != nil {
+			break
+		}
 	}
 
 	pool.Wait()
+	if err := ctx.Err(); err != nil {
+		log.Fatalf("Lookups stopped: %v", err)
+	}
 
 	// Output results in order (JSONL format)
 	for _, res := range results {
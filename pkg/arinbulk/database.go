@@ -8,7 +8,6 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
 	"net/netip"
 	"sort"
 	"strings"
@@ -18,6 +17,8 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const (
@@ -69,7 +70,7 @@ func (d *Database) Close() error {
 // BuildDatabaseStreaming creates a new ARIN bulk database with low memory usage
 // by writing organizations immediately and only keeping networks in memory for sorting
 func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
-	log.Printf("INFO: Building ARIN bulk database at %s", path)
+	logging.Library().Info("Building ARIN bulk database", "path", path)
 
 	// Open database
 	db, err := leveldb.OpenFile(path, &opt.Options{
@@ -95,7 +96,7 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 	orgBatch := new(leveldb.Batch)
 	orgBatchSize := 0
 
-	log.Printf("INFO: Parsing XML and writing organizations...")
+	logging.Library().Info("Parsing XML and writing organizations")
 
 	for {
 		token, err := decoder.Token()
@@ -163,7 +164,7 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 					orgBatchSize = 0
 
 					if orgCount%100000 == 0 {
-						log.Printf("INFO: Wrote %d organizations...", orgCount)
+						logging.Library().Info("Writing organizations", "orgs", orgCount)
 					}
 				}
 			}
@@ -177,10 +178,10 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 		}
 	}
 
-	log.Printf("INFO: Parsed %d networks, wrote %d organizations", len(nets), orgCount)
+	logging.Library().Info("Parsed networks and wrote organizations", "networks", len(nets), "orgs", orgCount)
 
 	// Sort networks: Start ascending, End descending (parents before children)
-	log.Printf("INFO: Sorting networks...")
+	logging.Library().Info("Sorting networks")
 	sort.Slice(nets, func(i, j int) bool {
 		if nets[i].Start != nets[j].Start {
 			return nets[i].Start < nets[j].Start
@@ -189,7 +190,7 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 	})
 
 	// Write networks to database
-	log.Printf("INFO: Writing networks to database...")
+	logging.Library().Info("Writing networks to database")
 	netBatch := new(leveldb.Batch)
 	batchSize := 0
 
@@ -224,7 +225,7 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 	}
 
 	// Write metadata
-	log.Printf("INFO: Writing metadata...")
+	logging.Library().Info("Writing metadata")
 	metadata := Metadata{
 		SchemaVersion: currentSchemaVersion,
 		BuildTime:     time.Now(),
@@ -239,7 +240,7 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 		return nil, fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	log.Printf("INFO: Database build complete - %d networks, %d orgs indexed", len(nets), orgCount)
+	logging.Library().Info("Database build complete", "networks", len(nets), "orgs", orgCount)
 
 	return &Database{
 		db:   db,
@@ -249,8 +250,7 @@ func BuildDatabaseStreaming(path string, r io.Reader) (*Database, error) {
 
 // BuildDatabase creates a new ARIN bulk database from parsed data
 func BuildDatabase(path string, nets []NetBlock, orgs map[string]Organization) (*Database, error) {
-	log.Printf("INFO: Building ARIN bulk database at %s", path)
-	log.Printf("INFO: Indexing %d networks, %d organizations", len(nets), len(orgs))
+	logging.Library().Info("Building ARIN bulk database", "path", path, "networks", len(nets), "orgs", len(orgs))
 
 	// Open database
 	db, err := leveldb.OpenFile(path, &opt.Options{
@@ -269,7 +269,7 @@ func BuildDatabase(path string, nets []NetBlock, orgs map[string]Organization) (
 	}()
 
 	// Sort networks: Start ascending, End descending (parents before children)
-	log.Printf("INFO: Sorting networks...")
+	logging.Library().Info("Sorting networks")
 	sort.Slice(nets, func(i, j int) bool {
 		if nets[i].Start != nets[j].Start {
 			return nets[i].Start < nets[j].Start
@@ -279,7 +279,7 @@ func BuildDatabase(path string, nets []NetBlock, orgs map[string]Organization) (
 	})
 
 	// Write networks to database
-	log.Printf("INFO: Writing networks to database...")
+	logging.Library().Info("Writing networks to database")
 	batch := new(leveldb.Batch)
 	batchSize := 0
 
@@ -317,7 +317,7 @@ func BuildDatabase(path string, nets []NetBlock, orgs map[string]Organization) (
 	}
 
 	// Write organizations
-	log.Printf("INFO: Writing organizations...")
+	logging.Library().Info("Writing organizations")
 	batch.Reset()
 	for _, org := range orgs {
 		key := []byte(prefixOrg + org.OrgID)
@@ -332,7 +332,7 @@ func BuildDatabase(path string, nets []NetBlock, orgs map[string]Organization) (
 	}
 
 	// Write metadata
-	log.Printf("INFO: Writing metadata...")
+	logging.Library().Info("Writing metadata")
 	metadata := Metadata{
 		SchemaVersion: currentSchemaVersion,
 		BuildTime:     time.Now(),
@@ -347,7 +347,7 @@ func BuildDatabase(path string, nets []NetBlock, orgs map[string]Organization) (
 		return nil, fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	log.Printf("INFO: Database build complete - %d networks, %d orgs indexed", len(nets), len(orgs))
+	logging.Library().Info("Database build complete", "networks", len(nets), "orgs", len(orgs))
 
 	return &Database{
 		db:   db,
@@ -538,6 +538,77 @@ func (d *Database) LookupPrefix(prefix netip.Prefix) (*Match, error) {
 	return d.buildMatch(mostSpecific, prefix)
 }
 
+// Overlapping returns every network that overlaps prefix: the networks that
+// cover its first address and all networks starting inside it. Callers
+// resolve them with MatchFor.
+func (d *Database) Overlapping(prefix netip.Prefix) ([]NetBlock, error) {
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("IPv6 not supported yet")
+	}
+	prefix = prefix.Masked()
+	queryStart := AddrToUint32(prefix.Addr())
+	queryEnd := queryStart + (1 << (32 - prefix.Bits())) - 1
+
+	rangeKey := func(start uint32) []byte {
+		key := make([]byte, 3+4+4)
+		copy(key[0:3], []byte(prefixRange))
+		binary.BigEndian.PutUint32(key[3:7], start)
+		return key
+	}
+
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(prefixRange)), nil)
+	defer iter.Release()
+
+	// Position on the first network starting after queryStart
+	positioned := false
+	if queryStart < 0xFFFFFFFF {
+		positioned = iter.Seek(rangeKey(queryStart + 1))
+	}
+
+	var nets []NetBlock
+
+	// Scan backward for networks covering queryStart (nothing spans > /8)
+	var ok bool
+	if positioned {
+		ok = iter.Prev()
+	} else {
+		ok = iter.Last()
+	}
+	for ; ok; ok = iter.Prev() {
+		var net NetBlock
+		if err := msgpack.Unmarshal(iter.Value(), &net); err != nil {
+			continue
+		}
+		if queryStart-net.Start > 0x01000000 {
+			break
+		}
+		if net.End >= queryStart {
+			nets = append(nets, net)
+		}
+	}
+
+	// Scan forward over networks starting inside the prefix
+	if positioned {
+		for ok = iter.Seek(rangeKey(queryStart + 1)); ok; ok = iter.Next() {
+			var net NetBlock
+			if err := msgpack.Unmarshal(iter.Value(), &net); err != nil {
+				continue
+			}
+			if net.Start > queryEnd {
+				break
+			}
+			nets = append(nets, net)
+		}
+	}
+
+	return nets, iter.Error()
+}
+
+// MatchFor resolves a network (e.g. from IterateRanges) into a Match
+func (d *Database) MatchFor(net NetBlock) (*Match, error) {
+	return d.buildMatch(net, netip.Prefix{})
+}
+
 func (d *Database) buildMatch(net NetBlock, queryPrefix netip.Prefix) (*Match, error) {
 	// Resolve organization
 	orgName := "(no org)"
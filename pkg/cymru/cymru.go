@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package cymru answers IP-to-ASN queries from local databases in the
+// pipe-delimited formats of the Team Cymru IP to ASN mapping service.
+package cymru
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/enrich"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+)
+
+// registries maps RIR names, as stored by iporg and iptoasn, to the names
+// used by Team Cymru
+var registries = map[string]string{
+	"ripe":    "ripencc",
+	"ripencc": "ripencc",
+	"arin":    "arin",
+	"apnic":   "apnic",
+	"lacnic":  "lacnic",
+	"afrinic": "afrinic",
+}
+
+// Origin is the answer to an IP origin query. ASN is 0 for registered
+// space that is not announced.
+type Origin struct {
+	ASN       int
+	Prefix    string
+	Country   string
+	Registry  string
+	Allocated string // Allocation date; not known to local databases
+	ASName    string
+	OrgName   string // Registered organisation (iporg only)
+}
+
+// String formats o as "ASN | BGP Prefix | CC | Registry | Allocated"
+func (o *Origin) String() string {
+	return join(ASNString(o.ASN), o.Prefix, o.Country, o.Registry, o.Allocated)
+}
+
+// Org formats o as "ASN | BGP Prefix | CC | Registry | Org Name", the
+// origin format with the registered organisation in place of the date
+func (o *Origin) Org() string {
+	return join(ASNString(o.ASN), o.Prefix, o.Country, o.Registry, o.OrgName)
+}
+
+// Verbose formats o as "AS | IP | BGP Prefix | CC | Registry | Allocated | AS Name",
+// the verbose whois format
+func (o *Origin) Verbose(ip string) string {
+	return join(ASNString(o.ASN), ip, o.Prefix, o.Country, o.Registry, o.Allocated, o.ASName)
+}
+
+// AS is the answer to an ASN query
+type AS struct {
+	ASN       int
+	Country   string
+	Registry  string
+	Allocated string
+	Name      string
+}
+
+// String formats a as "ASN | CC | Registry | Allocated | AS Name"
+func (a *AS) String() string {
+	return join(strconv.Itoa(a.ASN), a.Country, a.Registry, a.Allocated, a.Name)
+}
+
+// ASNString formats an ASN, or "NA" for none
+func ASNString(asn int) string {
+	if asn == 0 {
+		return "NA"
+	}
+	return strconv.Itoa(asn)
+}
+
+// join joins fields with " | "
+func join(fields ...string) string {
+	return strings.Join(fields, " | ")
+}
+
+// Resolver answers queries from an iporg database and, optionally, an
+// iptoasn store. Either may be nil.
+type Resolver struct {
+	db    *iporgdb.DB
+	store *iptoasn.Store
+	ripe  *ripebulk.Database
+	arin  *arinbulk.Database
+}
+
+// NewResolver creates a resolver over db and store
+func NewResolver(db *iporgdb.DB, store *iptoasn.Store) *Resolver {
+	return &Resolver{db: db, store: store}
+}
+
+// SetRegistries adds RIPE and ARIN bulk databases (either may be nil). They
+// supply the registry and country where the other sources have none, and
+// answer for registered space that is not announced.
+func (r *Resolver) SetRegistries(ripe *ripebulk.Database, arin *arinbulk.Database) {
+	r.ripe = ripe
+	r.arin = arin
+}
+
+// Origin returns the origin of addr, or model.ErrNotFound. The iporg
+// database is preferred; iptoasn answers for addresses it does not hold.
+func (r *Resolver) Origin(addr netip.Addr) (*Origin, error) {
+	addr = addr.Unmap()
+	o, err := r.origin(addr)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		return nil, err
+	}
+	if o == nil || o.Registry == "" || o.Country == "" {
+		o = r.registration(addr, o)
+	}
+	if o == nil {
+		return nil, model.ErrNotFound
+	}
+	return o, nil
+}
+
+// origin looks addr up in the iporg database, then iptoasn
+func (r *Resolver) origin(addr netip.Addr) (*Origin, error) {
+	if r.db != nil {
+		rec, err := r.db.GetByIP(addr)
+		if err == nil && rec.ASN != 0 {
+			reg, _ := registry(rec.RIR)
+			return &Origin{
+				ASN:      rec.ASN,
+				Prefix:   rec.Prefix,
+				Country:  rec.Country,
+				Registry: reg,
+				ASName:   rec.ASNName,
+				OrgName:  rec.OrgName,
+			}, nil
+		}
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	if r.store != nil {
+		p, err := r.store.LookupIP(addr)
+		if err != nil {
+			return nil, err
+		}
+		reg, name := iptoasnNames(p)
+		return &Origin{
+			ASN:      p.ASN,
+			Prefix:   p.CIDR,
+			Country:  p.Country,
+			Registry: reg,
+			ASName:   name,
+			OrgName:  name,
+		}, nil
+	}
+	return nil, model.ErrNotFound
+}
+
+// registration fills the registry and country of o (nil if addr is not
+// announced) from the bulk databases
+func (r *Resolver) registration(addr netip.Addr, o *Origin) *Origin {
+	if !addr.Is4() {
+		return o
+	}
+	var reg, country, org string
+	if r.ripe != nil {
+		if m, err := r.ripe.LookupIP(addr); err == nil && !enrich.IsRIPEPlaceholder(m.OrgName) {
+			reg, country, org = "ripencc", m.Country, m.OrgName
+		}
+	}
+	if reg == "" && r.arin != nil {
+		if m, err := r.arin.LookupIP(addr); err == nil {
+			reg, country, org = "arin", m.Country, m.OrgName
+		}
+	}
+	if reg == "" {
+		return o
+	}
+
+	if o == nil {
+		return &Origin{Registry: reg, Country: country, OrgName: org}
+	}
+	if o.Registry == "" {
+		o.Registry = reg
+	}
+	if o.Country == "" {
+		o.Country = country
+	}
+	return o
+}
+
+// AS returns the details of an ASN, or model.ErrNotFound. Only iptoasn
+// indexes ASNs, so this needs a store.
+func (r *Resolver) AS(ctx context.Context, asn int) (*AS, error) {
+	if r.store == nil {
+		return nil, model.ErrNotFound
+	}
+	prefixes, err := r.store.ListByASN(ctx, asn, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prefixes of AS%d: %w", asn, err)
+	}
+	if len(prefixes) == 0 {
+		return nil, model.ErrNotFound
+	}
+	p := prefixes[0]
+	reg, name := iptoasnNames(p)
+	return &AS{ASN: asn, Country: p.Country, Registry: reg, Name: name}, nil
+}
+
+// registry returns the Cymru name of a RIR and whether it is known
+func registry(rir string) (string, bool) {
+	name, ok := registries[strings.ToLower(rir)]
+	return name, ok
+}
+
+// iptoasnNames returns the registry and AS name of an iptoasn prefix. The
+// public iptoasn files carry the AS description in the column read as the
+// registry, so it is only taken as a registry if it names a RIR.
+func iptoasnNames(p *model.CanonicalPrefix) (reg, name string) {
+	name = p.ASName
+	reg, ok := registry(p.Registry)
+	if !ok && name == "" {
+		name = p.Registry
+	}
+	return reg, name
+}
+
+// ParseASN parses "AS15169" or "15169"
+func ParseASN(s string) (int, bool) {
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "AS")
+	asn, err := strconv.Atoi(s)
+	if err != nil || asn <= 0 {
+		return 0, false
+	}
+	return asn, true
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package cymru
+
+import (
+	"context"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/testutil"
+)
+
+func newTestResolver(t *testing.T) *Resolver {
+	t.Helper()
+	dir := t.TempDir()
+
+	db, err := iporgdb.Open(filepath.Join(dir, "iporgdb"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.PutRange(&model.Record{
+		Start: netip.MustParseAddr("8.8.8.0"), End: netip.MustParseAddr("8.8.8.255"), ASN: 15169, ASNName: "GOOGLE",
+		OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.0/24", Schema: 1,
+	}); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+
+	storePath := filepath.Join(dir, "iptoasn")
+	if err := testutil.BuildIPtoASN(storePath, []testutil.IPtoASNRow{
+		{Start: "8.8.8.0", End: "8.8.8.255", ASN: 15169, Country: "US", Registry: "arin", Name: "GOOGLE"},
+		{Start: "1.1.1.0", End: "1.1.1.255", ASN: 13335, Country: "US", Registry: "CLOUDFLARENET"},
+	}); err != nil {
+		t.Fatalf("Failed to build iptoasn: %v", err)
+	}
+	store, err := iptoasn.Open(storePath)
+	if err != nil {
+		t.Fatalf("Failed to open iptoasn: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return NewResolver(db, store)
+}
+
+func TestOrigin(t *testing.T) {
+	r := newTestResolver(t)
+
+	o, err := r.Origin(netip.MustParseAddr("8.8.8.8"))
+	if err != nil {
+		t.Fatalf("Origin failed: %v", err)
+	}
+	if got, want := o.String(), "15169 | 8.8.8.0/24 | US | arin | "; got != want {
+		t.Errorf("origin: got %q, want %q", got, want)
+	}
+	if got, want := o.Org(), "15169 | 8.8.8.0/24 | US | arin | Google LLC"; got != want {
+		t.Errorf("org: got %q, want %q", got, want)
+	}
+	if got, want := o.Verbose("8.8.8.8"), "15169 | 8.8.8.8 | 8.8.8.0/24 | US | arin |  | GOOGLE"; got != want {
+		t.Errorf("verbose: got %q, want %q", got, want)
+	}
+
+	// Not in iporgdb: answered from iptoasn, whose registry column holds the AS name
+	o, err = r.Origin(netip.MustParseAddr("1.1.1.1"))
+	if err != nil {
+		t.Fatalf("Origin failed: %v", err)
+	}
+	if o.ASN != 13335 || o.Prefix != "1.1.1.0/24" || o.Registry != "" || o.ASName != "CLOUDFLARENET" {
+		t.Errorf("iptoasn origin: got %+v", o)
+	}
+
+	if _, err := r.Origin(netip.MustParseAddr("192.0.2.1")); err != model.ErrNotFound {
+		t.Errorf("unknown address: got %v, want %v", err, model.ErrNotFound)
+	}
+}
+
+func TestRegistries(t *testing.T) {
+	r := newTestResolver(t)
+	dir := t.TempDir()
+
+	ripePath := filepath.Join(dir, "ripebulk")
+	if err := testutil.WriteRIPEBulk(ripePath, []testutil.Registration{
+		{Range: "1.1.1.0/24", OrgID: "ORG-EX1-RIPE", OrgName: "Example", Status: "ASSIGNED PA", Country: "AU"},
+	}); err != nil {
+		t.Fatalf("Failed to build RIPE bulk: %v", err)
+	}
+	ripe, err := ripebulk.OpenDatabase(ripePath)
+	if err != nil {
+		t.Fatalf("Failed to open RIPE bulk: %v", err)
+	}
+	t.Cleanup(func() { ripe.Close() })
+
+	arinPath := filepath.Join(dir, "arinbulk")
+	if err := testutil.WriteARINBulk(arinPath, []testutil.Registration{
+		{Range: "192.0.2.0/24", OrgID: "EX-1", OrgName: "Example Inc", Status: "Direct Assignment"},
+		{Range: "203.0.113.0/24", OrgID: "EX-2", OrgName: "Example Two", Status: "Direct Assignment"},
+	}); err != nil {
+		t.Fatalf("Failed to build ARIN bulk: %v", err)
+	}
+	arin, err := arinbulk.OpenDatabase(arinPath)
+	if err != nil {
+		t.Fatalf("Failed to open ARIN bulk: %v", err)
+	}
+	t.Cleanup(func() { arin.Close() })
+
+	r.SetRegistries(ripe, arin)
+
+	// Announced: the registry the iptoasn row lacked comes from RIPE
+	o, err := r.Origin(netip.MustParseAddr("1.1.1.1"))
+	if err != nil {
+		t.Fatalf("Origin failed: %v", err)
+	}
+	if got, want := o.String(), "13335 | 1.1.1.0/24 | US | ripencc | "; got != want {
+		t.Errorf("announced: got %q, want %q", got, want)
+	}
+
+	// Registered but not announced
+	o, err = r.Origin(netip.MustParseAddr("192.0.2.1"))
+	if err != nil {
+		t.Fatalf("Origin failed: %v", err)
+	}
+	if got, want := o.Verbose("192.0.2.1"), "NA | 192.0.2.1 |  |  | arin |  | "; got != want {
+		t.Errorf("unannounced: got %q, want %q", got, want)
+	}
+
+	if _, err := r.Origin(netip.MustParseAddr("198.51.100.1")); err != model.ErrNotFound {
+		t.Errorf("unregistered address: got %v, want %v", err, model.ErrNotFound)
+	}
+}
+
+func TestAS(t *testing.T) {
+	r := newTestResolver(t)
+
+	as, err := r.AS(context.Background(), 15169)
+	if err != nil {
+		t.Fatalf("AS failed: %v", err)
+	}
+	if got, want := as.String(), "15169 | US | arin |  | GOOGLE"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := r.AS(context.Background(), 64512); err != model.ErrNotFound {
+		t.Errorf("unknown ASN: got %v, want %v", err, model.ErrNotFound)
+	}
+}
+
+func TestParseASN(t *testing.T) {
+	for s, want := range map[string]int{"AS15169": 15169, "as15169": 15169, "15169": 15169, "AS": 0, "ASx": 0, "0": 0} {
+		got, ok := ParseASN(s)
+		if got != want || ok != (want != 0) {
+			t.Errorf("ParseASN(%q): got %d, %v, want %d", s, got, ok, want)
+		}
+	}
+}
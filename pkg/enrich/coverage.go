@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package enrich
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/wingedpig/iporg/pkg/util/logging"
+)
+
+// Coverer is implemented by sources that can return every registry object
+// overlapping a prefix in a single query. Modes B and C use it to resolve all
+// the blocks of an announced prefix without a lookup per block.
+type Coverer interface {
+	Cover(ctx context.Context, prefix netip.Prefix) (*Coverage, error)
+}
+
+// Coverage is the set of registry objects overlapping a prefix. It is
+// indexed on the first Lookup or Boundaries call; Add must not be called
+// after that.
+type Coverage struct {
+	Prefix  netip.Prefix
+	objects []coveredObject
+
+	once     sync.Once
+	bounds   []netip.Addr
+	segments []segment
+}
+
+// segment is a run of addresses inside the covered prefix that lies wholly
+// inside or outside every object
+type segment struct {
+	start   netip.Addr
+	objects []*coveredObject // Those containing the segment, most specific first
+}
+
+// coveredObject is one registry range and its result (nil for objects that
+// hide their parents without naming an owner, such as RIPE placeholders)
+type coveredObject struct {
+	start netip.Addr
+	end   netip.Addr
+	res   *Result
+}
+
+// NewCoverage creates an empty coverage of prefix
+func NewCoverage(prefix netip.Prefix) *Coverage {
+	return &Coverage{Prefix: prefix.Masked()}
+}
+
+// Add records a registry object spanning start-end
+func (c *Coverage) Add(start, end netip.Addr, res *Result) {
+	c.objects = append(c.objects, coveredObject{start: start, end: end, res: res})
+}
+
+// Lookup returns the result of the most specific object covering all of
+// block, or failing that the most specific object containing its first
+// address. ok is false if block lies outside the covered prefix.
+func (c *Coverage) Lookup(block netip.Prefix) (res *Result, ok bool) {
+	block = block.Masked()
+	if !c.Prefix.Contains(block.Addr()) || block.Bits() < c.Prefix.Bits() {
+		return nil, false
+	}
+	first := block.Addr()
+	last := lastAddr(block)
+
+	c.index()
+	i, found := slices.BinarySearchFunc(c.segments, first, func(s segment, addr netip.Addr) int {
+		return s.start.Compare(addr)
+	})
+	if !found {
+		i--
+	}
+	objects := c.segments[i].objects
+	if len(objects) == 0 {
+		return nil, true
+	}
+	for _, o := range objects {
+		if o.end.Compare(last) >= 0 {
+			return o.res, true
+		}
+	}
+	return objects[0].res, true
+}
+
+// Boundaries returns, in order, the addresses inside the covered prefix
+// (other than its first) at which some object starts or after which one ends.
+// Splitting the prefix at these addresses gives pieces that each lie wholly
+// inside or outside every object. The slice must not be modified.
+func (c *Coverage) Boundaries() []netip.Addr {
+	c.index()
+	return c.bounds
+}
+
+// index splits the covered prefix into segments at the object boundaries
+// and lists the objects containing each, sweeping the objects in start order
+func (c *Coverage) index() {
+	c.once.Do(func() {
+		first := c.Prefix.Addr()
+		last := lastAddr(c.Prefix)
+
+		var objects []*coveredObject
+		for i := range c.objects {
+			o := &c.objects[i]
+			if o.end.Compare(first) < 0 || o.start.Compare(last) > 0 {
+				continue
+			}
+			objects = append(objects, o)
+			if o.start.Compare(first) > 0 {
+				c.bounds = append(c.bounds, o.start)
+			}
+			if o.end.Compare(last) < 0 {
+				c.bounds = append(c.bounds, o.end.Next())
+			}
+		}
+		slices.SortFunc(c.bounds, netip.Addr.Compare)
+		c.bounds = slices.Compact(c.bounds)
+		slices.SortStableFunc(objects, func(a, b *coveredObject) int { return a.start.Compare(b.start) })
+
+		c.segments = make([]segment, 0, len(c.bounds)+1)
+		var active []*coveredObject
+		next := 0
+		for i := -1; i < len(c.bounds); i++ {
+			start := first
+			if i >= 0 {
+				start = c.bounds[i]
+			}
+			for ; next < len(objects) && objects[next].start.Compare(start) <= 0; next++ {
+				active = append(active, objects[next])
+			}
+			active = slices.DeleteFunc(active, func(o *coveredObject) bool { return o.end.Compare(start) < 0 })
+
+			// Every active object contains the whole segment, so the later
+			// start (then the earlier end) is the more specific. The sort is
+			// stable, so of identical objects the first added wins.
+			seg := segment{start: start, objects: slices.Clone(active)}
+			slices.SortStableFunc(seg.objects, func(a, b *coveredObject) int {
+				if n := b.start.Compare(a.start); n != 0 {
+					return n
+				}
+				return a.end.Compare(b.end)
+			})
+			c.segments = append(c.segments, seg)
+		}
+	})
+}
+
+// lastAddr returns the last address in prefix
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Cover asks every source in the pipeline that implements Coverer for the
+// objects overlapping prefix. The result is passed to Run in Query.Coverage.
+// Sources that fail are left out and fall back to per-query lookups.
+func (p *Pipeline) Cover(ctx context.Context, prefix netip.Prefix) map[string]*Coverage {
+	var coverage map[string]*Coverage
+	for name, src := range p.sources {
+		coverer, ok := src.(Coverer)
+		if !ok {
+			continue
+		}
+		cov, err := coverer.Cover(ctx, prefix)
+		if err != nil {
+			logging.Library().Warn("Coverage lookup failed", "source", name, "prefix", prefix, "err", err)
+			continue
+		}
+		if coverage == nil {
+			coverage = make(map[string]*Coverage)
+		}
+		coverage[name] = cov
+	}
+	return coverage
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+func TestCoverageLookup(t *testing.T) {
+	cov := NewCoverage(netip.MustParsePrefix("10.0.0.0/16"))
+	add := func(start, end, org string) {
+		var res *Result
+		if org != "" {
+			res = &Result{OrgName: org}
+		}
+		cov.Add(netip.MustParseAddr(start), netip.MustParseAddr(end), res)
+	}
+	add("10.0.0.0", "10.0.15.255", "Parent")
+	add("10.0.4.0", "10.0.4.255", "Customer")
+	add("10.0.8.0", "10.0.11.255", "") // Placeholder
+	add("10.0.16.0", "10.0.16.127", "Half")
+
+	tests := []struct {
+		block  string
+		want   string
+		wantOK bool
+	}{
+		{"10.0.0.0/24", "Parent", true},
+		{"10.0.4.0/24", "Customer", true},
+		{"10.0.4.0/22", "Parent", true},
+		{"10.0.9.0/24", "", true},      // Placeholder hides the parent
+		{"10.0.16.0/24", "Half", true}, // Nothing covers it all; first address wins
+		{"10.0.20.0/24", "", true},     // Registered nowhere
+		{"10.0.0.0/8", "", false},      // Wider than the covered prefix
+		{"192.168.0.0/24", "", false},  // Outside the covered prefix
+	}
+	for _, tt := range tests {
+		res, ok := cov.Lookup(netip.MustParsePrefix(tt.block))
+		got := ""
+		if res != nil {
+			got = res.OrgName
+		}
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Lookup(%s): got %q %v, want %q %v", tt.block, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCoverageLookupIndexed(t *testing.T) {
+	// Random nested CIDRs, as registry objects are, some repeated
+	rng := rand.New(rand.NewPCG(1, 2))
+	prefix := netip.MustParsePrefix("10.0.0.0/16")
+	cov := NewCoverage(prefix)
+	type object struct {
+		start, end netip.Addr
+		res        *Result
+	}
+	var objects []object
+	for i := range 500 {
+		bits := 8 + rng.IntN(21)
+		p, _ := netip.AddrFrom4([4]byte{10, 0, byte(rng.IntN(256)), byte(rng.IntN(256))}).Prefix(bits)
+		o := object{start: p.Addr(), end: lastAddr(p), res: &Result{OrgName: fmt.Sprint(i)}}
+		if i%50 == 0 && len(objects) > 0 {
+			o.start, o.end = objects[len(objects)-1].start, objects[len(objects)-1].end
+		}
+		objects = append(objects, o)
+		cov.Add(o.start, o.end, o.res)
+	}
+
+	// The most specific object containing all of the block, else its first
+	// address; of identical objects the first added
+	inside := func(a, b object) bool {
+		return a.start.Compare(b.start) >= 0 && a.end.Compare(b.end) <= 0 && (a.start != b.start || a.end != b.end)
+	}
+	reference := func(block netip.Prefix) *Result {
+		first, last := block.Addr(), lastAddr(block)
+		var covering, containing *object
+		for i := range objects {
+			o := &objects[i]
+			if o.start.Compare(first) > 0 || o.end.Compare(first) < 0 {
+				continue
+			}
+			if containing == nil || inside(*o, *containing) {
+				containing = o
+			}
+			if o.end.Compare(last) >= 0 && (covering == nil || inside(*o, *covering)) {
+				covering = o
+			}
+		}
+		switch {
+		case covering != nil:
+			return covering.res
+		case containing != nil:
+			return containing.res
+		}
+		return nil
+	}
+
+	for range 2000 {
+		block, _ := netip.AddrFrom4([4]byte{10, 0, byte(rng.IntN(256)), byte(rng.IntN(256))}).Prefix(16 + rng.IntN(17))
+		got, ok := cov.Lookup(block)
+		if want := reference(block); got != want || !ok {
+			t.Fatalf("Lookup(%s): got %v %v, want %v", block, got, ok, want)
+		}
+	}
+}
+
+// fakeCoverer is a fakeEnricher that also supplies coverage
+type fakeCoverer struct {
+	fakeEnricher
+	cov *Coverage
+}
+
+func (f *fakeCoverer) Cover(ctx context.Context, prefix netip.Prefix) (*Coverage, error) {
+	return f.cov, nil
+}
+
+func TestPipelineUsesCoverage(t *testing.T) {
+	cov := NewCoverage(netip.MustParsePrefix("192.0.2.0/24"))
+	cov.Add(netip.MustParseAddr("192.0.2.0"), netip.MustParseAddr("192.0.2.255"), &Result{OrgName: "Covered", RIR: "RIPE"})
+	src := &fakeCoverer{
+		fakeEnricher: fakeEnricher{name: SourceRIPEBulk, local: true, res: &Result{OrgName: "Queried"}},
+		cov:          cov,
+	}
+	p, err := NewPipeline(DefaultPrecedence(), nil, src)
+	if err != nil {
+		t.Fatalf("NewPipeline failed: %v", err)
+	}
+
+	q := testQuery()
+	q.Coverage = p.Cover(context.Background(), q.Prefix)
+	var rec model.Record
+	p.Run(context.Background(), q, &rec)
+	if rec.OrgName != "Covered" {
+		t.Errorf("got org %q, want %q", rec.OrgName, "Covered")
+	}
+	if src.calls != 0 {
+		t.Errorf("got %d Enrich calls, want 0", src.calls)
+	}
+}
+
+func TestCoverageBoundaries(t *testing.T) {
+	cov := NewCoverage(netip.MustParsePrefix("10.0.0.0/22"))
+	cov.Add(netip.MustParseAddr("9.0.0.0"), netip.MustParseAddr("10.255.255.255"), nil)
+	cov.Add(netip.MustParseAddr("10.0.1.0"), netip.MustParseAddr("10.0.1.255"), nil)
+	cov.Add(netip.MustParseAddr("10.0.1.0"), netip.MustParseAddr("10.0.1.127"), nil)
+	cov.Add(netip.MustParseAddr("10.0.3.128"), netip.MustParseAddr("10.0.7.255"), nil)
+
+	var got []string
+	for _, addr := range cov.Boundaries() {
+		got = append(got, addr.String())
+	}
+	want := []string{"10.0.1.0", "10.0.1.128", "10.0.2.0", "10.0.3.128"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
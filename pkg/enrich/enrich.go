@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package enrich resolves record fields from several data sources using a
+// configurable per-field precedence.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// Source names used in precedence lists
+const (
+	SourceMaxMind  = "maxmind"
+	SourceRIPEBulk = "ripe_bulk"
+	SourceARINBulk = "arin_bulk"
+	SourceRDAP     = "rdap"
+)
+
+// Query describes the address space being enriched
+type Query struct {
+	Prefix netip.Prefix // Announced prefix (Mode A) or block (Modes B and C)
+	Addr   netip.Addr   // Representative address for point lookups
+	Point  bool         // Look up Addr rather than the whole Prefix
+
+	// Coverage prefetched per source for the enclosing announced prefix
+	// (see Pipeline.Cover); sources found here are not queried
+	Coverage map[string]*Coverage
+}
+
+// Result holds what a single source knows about a query. Empty values mean
+// the source has no opinion on that field.
+type Result struct {
+	ASN         int
+	ASNName     string
+	OrgName     string
+	SourceRole  string
+	StatusLabel string
+	RIR         string
+	Country     string
+	Region      string
+	City        string
+	Lat         float64
+	Lon         float64
+}
+
+// Enricher is a data source that can describe a query
+type Enricher interface {
+	// Name is the source name used in precedence lists
+	Name() string
+
+	// Local reports whether lookups are served from local files.
+	// Remote sources are not queried once another source has matched.
+	Local() bool
+
+	// Enrich looks up q. It returns nil, nil if the source has no data.
+	Enrich(ctx context.Context, q Query) (*Result, error)
+}
+
+// Observer is called after every source lookup
+type Observer func(source string, q Query, elapsed time.Duration, res *Result, err error)
+
+// Trace records how a query was resolved
+type Trace struct {
+	Results map[string]*Result // Result per consulted source (nil if no match)
+	Errors  map[string]error   // Errors per consulted source
+	Chosen  map[string]string  // Field -> source that supplied it
+}
+
+// Matched reports whether any of the named sources returned a result
+func (t *Trace) Matched(sources ...string) bool {
+	for _, name := range sources {
+		if t.Results[name] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipeline resolves record fields from a set of enrichers
+type Pipeline struct {
+	sources    map[string]Enricher
+	precedence Precedence
+	observe    Observer
+}
+
+// NewPipeline creates a pipeline over the given enrichers. Sources named in
+// the precedence but not supplied are skipped, so optional inputs can simply
+// be left out. Enrichers with names other than the built-in sources may be
+// referenced by name in prec.
+func NewPipeline(prec Precedence, observe Observer, sources ...Enricher) (*Pipeline, error) {
+	p := &Pipeline{
+		sources:    make(map[string]Enricher, len(sources)),
+		precedence: prec,
+		observe:    observe,
+	}
+	for _, src := range sources {
+		if _, dup := p.sources[src.Name()]; dup {
+			return nil, fmt.Errorf("duplicate enricher %q", src.Name())
+		}
+		p.sources[src.Name()] = src
+	}
+
+	if err := prec.validate(p.sources); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Has reports whether a source with the given name is part of the pipeline
+func (p *Pipeline) Has(name string) bool {
+	_, ok := p.sources[name]
+	return ok
+}
+
+// Run resolves q into rec. Fields are resolved in the order org, rir, asn,
+// country, geo; country and geo may use a per-RIR precedence once the RIR
+// is known. Each field takes the first non-empty value from its precedence
+// list. Sources are queried at most once per call.
+func (p *Pipeline) Run(ctx context.Context, q Query, rec *model.Record) *Trace {
+	s := &session{
+		p:   p,
+		ctx: ctx,
+		q:   q,
+		trace: &Trace{
+			Results: make(map[string]*Result),
+			Errors:  make(map[string]error),
+			Chosen:  make(map[string]string),
+		},
+	}
+
+	if res := s.resolve(FieldOrg, "", func(r *Result) bool { return r.OrgName != "" }); res != nil {
+		rec.OrgName = res.OrgName
+		rec.SourceRole = res.SourceRole
+	}
+
+	rec.RIR = "UNKNOWN"
+	if res := s.resolve(FieldRIR, "", func(r *Result) bool { return r.RIR != "" }); res != nil {
+		rec.RIR = res.RIR
+		rec.StatusLabel = res.StatusLabel
+	}
+	rir := rec.RIR
+
+	if res := s.resolve(FieldASN, rir, func(r *Result) bool { return r.ASN != 0 }); res != nil {
+		rec.ASN = res.ASN
+		rec.ASNName = res.ASNName
+	}
+
+	if res := s.resolve(FieldCountry, rir, func(r *Result) bool { return r.Country != "" }); res != nil {
+		rec.Country = res.Country
+	}
+
+	hasGeo := func(r *Result) bool { return r.Region != "" || r.City != "" || r.Lat != 0 || r.Lon != 0 }
+	if res := s.resolve(FieldGeo, rir, hasGeo); res != nil {
+		rec.Region = res.Region
+		rec.City = res.City
+		rec.Lat = res.Lat
+		rec.Lon = res.Lon
+	}
+
+	return s.trace
+}
+
+// session caches source results for a single Run
+type session struct {
+	p       *Pipeline
+	ctx     context.Context
+	q       Query
+	trace   *Trace
+	matched bool // Some source has returned a result
+}
+
+// query returns the (cached) result of a source
+func (s *session) query(src Enricher) *Result {
+	name := src.Name()
+	if res, done := s.trace.Results[name]; done {
+		return res
+	}
+	if _, failed := s.trace.Errors[name]; failed {
+		return nil
+	}
+
+	start := time.Now()
+	res, err := s.enrich(src)
+	if s.p.observe != nil {
+		s.p.observe(name, s.q, time.Since(start), res, err)
+	}
+	if err != nil {
+		s.trace.Errors[name] = err
+		return nil
+	}
+
+	s.trace.Results[name] = res
+	if res != nil {
+		s.matched = true
+	}
+	return res
+}
+
+// enrich answers from prefetched coverage when it applies, otherwise asks src
+func (s *session) enrich(src Enricher) (*Result, error) {
+	if cov := s.q.Coverage[src.Name()]; cov != nil {
+		if res, ok := cov.Lookup(s.q.Prefix); ok {
+			return res, nil
+		}
+	}
+	return src.Enrich(s.ctx, s.q)
+}
+
+// resolve walks the precedence list for a field and returns the first result
+// with a value. Remote sources are skipped once any source has matched.
+func (s *session) resolve(field, rir string, has func(*Result) bool) *Result {
+	for _, name := range s.p.precedence.Order(field, rir) {
+		src, ok := s.p.sources[name]
+		if !ok {
+			continue
+		}
+
+		_, queried := s.trace.Results[name]
+		if !queried && !src.Local() && s.matched {
+			continue
+		}
+
+		res := s.query(src)
+		if res != nil && has(res) {
+			s.trace.Chosen[field] = name
+			return res
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package enrich
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// fakeEnricher returns a fixed result and counts calls
+type fakeEnricher struct {
+	name  string
+	local bool
+	res   *Result
+	err   error
+	calls int
+}
+
+func (f *fakeEnricher) Name() string { return f.name }
+func (f *fakeEnricher) Local() bool  { return f.local }
+func (f *fakeEnricher) Enrich(ctx context.Context, q Query) (*Result, error) {
+	f.calls++
+	return f.res, f.err
+}
+
+func testQuery() Query {
+	prefix := netip.MustParsePrefix("192.0.2.0/24")
+	return Query{Prefix: prefix, Addr: prefix.Addr()}
+}
+
+func TestPipelineDefaultPrecedence(t *testing.T) {
+	tests := []struct {
+		name        string
+		ripe        *Result
+		arin        *Result
+		rdap        *Result
+		rdapErr     error
+		wantOrg     string
+		wantRole    string
+		wantRIR     string
+		wantCountry string
+		wantRDAP    int // expected RDAP calls
+	}{
+		{
+			name:        "ripe bulk wins",
+			ripe:        &Result{OrgName: "RIPE Org", SourceRole: "ripe_bulk", RIR: "RIPE", Country: "GB"},
+			rdap:        &Result{OrgName: "RDAP Org", RIR: "RIPE"},
+			wantOrg:     "RIPE Org",
+			wantRole:    "ripe_bulk",
+			wantRIR:     "RIPE",
+			wantCountry: "GB",
+			wantRDAP:    0,
+		},
+		{
+			name:        "bulk match without org falls back to ASN name, not RDAP",
+			arin:        &Result{SourceRole: "arin_bulk", RIR: "ARIN"},
+			rdap:        &Result{OrgName: "RDAP Org", RIR: "ARIN", Country: "CA"},
+			wantOrg:     "Example ASN",
+			wantRole:    "asn_fallback",
+			wantRIR:     "ARIN",
+			wantCountry: "US",
+			wantRDAP:    0,
+		},
+		{
+			name:        "rdap used when no bulk coverage",
+			rdap:        &Result{OrgName: "RDAP Org", SourceRole: "registrant", RIR: "ARIN", Country: "CA"},
+			wantOrg:     "RDAP Org",
+			wantRole:    "registrant",
+			wantRIR:     "ARIN",
+			wantCountry: "CA",
+			wantRDAP:    1,
+		},
+		{
+			name:        "rdap failure",
+			rdapErr:     errors.New("timeout"),
+			wantOrg:     "Example ASN",
+			wantRole:    "asn_fallback",
+			wantRIR:     "UNKNOWN",
+			wantCountry: "US",
+			wantRDAP:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mm := &fakeEnricher{name: SourceMaxMind, local: true, res: &Result{
+				ASN: 64500, ASNName: "Example ASN", OrgName: "Example ASN", SourceRole: "asn_fallback",
+				Country: "US", City: "Springfield",
+			}}
+			ripe := &fakeEnricher{name: SourceRIPEBulk, local: true, res: tt.ripe}
+			arin := &fakeEnricher{name: SourceARINBulk, local: true, res: tt.arin}
+			rdap := &fakeEnricher{name: SourceRDAP, res: tt.rdap, err: tt.rdapErr}
+
+			observed := 0
+			observe := func(string, Query, time.Duration, *Result, error) { observed++ }
+
+			p, err := NewPipeline(DefaultPrecedence(), observe, mm, ripe, arin, rdap)
+			if err != nil {
+				t.Fatalf("NewPipeline failed: %v", err)
+			}
+
+			rec := &model.Record{}
+			trace := p.Run(context.Background(), testQuery(), rec)
+
+			if rec.OrgName != tt.wantOrg || rec.SourceRole != tt.wantRole {
+				t.Errorf("org = %q/%q, want %q/%q", rec.OrgName, rec.SourceRole, tt.wantOrg, tt.wantRole)
+			}
+			if rec.RIR != tt.wantRIR {
+				t.Errorf("RIR = %q, want %q", rec.RIR, tt.wantRIR)
+			}
+			if rec.Country != tt.wantCountry {
+				t.Errorf("Country = %q, want %q", rec.Country, tt.wantCountry)
+			}
+			if rec.ASN != 64500 || rec.City != "Springfield" {
+				t.Errorf("ASN/City = %d/%q, want 64500/Springfield", rec.ASN, rec.City)
+			}
+			if rdap.calls != tt.wantRDAP {
+				t.Errorf("RDAP called %d times, want %d", rdap.calls, tt.wantRDAP)
+			}
+			if mm.calls != 1 || ripe.calls != 1 {
+				t.Errorf("local sources called %d/%d times, want 1/1", mm.calls, ripe.calls)
+			}
+			if observed != mm.calls+ripe.calls+arin.calls+rdap.calls {
+				t.Errorf("observer saw %d lookups, want %d", observed, mm.calls+ripe.calls+arin.calls+rdap.calls)
+			}
+			if tt.rdapErr != nil && trace.Errors[SourceRDAP] == nil {
+				t.Errorf("trace is missing the RDAP error")
+			}
+		})
+	}
+}
+
+func TestPipelineRIRSpecificPrecedence(t *testing.T) {
+	prec, err := ParsePrecedence("country=ripe_bulk,arin_bulk,maxmind;country:ARIN=maxmind")
+	if err != nil {
+		t.Fatalf("ParsePrecedence failed: %v", err)
+	}
+	prec = DefaultPrecedence().Merge(prec)
+
+	mm := &fakeEnricher{name: SourceMaxMind, local: true, res: &Result{Country: "US"}}
+	arin := &fakeEnricher{name: SourceARINBulk, local: true, res: &Result{OrgName: "ARIN Org", RIR: "ARIN", Country: "CA"}}
+	ripe := &fakeEnricher{name: SourceRIPEBulk, local: true, res: &Result{OrgName: "RIPE Org", RIR: "RIPE", Country: "GB"}}
+
+	tests := []struct {
+		name        string
+		sources     []Enricher
+		wantCountry string
+	}{
+		{"ARIN prefers MaxMind", []Enricher{mm, arin}, "US"},
+		{"RIPE prefers registry", []Enricher{mm, ripe}, "GB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipeline(prec, nil, tt.sources...)
+			if err != nil {
+				t.Fatalf("NewPipeline failed: %v", err)
+			}
+			rec := &model.Record{}
+			trace := p.Run(context.Background(), testQuery(), rec)
+			if rec.Country != tt.wantCountry {
+				t.Errorf("Country = %q, want %q", rec.Country, tt.wantCountry)
+			}
+			if trace.Chosen[FieldCountry] == "" {
+				t.Errorf("trace did not record the country source")
+			}
+		})
+	}
+}
+
+func TestParsePrecedenceErrors(t *testing.T) {
+	tests := []string{
+		"org",                   // missing sources
+		"colour=maxmind",        // unknown field
+		"org=whois",             // unknown source
+		"org:RIPE=ripe_bulk",    // org cannot be qualified
+		"country:=maxmind,rdap", // empty RIR
+	}
+
+	for _, s := range tests {
+		if _, err := ParsePrecedence(s); err == nil {
+			t.Errorf("ParsePrecedence(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestPrecedenceStringRoundTrip(t *testing.T) {
+	prec := DefaultPrecedence()
+	parsed, err := ParsePrecedence(prec.String())
+	if err != nil {
+		t.Fatalf("ParsePrecedence failed: %v", err)
+	}
+	if parsed.String() != prec.String() {
+		t.Errorf("got %q, want %q", parsed.String(), prec.String())
+	}
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package enrich
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields that can be given a precedence
+const (
+	FieldOrg     = "org"     // OrgName and SourceRole
+	FieldRIR     = "rir"     // RIR and StatusLabel
+	FieldASN     = "asn"     // ASN and ASNName
+	FieldCountry = "country" // Country
+	FieldGeo     = "geo"     // Region, City, Lat, Lon
+)
+
+var knownFields = map[string]bool{
+	FieldOrg: true, FieldRIR: true, FieldASN: true, FieldCountry: true, FieldGeo: true,
+}
+
+var knownSources = map[string]bool{
+	SourceMaxMind: true, SourceRIPEBulk: true, SourceARINBulk: true, SourceRDAP: true,
+}
+
+// Precedence maps a field to the ordered list of sources consulted for it.
+// Keys are either a field name ("country") or a field name qualified by RIR
+// ("country:ARIN"); the qualified form applies to records whose RIR matches
+// and is only supported for fields resolved after the RIR.
+type Precedence map[string][]string
+
+// DefaultPrecedence returns the builder's standard order: registry data
+// first, MaxMind as the fallback
+func DefaultPrecedence() Precedence {
+	return Precedence{
+		FieldOrg:     {SourceRIPEBulk, SourceARINBulk, SourceRDAP, SourceMaxMind},
+		FieldRIR:     {SourceRIPEBulk, SourceARINBulk, SourceRDAP},
+		FieldASN:     {SourceMaxMind},
+		FieldCountry: {SourceRIPEBulk, SourceARINBulk, SourceRDAP, SourceMaxMind},
+		FieldGeo:     {SourceMaxMind},
+	}
+}
+
+// Order returns the source order for a field, preferring a RIR-specific entry
+func (p Precedence) Order(field, rir string) []string {
+	if rir != "" {
+		if order, ok := p[field+":"+rir]; ok {
+			return order
+		}
+	}
+	return p[field]
+}
+
+// Merge returns a copy of p with the entries of other replacing its own
+func (p Precedence) Merge(other Precedence) Precedence {
+	merged := make(Precedence, len(p)+len(other))
+	for k, v := range p {
+		merged[k] = v
+	}
+	for k, v := range other {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Validate checks field and source names
+func (p Precedence) Validate() error {
+	return p.validate(nil)
+}
+
+// validate checks p, also accepting the source names in extra
+func (p Precedence) validate(extra map[string]Enricher) error {
+	for key, order := range p {
+		field, rir, qualified := strings.Cut(key, ":")
+		if !knownFields[field] {
+			return fmt.Errorf("unknown precedence field %q", field)
+		}
+		if qualified && (rir == "" || field == FieldOrg || field == FieldRIR) {
+			return fmt.Errorf("invalid precedence key %q: only asn, country and geo can be qualified by RIR", key)
+		}
+		for _, name := range order {
+			if _, ok := extra[name]; !ok && !knownSources[name] {
+				return fmt.Errorf("unknown source %q in precedence for %s", name, key)
+			}
+		}
+	}
+	return nil
+}
+
+// String formats p in the syntax accepted by ParsePrecedence
+func (p Precedence) String() string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+strings.Join(p[k], ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// ParsePrecedence parses "field=src,src;field:RIR=src,..." as used by the
+// --precedence flag, e.g. "country=ripe_bulk,maxmind;country:ARIN=maxmind"
+func ParsePrecedence(s string) (Precedence, error) {
+	p := make(Precedence)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, list, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid precedence entry %q: want field=source,...", entry)
+		}
+		key = strings.TrimSpace(key)
+		if field, rir, qualified := strings.Cut(key, ":"); qualified {
+			key = field + ":" + strings.ToUpper(rir)
+		}
+
+		var order []string
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				order = append(order, name)
+			}
+		}
+		p[key] = order
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
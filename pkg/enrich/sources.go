@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+	"github.com/wingedpig/iporg/pkg/sources/maxmind"
+	"github.com/wingedpig/iporg/pkg/sources/rdap"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+)
+
+// maxMindEnricher supplies ASN and geo data from MaxMind. Its org value is
+// the ASN organisation, marked as an asn_fallback.
+type maxMindEnricher struct {
+	readers *maxmind.Readers
+}
+
+// NewMaxMind wraps MaxMind ASN and City readers
+func NewMaxMind(readers *maxmind.Readers) Enricher {
+	return &maxMindEnricher{readers: readers}
+}
+
+func (e *maxMindEnricher) Name() string { return SourceMaxMind }
+func (e *maxMindEnricher) Local() bool  { return true }
+
+func (e *maxMindEnricher) Enrich(ctx context.Context, q Query) (*Result, error) {
+	res := &Result{}
+
+	asn, asnName, asnErr := e.readers.ASNInfo(q.Addr)
+	if asnErr != nil {
+		logging.Library().Warn("MaxMind ASN lookup failed", "prefix", q.Prefix, "err", asnErr)
+	} else {
+		res.ASN = asn
+		res.ASNName = asnName
+		res.OrgName = asnName
+		res.SourceRole = "asn_fallback"
+	}
+
+	geo, geoErr := e.readers.Geo(q.Addr)
+	if geoErr != nil {
+		logging.Library().Warn("MaxMind geo lookup failed", "prefix", q.Prefix, "err", geoErr)
+	} else {
+		res.Country = geo.Country
+		res.Region = geo.Region
+		res.City = geo.City
+		res.Lat = geo.Lat
+		res.Lon = geo.Lon
+	}
+
+	if asnErr != nil && geoErr != nil {
+		return nil, fmt.Errorf("maxmind lookups failed: %w", asnErr)
+	}
+	return res, nil
+}
+
+// ripeBulkEnricher supplies registration data from the RIPE bulk database
+type ripeBulkEnricher struct {
+	db *ripebulk.Database
+}
+
+// NewRIPEBulk wraps a RIPE bulk database
+func NewRIPEBulk(db *ripebulk.Database) Enricher {
+	return &ripeBulkEnricher{db: db}
+}
+
+func (e *ripeBulkEnricher) Name() string { return SourceRIPEBulk }
+func (e *ripeBulkEnricher) Local() bool  { return true }
+
+func (e *ripeBulkEnricher) Enrich(ctx context.Context, q Query) (*Result, error) {
+	// IPv6 not supported in RIPE bulk yet
+	if !q.Addr.Is4() {
+		return nil, nil
+	}
+
+	var match *ripebulk.Match
+	var err error
+	if q.Point {
+		match, err = e.db.LookupIP(q.Addr)
+	} else {
+		match, err = e.db.LookupPrefix(q.Prefix)
+	}
+	if err != nil {
+		// Not found in RIPE region
+		return nil, nil
+	}
+	return ripeResult(match), nil
+}
+
+// Cover resolves every inetnum overlapping prefix with one range scan
+func (e *ripeBulkEnricher) Cover(ctx context.Context, prefix netip.Prefix) (*Coverage, error) {
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("IPv6 not supported")
+	}
+	inetnums, err := e.db.Overlapping(prefix)
+	if err != nil {
+		return nil, err
+	}
+	cov := NewCoverage(prefix)
+	for _, inet := range inetnums {
+		cov.Add(ripebulk.Uint32ToAddr(inet.Start), ripebulk.Uint32ToAddr(inet.End), ripeResult(e.db.MatchFor(inet)))
+	}
+	return cov, nil
+}
+
+// ripeResult converts a RIPE bulk match; nil and placeholder matches give nil
+func ripeResult(match *ripebulk.Match) *Result {
+	// Filter out RIPE's placeholder entries for non-RIPE address space
+	if match == nil || IsRIPEPlaceholder(match.OrgName) {
+		return nil
+	}
+
+	return &Result{
+		OrgName:     rdap.CleanOrgName(match.OrgName),
+		SourceRole:  SourceRIPEBulk,
+		StatusLabel: match.Status,
+		RIR:         "RIPE",
+		Country:     match.Country,
+	}
+}
+
+// IsRIPEPlaceholder checks if an organization name is a RIPE placeholder
+// for non-RIPE address space (ARIN, APNIC, etc.)
+func IsRIPEPlaceholder(orgName string) bool {
+	switch orgName {
+	case "NON-RIPE-NCC-MANAGED-ADDRESS-BLOCK", "UNALLOCATED", "RESERVED":
+		return true
+	}
+	return false
+}
+
+// arinBulkEnricher supplies registration data from the ARIN bulk database
+type arinBulkEnricher struct {
+	db *arinbulk.Database
+}
+
+// NewARINBulk wraps an ARIN bulk database
+func NewARINBulk(db *arinbulk.Database) Enricher {
+	return &arinBulkEnricher{db: db}
+}
+
+func (e *arinBulkEnricher) Name() string { return SourceARINBulk }
+func (e *arinBulkEnricher) Local() bool  { return true }
+
+func (e *arinBulkEnricher) Enrich(ctx context.Context, q Query) (*Result, error) {
+	// IPv6 not supported in ARIN bulk yet
+	if !q.Addr.Is4() {
+		return nil, nil
+	}
+
+	var match *arinbulk.Match
+	var err error
+	if q.Point {
+		match, err = e.db.LookupIP(q.Addr)
+	} else {
+		match, err = e.db.LookupPrefix(q.Prefix)
+	}
+	if err != nil {
+		// Not found in ARIN region
+		return nil, nil
+	}
+	return arinResult(match), nil
+}
+
+// Cover resolves every network overlapping prefix with one range scan
+func (e *arinBulkEnricher) Cover(ctx context.Context, prefix netip.Prefix) (*Coverage, error) {
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("IPv6 not supported")
+	}
+	nets, err := e.db.Overlapping(prefix)
+	if err != nil {
+		return nil, err
+	}
+	cov := NewCoverage(prefix)
+	for _, net := range nets {
+		match, err := e.db.MatchFor(net)
+		if err != nil {
+			match = nil
+		}
+		cov.Add(arinbulk.Uint32ToAddr(net.Start), arinbulk.Uint32ToAddr(net.End), arinResult(match))
+	}
+	return cov, nil
+}
+
+// arinResult converts an ARIN bulk match; a nil match gives nil
+func arinResult(match *arinbulk.Match) *Result {
+	if match == nil {
+		return nil
+	}
+
+	return &Result{
+		OrgName:     rdap.CleanOrgName(match.OrgName),
+		SourceRole:  SourceARINBulk,
+		StatusLabel: match.NetType,
+		RIR:         "ARIN",
+		Country:     match.Country,
+	}
+}
+
+// RDAPLookup is the subset of the RDAP clients used for enrichment
+type RDAPLookup interface {
+	OrgForPrefix(ctx context.Context, prefix string) (*model.RDAPOrg, error)
+	OrgForIP(ctx context.Context, ip netip.Addr) (*model.RDAPOrg, error)
+}
+
+// rdapEnricher supplies registration data from RDAP
+type rdapEnricher struct {
+	client RDAPLookup
+}
+
+// NewRDAP wraps an RDAP client (usually an rdap.CachedClient)
+func NewRDAP(client RDAPLookup) Enricher {
+	return &rdapEnricher{client: client}
+}
+
+func (e *rdapEnricher) Name() string { return SourceRDAP }
+func (e *rdapEnricher) Local() bool  { return false }
+
+func (e *rdapEnricher) Enrich(ctx context.Context, q Query) (*Result, error) {
+	var org *model.RDAPOrg
+	var err error
+	if q.Point {
+		org, err = e.client.OrgForIP(ctx, q.Addr)
+	} else {
+		org, err = e.client.OrgForPrefix(ctx, q.Prefix.String())
+	}
+	if errors.Is(err, rdap.ErrNoData) {
+		return nil, nil // Nothing registered; not a failure
+	}
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, nil
+	}
+
+	return &Result{
+		OrgName:     rdap.CleanOrgName(org.OrgName),
+		SourceRole:  org.SourceRole,
+		StatusLabel: org.StatusLabel,
+		RIR:         org.RIR,
+		Country:     org.Country,
+	}, nil
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// sweepSteps is how many records a sweep steps over before seeking instead
+const sweepSteps = 16
+
+// LookupBatch looks up many IPs at once. The IPs are visited in address
+// order by one iterator per address family, which steps forward between
+// nearby IPs instead of seeking for each. The result for ips[i] is at index
+// i, and is nil if the IP is invalid or not in the database. Overrides are
+// applied as in GetByIP. The cache and observer are not used: batches are
+// usually one-off, and would evict the addresses the cache is for.
+func (d *DB) LookupBatch(ips []netip.Addr) ([]*model.Record, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return nil, model.ErrDatabaseClosed
+	}
+
+	order := make([]int, 0, len(ips))
+	for i, ip := range ips {
+		if ip.IsValid() {
+			order = append(order, i)
+		}
+	}
+	// IPv4 sorts before IPv6
+	slices.SortStableFunc(order, func(a, b int) int { return ips[a].Compare(ips[b]) })
+
+	results := make([]*model.Record, len(ips))
+	var s *sweep
+	defer func() {
+		if s != nil {
+			s.iter.Release()
+		}
+	}()
+	now := time.Now()
+	for _, i := range order {
+		ip := ips[i]
+		if s == nil || s.v4 != ip.Is4() {
+			if s != nil {
+				s.iter.Release()
+			}
+			s = d.newSweep(ip.Is4())
+		}
+
+		rec, err := s.find(ip)
+		if err != nil {
+			return nil, err
+		}
+		if rec == nil {
+			continue
+		}
+		r := *rec
+		if d.overrides != nil {
+			d.overrides.ApplyIP(ip, &r, now)
+		}
+		results[i] = &r
+	}
+	return results, nil
+}
+
+// sweep finds the records of ascending IPs of one family. The iterator is
+// kept on the record after cur, the last record starting at or before the
+// previous IP.
+type sweep struct {
+	v4     bool
+	iter   iterator.Iterator
+	seeked bool
+
+	curKey []byte // Nil if no record starts at or before the previous IP
+	curVal []byte
+	cur    *model.Record // Decoded from curKey and curVal when needed
+}
+
+func (d *DB) newSweep(v4 bool) *sweep {
+	prefix := ipcodec.PrefixRangeV6
+	if v4 {
+		prefix = ipcodec.PrefixRangeV4
+	}
+	return &sweep{v4: v4, iter: d.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)}
+}
+
+// find returns the record containing ip, or nil. As with GetByIP, that is
+// the record with the greatest start at or before ip, if it ends at or
+// after ip.
+func (s *sweep) find(ip netip.Addr) (*model.Record, error) {
+	if !s.seeked {
+		s.seek(ip)
+	} else if ok, err := s.advance(ip); err != nil {
+		return nil, err
+	} else if !ok {
+		s.seek(ip)
+	}
+	if err := s.iter.Error(); err != nil {
+		return nil, err
+	}
+
+	if s.curKey == nil {
+		return nil, nil
+	}
+	if s.cur == nil {
+		start, err := ipcodec.DecodeRangeKey(s.curKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key: %w", err)
+		}
+		s.cur, err = decodeRecord(ipcodec.IPToBytes(start), s.curVal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode record: %w", err)
+		}
+	}
+	if ip.Compare(s.cur.End) > 0 {
+		return nil, nil
+	}
+	return s.cur, nil
+}
+
+// advance steps forward over the records starting at or before ip,
+// reporting false if there are more than sweepSteps of them
+func (s *sweep) advance(ip netip.Addr) (bool, error) {
+	for range sweepSteps {
+		if !s.iter.Valid() {
+			return true, nil
+		}
+		start, err := ipcodec.DecodeRangeKey(s.iter.Key())
+		if err != nil {
+			return false, fmt.Errorf("invalid key: %w", err)
+		}
+		if start.Compare(ip) > 0 {
+			return true, nil
+		}
+		s.take()
+		s.iter.Next()
+	}
+	if !s.iter.Valid() {
+		return true, nil
+	}
+	start, err := ipcodec.DecodeRangeKey(s.iter.Key())
+	if err != nil {
+		return false, fmt.Errorf("invalid key: %w", err)
+	}
+	return start.Compare(ip) > 0, nil
+}
+
+// seek positions the sweep for ip from scratch
+func (s *sweep) seek(ip netip.Addr) {
+	s.seeked = true
+	s.curKey, s.curVal, s.cur = nil, nil, nil
+
+	found := s.iter.Seek(ipcodec.EncodeRangeKey(ip))
+	if found {
+		if start, err := ipcodec.DecodeRangeKey(s.iter.Key()); err == nil && start == ip {
+			s.take()
+			s.iter.Next()
+			return
+		}
+	}
+
+	// The record before the first starting after ip
+	var ok bool
+	if found {
+		ok = s.iter.Prev()
+	} else {
+		ok = s.iter.Last()
+	}
+	if !ok {
+		s.iter.First()
+		return
+	}
+	s.take()
+	s.iter.Next()
+}
+
+// take makes the record at the iterator cur
+func (s *sweep) take() {
+	s.curKey = append(s.curKey[:0], s.iter.Key()...)
+	s.curVal = append(s.curVal[:0], s.iter.Value()...)
+	s.cur = nil
+}
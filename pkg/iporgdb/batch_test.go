@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// newBatchTestDB stores n /24 ranges, every other /24 from 10.0.0.0, and
+// two IPv6 ranges
+func newBatchTestDB(tb testing.TB, n int) *DB {
+	tb.Helper()
+	db, err := Open(tb.TempDir())
+	if err != nil {
+		tb.Fatalf("Failed to open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	loader, err := db.NewBulkLoader(BulkLoadOptions{})
+	if err != nil {
+		tb.Fatalf("Failed to create loader: %v", err)
+	}
+	defer loader.Close()
+	for i := range n {
+		start := netip.AddrFrom4([4]byte{10, byte(i >> 7), byte(i << 1), 0})
+		end := netip.AddrFrom4([4]byte{10, byte(i >> 7), byte(i << 1), 255})
+		if err := loader.Add(&model.Record{Start: start, End: end, ASN: i + 1, Prefix: start.String() + "/24", Schema: 1}); err != nil {
+			tb.Fatalf("Failed to add range: %v", err)
+		}
+	}
+	for i, p := range []string{"2001:db8::/48", "2001:db8:2::/48"} {
+		prefix := netip.MustParsePrefix(p)
+		end := prefix.Addr().As16()
+		for j := 6; j < 16; j++ {
+			end[j] = 0xff
+		}
+		if err := loader.Add(&model.Record{Start: prefix.Addr(), End: netip.AddrFrom16(end), ASN: 64500 + i, Prefix: p, Schema: 1}); err != nil {
+			tb.Fatalf("Failed to add range: %v", err)
+		}
+	}
+	if _, err := loader.Commit(); err != nil {
+		tb.Fatalf("Failed to commit: %v", err)
+	}
+	// Lookups read table files, as in a built database, not the memtable
+	if err := db.CompactDB(context.Background()); err != nil {
+		tb.Fatalf("Failed to compact: %v", err)
+	}
+	return db
+}
+
+func TestLookupBatch(t *testing.T) {
+	db := newBatchTestDB(t, 1000)
+
+	ips := []netip.Addr{
+		netip.MustParseAddr("10.0.0.0"), // Start of the first range
+		netip.MustParseAddr("10.0.1.1"), // Gap
+		netip.MustParseAddr("9.255.255.255"),
+		netip.MustParseAddr("10.0.0.255"), // End of the first range
+		{},                                // Invalid
+		netip.MustParseAddr("2001:db8:1::1"),
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("10.15.206.7"), // Last range
+		netip.MustParseAddr("10.15.207.0"),
+		netip.MustParseAddr("2001:db8:2::1"),
+		netip.MustParseAddr("10.0.0.1"), // Duplicate range
+	}
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 2000 {
+		ips = append(ips, netip.AddrFrom4([4]byte{10, byte(rng.IntN(17)), byte(rng.IntN(256)), byte(rng.IntN(256))}))
+	}
+
+	results, err := db.LookupBatch(ips)
+	if err != nil {
+		t.Fatalf("LookupBatch failed: %v", err)
+	}
+	if len(results) != len(ips) {
+		t.Fatalf("got %d results, want %d", len(results), len(ips))
+	}
+	found := 0
+	for i, ip := range ips {
+		want, err := db.GetByIP(ip)
+		if err != nil && !errors.Is(err, model.ErrNotFound) && !errors.Is(err, model.ErrInvalidIP) {
+			t.Fatalf("GetByIP(%v) failed: %v", ip, err)
+		}
+		got := results[i]
+		if (got == nil) != (want == nil) || (got != nil && (got.Start != want.Start || got.ASN != want.ASN)) {
+			t.Errorf("%v: got %+v, want %+v", ip, got, want)
+		}
+		if got != nil {
+			found++
+		}
+	}
+	if found == 0 || found == len(ips) {
+		t.Errorf("found %d of %d; want some found and some not", found, len(ips))
+	}
+
+	// Results are copies, so changing one doesn't change another
+	results[0].ASN = 0
+	if results[3].ASN != 1 {
+		t.Errorf("got ASN %d for a shared range, want 1", results[3].ASN)
+	}
+
+	db.Close()
+	if _, err := db.LookupBatch(ips); err != model.ErrDatabaseClosed {
+		t.Errorf("closed database: got %v, want %v", err, model.ErrDatabaseClosed)
+	}
+}
+
+// benchmarkIPs returns n sorted IPs spread over the ranges of newBatchTestDB
+func benchmarkIPs(n, ranges int) []netip.Addr {
+	rng := rand.New(rand.NewPCG(1, 2))
+	ips := make([]netip.Addr, n)
+	for i := range ips {
+		r := rng.IntN(ranges)
+		ips[i] = netip.AddrFrom4([4]byte{10, byte(r >> 7), byte(r<<1) + byte(rng.IntN(2)), byte(rng.IntN(256))})
+	}
+	slices.SortFunc(ips, netip.Addr.Compare)
+	return ips
+}
+
+// BenchmarkGetByIPSorted benchmarks individual lookups of a sorted list
+func BenchmarkGetByIPSorted(b *testing.B) {
+	db := newBatchTestDB(b, 20000)
+	ips := benchmarkIPs(10000, 20000)
+
+	b.ResetTimer()
+	for range b.N {
+		for _, ip := range ips {
+			if _, err := db.GetByIP(ip); err != nil && !errors.Is(err, model.ErrNotFound) {
+				b.Fatalf("GetByIP failed: %v", err)
+			}
+		}
+	}
+}
+
+// BenchmarkLookupBatchSorted benchmarks a batch lookup of the same list
+func BenchmarkLookupBatchSorted(b *testing.B) {
+	db := newBatchTestDB(b, 20000)
+	ips := benchmarkIPs(10000, 20000)
+
+	b.ResetTimer()
+	for range b.N {
+		if _, err := db.LookupBatch(ips); err != nil {
+			b.Fatalf("LookupBatch failed: %v", err)
+		}
+	}
+}
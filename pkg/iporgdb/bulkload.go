@@ -0,0 +1,729 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// BulkLoadOptions configures a BulkLoader
+type BulkLoadOptions struct {
+	TempDir      string // Directory for sorted runs (default: os.TempDir())
+	RunSize      int    // Records held in memory before spilling a run (default: 100000)
+	BatchSize    int    // Records per WriteBatch (default: 10000)
+	KeepExisting bool   // Resolve existing range records together with the new ones
+
+	// OnConflict is called when two overlapping records have the same
+	// prefix length; kept is the record that wins the overlap
+	OnConflict func(kept, dropped *model.Record)
+}
+
+// LoadStats summarises a bulk load
+type LoadStats struct {
+	Added      int64 // Records added (including existing records if kept)
+	Runs       int   // Sorted runs spilled to disk
+	Duplicates int64 // Identical records dropped
+	Conflicts  int64 // Same-length overlaps
+	Shadowed   int64 // Records fully covered by more specific records
+	Trimmed    int64 // Records cut around more specific records
+	Removed    int64 // Existing range records deleted or overwritten
+	Written    int64 // Range records in the result
+}
+
+// BulkLoader collects records and loads them in a single sorted pass.
+// Overlaps are resolved deterministically: the record with the longest
+// prefix wins each address, so the result does not depend on the order in
+// which records were added. Commit replaces all range records in the
+// database, resolving everything before it writes, so a failed commit
+// leaves the existing records in place.
+type BulkLoader struct {
+	db   *DB
+	opts BulkLoadOptions
+	dir  string
+
+	mu    sync.Mutex
+	buf   []*loadEntry
+	runs  []string
+	stats LoadStats
+}
+
+// loadEntry is a record with its encoded form
+type loadEntry struct {
+	key  []byte // EncodeRangeKey(start)
+	val  []byte // encodeRecord(rec)
+	rec  *model.Record
+	bits int // Prefix length of rec.Prefix, used as specificity
+
+	emitted int  // Pieces started during resolution
+	trimmed bool // Written with a narrower range than added
+}
+
+// NewBulkLoader creates a loader that writes into d on Commit
+func (d *DB) NewBulkLoader(opts BulkLoadOptions) (*BulkLoader, error) {
+	if opts.RunSize <= 0 {
+		opts.RunSize = 100000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10000
+	}
+
+	dir, err := os.MkdirTemp(opts.TempDir, "iporg-bulkload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	return &BulkLoader{
+		db:   d,
+		opts: opts,
+		dir:  dir,
+		buf:  make([]*loadEntry, 0, opts.RunSize),
+	}, nil
+}
+
+// Add queues a record. It is safe for concurrent use.
+func (l *BulkLoader) Add(rec *model.Record) error {
+	if !rec.Start.IsValid() || !rec.End.IsValid() || rec.Start.Is4() != rec.End.Is4() {
+		return model.ErrInvalidRange
+	}
+	if rec.Start.Compare(rec.End) > 0 {
+		return fmt.Errorf("%w: start %v > end %v", model.ErrInvalidRange, rec.Start, rec.End)
+	}
+
+	val, err := encodeRecord(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.add(ipcodec.EncodeRangeKey(rec.Start), val)
+}
+
+// add buffers an encoded record, spilling a run when the buffer is full.
+// Caller holds l.mu.
+func (l *BulkLoader) add(key, val []byte) error {
+	l.buf = append(l.buf, &loadEntry{key: key, val: val})
+	l.stats.Added++
+	if len(l.buf) >= l.opts.RunSize {
+		return l.spill()
+	}
+	return nil
+}
+
+// spill sorts the buffer and writes it to a run file
+func (l *BulkLoader) spill() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	sort.Slice(l.buf, func(i, j int) bool { return entryLess(l.buf[i], l.buf[j]) })
+
+	path := filepath.Join(l.dir, fmt.Sprintf("run-%06d", len(l.runs)))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create run: %w", err)
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+	for _, e := range l.buf {
+		if err := writeRunEntry(w, e); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write run: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+
+	l.runs = append(l.runs, path)
+	l.stats.Runs++
+	l.buf = l.buf[:0]
+	return nil
+}
+
+// Commit resolves overlaps and replaces the range records in the database
+func (l *BulkLoader) Commit() (*LoadStats, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.opts.KeepExisting {
+		err := l.scanRanges(func(key, val []byte) error {
+			return l.add(append([]byte(nil), key...), append([]byte(nil), val...))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing ranges: %w", err)
+		}
+	}
+	if err := l.spill(); err != nil {
+		return nil, err
+	}
+
+	resolved, err := l.resolve()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.apply(resolved); err != nil {
+		return nil, fmt.Errorf("failed to write ranges: %w", err)
+	}
+
+	stats := l.stats
+	return &stats, nil
+}
+
+// resolve merges the runs into a single run of non-overlapping records
+func (l *BulkLoader) resolve() (string, error) {
+	merged, err := openMerge(l.runs)
+	if err != nil {
+		return "", err
+	}
+	defer merged.close()
+
+	path := filepath.Join(l.dir, "resolved")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create run: %w", err)
+	}
+	defer f.Close()
+	w := &runWriter{w: bufio.NewWriterSize(f, 1<<20)}
+
+	r := &resolver{loader: l, out: w}
+	for {
+		e, err := merged.next()
+		if err != nil {
+			return "", fmt.Errorf("failed to read runs: %w", err)
+		}
+		if e == nil {
+			break
+		}
+		if err := r.push(e); err != nil {
+			return "", err
+		}
+	}
+	if err := r.finish(); err != nil {
+		return "", err
+	}
+	if err := w.w.Flush(); err != nil {
+		return "", fmt.Errorf("failed to write run: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write run: %w", err)
+	}
+
+	l.stats.Written = w.written
+	return path, nil
+}
+
+// apply brings the range records in line with a resolved run. Existing
+// records are walked alongside it, so each batch holds the deletes and
+// puts for one key span and readers never see a span emptied.
+func (l *BulkLoader) apply(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open run: %w", err)
+	}
+	defer f.Close()
+	r := bufio.NewReaderSize(f, 1<<20)
+
+	existing := l.newRangeCursor()
+	defer existing.release()
+
+	w := &batchWriter{db: l.db, size: l.opts.BatchSize}
+	for {
+		e, err := readRunEntry(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		for existing.valid() && bytes.Compare(existing.key, e.key) < 0 {
+			if err := w.delete(existing.key); err != nil {
+				return err
+			}
+			l.stats.Removed++
+			existing.next()
+		}
+		if existing.valid() && bytes.Equal(existing.key, e.key) {
+			same := bytes.Equal(existing.val, e.val)
+			existing.next()
+			if same {
+				continue
+			}
+			l.stats.Removed++
+		}
+		if err := w.put(e.key, e.val); err != nil {
+			return err
+		}
+	}
+	for ; existing.valid(); existing.next() {
+		if err := w.delete(existing.key); err != nil {
+			return err
+		}
+		l.stats.Removed++
+	}
+	if err := existing.err; err != nil {
+		return fmt.Errorf("failed to read existing ranges: %w", err)
+	}
+	return w.flush()
+}
+
+// Close removes the loader's temporary files
+func (l *BulkLoader) Close() error {
+	return os.RemoveAll(l.dir)
+}
+
+// scanRanges calls fn for every range record in the database
+func (l *BulkLoader) scanRanges(fn func(key, val []byte) error) error {
+	for _, prefix := range []string{ipcodec.PrefixRangeV4, ipcodec.PrefixRangeV6} {
+		iter := l.db.NewIterator(util.BytesPrefix([]byte(prefix)))
+		for iter.Next() {
+			if err := fn(iter.Key(), iter.Value()); err != nil {
+				iter.Release()
+				return err
+			}
+		}
+		iter.Release()
+		if err := iter.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rangeCursor walks the existing range records in key order, IPv4 first.
+// Each iterator reads a snapshot, so the loader's own writes are not seen.
+type rangeCursor struct {
+	iters    []iterator.Iterator
+	key, val []byte
+	err      error
+}
+
+func (l *BulkLoader) newRangeCursor() *rangeCursor {
+	c := &rangeCursor{}
+	for _, prefix := range []string{ipcodec.PrefixRangeV4, ipcodec.PrefixRangeV6} {
+		c.iters = append(c.iters, l.db.NewIterator(util.BytesPrefix([]byte(prefix))))
+	}
+	c.next()
+	return c
+}
+
+func (c *rangeCursor) valid() bool {
+	return c.key != nil
+}
+
+// next moves to the following record, leaving key nil at the end
+func (c *rangeCursor) next() {
+	c.key, c.val = nil, nil
+	for len(c.iters) > 0 {
+		iter := c.iters[0]
+		if iter.Next() {
+			c.key = append([]byte(nil), iter.Key()...)
+			c.val = append([]byte(nil), iter.Value()...)
+			return
+		}
+		if err := iter.Error(); err != nil && c.err == nil {
+			c.err = err
+		}
+		iter.Release()
+		c.iters = c.iters[1:]
+	}
+}
+
+func (c *rangeCursor) release() {
+	for _, iter := range c.iters {
+		iter.Release()
+	}
+	c.iters = nil
+}
+
+// entryLess orders entries by key, then by encoded value
+func entryLess(a, b *loadEntry) bool {
+	if c := bytes.Compare(a.key, b.key); c != 0 {
+		return c < 0
+	}
+	return bytes.Compare(a.val, b.val) < 0
+}
+
+// beats reports whether a takes precedence over b where they overlap:
+// longer prefix, then smaller range, then encoded bytes
+func (a *loadEntry) beats(b *loadEntry) bool {
+	if a.bits != b.bits {
+		return a.bits > b.bits
+	}
+	if c := a.rec.End.Compare(b.rec.End); c != 0 {
+		return c < 0
+	}
+	if c := a.rec.Start.Compare(b.rec.Start); c != 0 {
+		return c > 0
+	}
+	return bytes.Compare(a.val, b.val) < 0
+}
+
+// Run file format: uvarint key length, key, uvarint value length, value
+
+func writeRunEntry(w *bufio.Writer, e *loadEntry) error {
+	var lenBuf [binary.MaxVarintLen64]byte
+	for _, b := range [][]byte{e.key, e.val} {
+		n := binary.PutUvarint(lenBuf[:], uint64(len(b)))
+		if _, err := w.Write(lenBuf[:n]); err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRunEntry(r *bufio.Reader) (*loadEntry, error) {
+	var parts [2][]byte
+	for i := range parts {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			if i == 0 && err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("truncated run: %w", err)
+		}
+		parts[i] = make([]byte, n)
+		if _, err := io.ReadFull(r, parts[i]); err != nil {
+			return nil, fmt.Errorf("truncated run: %w", err)
+		}
+	}
+	return &loadEntry{key: parts[0], val: parts[1]}, nil
+}
+
+// runReader is one input of the k-way merge
+type runReader struct {
+	f    *os.File
+	r    *bufio.Reader
+	head *loadEntry
+}
+
+// mergeHeap orders run readers by their head entry
+type mergeHeap []*runReader
+
+func (h mergeHeap) Len() int           { return len(h) }
+func (h mergeHeap) Less(i, j int) bool { return entryLess(h[i].head, h[j].head) }
+func (h mergeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)        { *h = append(*h, x.(*runReader)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// runMerger merges sorted runs into a single sorted stream
+type runMerger struct {
+	heap  mergeHeap
+	files []*os.File
+}
+
+func openMerge(paths []string) (*runMerger, error) {
+	m := &runMerger{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			m.close()
+			return nil, fmt.Errorf("failed to open run: %w", err)
+		}
+		m.files = append(m.files, f)
+
+		rr := &runReader{f: f, r: bufio.NewReaderSize(f, 1<<20)}
+		head, err := readRunEntry(rr.r)
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			m.close()
+			return nil, err
+		}
+		rr.head = head
+		m.heap = append(m.heap, rr)
+	}
+	heap.Init(&m.heap)
+	return m, nil
+}
+
+// next returns the next entry, or nil at the end
+func (m *runMerger) next() (*loadEntry, error) {
+	if len(m.heap) == 0 {
+		return nil, nil
+	}
+	rr := m.heap[0]
+	e := rr.head
+
+	head, err := readRunEntry(rr.r)
+	switch {
+	case err == io.EOF:
+		heap.Pop(&m.heap)
+	case err != nil:
+		return nil, err
+	default:
+		rr.head = head
+		heap.Fix(&m.heap, 0)
+	}
+	return e, nil
+}
+
+func (m *runMerger) close() {
+	for _, f := range m.files {
+		f.Close()
+	}
+}
+
+// activeHeap holds the records covering the sweep position, highest precedence first
+type activeHeap []*loadEntry
+
+func (h activeHeap) Len() int           { return len(h) }
+func (h activeHeap) Less(i, j int) bool { return h[i].beats(h[j]) }
+func (h activeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *activeHeap) Push(x any)        { *h = append(*h, x.(*loadEntry)) }
+func (h *activeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// resolver sweeps records in start order and emits non-overlapping pieces
+type resolver struct {
+	loader *BulkLoader
+	out    *runWriter
+
+	active activeHeap
+	pos    netip.Addr // First address not yet emitted
+	done   bool       // The end of the address space was emitted
+	prev   *loadEntry // Previous entry, for duplicate detection
+
+	// Pending output piece, extended while the owner stays the same
+	owner      *loadEntry
+	start, end netip.Addr
+}
+
+// push adds the next record in key order
+func (r *resolver) push(e *loadEntry) error {
+	if r.prev != nil && bytes.Equal(e.key, r.prev.key) && bytes.Equal(e.val, r.prev.val) {
+		r.loader.stats.Duplicates++
+		return nil
+	}
+	r.prev = e
+
+	rec, err := decodeRecord(e.key[len(ipcodec.PrefixRangeV4):], e.val)
+	if err != nil {
+		return fmt.Errorf("failed to decode record: %w", err)
+	}
+	e.rec = rec
+	e.bits = getPrefixLen(rec.Prefix)
+
+	// Finish the previous address family before starting the next
+	if r.pos.IsValid() && r.pos.Is4() != rec.Start.Is4() {
+		if err := r.finish(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.advance(rec.Start, false); err != nil {
+		return err
+	}
+	if len(r.active) == 0 {
+		r.pos = rec.Start
+		r.done = false
+	}
+
+	for _, a := range r.active {
+		if a.bits == e.bits && a.rec.End.Compare(rec.Start) >= 0 {
+			r.conflict(a, e)
+		}
+	}
+	heap.Push(&r.active, e)
+	return nil
+}
+
+// conflict reports a same-length overlap
+func (r *resolver) conflict(a, b *loadEntry) {
+	r.loader.stats.Conflicts++
+	if r.loader.opts.OnConflict == nil {
+		return
+	}
+	if a.beats(b) {
+		r.loader.opts.OnConflict(a.rec, b.rec)
+	} else {
+		r.loader.opts.OnConflict(b.rec, a.rec)
+	}
+}
+
+// advance emits pieces up to (but not including) to, or everything if final
+func (r *resolver) advance(to netip.Addr, final bool) error {
+	for {
+		r.popExpired()
+		if len(r.active) == 0 || r.done {
+			return nil
+		}
+		top := r.active[0]
+
+		if final || top.rec.End.Less(to) {
+			if err := r.emit(top, r.pos, top.rec.End); err != nil {
+				return err
+			}
+			if !top.rec.End.Next().IsValid() {
+				r.done = true
+				continue
+			}
+			r.pos = top.rec.End.Next()
+			continue
+		}
+
+		if r.pos.Less(to) {
+			if err := r.emit(top, r.pos, to.Prev()); err != nil {
+				return err
+			}
+			r.pos = to
+		}
+		return nil
+	}
+}
+
+// popExpired removes records that end before the sweep position
+func (r *resolver) popExpired() {
+	for len(r.active) > 0 {
+		top := r.active[0]
+		if !r.done && !top.rec.End.Less(r.pos) {
+			return
+		}
+		heap.Pop(&r.active)
+		if top.emitted == 0 {
+			r.loader.stats.Shadowed++
+		}
+	}
+}
+
+// emit extends the pending piece or starts a new one
+func (r *resolver) emit(owner *loadEntry, start, end netip.Addr) error {
+	if r.owner == owner && r.end.Next() == start {
+		r.end = end
+		return nil
+	}
+	if err := r.flush(); err != nil {
+		return err
+	}
+	r.owner, r.start, r.end = owner, start, end
+	owner.emitted++
+	return nil
+}
+
+// flush writes the pending piece
+func (r *resolver) flush() error {
+	if r.owner == nil {
+		return nil
+	}
+	owner := r.owner
+	r.owner = nil
+
+	if r.start == owner.rec.Start && r.end == owner.rec.End {
+		return r.out.put(owner.key, owner.val)
+	}
+
+	// Record cut around a more specific one
+	if !owner.trimmed {
+		owner.trimmed = true
+		r.loader.stats.Trimmed++
+	}
+	piece := *owner.rec
+	piece.Start = r.start
+	piece.End = r.end
+	val, err := encodeRecord(&piece)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+	return r.out.put(ipcodec.EncodeRangeKey(r.start), val)
+}
+
+// finish emits all remaining pieces
+func (r *resolver) finish() error {
+	if err := r.advance(netip.Addr{}, true); err != nil {
+		return err
+	}
+	r.popExpired()
+	r.active = r.active[:0]
+	r.pos = netip.Addr{}
+	r.done = false
+	return r.flush()
+}
+
+// runWriter writes resolved records to a run file
+type runWriter struct {
+	w       *bufio.Writer
+	written int64
+}
+
+func (w *runWriter) put(key, val []byte) error {
+	w.written++
+	if err := writeRunEntry(w.w, &loadEntry{key: key, val: val}); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+// batchWriter writes puts and deletes in fixed-size batches
+type batchWriter struct {
+	db    *DB
+	size  int
+	batch leveldb.Batch
+}
+
+func (w *batchWriter) put(key, val []byte) error {
+	w.batch.Put(key, val)
+	if w.batch.Len() >= w.size {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *batchWriter) delete(key []byte) error {
+	w.batch.Delete(key)
+	if w.batch.Len() >= w.size {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *batchWriter) flush() error {
+	if w.batch.Len() == 0 {
+		return nil
+	}
+
+	w.db.mu.RLock()
+	defer w.db.mu.RUnlock()
+	if w.db.closed {
+		return model.ErrDatabaseClosed
+	}
+	if err := w.db.db.Write(&w.batch, nil); err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+	w.db.invalidate()
+	w.batch.Reset()
+	return nil
+}
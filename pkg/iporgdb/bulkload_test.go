@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// testRecord builds a record covering a whole CIDR
+func testRecord(cidr, org string) *model.Record {
+	start, end, _ := ipcodec.CIDRToRange(cidr)
+	return &model.Record{Start: start, End: end, Prefix: cidr, OrgName: org, Schema: 1}
+}
+
+// dumpRanges renders all range records as "start-end:org" lines
+func dumpRanges(t *testing.T, db *DB) string {
+	t.Helper()
+	var lines []string
+	for _, v4 := range []bool{true, false} {
+		err := db.IterateRanges(v4, func(rec *model.Record) error {
+			lines = append(lines, fmt.Sprintf("%s-%s:%s", rec.Start, rec.End, rec.OrgName))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("IterateRanges failed: %v", err)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func bulkLoad(t *testing.T, db *DB, opts BulkLoadOptions, recs ...*model.Record) *LoadStats {
+	t.Helper()
+	loader, err := db.NewBulkLoader(opts)
+	if err != nil {
+		t.Fatalf("NewBulkLoader failed: %v", err)
+	}
+	defer loader.Close()
+
+	for _, rec := range recs {
+		if err := loader.Add(rec); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	stats, err := loader.Commit()
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	return stats
+}
+
+func TestBulkLoadMostSpecificWins(t *testing.T) {
+	recs := []*model.Record{
+		testRecord("10.0.0.0/16", "Parent"),
+		testRecord("10.0.1.0/24", "Child A"),
+		testRecord("10.0.3.0/24", "Child B"),
+		testRecord("10.0.3.128/25", "Grandchild"),
+		testRecord("10.0.255.0/24", "Child C"),
+		testRecord("192.0.2.0/24", "Other"),
+		testRecord("2001:db8::/32", "IPv6"),
+	}
+	want := strings.Join([]string{
+		"10.0.0.0-10.0.0.255:Parent",
+		"10.0.1.0-10.0.1.255:Child A",
+		"10.0.2.0-10.0.2.255:Parent",
+		"10.0.3.0-10.0.3.127:Child B",
+		"10.0.3.128-10.0.3.255:Grandchild",
+		"10.0.4.0-10.0.254.255:Parent",
+		"10.0.255.0-10.0.255.255:Child C",
+		"192.0.2.0-192.0.2.255:Other",
+		"2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff:IPv6",
+	}, "\n")
+
+	// Input order and run size must not change the result
+	orders := map[string][]int{
+		"forward": {0, 1, 2, 3, 4, 5, 6},
+		"reverse": {6, 5, 4, 3, 2, 1, 0},
+		"mixed":   {3, 0, 5, 1, 6, 4, 2},
+	}
+	for name, order := range orders {
+		for _, runSize := range []int{0, 2} {
+			t.Run(fmt.Sprintf("%s/run%d", name, runSize), func(t *testing.T) {
+				tmpDir, err := os.MkdirTemp("", "iporgdb-test-*")
+				if err != nil {
+					t.Fatalf("Failed to create temp dir: %v", err)
+				}
+				defer os.RemoveAll(tmpDir)
+
+				db, err := Open(tmpDir)
+				if err != nil {
+					t.Fatalf("Failed to open database: %v", err)
+				}
+				defer db.Close()
+
+				var input []*model.Record
+				for _, i := range order {
+					input = append(input, recs[i])
+				}
+				stats := bulkLoad(t, db, BulkLoadOptions{TempDir: tmpDir, RunSize: runSize}, input...)
+
+				if got := dumpRanges(t, db); got != want {
+					t.Errorf("got ranges:\n%s\nwant:\n%s", got, want)
+				}
+				if stats.Written != 9 || stats.Trimmed != 2 || stats.Conflicts != 0 {
+					t.Errorf("got written=%d trimmed=%d conflicts=%d, want 9/2/0",
+						stats.Written, stats.Trimmed, stats.Conflicts)
+				}
+
+				rec, err := db.GetByIP(netip.MustParseAddr("10.0.3.200"))
+				if err != nil || rec.OrgName != "Grandchild" || rec.Prefix != "10.0.3.128/25" {
+					t.Errorf("got %+v (err %v), want Grandchild", rec, err)
+				}
+			})
+		}
+	}
+}
+
+func TestBulkLoadConflictsAndDuplicates(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "iporgdb-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var conflicts []string
+	opts := BulkLoadOptions{
+		TempDir: tmpDir,
+		OnConflict: func(kept, dropped *model.Record) {
+			conflicts = append(conflicts, kept.OrgName+">"+dropped.OrgName)
+		},
+	}
+
+	stats := bulkLoad(t, db, opts,
+		testRecord("192.0.2.0/24", "Same"),
+		testRecord("192.0.2.0/24", "Same"),
+		testRecord("198.51.100.0/24", "Beta"),
+		testRecord("198.51.100.0/24", "Alpha"),
+	)
+
+	if stats.Duplicates != 1 {
+		t.Errorf("got %d duplicates, want 1", stats.Duplicates)
+	}
+	if stats.Conflicts != 1 || len(conflicts) != 1 {
+		t.Fatalf("got %d conflicts (%v), want 1", stats.Conflicts, conflicts)
+	}
+
+	// The winner is chosen by encoded bytes, so it is stable across runs
+	want := "192.0.2.0-192.0.2.255:Same\n198.51.100.0-198.51.100.255:" + strings.SplitN(conflicts[0], ">", 2)[0]
+	if got := dumpRanges(t, db); got != want {
+		t.Errorf("got ranges:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBulkLoadReplacesAndKeepsExisting(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "iporgdb-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.PutRange(testRecord("203.0.113.0/24", "Old")); err != nil {
+		t.Fatalf("PutRange failed: %v", err)
+	}
+
+	// KeepExisting resolves the old record together with the new ones
+	stats := bulkLoad(t, db, BulkLoadOptions{TempDir: tmpDir, KeepExisting: true},
+		testRecord("203.0.113.64/26", "New"))
+	want := "203.0.113.0-203.0.113.63:Old\n203.0.113.64-203.0.113.127:New\n203.0.113.128-203.0.113.255:Old"
+	if got := dumpRanges(t, db); got != want {
+		t.Errorf("got ranges:\n%s\nwant:\n%s", got, want)
+	}
+	if stats.Removed != 1 {
+		t.Errorf("got %d removed, want 1", stats.Removed)
+	}
+
+	// Without it, existing ranges are replaced
+	bulkLoad(t, db, BulkLoadOptions{TempDir: tmpDir}, testRecord("192.0.2.0/24", "Only"))
+	if got := dumpRanges(t, db); got != "192.0.2.0-192.0.2.255:Only" {
+		t.Errorf("got ranges:\n%s\nwant only the new record", got)
+	}
+}
+
+func TestBulkLoadFailedCommitKeepsExisting(t *testing.T) {
+	tmpDir := t.TempDir()
+	db, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	bulkLoad(t, db, BulkLoadOptions{TempDir: tmpDir},
+		testRecord("192.0.2.0/24", "Old"), testRecord("198.51.100.0/24", "Old"), testRecord("2001:db8::/32", "Old"))
+	before := dumpRanges(t, db)
+
+	// A run that can't be read fails the commit before anything is written
+	loader, err := db.NewBulkLoader(BulkLoadOptions{TempDir: tmpDir, RunSize: 1, BatchSize: 1})
+	if err != nil {
+		t.Fatalf("NewBulkLoader failed: %v", err)
+	}
+	defer loader.Close()
+	for _, cidr := range []string{"192.0.2.0/25", "203.0.113.0/24"} {
+		if err := loader.Add(testRecord(cidr, "New")); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if err := os.Truncate(loader.runs[len(loader.runs)-1], 3); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if _, err := loader.Commit(); err == nil {
+		t.Fatalf("Commit of a truncated run succeeded")
+	}
+	if got := dumpRanges(t, db); got != before {
+		t.Errorf("got ranges:\n%s\nwant:\n%s", got, before)
+	}
+
+	// Unchanged records are left alone; the rest are replaced or deleted
+	stats := bulkLoad(t, db, BulkLoadOptions{TempDir: tmpDir, BatchSize: 1},
+		testRecord("192.0.2.0/24", "Old"), testRecord("198.51.100.0/24", "New"))
+	want := "192.0.2.0-192.0.2.255:Old\n198.51.100.0-198.51.100.255:New"
+	if got := dumpRanges(t, db); got != want {
+		t.Errorf("got ranges:\n%s\nwant:\n%s", got, want)
+	}
+	if stats.Removed != 2 || stats.Written != 2 {
+		t.Errorf("got %d removed, %d written; want 2, 2", stats.Removed, stats.Written)
+	}
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"container/list"
+	"hash/maphash"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// cacheShards is the number of independently locked parts of the cache
+const cacheShards = 32
+
+// recordCache is a sharded LRU of lookup results keyed by IP. Results are
+// stored before overrides are applied, so expiring overrides stay correct.
+type recordCache struct {
+	seed   maphash.Seed
+	gen    atomic.Uint64 // Bumped by clear, so in-flight lookups don't store stale results
+	shards [cacheShards]cacheShard
+}
+
+type cacheShard struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // Front is most recently used
+	items map[netip.Addr]*list.Element
+}
+
+type cacheEntry struct {
+	ip  netip.Addr
+	rec *model.Record // nil if the IP is not in the database
+}
+
+// newRecordCache creates a cache of about size entries
+func newRecordCache(size int) *recordCache {
+	c := &recordCache{seed: maphash.MakeSeed()}
+	per := max(size/cacheShards, 1)
+	for i := range c.shards {
+		c.shards[i] = cacheShard{size: per, order: list.New(), items: make(map[netip.Addr]*list.Element)}
+	}
+	return c
+}
+
+func (c *recordCache) shard(ip netip.Addr) *cacheShard {
+	b := ip.As16()
+	return &c.shards[maphash.Bytes(c.seed, b[:])%cacheShards]
+}
+
+// get returns the cached result for ip and whether there was one
+func (c *recordCache) get(ip netip.Addr) (rec *model.Record, ok bool) {
+	s := c.shard(ip)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.items[ip]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).rec, true
+}
+
+// put caches rec for ip unless the cache was cleared since gen was read
+func (c *recordCache) put(gen uint64, ip netip.Addr, rec *model.Record) {
+	s := c.shard(ip)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.gen.Load() != gen {
+		return
+	}
+	if e, ok := s.items[ip]; ok {
+		e.Value.(*cacheEntry).rec = rec
+		s.order.MoveToFront(e)
+		return
+	}
+	s.items[ip] = s.order.PushFront(&cacheEntry{ip: ip, rec: rec})
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*cacheEntry).ip)
+	}
+}
+
+// clear removes every entry
+func (c *recordCache) clear() {
+	c.gen.Add(1)
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		s.order.Init()
+		clear(s.items)
+		s.mu.Unlock()
+	}
+}
+
+// len returns the number of entries
+func (c *recordCache) len() int {
+	n := 0
+	for i := range c.shards {
+		s := &c.shards[i]
+		s.mu.Lock()
+		n += s.order.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// SetCacheSize enables an in-process LRU of about size GetByIP results,
+// keyed by IP, or disables it if size is 0. The cache is cleared whenever
+// range records are written and on Reload.
+func (d *DB) SetCacheSize(size int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if size <= 0 {
+		d.cache = nil
+		return
+	}
+	d.cache = newRecordCache(size)
+}
+
+// invalidate clears the cache, if any. Caller must hold d.mu.
+func (d *DB) invalidate() {
+	if d.cache != nil {
+		d.cache.clear()
+	}
+}
@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
+)
+
+func TestRecordCache(t *testing.T) {
+	c := newRecordCache(cacheShards) // One entry per shard
+	a := netip.MustParseAddr("192.0.2.1")
+	rec := &model.Record{ASN: 64500}
+
+	c.put(c.gen.Load(), a, rec)
+	if got, ok := c.get(a); !ok || got != rec {
+		t.Errorf("got %v, %v; want the record", got, ok)
+	}
+
+	// A second address in the same shard evicts the first
+	var b netip.Addr
+	for i := 2; i < 1000; i++ {
+		b = netip.AddrFrom4([4]byte{192, 0, 2, byte(i)})
+		if c.shard(b) == c.shard(a) && b != a {
+			break
+		}
+	}
+	c.put(c.gen.Load(), b, nil)
+	if _, ok := c.get(a); ok {
+		t.Errorf("evicted entry: still cached")
+	}
+	if got, ok := c.get(b); !ok || got != nil {
+		t.Errorf("negative entry: got %v, %v; want nil, true", got, ok)
+	}
+
+	// Results of lookups that started before a clear are not stored
+	gen := c.gen.Load()
+	c.clear()
+	c.put(gen, a, rec)
+	if c.len() != 0 {
+		t.Errorf("got %d entries after clear, want 0", c.len())
+	}
+}
+
+func TestGetByIPCached(t *testing.T) {
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetCacheSize(1000)
+
+	rec := &model.Record{
+		Start: netip.MustParseAddr("203.0.113.0"), End: netip.MustParseAddr("203.0.113.255"),
+		ASN: 64500, OrgName: "Upstream ISP", Prefix: "203.0.113.0/24",
+	}
+	if err := db.PutRange(rec); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+	ip := netip.MustParseAddr("203.0.113.200")
+
+	got, err := db.GetByIP(ip)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	got.OrgName = "Changed by caller"
+	if got, _ := db.GetByIP(ip); got == nil || got.OrgName != "Upstream ISP" {
+		t.Errorf("cached record was modified through a result: got %+v", got)
+	}
+	if _, err := db.GetByIP(netip.MustParseAddr("198.51.100.1")); err != model.ErrNotFound {
+		t.Errorf("unknown address: got %v, want %v", err, model.ErrNotFound)
+	}
+	if n := db.cache.len(); n != 2 {
+		t.Errorf("got %d cached entries, want 2", n)
+	}
+
+	// Overrides apply to cached results
+	set, err := overrides.Parse(strings.NewReader(`{"overrides":[{"cidr":"203.0.113.128/25","org_name":"Our Customer"}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse overrides: %v", err)
+	}
+	db.SetOverrides(set)
+	if got, _ := db.GetByIP(ip); got == nil || got.OrgName != "Our Customer" {
+		t.Errorf("override: got %+v", got)
+	}
+	db.SetOverrides(nil)
+
+	// Writing a range clears the cache; other writes do not
+	if err := db.SetMetadata("test", "value"); err != nil {
+		t.Fatalf("SetMetadata failed: %v", err)
+	}
+	if n := db.cache.len(); n != 2 {
+		t.Errorf("got %d cached entries after a metadata write, want 2", n)
+	}
+	if err := db.DeleteRange(rec.Start); err != nil {
+		t.Fatalf("DeleteRange failed: %v", err)
+	}
+	if _, err := db.GetByIP(ip); err != model.ErrNotFound {
+		t.Errorf("deleted range: got %v, want %v", err, model.ErrNotFound)
+	}
+
+	db.SetCacheSize(0)
+	if db.cache != nil {
+		t.Errorf("cache still enabled after SetCacheSize(0)")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "current")
+	put := func(path string, asn int) {
+		t.Helper()
+		db, err := Open(path)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		defer db.Close()
+		if err := db.PutRange(&model.Record{
+			Start: netip.MustParseAddr("203.0.113.0"), End: netip.MustParseAddr("203.0.113.255"), ASN: asn,
+		}); err != nil {
+			t.Fatalf("Failed to put range: %v", err)
+		}
+	}
+	put(path, 64500)
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetCacheSize(100)
+	ip := netip.MustParseAddr("203.0.113.1")
+	if rec, err := db.GetByIP(ip); err != nil || rec.ASN != 64500 {
+		t.Fatalf("got %v, %v; want AS64500", rec, err)
+	}
+
+	// A rebuilt database moved into place is served after Reload
+	put(filepath.Join(dir, "new"), 64501)
+	if err := os.Rename(path, filepath.Join(dir, "old")); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := os.Rename(filepath.Join(dir, "new"), path); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := db.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if rec, err := db.GetByIP(ip); err != nil || rec.ASN != 64501 {
+		t.Errorf("after reload: got %v, %v; want AS64501", rec, err)
+	}
+
+	// Reloading the same directory reopens it
+	if err := db.Reload(); err != nil {
+		t.Fatalf("Reload of the same directory failed: %v", err)
+	}
+	if rec, err := db.GetByIP(ip); err != nil || rec.ASN != 64501 {
+		t.Errorf("after second reload: got %v, %v; want AS64501", rec, err)
+	}
+
+	// A reload midway through a swap fails without creating an empty
+	// database in place, and the old handle keeps serving
+	put(filepath.Join(dir, "new"), 64502)
+	if err := os.Rename(path, filepath.Join(dir, "older")); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := db.Reload(); err == nil {
+		t.Errorf("Reload of a missing database succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Reload created %s: %v", path, err)
+	}
+	if rec, err := db.GetByIP(ip); err != nil || rec.ASN != 64501 {
+		t.Errorf("after failed reload: got %v, %v; want AS64501", rec, err)
+	}
+	if err := os.Rename(filepath.Join(dir, "new"), path); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if err := db.Reload(); err != nil {
+		t.Fatalf("Reload after swap failed: %v", err)
+	}
+	if rec, err := db.GetByIP(ip); err != nil || rec.ASN != 64502 {
+		t.Errorf("after swap: got %v, %v; want AS64502", rec, err)
+	}
+}
+
+func TestReloadRecovers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "current")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	rec := &model.Record{
+		Start: netip.MustParseAddr("203.0.113.0"), End: netip.MustParseAddr("203.0.113.255"), ASN: 64500,
+	}
+	if err := db.PutRange(rec); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+
+	// A directory that can't be reopened leaves the database closed
+	current := filepath.Join(path, "CURRENT")
+	manifest, err := os.ReadFile(current)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if err := os.WriteFile(current, []byte("garbage\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := db.Reload(); err == nil {
+		t.Fatalf("Reload of a corrupt database succeeded")
+	}
+	if !db.IsClosed() {
+		t.Errorf("database open after failed reload")
+	}
+
+	// A later Reload reopens it once the directory is fixed
+	if err := os.WriteFile(current, manifest, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := db.Reload(); err != nil {
+		t.Fatalf("Reload after repair failed: %v", err)
+	}
+	if got, err := db.GetByIP(netip.MustParseAddr("203.0.113.1")); err != nil || got.ASN != 64500 {
+		t.Errorf("after recovery: got %v, %v; want AS64500", got, err)
+	}
+
+	// Close is final
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := db.Reload(); err != model.ErrDatabaseClosed {
+		t.Errorf("Reload after Close: got %v, want %v", err, model.ErrDatabaseClosed)
+	}
+}
@@ -6,6 +6,8 @@ package iporgdb
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,35 +18,101 @@ import (
 	"github.com/vmihailenco/msgpack/v5"
 
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
 	"github.com/wingedpig/iporg/pkg/util/ipcodec"
 )
 
 // DB wraps a LevelDB instance for IP organization data
 type DB struct {
-	db     *leveldb.DB
-	mu     sync.RWMutex
-	path   string
-	closed bool
+	db        *leveldb.DB
+	mu        sync.RWMutex
+	path      string
+	closed    bool
+	reloading bool           // Closed by a failed Reload, which may still retry
+	dir       os.FileInfo    // Directory the open handle was opened from
+	overrides *overrides.Set // Optional lookup-time overlay
+	observer  LookupObserver // Optional: notified of every GetByIP
+	cache     *recordCache   // Optional: see SetCacheSize
 }
 
 // Open opens or creates a LevelDB database at the specified path
 func Open(path string) (*DB, error) {
+	db, err := openLevelDB(path, false)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.Stat(path)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	return &DB{
+		db:   db,
+		path: path,
+		dir:  dir,
+	}, nil
+}
+
+func openLevelDB(path string, mustExist bool) (*leveldb.DB, error) {
 	opts := &opt.Options{
 		// Use snappy compression for values
 		Compression: opt.SnappyCompression,
 		// Increase write buffer for faster builds
 		WriteBuffer: 64 * 1024 * 1024, // 64MB
+		// Don't create an empty database in place of one being swapped
+		ErrorIfMissing: mustExist,
 	}
 
 	db, err := leveldb.OpenFile(path, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	return db, nil
+}
 
-	return &DB{
-		db:   db,
-		path: path,
-	}, nil
+// Reload reopens the database at its path, so that a database rebuilt
+// elsewhere and moved into place is served, and clears the cache. A
+// database moved into place is opened before the old handle is closed, so
+// a failed reload keeps serving the old one. Reopening the same directory
+// has to close the old handle first; if that fails the database is closed
+// until a later Reload succeeds.
+func (d *DB) Reload() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed && !d.reloading {
+		return model.ErrDatabaseClosed
+	}
+
+	dir, err := os.Stat(d.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat database: %w", err)
+	}
+
+	// The old handle locks its own directory
+	if !d.closed && os.SameFile(dir, d.dir) {
+		d.closed = true
+		d.reloading = true
+		if err := d.db.Close(); err != nil {
+			return fmt.Errorf("failed to close database: %w", err)
+		}
+	}
+
+	db, err := openLevelDB(d.path, true)
+	if err != nil {
+		return err
+	}
+	if !d.closed {
+		d.db.Close()
+	}
+
+	d.db = db
+	d.dir = dir
+	d.closed = false
+	d.reloading = false
+	d.invalidate()
+	return nil
 }
 
 // Close closes the database
@@ -53,6 +121,11 @@ func (d *DB) Close() error {
 	defer d.mu.Unlock()
 
 	if d.closed {
+		if d.reloading {
+			// Closed by a failed Reload; stop it from reopening
+			d.reloading = false
+			return nil
+		}
 		return model.ErrDatabaseClosed
 	}
 
@@ -100,7 +173,13 @@ func (d *DB) Put(key, value []byte) error {
 		return model.ErrDatabaseClosed
 	}
 
-	return d.db.Put(key, value, nil)
+	if err := d.db.Put(key, value, nil); err != nil {
+		return err
+	}
+	if isRangeKey(key) {
+		d.invalidate()
+	}
+	return nil
 }
 
 // Delete removes a key-value pair
@@ -112,7 +191,13 @@ func (d *DB) Delete(key []byte) error {
 		return model.ErrDatabaseClosed
 	}
 
-	return d.db.Delete(key, nil)
+	if err := d.db.Delete(key, nil); err != nil {
+		return err
+	}
+	if isRangeKey(key) {
+		d.invalidate()
+	}
+	return nil
 }
 
 // NewIterator creates a new iterator
@@ -133,15 +218,29 @@ func (d *DB) WriteBatch(ops []BatchOp) error {
 	}
 
 	batch := new(leveldb.Batch)
+	ranges := false
 	for _, op := range ops {
 		if op.Delete {
 			batch.Delete(op.Key)
 		} else {
 			batch.Put(op.Key, op.Value)
 		}
+		ranges = ranges || isRangeKey(op.Key)
+	}
+
+	if err := d.db.Write(batch, nil); err != nil {
+		return err
 	}
+	if ranges {
+		d.invalidate()
+	}
+	return nil
+}
 
-	return d.db.Write(batch, nil)
+// isRangeKey reports whether key holds a range record
+func isRangeKey(key []byte) bool {
+	s := string(key)
+	return strings.HasPrefix(s, ipcodec.PrefixRangeV4) || strings.HasPrefix(s, ipcodec.PrefixRangeV6)
 }
 
 // BatchOp represents a batch operation
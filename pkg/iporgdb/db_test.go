@@ -7,10 +7,12 @@ import (
 	"context"
 	"net/netip"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
 )
 
 func TestOpenClose(t *testing.T) {
@@ -260,6 +262,30 @@ func TestMetadata(t *testing.T) {
 	if builderVer != "test-123" {
 		t.Errorf("got builder version %s, want test-123", builderVer)
 	}
+
+	// Test build config
+	if cfg, err := db.GetBuildConfig(); err != nil || cfg != nil {
+		t.Errorf("got build config %v (err %v), want nil", cfg, err)
+	}
+	want := &model.BuildConfig{
+		DBPath:      "./iporgdb",
+		Workers:     8,
+		CacheTTL:    36 * time.Hour,
+		MinPrefixV4: 22,
+		Precedence:  map[string][]string{"org": {"ripe_bulk", "maxmind"}},
+		Profile:     "europe-accurate",
+	}
+	if err := db.SetBuildConfig(want); err != nil {
+		t.Fatalf("Failed to set build config: %v", err)
+	}
+	got, err := db.GetBuildConfig()
+	if err != nil {
+		t.Fatalf("Failed to get build config: %v", err)
+	}
+	if got == nil || got.CacheTTL != want.CacheTTL || got.Workers != want.Workers ||
+		got.MinPrefixV4 != want.MinPrefixV4 || got.Profile != want.Profile || len(got.Precedence["org"]) != 2 {
+		t.Errorf("got build config %+v, want %+v", got, want)
+	}
 }
 
 func TestStats(t *testing.T) {
@@ -424,3 +450,209 @@ func TestOverlapMultipleChildren(t *testing.T) {
 		}
 	}
 }
+
+func TestOverridesOverlay(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "iporgdb-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	rec := &model.Record{
+		Start:      netip.MustParseAddr("203.0.113.0"),
+		End:        netip.MustParseAddr("203.0.113.255"),
+		ASN:        64500,
+		OrgName:    "Upstream ISP",
+		Country:    "US",
+		Prefix:     "203.0.113.0/24",
+		SourceRole: "registrant",
+	}
+	if err := db.PutRange(rec); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+
+	set, err := overrides.Parse(strings.NewReader(`{"overrides":[
+		{"cidr":"203.0.113.128/25","org_name":"Our Customer","country":"GB"},
+		{"cidr":"198.51.100.0/24","org_name":"Stale"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse overrides: %v", err)
+	}
+	db.SetOverrides(set)
+
+	got, err := db.GetByIP(netip.MustParseAddr("203.0.113.200"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got.OrgName != "Our Customer" || got.Country != "GB" || got.ASN != 64500 {
+		t.Errorf("got %s/%s/AS%d, want Our Customer/GB/AS64500", got.OrgName, got.Country, got.ASN)
+	}
+
+	// Outside the override the stored record is returned unchanged
+	got, err = db.GetByIP(netip.MustParseAddr("203.0.113.10"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got.OrgName != "Upstream ISP" {
+		t.Errorf("got org %s, want Upstream ISP", got.OrgName)
+	}
+
+	for _, o := range set.Entries() {
+		overlaps, err := db.HasOverlap(o.Prefix())
+		if err != nil {
+			t.Fatalf("HasOverlap(%s) failed: %v", o.Prefix(), err)
+		}
+		want := o.CIDR == "203.0.113.128/25"
+		if overlaps != want {
+			t.Errorf("HasOverlap(%s) = %v, want %v", o.Prefix(), overlaps, want)
+		}
+	}
+
+	// Clearing the overlay restores stored data
+	db.SetOverrides(nil)
+	got, err = db.GetByIP(netip.MustParseAddr("203.0.113.200"))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if got.OrgName != "Upstream ISP" {
+		t.Errorf("got org %s after clearing overlay, want Upstream ISP", got.OrgName)
+	}
+}
+
+func TestLookupObserver(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "iporgdb-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	rec := &model.Record{
+		Start:   netip.MustParseAddr("203.0.113.0"),
+		End:     netip.MustParseAddr("203.0.113.255"),
+		ASN:     64500,
+		OrgName: "Example",
+		Prefix:  "203.0.113.0/24",
+	}
+	if err := db.PutRange(rec); err != nil {
+		t.Fatalf("Failed to put range: %v", err)
+	}
+
+	var errs []error
+	db.SetObserver(func(elapsed time.Duration, err error) {
+		errs = append(errs, err)
+	})
+
+	db.LookupString("203.0.113.1")
+	db.LookupString("198.51.100.1")
+	if len(errs) != 2 {
+		t.Fatalf("got %d observations, want 2", len(errs))
+	}
+	if errs[0] != nil {
+		t.Errorf("got error %v for a found IP, want nil", errs[0])
+	}
+	if errs[1] != model.ErrNotFound {
+		t.Errorf("got error %v, want %v", errs[1], model.ErrNotFound)
+	}
+
+	db.SetObserver(nil)
+	db.LookupString("203.0.113.1")
+	if len(errs) != 2 {
+		t.Errorf("got %d observations after removing the observer, want 2", len(errs))
+	}
+}
+
+func TestLookupPrefix(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "iporgdb-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for _, r := range []struct{ start, end, org string }{
+		{"10.0.0.0", "10.0.0.127", "Low"},
+		{"10.0.0.128", "10.0.1.255", "High"},
+		{"10.0.3.0", "10.0.3.255", "Other"},
+	} {
+		rec := &model.Record{
+			Start:   netip.MustParseAddr(r.start),
+			End:     netip.MustParseAddr(r.end),
+			OrgName: r.org,
+		}
+		if err := db.PutRange(rec); err != nil {
+			t.Fatalf("Failed to put range: %v", err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"10.0.0.0/16", []string{"Low", "High", "Other"}},
+		{"10.0.1.0/24", []string{"High"}}, // Covered by a range starting earlier
+		{"10.0.0.0/24", []string{"Low", "High"}},
+		{"10.0.0.64/26", []string{"Low"}},
+		{"10.0.2.0/24", nil},
+	}
+	for _, tt := range tests {
+		recs, truncated, err := db.LookupPrefix(netip.MustParsePrefix(tt.prefix), 0)
+		if tt.want == nil {
+			if err != model.ErrNotFound {
+				t.Errorf("%s: got error %v, want %v", tt.prefix, err, model.ErrNotFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: lookup failed: %v", tt.prefix, err)
+		}
+		var got []string
+		for _, rec := range recs {
+			got = append(got, rec.OrgName)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") || truncated {
+			t.Errorf("%s: got %v, %v; want %v", tt.prefix, got, truncated, tt.want)
+		}
+	}
+
+	// A limit stops the scan, counting a range that starts earlier
+	for _, tt := range []struct {
+		prefix        string
+		limit         int
+		want          string
+		wantTruncated bool
+	}{
+		{"10.0.0.0/16", 2, "Low,High", true},
+		{"10.0.0.0/16", 3, "Low,High,Other", false},
+		{"10.0.1.0/16", 1, "Low", true},
+		{"10.0.1.0/24", 1, "High", false},
+	} {
+		recs, truncated, err := db.LookupPrefix(netip.MustParsePrefix(tt.prefix), tt.limit)
+		if err != nil {
+			t.Fatalf("%s: lookup failed: %v", tt.prefix, err)
+		}
+		var got []string
+		for _, rec := range recs {
+			got = append(got, rec.OrgName)
+		}
+		if strings.Join(got, ",") != tt.want || truncated != tt.wantTruncated {
+			t.Errorf("%s limit %d: got %v, %v; want %s, %v", tt.prefix, tt.limit, got, truncated, tt.want, tt.wantTruncated)
+		}
+	}
+}
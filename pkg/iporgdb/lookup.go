@@ -4,19 +4,40 @@
 package iporgdb
 
 import (
+	"errors"
 	"fmt"
 	"net/netip"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/wingedpig/iporg/pkg/model"
 	"github.com/wingedpig/iporg/pkg/util/ipcodec"
 )
 
+// LookupObserver is notified of the duration and outcome of every lookup
+type LookupObserver func(elapsed time.Duration, err error)
+
+// SetObserver installs fn to be called after every GetByIP, including those
+// made by LookupString. Pass nil to remove it.
+func (d *DB) SetObserver(fn LookupObserver) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.observer = fn
+}
+
 // GetByIP performs an IP lookup using the seek/prev algorithm
-// Returns the record containing the IP, or ErrNotFound if not found
-func (d *DB) GetByIP(ip netip.Addr) (*model.Record, error) {
+// Returns the record containing the IP, or ErrNotFound if not found.
+// If an overrides overlay is set, matching overrides are applied to the result.
+func (d *DB) GetByIP(ip netip.Addr) (rec *model.Record, err error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
+	if d.observer != nil {
+		start := time.Now()
+		defer func() { d.observer(time.Since(start), err) }()
+	}
+
 	if d.closed {
 		return nil, model.ErrDatabaseClosed
 	}
@@ -25,6 +46,46 @@ func (d *DB) GetByIP(ip netip.Addr) (*model.Record, error) {
 		return nil, model.ErrInvalidIP
 	}
 
+	rec, err = d.cachedLookup(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.overrides != nil {
+		d.overrides.ApplyIP(ip, rec, time.Now())
+	}
+
+	return rec, nil
+}
+
+// cachedLookup is lookup through the cache, if enabled. The caller may
+// modify the record returned. Caller must hold d.mu.
+func (d *DB) cachedLookup(ip netip.Addr) (*model.Record, error) {
+	if d.cache == nil {
+		return d.lookup(ip)
+	}
+	if rec, ok := d.cache.get(ip); ok {
+		if rec == nil {
+			return nil, model.ErrNotFound
+		}
+		r := *rec
+		return &r, nil
+	}
+
+	gen := d.cache.gen.Load()
+	rec, err := d.lookup(ip)
+	switch {
+	case err == nil:
+		r := *rec
+		d.cache.put(gen, ip, &r)
+	case errors.Is(err, model.ErrNotFound):
+		d.cache.put(gen, ip, nil)
+	}
+	return rec, err
+}
+
+// lookup finds the stored record containing ip. Caller must hold d.mu.
+func (d *DB) lookup(ip netip.Addr) (*model.Record, error) {
 	// Determine the key prefix based on IP version
 	var prefix string
 	if ip.Is4() {
@@ -124,6 +185,74 @@ func (d *DB) GetByIP(ip netip.Addr) (*model.Record, error) {
 	return nil, model.ErrNotFound
 }
 
+// LookupPrefix returns the stored records overlapping prefix, in address
+// order, or ErrNotFound if there are none. Overrides covering a whole
+// record are applied to it. If limit is positive at most limit records are
+// read, and truncated reports whether there were more.
+func (d *DB) LookupPrefix(prefix netip.Prefix, limit int) (recs []*model.Record, truncated bool, err error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return nil, false, model.ErrDatabaseClosed
+	}
+	if !prefix.IsValid() {
+		return nil, false, model.ErrInvalidIP
+	}
+
+	prefix = prefix.Masked()
+	start, end, err := ipcodec.CIDRToRange(prefix.String())
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A range starting before the prefix that covers its first address
+	if rec, err := d.lookup(start); err == nil {
+		if rec.Start.Compare(start) < 0 {
+			recs = append(recs, rec)
+		}
+	} else if err != model.ErrNotFound {
+		return nil, false, err
+	}
+
+	// Every range starting inside the prefix
+	iter := d.db.NewIterator(&util.Range{
+		Start: ipcodec.EncodeRangeKey(start),
+		Limit: append(ipcodec.EncodeRangeKey(end), 0x00),
+	}, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if limit > 0 && len(recs) == limit {
+			truncated = true
+			break
+		}
+		startIP, err := ipcodec.DecodeRangeKey(iter.Key())
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid key: %w", err)
+		}
+		rec, err := decodeRecord(ipcodec.IPToBytes(startIP), iter.Value())
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode record: %w", err)
+		}
+		recs = append(recs, rec)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, false, err
+	}
+
+	if len(recs) == 0 {
+		return nil, false, model.ErrNotFound
+	}
+	if d.overrides != nil {
+		now := time.Now()
+		for _, rec := range recs {
+			d.overrides.ApplyRange(rec, now)
+		}
+	}
+	return recs, truncated, nil
+}
+
 // LookupString is a convenience method that parses an IP string and performs lookup
 func (d *DB) LookupString(ipStr string) (*model.Record, error) {
 	ip, err := ipcodec.ParseIP(ipStr)
@@ -7,11 +7,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/syndtr/goleveldb/leveldb/util"
+
 	"github.com/wingedpig/iporg/pkg/model"
 	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 // Metadata keys
@@ -19,6 +21,7 @@ const (
 	metaKeySchema         = "schema"
 	metaKeyBuiltAt        = "built_at"
 	metaKeyBuilderVersion = "builder_version"
+	metaKeyBuildConfig    = "build_config"
 )
 
 // SetMetadata sets a metadata key-value pair
@@ -86,6 +89,31 @@ func (d *DB) GetBuilderVersion() (string, error) {
 	return d.GetMetadata(metaKeyBuilderVersion)
 }
 
+// SetBuildConfig records the effective configuration used to build the database
+func (d *DB) SetBuildConfig(cfg *model.BuildConfig) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal build config: %w", err)
+	}
+	return d.SetMetadata(metaKeyBuildConfig, string(data))
+}
+
+// GetBuildConfig retrieves the build configuration, or nil if none was recorded
+func (d *DB) GetBuildConfig() (*model.BuildConfig, error) {
+	value, err := d.GetMetadata(metaKeyBuildConfig)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+	cfg := &model.BuildConfig{}
+	if err := json.Unmarshal([]byte(value), cfg); err != nil {
+		return nil, fmt.Errorf("invalid build config: %w", err)
+	}
+	return cfg, nil
+}
+
 // SetCache stores a cached value with a category and key
 func (d *DB) SetCache(category, key string, value interface{}) error {
 	data, err := json.Marshal(value)
@@ -112,6 +140,21 @@ func (d *DB) DeleteCache(category, key string) error {
 	return d.Delete(ipcodec.CacheKey(category, key))
 }
 
+// IterateCache calls fn with the key and raw JSON value of every cached entry
+// in a category, in key order. The value is only valid during the call.
+func (d *DB) IterateCache(category string, fn func(key string, value []byte) error) error {
+	prefix := ipcodec.CacheKey(category, "")
+	iter := d.NewIterator(util.BytesPrefix(prefix))
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := fn(string(iter.Key()[len(prefix):]), iter.Value()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
 // Stats computes database statistics
 func (d *DB) Stats(ctx context.Context) (*model.Stats, error) {
 	stats := &model.Stats{
@@ -123,21 +166,21 @@ func (d *DB) Stats(ctx context.Context) (*model.Stats, error) {
 	// Get schema version
 	version, err := d.GetSchemaVersion()
 	if err != nil {
-		log.Printf("WARN: Failed to get schema version: %v", err)
+		logging.Library().Warn("Failed to get schema version", "err", err)
 	}
 	stats.SchemaVersion = version
 
 	// Get built at
 	builtAt, err := d.GetBuiltAt()
 	if err != nil {
-		log.Printf("WARN: Failed to get built_at: %v", err)
+		logging.Library().Warn("Failed to get built_at", "err", err)
 	}
 	stats.LastBuiltAt = builtAt
 
 	// Get builder version
 	builderVersion, err := d.GetBuilderVersion()
 	if err != nil {
-		log.Printf("WARN: Failed to get builder version: %v", err)
+		logging.Library().Warn("Failed to get builder version", "err", err)
 	}
 	stats.BuilderVersion = builderVersion
 
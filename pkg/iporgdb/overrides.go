@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package iporgdb
+
+import (
+	"net/netip"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/overrides"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// SetOverrides installs an overrides overlay that GetByIP applies to every
+// result. Pass nil to remove the overlay. Stored records are not modified.
+func (d *DB) SetOverrides(set *overrides.Set) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.overrides = set
+}
+
+// Overrides returns the current overrides overlay, or nil if none is set
+func (d *DB) Overrides() *overrides.Set {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.overrides
+}
+
+// HasOverlap reports whether any stored range overlaps prefix
+func (d *DB) HasOverlap(prefix netip.Prefix) (bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return false, model.ErrDatabaseClosed
+	}
+
+	prefix = prefix.Masked()
+	start, end, err := ipcodec.CIDRToRange(prefix.String())
+	if err != nil {
+		return false, err
+	}
+
+	// A range starting at or before the prefix that covers its first address
+	if _, err := d.lookup(start); err == nil {
+		return true, nil
+	} else if err != model.ErrNotFound {
+		return false, err
+	}
+
+	// Any range starting inside the prefix
+	iter := d.db.NewIterator(&util.Range{
+		Start: ipcodec.EncodeRangeKey(start),
+		Limit: append(ipcodec.EncodeRangeKey(end), 0x00),
+	}, nil)
+	defer iter.Release()
+
+	if iter.Next() {
+		return true, nil
+	}
+	return false, iter.Error()
+}
@@ -5,13 +5,13 @@ package iporgdb
 
 import (
 	"fmt"
-	"log"
 	"net/netip"
 
 	"github.com/syndtr/goleveldb/leveldb/util"
 
 	"github.com/wingedpig/iporg/pkg/model"
 	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 // PutRange stores an IP range record in the database
@@ -123,7 +123,7 @@ func (d *DB) checkOverlap(newRec *model.Record) error {
 			// Ranges overlap - determine if it's acceptable
 			if existingRec.Start == newRec.Start && existingRec.End == newRec.End {
 				// Exact match - this is an update, which is OK
-				log.Printf("INFO: Updating existing range %v-%v", newRec.Start, newRec.End)
+				logging.Library().Debug("Updating existing range", "start", newRec.Start, "end", newRec.End)
 				return nil
 			}
 
@@ -134,14 +134,14 @@ func (d *DB) checkOverlap(newRec *model.Record) error {
 			if newPrefixLen > existingPrefixLen {
 				// New range is MORE specific than existing - delete the parent and accept the child
 				// More specific ranges have better organization data
-				log.Printf("INFO: New range %s is more specific than existing %s (will delete parent)",
-					newRec.Prefix, existingRec.Prefix)
+				logging.Library().Debug("New range is more specific than existing; deleting parent",
+					"prefix", newRec.Prefix, "parent", existingRec.Prefix)
 				keysToDelete = append(keysToDelete, key)
 			} else if newPrefixLen < existingPrefixLen {
 				// New range is LESS specific - reject it, keep the more specific child
 				// More specific child has better organization data
-				log.Printf("INFO: Skipping less specific range %s (covered by more specific %s)",
-					newRec.Prefix, existingRec.Prefix)
+				logging.Library().Debug("Skipping less specific range covered by a more specific one",
+					"prefix", newRec.Prefix, "child", existingRec.Prefix)
 				return fmt.Errorf("%w: %s contains more specific %s",
 					model.ErrOverlap, newRec.Prefix, existingRec.Prefix)
 			} else {
@@ -202,13 +202,13 @@ func (d *DB) IterateRanges(v4 bool, fn func(*model.Record) error) error {
 
 		startIP, err := ipcodec.DecodeRangeKey(key)
 		if err != nil {
-			log.Printf("WARN: Failed to decode key: %v", err)
+			logging.Library().Warn("Failed to decode key", "err", err)
 			continue
 		}
 
 		rec, err := decodeRecord(ipcodec.IPToBytes(startIP), value)
 		if err != nil {
-			log.Printf("WARN: Failed to decode record for %v: %v", startIP, err)
+			logging.Library().Warn("Failed to decode record", "start", startIP, "err", err)
 			continue
 		}
 
@@ -0,0 +1,665 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: iporg.proto
+
+package iporgpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LookupRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Ip    string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	// Echoed in the response, to match answers on LookupStream
+	Id            string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupRequest) Reset() {
+	*x = LookupRequest{}
+	mi := &file_iporg_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupRequest) ProtoMessage() {}
+
+func (x *LookupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupRequest.ProtoReflect.Descriptor instead.
+func (*LookupRequest) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LookupRequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *LookupRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// LookupResult mirrors model.LookupResult
+type LookupResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ip            string                 `protobuf:"bytes,1,opt,name=ip,proto3" json:"ip,omitempty"`
+	Asn           uint32                 `protobuf:"varint,2,opt,name=asn,proto3" json:"asn,omitempty"`
+	AsnName       string                 `protobuf:"bytes,3,opt,name=asn_name,json=asnName,proto3" json:"asn_name,omitempty"`
+	OrgName       string                 `protobuf:"bytes,4,opt,name=org_name,json=orgName,proto3" json:"org_name,omitempty"`
+	Rir           string                 `protobuf:"bytes,5,opt,name=rir,proto3" json:"rir,omitempty"`
+	Country       string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	Region        string                 `protobuf:"bytes,7,opt,name=region,proto3" json:"region,omitempty"`
+	City          string                 `protobuf:"bytes,8,opt,name=city,proto3" json:"city,omitempty"`
+	Lat           float64                `protobuf:"fixed64,9,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lon           float64                `protobuf:"fixed64,10,opt,name=lon,proto3" json:"lon,omitempty"`
+	Prefix        string                 `protobuf:"bytes,11,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	SourceRole    string                 `protobuf:"bytes,12,opt,name=source_role,json=sourceRole,proto3" json:"source_role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupResult) Reset() {
+	*x = LookupResult{}
+	mi := &file_iporg_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupResult) ProtoMessage() {}
+
+func (x *LookupResult) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupResult.ProtoReflect.Descriptor instead.
+func (*LookupResult) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LookupResult) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *LookupResult) GetAsn() uint32 {
+	if x != nil {
+		return x.Asn
+	}
+	return 0
+}
+
+func (x *LookupResult) GetAsnName() string {
+	if x != nil {
+		return x.AsnName
+	}
+	return ""
+}
+
+func (x *LookupResult) GetOrgName() string {
+	if x != nil {
+		return x.OrgName
+	}
+	return ""
+}
+
+func (x *LookupResult) GetRir() string {
+	if x != nil {
+		return x.Rir
+	}
+	return ""
+}
+
+func (x *LookupResult) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *LookupResult) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *LookupResult) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *LookupResult) GetLat() float64 {
+	if x != nil {
+		return x.Lat
+	}
+	return 0
+}
+
+func (x *LookupResult) GetLon() float64 {
+	if x != nil {
+		return x.Lon
+	}
+	return 0
+}
+
+func (x *LookupResult) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *LookupResult) GetSourceRole() string {
+	if x != nil {
+		return x.SourceRole
+	}
+	return ""
+}
+
+type LookupResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Unset if the lookup failed
+	Result *LookupResult `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"`
+	// gRPC status code and message of a failed lookup on LookupStream
+	Code          uint32 `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupResponse) Reset() {
+	*x = LookupResponse{}
+	mi := &file_iporg_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupResponse) ProtoMessage() {}
+
+func (x *LookupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupResponse.ProtoReflect.Descriptor instead.
+func (*LookupResponse) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LookupResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LookupResponse) GetResult() *LookupResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *LookupResponse) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *LookupResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type LookupCIDRRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cidr  string                 `protobuf:"bytes,1,opt,name=cidr,proto3" json:"cidr,omitempty"`
+	// Maximum number of results; 0 for the server's limit
+	Limit         uint32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupCIDRRequest) Reset() {
+	*x = LookupCIDRRequest{}
+	mi := &file_iporg_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupCIDRRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupCIDRRequest) ProtoMessage() {}
+
+func (x *LookupCIDRRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupCIDRRequest.ProtoReflect.Descriptor instead.
+func (*LookupCIDRRequest) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *LookupCIDRRequest) GetCidr() string {
+	if x != nil {
+		return x.Cidr
+	}
+	return ""
+}
+
+func (x *LookupCIDRRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type LookupCIDRResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Results []*LookupResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	// More records overlap the prefix than were returned
+	Truncated     bool `protobuf:"varint,2,opt,name=truncated,proto3" json:"truncated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LookupCIDRResponse) Reset() {
+	*x = LookupCIDRResponse{}
+	mi := &file_iporg_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LookupCIDRResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LookupCIDRResponse) ProtoMessage() {}
+
+func (x *LookupCIDRResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LookupCIDRResponse.ProtoReflect.Descriptor instead.
+func (*LookupCIDRResponse) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *LookupCIDRResponse) GetResults() []*LookupResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *LookupCIDRResponse) GetTruncated() bool {
+	if x != nil {
+		return x.Truncated
+	}
+	return false
+}
+
+type InfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InfoRequest) Reset() {
+	*x = InfoRequest{}
+	mi := &file_iporg_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoRequest) ProtoMessage() {}
+
+func (x *InfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
+func (*InfoRequest) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{5}
+}
+
+type InfoResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	BuiltAt          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=built_at,json=builtAt,proto3" json:"built_at,omitempty"`
+	BuilderVersion   string                 `protobuf:"bytes,2,opt,name=builder_version,json=builderVersion,proto3" json:"builder_version,omitempty"`
+	SchemaVersion    int32                  `protobuf:"varint,3,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	TotalRecords     int64                  `protobuf:"varint,4,opt,name=total_records,json=totalRecords,proto3" json:"total_records,omitempty"`
+	Ipv4Records      int64                  `protobuf:"varint,5,opt,name=ipv4_records,json=ipv4Records,proto3" json:"ipv4_records,omitempty"`
+	Ipv6Records      int64                  `protobuf:"varint,6,opt,name=ipv6_records,json=ipv6Records,proto3" json:"ipv6_records,omitempty"`
+	RecordsByRir     map[string]int64       `protobuf:"bytes,7,rep,name=records_by_rir,json=recordsByRir,proto3" json:"records_by_rir,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RecordsByRole    map[string]int64       `protobuf:"bytes,8,rep,name=records_by_role,json=recordsByRole,proto3" json:"records_by_role,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	RecordsByCountry map[string]int64       `protobuf:"bytes,9,rep,name=records_by_country,json=recordsByCountry,proto3" json:"records_by_country,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *InfoResponse) Reset() {
+	*x = InfoResponse{}
+	mi := &file_iporg_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoResponse) ProtoMessage() {}
+
+func (x *InfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_iporg_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return file_iporg_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *InfoResponse) GetBuiltAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.BuiltAt
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetBuilderVersion() string {
+	if x != nil {
+		return x.BuilderVersion
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetSchemaVersion() int32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetTotalRecords() int64 {
+	if x != nil {
+		return x.TotalRecords
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetIpv4Records() int64 {
+	if x != nil {
+		return x.Ipv4Records
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetIpv6Records() int64 {
+	if x != nil {
+		return x.Ipv6Records
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetRecordsByRir() map[string]int64 {
+	if x != nil {
+		return x.RecordsByRir
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetRecordsByRole() map[string]int64 {
+	if x != nil {
+		return x.RecordsByRole
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetRecordsByCountry() map[string]int64 {
+	if x != nil {
+		return x.RecordsByCountry
+	}
+	return nil
+}
+
+var File_iporg_proto protoreflect.FileDescriptor
+
+const file_iporg_proto_rawDesc = "" +
+	"\n" +
+	"\viporg.proto\x12\biporg.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"/\n" +
+	"\rLookupRequest\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"\x9b\x02\n" +
+	"\fLookupResult\x12\x0e\n" +
+	"\x02ip\x18\x01 \x01(\tR\x02ip\x12\x10\n" +
+	"\x03asn\x18\x02 \x01(\rR\x03asn\x12\x19\n" +
+	"\basn_name\x18\x03 \x01(\tR\aasnName\x12\x19\n" +
+	"\borg_name\x18\x04 \x01(\tR\aorgName\x12\x10\n" +
+	"\x03rir\x18\x05 \x01(\tR\x03rir\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12\x16\n" +
+	"\x06region\x18\a \x01(\tR\x06region\x12\x12\n" +
+	"\x04city\x18\b \x01(\tR\x04city\x12\x10\n" +
+	"\x03lat\x18\t \x01(\x01R\x03lat\x12\x10\n" +
+	"\x03lon\x18\n" +
+	" \x01(\x01R\x03lon\x12\x16\n" +
+	"\x06prefix\x18\v \x01(\tR\x06prefix\x12\x1f\n" +
+	"\vsource_role\x18\f \x01(\tR\n" +
+	"sourceRole\"z\n" +
+	"\x0eLookupResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12.\n" +
+	"\x06result\x18\x02 \x01(\v2\x16.iporg.v1.LookupResultR\x06result\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\rR\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"=\n" +
+	"\x11LookupCIDRRequest\x12\x12\n" +
+	"\x04cidr\x18\x01 \x01(\tR\x04cidr\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\"d\n" +
+	"\x12LookupCIDRResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.iporg.v1.LookupResultR\aresults\x12\x1c\n" +
+	"\ttruncated\x18\x02 \x01(\bR\ttruncated\"\r\n" +
+	"\vInfoRequest\"\xc7\x05\n" +
+	"\fInfoResponse\x125\n" +
+	"\bbuilt_at\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\abuiltAt\x12'\n" +
+	"\x0fbuilder_version\x18\x02 \x01(\tR\x0ebuilderVersion\x12%\n" +
+	"\x0eschema_version\x18\x03 \x01(\x05R\rschemaVersion\x12#\n" +
+	"\rtotal_records\x18\x04 \x01(\x03R\ftotalRecords\x12!\n" +
+	"\fipv4_records\x18\x05 \x01(\x03R\vipv4Records\x12!\n" +
+	"\fipv6_records\x18\x06 \x01(\x03R\vipv6Records\x12N\n" +
+	"\x0erecords_by_rir\x18\a \x03(\v2(.iporg.v1.InfoResponse.RecordsByRirEntryR\frecordsByRir\x12Q\n" +
+	"\x0frecords_by_role\x18\b \x03(\v2).iporg.v1.InfoResponse.RecordsByRoleEntryR\rrecordsByRole\x12Z\n" +
+	"\x12records_by_country\x18\t \x03(\v2,.iporg.v1.InfoResponse.RecordsByCountryEntryR\x10recordsByCountry\x1a?\n" +
+	"\x11RecordsByRirEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a@\n" +
+	"\x12RecordsByRoleEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aC\n" +
+	"\x15RecordsByCountryEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x012\x8b\x02\n" +
+	"\x05IPOrg\x12;\n" +
+	"\x06Lookup\x12\x17.iporg.v1.LookupRequest\x1a\x18.iporg.v1.LookupResponse\x12E\n" +
+	"\fLookupStream\x12\x17.iporg.v1.LookupRequest\x1a\x18.iporg.v1.LookupResponse(\x010\x01\x12G\n" +
+	"\n" +
+	"LookupCIDR\x12\x1b.iporg.v1.LookupCIDRRequest\x1a\x1c.iporg.v1.LookupCIDRResponse\x125\n" +
+	"\x04Info\x12\x15.iporg.v1.InfoRequest\x1a\x16.iporg.v1.InfoResponseB(Z&github.com/wingedpig/iporg/pkg/iporgpbb\x06proto3"
+
+var (
+	file_iporg_proto_rawDescOnce sync.Once
+	file_iporg_proto_rawDescData []byte
+)
+
+func file_iporg_proto_rawDescGZIP() []byte {
+	file_iporg_proto_rawDescOnce.Do(func() {
+		file_iporg_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_iporg_proto_rawDesc), len(file_iporg_proto_rawDesc)))
+	})
+	return file_iporg_proto_rawDescData
+}
+
+var file_iporg_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_iporg_proto_goTypes = []any{
+	(*LookupRequest)(nil),         // 0: iporg.v1.LookupRequest
+	(*LookupResult)(nil),          // 1: iporg.v1.LookupResult
+	(*LookupResponse)(nil),        // 2: iporg.v1.LookupResponse
+	(*LookupCIDRRequest)(nil),     // 3: iporg.v1.LookupCIDRRequest
+	(*LookupCIDRResponse)(nil),    // 4: iporg.v1.LookupCIDRResponse
+	(*InfoRequest)(nil),           // 5: iporg.v1.InfoRequest
+	(*InfoResponse)(nil),          // 6: iporg.v1.InfoResponse
+	nil,                           // 7: iporg.v1.InfoResponse.RecordsByRirEntry
+	nil,                           // 8: iporg.v1.InfoResponse.RecordsByRoleEntry
+	nil,                           // 9: iporg.v1.InfoResponse.RecordsByCountryEntry
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_iporg_proto_depIdxs = []int32{
+	1,  // 0: iporg.v1.LookupResponse.result:type_name -> iporg.v1.LookupResult
+	1,  // 1: iporg.v1.LookupCIDRResponse.results:type_name -> iporg.v1.LookupResult
+	10, // 2: iporg.v1.InfoResponse.built_at:type_name -> google.protobuf.Timestamp
+	7,  // 3: iporg.v1.InfoResponse.records_by_rir:type_name -> iporg.v1.InfoResponse.RecordsByRirEntry
+	8,  // 4: iporg.v1.InfoResponse.records_by_role:type_name -> iporg.v1.InfoResponse.RecordsByRoleEntry
+	9,  // 5: iporg.v1.InfoResponse.records_by_country:type_name -> iporg.v1.InfoResponse.RecordsByCountryEntry
+	0,  // 6: iporg.v1.IPOrg.Lookup:input_type -> iporg.v1.LookupRequest
+	0,  // 7: iporg.v1.IPOrg.LookupStream:input_type -> iporg.v1.LookupRequest
+	3,  // 8: iporg.v1.IPOrg.LookupCIDR:input_type -> iporg.v1.LookupCIDRRequest
+	5,  // 9: iporg.v1.IPOrg.Info:input_type -> iporg.v1.InfoRequest
+	2,  // 10: iporg.v1.IPOrg.Lookup:output_type -> iporg.v1.LookupResponse
+	2,  // 11: iporg.v1.IPOrg.LookupStream:output_type -> iporg.v1.LookupResponse
+	4,  // 12: iporg.v1.IPOrg.LookupCIDR:output_type -> iporg.v1.LookupCIDRResponse
+	6,  // 13: iporg.v1.IPOrg.Info:output_type -> iporg.v1.InfoResponse
+	10, // [10:14] is the sub-list for method output_type
+	6,  // [6:10] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_iporg_proto_init() }
+func file_iporg_proto_init() {
+	if File_iporg_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_iporg_proto_rawDesc), len(file_iporg_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_iporg_proto_goTypes,
+		DependencyIndexes: file_iporg_proto_depIdxs,
+		MessageInfos:      file_iporg_proto_msgTypes,
+	}.Build()
+	File_iporg_proto = out.File
+	file_iporg_proto_goTypes = nil
+	file_iporg_proto_depIdxs = nil
+}
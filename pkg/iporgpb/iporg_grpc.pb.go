@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: iporg.proto
+
+package iporgpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	IPOrg_Lookup_FullMethodName       = "/iporg.v1.IPOrg/Lookup"
+	IPOrg_LookupStream_FullMethodName = "/iporg.v1.IPOrg/LookupStream"
+	IPOrg_LookupCIDR_FullMethodName   = "/iporg.v1.IPOrg/LookupCIDR"
+	IPOrg_Info_FullMethodName         = "/iporg.v1.IPOrg/Info"
+)
+
+// IPOrgClient is the client API for IPOrg service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// IPOrg answers lookups from an iporg database
+type IPOrgClient interface {
+	// Lookup returns the record containing an IP address
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// LookupStream answers a stream of lookups in order. Failed lookups are
+	// reported in their response and do not end the stream.
+	LookupStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[LookupRequest, LookupResponse], error)
+	// LookupCIDR returns the records overlapping a prefix
+	LookupCIDR(ctx context.Context, in *LookupCIDRRequest, opts ...grpc.CallOption) (*LookupCIDRResponse, error)
+	// Info returns build metadata and record counts
+	Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
+}
+
+type iPOrgClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIPOrgClient(cc grpc.ClientConnInterface) IPOrgClient {
+	return &iPOrgClient{cc}
+}
+
+func (c *iPOrgClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, IPOrg_Lookup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iPOrgClient) LookupStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[LookupRequest, LookupResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &IPOrg_ServiceDesc.Streams[0], IPOrg_LookupStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[LookupRequest, LookupResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IPOrg_LookupStreamClient = grpc.BidiStreamingClient[LookupRequest, LookupResponse]
+
+func (c *iPOrgClient) LookupCIDR(ctx context.Context, in *LookupCIDRRequest, opts ...grpc.CallOption) (*LookupCIDRResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LookupCIDRResponse)
+	err := c.cc.Invoke(ctx, IPOrg_LookupCIDR_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *iPOrgClient) Info(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, IPOrg_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IPOrgServer is the server API for IPOrg service.
+// All implementations must embed UnimplementedIPOrgServer
+// for forward compatibility.
+//
+// IPOrg answers lookups from an iporg database
+type IPOrgServer interface {
+	// Lookup returns the record containing an IP address
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// LookupStream answers a stream of lookups in order. Failed lookups are
+	// reported in their response and do not end the stream.
+	LookupStream(grpc.BidiStreamingServer[LookupRequest, LookupResponse]) error
+	// LookupCIDR returns the records overlapping a prefix
+	LookupCIDR(context.Context, *LookupCIDRRequest) (*LookupCIDRResponse, error)
+	// Info returns build metadata and record counts
+	Info(context.Context, *InfoRequest) (*InfoResponse, error)
+	mustEmbedUnimplementedIPOrgServer()
+}
+
+// UnimplementedIPOrgServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIPOrgServer struct{}
+
+func (UnimplementedIPOrgServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedIPOrgServer) LookupStream(grpc.BidiStreamingServer[LookupRequest, LookupResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method LookupStream not implemented")
+}
+func (UnimplementedIPOrgServer) LookupCIDR(context.Context, *LookupCIDRRequest) (*LookupCIDRResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LookupCIDR not implemented")
+}
+func (UnimplementedIPOrgServer) Info(context.Context, *InfoRequest) (*InfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedIPOrgServer) mustEmbedUnimplementedIPOrgServer() {}
+func (UnimplementedIPOrgServer) testEmbeddedByValue()               {}
+
+// UnsafeIPOrgServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IPOrgServer will
+// result in compilation errors.
+type UnsafeIPOrgServer interface {
+	mustEmbedUnimplementedIPOrgServer()
+}
+
+func RegisterIPOrgServer(s grpc.ServiceRegistrar, srv IPOrgServer) {
+	// If the following call pancis, it indicates UnimplementedIPOrgServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&IPOrg_ServiceDesc, srv)
+}
+
+func _IPOrg_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPOrgServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPOrg_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPOrgServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IPOrg_LookupStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IPOrgServer).LookupStream(&grpc.GenericServerStream[LookupRequest, LookupResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type IPOrg_LookupStreamServer = grpc.BidiStreamingServer[LookupRequest, LookupResponse]
+
+func _IPOrg_LookupCIDR_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupCIDRRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPOrgServer).LookupCIDR(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPOrg_LookupCIDR_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPOrgServer).LookupCIDR(ctx, req.(*LookupCIDRRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IPOrg_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IPOrgServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IPOrg_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IPOrgServer).Info(ctx, req.(*InfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IPOrg_ServiceDesc is the grpc.ServiceDesc for IPOrg service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IPOrg_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "iporg.v1.IPOrg",
+	HandlerType: (*IPOrgServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lookup",
+			Handler:    _IPOrg_Lookup_Handler,
+		},
+		{
+			MethodName: "LookupCIDR",
+			Handler:    _IPOrg_LookupCIDR_Handler,
+		},
+		{
+			MethodName: "Info",
+			Handler:    _IPOrg_Info_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "LookupStream",
+			Handler:       _IPOrg_LookupStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "iporg.proto",
+}
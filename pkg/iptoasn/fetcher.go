@@ -47,6 +47,11 @@ func NewFetcher(sourceURL, cacheDir string) *Fetcher {
 	}
 }
 
+// SetTransport sets the transport used for downloads (default: http.DefaultTransport)
+func (f *Fetcher) SetTransport(rt http.RoundTripper) {
+	f.client.Transport = rt
+}
+
 // Fetch downloads the iptoasn data if it has changed since last fetch
 // Returns the path to the cached file and metadata
 func (f *Fetcher) Fetch(ctx context.Context) (*model.FetchMetadata, error) {
@@ -210,6 +210,45 @@ func (s *Store) WalkV4(ctx context.Context, startKey []byte, fn func(k []byte, p
 	return iter.Error()
 }
 
+// LookupIP returns the IPv4 prefix containing ip, or model.ErrNotFound
+func (s *Store) LookupIP(ip netip.Addr) (*model.CanonicalPrefix, error) {
+	ip = ip.Unmap()
+	if !ip.Is4() {
+		return nil, model.ErrNotFound
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, model.ErrDatabaseClosed
+	}
+
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(prefixGlobalV4)), nil)
+	defer iter.Release()
+
+	// The containing prefix is the last one starting at or before ip
+	key := makeGlobalKey(ipToUint32(ip))
+	if !iter.Seek(key) || string(iter.Key()) != string(key) {
+		if !iter.Prev() {
+			if err := iter.Error(); err != nil {
+				return nil, err
+			}
+			return nil, model.ErrNotFound
+		}
+	}
+
+	var p model.CanonicalPrefix
+	if err := msgpack.Unmarshal(iter.Value(), &p); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal prefix: %w", err)
+	}
+	prefix, err := netip.ParsePrefix(p.CIDR)
+	if err != nil || !prefix.Contains(ip) {
+		return nil, model.ErrNotFound
+	}
+	return &p, nil
+}
+
 // ListByASN returns all prefixes for a given ASN
 func (s *Store) ListByASN(ctx context.Context, asn int, collapsed bool) ([]*model.CanonicalPrefix, error) {
 	s.mu.RLock()
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package metrics exposes Prometheus metrics for builds and lookups
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ratelimit"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "iporg"
+
+// Path is where Serve exposes the metrics
+const Path = "/metrics"
+
+// NewRegistry creates a registry holding the Go runtime and process collectors
+func NewRegistry() *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// Handler returns an HTTP handler serving the metrics in reg
+func Handler(reg *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg})
+}
+
+// Serve exposes reg on addr until the server fails
+func Serve(addr string, reg *prometheus.Registry) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler(reg))
+	return http.ListenAndServe(addr, mux)
+}
+
+// Lookup records database lookup latency and outcomes
+type Lookup struct {
+	latency prometheus.Histogram
+	results *prometheus.CounterVec
+}
+
+// NewLookup creates the lookup metrics and registers them with reg
+func NewLookup(reg prometheus.Registerer) *Lookup {
+	m := &Lookup{
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "lookup_duration_seconds",
+			Help:      "Time spent looking up an IP in the database.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "lookups_total",
+			Help:      "Database lookups by result (found, not_found, invalid or error).",
+		}, []string{"result"}),
+	}
+	reg.MustRegister(m.latency, m.results)
+	return m
+}
+
+// Observe records one lookup; it matches iporgdb.LookupObserver
+func (m *Lookup) Observe(elapsed time.Duration, err error) {
+	m.latency.Observe(elapsed.Seconds())
+	m.results.WithLabelValues(lookupResult(err)).Inc()
+}
+
+// lookupResult classifies a lookup error as a result label
+func lookupResult(err error) string {
+	switch {
+	case err == nil:
+		return "found"
+	case errors.Is(err, model.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, model.ErrInvalidIP):
+		return "invalid"
+	default:
+		return "error"
+	}
+}
+
+// Build records enrichment source latency and build progress
+type Build struct {
+	latency      *prometheus.HistogramVec
+	results      *prometheus.CounterVec
+	lastProgress prometheus.Gauge
+}
+
+// NewBuild creates the build metrics and registers them with reg
+func NewBuild(reg prometheus.Registerer) *Build {
+	m := &Build{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "build",
+			Name:      "source_duration_seconds",
+			Help:      "Time spent in each enrichment source lookup.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"source"}),
+		results: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "build",
+			Name:      "source_lookups_total",
+			Help:      "Enrichment source lookups by result (hit, miss or error).",
+		}, []string{"source", "result"}),
+		lastProgress: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "build",
+			Name:      "last_progress_timestamp_seconds",
+			Help:      "Unix time at which the build last completed a lookup or prefix.",
+		}),
+	}
+	m.lastProgress.SetToCurrentTime()
+	reg.MustRegister(m.latency, m.results, m.lastProgress)
+	return m
+}
+
+// ObserveSource records one source lookup. A nil Build ignores it.
+func (m *Build) ObserveSource(source string, elapsed time.Duration, hit bool, err error) {
+	if m == nil {
+		return
+	}
+	result := "miss"
+	switch {
+	case err != nil:
+		result = "error"
+	case hit:
+		result = "hit"
+	}
+	m.latency.WithLabelValues(source).Observe(elapsed.Seconds())
+	m.results.WithLabelValues(source, result).Inc()
+	m.lastProgress.SetToCurrentTime()
+}
+
+// Progress marks the build as having made progress. A nil Build ignores it.
+func (m *Build) Progress() {
+	if m == nil {
+		return
+	}
+	m.lastProgress.SetToCurrentTime()
+}
+
+// upstreamCollector reports rate limiter state for upstream hosts
+type upstreamCollector struct {
+	rates     func() []ratelimit.HostRate
+	requests  *prometheus.Desc
+	throttled *prometheus.Desc
+	rate      *prometheus.Desc
+}
+
+// NewUpstreamCollector creates a collector reporting requests, throttled
+// (HTTP 429/503) responses and the current rate for every host returned by
+// rates at scrape time
+func NewUpstreamCollector(rates func() []ratelimit.HostRate) prometheus.Collector {
+	return &upstreamCollector{
+		rates: rates,
+		requests: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "upstream", "requests_total"),
+			"Requests sent to an upstream host.", []string{"host"}, nil),
+		throttled: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "upstream", "throttled_total"),
+			"Requests an upstream host answered with a rate limit response.", []string{"host"}, nil),
+		rate: prometheus.NewDesc(prometheus.BuildFQName(Namespace, "upstream", "rate"),
+			"Current request rate limit per second for an upstream host (0 = unlimited).", []string{"host"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *upstreamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requests
+	ch <- c.throttled
+	ch <- c.rate
+}
+
+// Collect implements prometheus.Collector
+func (c *upstreamCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, r := range c.rates() {
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(r.Requests), r.Host)
+		ch <- prometheus.MustNewConstMetric(c.throttled, prometheus.CounterValue, float64(r.Throttled), r.Host)
+		ch <- prometheus.MustNewConstMetric(c.rate, prometheus.GaugeValue, r.Rate, r.Host)
+	}
+}
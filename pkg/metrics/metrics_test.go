@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ratelimit"
+)
+
+func TestLookupResults(t *testing.T) {
+	reg := NewRegistry()
+	m := NewLookup(reg)
+
+	m.Observe(time.Millisecond, nil)
+	m.Observe(time.Millisecond, nil)
+	m.Observe(time.Millisecond, model.ErrNotFound)
+	m.Observe(time.Millisecond, model.ErrInvalidIP)
+	m.Observe(time.Millisecond, errors.New("disk on fire"))
+
+	tests := map[string]float64{"found": 2, "not_found": 1, "invalid": 1, "error": 1}
+	for result, want := range tests {
+		if got := testutil.ToFloat64(m.results.WithLabelValues(result)); got != want {
+			t.Errorf("%s: got %v, want %v", result, got, want)
+		}
+	}
+	if got := testutil.CollectAndCount(m.latency); got != 1 {
+		t.Errorf("got %d latency series, want 1", got)
+	}
+}
+
+func TestBuildSources(t *testing.T) {
+	reg := NewRegistry()
+	m := NewBuild(reg)
+
+	m.ObserveSource("rdap", time.Second, true, nil)
+	m.ObserveSource("rdap", time.Second, false, nil)
+	m.ObserveSource("rdap", time.Second, false, errors.New("timeout"))
+
+	for _, result := range []string{"hit", "miss", "error"} {
+		if got := testutil.ToFloat64(m.results.WithLabelValues("rdap", result)); got != 1 {
+			t.Errorf("%s: got %v, want 1", result, got)
+		}
+	}
+	if got := testutil.ToFloat64(m.lastProgress); got < float64(time.Now().Add(-time.Minute).Unix()) {
+		t.Errorf("got last progress %v, want about now", got)
+	}
+
+	// A nil Build is a no-op
+	var none *Build
+	none.ObserveSource("rdap", time.Second, true, nil)
+	none.Progress()
+}
+
+func TestHandler(t *testing.T) {
+	reg := NewRegistry()
+	reg.MustRegister(NewUpstreamCollector(func() []ratelimit.HostRate {
+		return []ratelimit.HostRate{{Host: "rdap.example", Rate: 2.5, MaxRate: 5, Requests: 10, Throttled: 3}}
+	}))
+
+	srv := httptest.NewServer(Handler(reg))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+
+	for _, want := range []string{
+		`iporg_upstream_throttled_total{host="rdap.example"} 3`,
+		`iporg_upstream_requests_total{host="rdap.example"} 10`,
+		`iporg_upstream_rate{host="rdap.example"} 2.5`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
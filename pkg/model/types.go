@@ -4,10 +4,16 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/netip"
 	"time"
 )
 
+// SourceRoleRegistered marks records for registered address space that is
+// not announced in BGP; these records have no ASN
+const SourceRoleRegistered = "registered_unannounced"
+
 // Record represents a single IP range with associated metadata
 type Record struct {
 	Start       netip.Addr // Start IP of range
@@ -44,31 +50,104 @@ type Stats struct {
 // BuildConfig contains configuration for the build process
 type BuildConfig struct {
 	// Input files
-	ASNFile       string
-	MMDBASNPath   string
-	MMDBCityPath  string
-	IPtoASNDBPath  string // Optional: use iptoasn database instead of RIPEstat API
-	RIPEBulkDBPath string // Optional: use RIPE bulk database instead of RDAP for RIPE region
-	ARINBulkDBPath string // Optional: use ARIN bulk database instead of RDAP for ARIN region
+	ASNFile        string `json:"asn_file,omitempty"`
+	MMDBASNPath    string `json:"mmdb_asn,omitempty"`
+	MMDBCityPath   string `json:"mmdb_city,omitempty"`
+	IPtoASNDBPath  string `json:"iptoasn_db,omitempty"`   // Optional: use iptoasn database instead of RIPEstat API
+	RIPEBulkDBPath string `json:"ripe_bulk_db,omitempty"` // Optional: use RIPE bulk database instead of RDAP for RIPE region
+	ARINBulkDBPath string `json:"arin_bulk_db,omitempty"` // Optional: use ARIN bulk database instead of RDAP for ARIN region
+	OverridesFile  string `json:"overrides,omitempty"`    // Optional: corrections applied after enrichment
+
+	// Prefix selection other than by ASN
+	PrefixFile string   `json:"prefix_file,omitempty"` // Explicit CIDR list, one per line
+	Countries  []string `json:"countries,omitempty"`   // Prefixes registered in these countries
+	RIRs       []string `json:"rirs,omitempty"`        // Prefixes registered with these RIRs
 
 	// Output
-	DBPath string
+	DBPath       string  `json:"db,omitempty"`
+	Append       bool    `json:"append"`            // Keep records from earlier builds
+	MaxErrorRate float64 `json:"max_error_rate"`    // Errors per prefix above which existing records are not replaced
+	TempDir      string  `json:"tmp_dir,omitempty"` // Directory for bulk load spill files
 
 	// Processing options
-	Workers        int
-	CacheTTL       time.Duration
-	SplitByMaxMind bool // Mode B: split by MaxMind city blocks
-	IPv4Only       bool // Skip IPv6 prefixes entirely
-	AllASNs        bool // Build for all ASNs from iptoasn database
-	BulkOnly       bool // Only process ASNs/prefixes with bulk database coverage
+	Workers         int           `json:"workers,omitempty"`
+	CacheTTL        time.Duration `json:"-"`                 // Encoded as cache_ttl, e.g. "168h"
+	NegativeTTL     time.Duration `json:"-"`                 // Encoded as negative_cache_ttl; TTL for "no data" results
+	SplitByMaxMind  bool          `json:"split_by_maxmind"`  // Mode B: split by MaxMind city blocks
+	SplitByRegistry bool          `json:"split_by_registry"` // Mode C: split on bulk registry sub-allocations
+	MinPrefixV4     int           `json:"min_prefix_v4,omitempty"`
+	MinPrefixV6     int           `json:"min_prefix_v6,omitempty"`
+	IPv4Only        bool          `json:"ipv4_only"`       // Skip IPv6 prefixes entirely
+	AllASNs         bool          `json:"all_asns"`        // Build for all ASNs from iptoasn database
+	BulkOnly        bool          `json:"bulk_only"`       // Only process ASNs/prefixes with bulk database coverage
+	FillRegistered  bool          `json:"fill_registered"` // Add registered but unannounced ranges from bulk data
+
+	// Source precedence per field ("org", "country", "country:ARIN", ...),
+	// merged over the builder's defaults. See pkg/enrich.
+	Precedence map[string][]string `json:"precedence,omitempty"`
 
 	// API configuration
-	RIPEBaseURL      string
-	RDAPBootstrapURL string
-	UserAgent        string
+	RIPEBaseURL      string `json:"ripe_base,omitempty"`
+	RDAPBootstrapURL string `json:"rdap_bootstrap,omitempty"`
+	UserAgent        string `json:"user_agent,omitempty"`
 
 	// Rate limiting
-	RDAPRateLimit float64 // requests per second
+	RDAPRateLimit float64 `json:"rdap_rate_limit,omitempty"` // requests per second
+
+	// RDAP cache store (default: the output database)
+	RDAPCacheDBPath string `json:"rdap_cache_db,omitempty"`
+
+	// HTTP archive: record upstream traffic to, or replay it from, a directory
+	HTTPRecordDir string `json:"http_record,omitempty"`
+	HTTPReplayDir string `json:"http_replay,omitempty"`
+
+	// Profile is the name of the config file profile used, if any
+	Profile string `json:"profile,omitempty"`
+}
+
+// buildConfigJSON adds the human-readable duration fields to BuildConfig
+type buildConfigJSON struct {
+	buildConfigAlias
+	CacheTTL    string `json:"cache_ttl,omitempty"`
+	NegativeTTL string `json:"negative_cache_ttl,omitempty"`
+}
+
+type buildConfigAlias BuildConfig
+
+// MarshalJSON encodes durations as strings such as "168h0m0s"
+func (c BuildConfig) MarshalJSON() ([]byte, error) {
+	out := buildConfigJSON{buildConfigAlias: buildConfigAlias(c)}
+	if c.CacheTTL != 0 {
+		out.CacheTTL = c.CacheTTL.String()
+	}
+	if c.NegativeTTL != 0 {
+		out.NegativeTTL = c.NegativeTTL.String()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a config, leaving fields absent from the input unchanged
+func (c *BuildConfig) UnmarshalJSON(data []byte) error {
+	in := buildConfigJSON{buildConfigAlias: buildConfigAlias(*c)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*c = BuildConfig(in.buildConfigAlias)
+	if in.CacheTTL != "" {
+		ttl, err := time.ParseDuration(in.CacheTTL)
+		if err != nil {
+			return fmt.Errorf("invalid cache_ttl: %w", err)
+		}
+		c.CacheTTL = ttl
+	}
+	if in.NegativeTTL != "" {
+		ttl, err := time.ParseDuration(in.NegativeTTL)
+		if err != nil {
+			return fmt.Errorf("invalid negative_cache_ttl: %w", err)
+		}
+		c.NegativeTTL = ttl
+	}
+	return nil
 }
 
 // RDAPOrg represents organization information extracted from RDAP
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package overrides applies user-supplied corrections to iporg records.
+//
+// An overrides file is a JSON document listing CIDRs and the record fields
+// that should be replaced for addresses inside them:
+//
+//	{
+//	  "overrides": [
+//	    {
+//	      "cidr": "203.0.113.0/24",
+//	      "org_name": "Example Customer Ltd",
+//	      "country": "GB",
+//	      "comment": "Registry still shows the old upstream (TICKET-123)",
+//	      "expires": "2026-06-30"
+//	    }
+//	  ]
+//	}
+//
+// Only the fields present in an entry are changed. Expired entries are
+// ignored. When several entries cover the same address, the most specific
+// one wins field by field.
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+)
+
+// SourceRole is set on records whose org name was replaced by an override
+// that does not specify its own source_role
+const SourceRole = "override"
+
+// Override is a single correction entry
+type Override struct {
+	CIDR    string `json:"cidr"`
+	Comment string `json:"comment,omitempty"`
+	Expires string `json:"expires,omitempty"` // YYYY-MM-DD or RFC3339, empty for never
+
+	// Record fields; nil means "leave unchanged"
+	ASN         *int     `json:"asn,omitempty"`
+	ASNName     *string  `json:"asn_name,omitempty"`
+	OrgName     *string  `json:"org_name,omitempty"`
+	RIR         *string  `json:"rir,omitempty"`
+	Country     *string  `json:"country,omitempty"`
+	Region      *string  `json:"region,omitempty"`
+	City        *string  `json:"city,omitempty"`
+	Lat         *float64 `json:"lat,omitempty"`
+	Lon         *float64 `json:"lon,omitempty"`
+	SourceRole  *string  `json:"source_role,omitempty"`
+	StatusLabel *string  `json:"status_label,omitempty"`
+
+	prefix    netip.Prefix
+	last      netip.Addr // Last address of prefix
+	expiresAt time.Time
+}
+
+// Prefix returns the parsed CIDR of the override
+func (o *Override) Prefix() netip.Prefix {
+	return o.prefix
+}
+
+// ExpiresAt returns the expiry time, or the zero time if the override never expires
+func (o *Override) ExpiresAt() time.Time {
+	return o.expiresAt
+}
+
+// Expired reports whether the override has expired at the given time
+func (o *Override) Expired(now time.Time) bool {
+	return !o.expiresAt.IsZero() && !now.Before(o.expiresAt)
+}
+
+// Apply copies the override's fields onto rec
+func (o *Override) Apply(rec *model.Record) {
+	if o.ASN != nil {
+		rec.ASN = *o.ASN
+	}
+	if o.ASNName != nil {
+		rec.ASNName = *o.ASNName
+	}
+	if o.OrgName != nil {
+		rec.OrgName = *o.OrgName
+		rec.SourceRole = SourceRole
+	}
+	if o.RIR != nil {
+		rec.RIR = *o.RIR
+	}
+	if o.Country != nil {
+		rec.Country = *o.Country
+	}
+	if o.Region != nil {
+		rec.Region = *o.Region
+	}
+	if o.City != nil {
+		rec.City = *o.City
+	}
+	if o.Lat != nil {
+		rec.Lat = *o.Lat
+	}
+	if o.Lon != nil {
+		rec.Lon = *o.Lon
+	}
+	if o.SourceRole != nil {
+		rec.SourceRole = *o.SourceRole
+	}
+	if o.StatusLabel != nil {
+		rec.StatusLabel = *o.StatusLabel
+	}
+}
+
+// file is the on-disk representation of an overrides file
+type file struct {
+	Overrides []*Override `json:"overrides"`
+}
+
+// Set is a parsed collection of overrides, ordered least specific first
+type Set struct {
+	entries []*Override
+}
+
+// Load reads an overrides file from disk
+func Load(path string) (*Set, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open overrides file: %w", err)
+	}
+	defer f.Close()
+
+	set, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Parse reads overrides from r
+func Parse(r io.Reader) (*Set, error) {
+	var doc file
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid overrides file: %w", err)
+	}
+
+	for i, o := range doc.Overrides {
+		if o == nil {
+			return nil, fmt.Errorf("override %d: empty entry", i)
+		}
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(o.CIDR))
+		if err != nil {
+			return nil, fmt.Errorf("override %d: invalid cidr %q: %w", i, o.CIDR, err)
+		}
+		o.prefix = prefix.Masked()
+		if _, o.last, err = ipcodec.CIDRToRange(o.prefix.String()); err != nil {
+			return nil, fmt.Errorf("override %d: invalid cidr %q: %w", i, o.CIDR, err)
+		}
+
+		if o.Expires != "" {
+			o.expiresAt, err = parseExpiry(o.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("override %d (%s): %w", i, o.CIDR, err)
+			}
+		}
+	}
+
+	// Least specific first so that more specific entries are applied last
+	entries := doc.Overrides
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].prefix.Bits() < entries[j].prefix.Bits()
+	})
+
+	return &Set{entries: entries}, nil
+}
+
+// parseExpiry accepts a plain date (expiring at the end of that day, UTC) or an RFC3339 timestamp
+func parseExpiry(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expires %q: want YYYY-MM-DD or RFC3339", s)
+	}
+	return t, nil
+}
+
+// Len returns the number of overrides in the set, including expired ones
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.entries)
+}
+
+// Entries returns all overrides, least specific first
+func (s *Set) Entries() []*Override {
+	if s == nil {
+		return nil
+	}
+	return s.entries
+}
+
+// SplitRange cuts rec where active overrides that cover only part of it
+// start and end, so that every override covers each piece either fully or
+// not at all. It returns rec itself if no override cuts it.
+func (s *Set) SplitRange(rec *model.Record, now time.Time) []*model.Record {
+	if s == nil {
+		return []*model.Record{rec}
+	}
+
+	// First address of each piece after the first
+	var cuts []netip.Addr
+	for _, o := range s.entries {
+		if o.Expired(now) || o.prefix.Addr().Is4() != rec.Start.Is4() {
+			continue
+		}
+		if o.last.Less(rec.Start) || rec.End.Less(o.prefix.Addr()) {
+			continue
+		}
+		if rec.Start.Less(o.prefix.Addr()) {
+			cuts = append(cuts, o.prefix.Addr())
+		}
+		if o.last.Less(rec.End) {
+			cuts = append(cuts, o.last.Next())
+		}
+	}
+	if len(cuts) == 0 {
+		return []*model.Record{rec}
+	}
+	slices.SortFunc(cuts, netip.Addr.Compare)
+	cuts = slices.Compact(cuts)
+
+	pieces := make([]*model.Record, 0, len(cuts)+1)
+	start := rec.Start
+	for _, cut := range cuts {
+		piece := *rec
+		piece.Start, piece.End = start, cut.Prev()
+		pieces = append(pieces, &piece)
+		start = cut
+	}
+	piece := *rec
+	piece.Start = start
+	return append(pieces, &piece)
+}
+
+// ApplyRange applies every active override whose CIDR covers the whole
+// range [start, end] to rec. It returns true if any override was applied.
+// Overrides that only cover part of the range are not applied; the builder
+// splits records with SplitRange first.
+func (s *Set) ApplyRange(rec *model.Record, now time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	applied := false
+	for _, o := range s.entries {
+		if o.Expired(now) {
+			continue
+		}
+		if o.prefix.Contains(rec.Start) && o.prefix.Contains(rec.End) {
+			o.Apply(rec)
+			applied = true
+		}
+	}
+	return applied
+}
+
+// ApplyIP applies every active override whose CIDR contains ip to rec.
+// It returns true if any override was applied.
+func (s *Set) ApplyIP(ip netip.Addr, rec *model.Record, now time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	applied := false
+	for _, o := range s.entries {
+		if o.Expired(now) {
+			continue
+		}
+		if o.prefix.Contains(ip) {
+			o.Apply(rec)
+			applied = true
+		}
+	}
+	return applied
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package overrides
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+const testFile = `{
+  "overrides": [
+    {"cidr": "203.0.113.128/25", "city": "Leeds", "comment": "more specific"},
+    {"cidr": "203.0.113.0/24", "org_name": "Example Customer Ltd", "country": "GB", "city": "London"},
+    {"cidr": "198.51.100.0/24", "org_name": "Old Customer", "expires": "2020-01-31"}
+  ]
+}`
+
+func TestParse(t *testing.T) {
+	set, err := Parse(strings.NewReader(testFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if set.Len() != 3 {
+		t.Fatalf("got %d overrides, want 3", set.Len())
+	}
+
+	// Least specific first
+	if got := set.Entries()[2].Prefix().String(); got != "203.0.113.128/25" {
+		t.Errorf("last entry = %s, want 203.0.113.128/25", got)
+	}
+
+	expired := set.Entries()[1]
+	if expired.CIDR != "198.51.100.0/24" {
+		t.Fatalf("unexpected entry order: %s", expired.CIDR)
+	}
+	if !expired.Expired(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("override should be expired on 2020-02-01")
+	}
+	if expired.Expired(time.Date(2020, 1, 31, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("override should still be active on its expiry date")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"invalid cidr", `{"overrides":[{"cidr":"not-a-cidr"}]}`},
+		{"invalid expiry", `{"overrides":[{"cidr":"10.0.0.0/8","expires":"tomorrow"}]}`},
+		{"unknown field", `{"overrides":[{"cidr":"10.0.0.0/8","org":"typo"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.doc)); err == nil {
+				t.Errorf("Parse(%s) succeeded, want error", tt.doc)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	set, err := Parse(strings.NewReader(testFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		start, end  string
+		ip          string
+		wantApplied bool
+		wantOrg     string
+		wantCity    string
+	}{
+		{"covered range", "203.0.113.0", "203.0.113.255", "203.0.113.10", true, "Example Customer Ltd", "London"},
+		{"more specific wins", "203.0.113.128", "203.0.113.255", "203.0.113.200", true, "Example Customer Ltd", "Leeds"},
+		{"expired override", "198.51.100.0", "198.51.100.255", "198.51.100.1", false, "Registry Org", ""},
+		{"no override", "192.0.2.0", "192.0.2.255", "192.0.2.1", false, "Registry Org", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &model.Record{
+				Start:   netip.MustParseAddr(tt.start),
+				End:     netip.MustParseAddr(tt.end),
+				OrgName: "Registry Org",
+			}
+			if got := set.ApplyRange(rec, now); got != tt.wantApplied {
+				t.Errorf("ApplyRange() = %v, want %v", got, tt.wantApplied)
+			}
+			if rec.OrgName != tt.wantOrg {
+				t.Errorf("OrgName = %q, want %q", rec.OrgName, tt.wantOrg)
+			}
+			if rec.City != tt.wantCity {
+				t.Errorf("City = %q, want %q", rec.City, tt.wantCity)
+			}
+			if tt.wantApplied && rec.SourceRole != SourceRole {
+				t.Errorf("SourceRole = %q, want %q", rec.SourceRole, SourceRole)
+			}
+
+			rec = &model.Record{OrgName: "Registry Org"}
+			if got := set.ApplyIP(netip.MustParseAddr(tt.ip), rec, now); got != tt.wantApplied {
+				t.Errorf("ApplyIP() = %v, want %v", got, tt.wantApplied)
+			}
+			if rec.OrgName != tt.wantOrg {
+				t.Errorf("ApplyIP OrgName = %q, want %q", rec.OrgName, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestApplyRangePartialCoverage(t *testing.T) {
+	set, err := Parse(strings.NewReader(testFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	// A /16 record is only partly covered by the /24 override, so the
+	// build-time pass must leave it alone
+	rec := &model.Record{
+		Start:   netip.MustParseAddr("203.0.0.0"),
+		End:     netip.MustParseAddr("203.0.255.255"),
+		OrgName: "Registry Org",
+	}
+	if set.ApplyRange(rec, time.Now()) {
+		t.Errorf("ApplyRange applied an override that only partly covers the range")
+	}
+}
+
+func TestSplitRange(t *testing.T) {
+	set, err := Parse(strings.NewReader(testFile))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start, end string
+		want       []string // start-end, org, city of each piece
+	}{
+		{"no override", "192.0.2.0", "192.0.2.255", []string{"192.0.2.0-192.0.2.255 Registry Org "}},
+		{"expired override", "198.51.0.0", "198.51.255.255", []string{"198.51.0.0-198.51.255.255 Registry Org "}},
+		{"covered range", "203.0.113.0", "203.0.113.127", []string{"203.0.113.0-203.0.113.127 Example Customer Ltd London"}},
+		{"nested overrides", "203.0.112.0", "203.0.115.255", []string{
+			"203.0.112.0-203.0.112.255 Registry Org ",
+			"203.0.113.0-203.0.113.127 Example Customer Ltd London",
+			"203.0.113.128-203.0.113.255 Example Customer Ltd Leeds",
+			"203.0.114.0-203.0.115.255 Registry Org ",
+		}},
+		{"override at the end", "203.0.113.64", "203.0.113.255", []string{
+			"203.0.113.64-203.0.113.127 Example Customer Ltd London",
+			"203.0.113.128-203.0.113.255 Example Customer Ltd Leeds",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &model.Record{
+				Start:   netip.MustParseAddr(tt.start),
+				End:     netip.MustParseAddr(tt.end),
+				OrgName: "Registry Org",
+				Prefix:  "203.0.112.0/22",
+			}
+			var got []string
+			for _, piece := range set.SplitRange(rec, now) {
+				if piece.Prefix != rec.Prefix {
+					t.Errorf("piece prefix = %q, want %q", piece.Prefix, rec.Prefix)
+				}
+				set.ApplyRange(piece, now)
+				got = append(got, fmt.Sprintf("%s-%s %s %s", piece.Start, piece.End, piece.OrgName, piece.City))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
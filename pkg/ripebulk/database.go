@@ -6,7 +6,6 @@ package ripebulk
 import (
 	"encoding/binary"
 	"fmt"
-	"log"
 	"net/netip"
 	"sort"
 	"strings"
@@ -17,6 +16,8 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/opt"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vmihailenco/msgpack/v5"
+
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const (
@@ -124,8 +125,7 @@ func isValidOrgRemark(remark string) bool {
 
 // BuildDatabase creates a new RIPE bulk database from parsed data
 func BuildDatabase(path string, inetnums []Inetnum, orgs map[string]Organisation) (*Database, error) {
-	log.Printf("INFO: Building RIPE bulk database at %s", path)
-	log.Printf("INFO: Indexing %d inetnums, %d organisations", len(inetnums), len(orgs))
+	logging.Library().Info("Building RIPE bulk database", "path", path, "inetnums", len(inetnums), "orgs", len(orgs))
 
 	// Open database
 	db, err := leveldb.OpenFile(path, &opt.Options{
@@ -144,7 +144,7 @@ func BuildDatabase(path string, inetnums []Inetnum, orgs map[string]Organisation
 	}()
 
 	// Sort inetnums: Start ascending, End descending (parents before children)
-	log.Printf("INFO: Sorting inetnums...")
+	logging.Library().Info("Sorting inetnums")
 	sort.Slice(inetnums, func(i, j int) bool {
 		if inetnums[i].Start != inetnums[j].Start {
 			return inetnums[i].Start < inetnums[j].Start
@@ -154,7 +154,7 @@ func BuildDatabase(path string, inetnums []Inetnum, orgs map[string]Organisation
 	})
 
 	// Write ranges
-	log.Printf("INFO: Writing ranges to database...")
+	logging.Library().Info("Writing ranges to database")
 	batch := new(leveldb.Batch)
 	batchCount := 0
 
@@ -186,7 +186,7 @@ func BuildDatabase(path string, inetnums []Inetnum, orgs map[string]Organisation
 	}
 
 	// Write organisations
-	log.Printf("INFO: Writing organisations to database...")
+	logging.Library().Info("Writing organisations to database")
 	batch.Reset()
 	batchCount = 0
 
@@ -217,7 +217,7 @@ func BuildDatabase(path string, inetnums []Inetnum, orgs map[string]Organisation
 	}
 
 	// Write metadata
-	log.Printf("INFO: Writing metadata...")
+	logging.Library().Info("Writing metadata")
 	metadata := Metadata{
 		SchemaVersion: currentSchemaVersion,
 		BuildTime:     time.Now(),
@@ -235,7 +235,7 @@ func BuildDatabase(path string, inetnums []Inetnum, orgs map[string]Organisation
 		return nil, fmt.Errorf("failed to write metadata: %w", err)
 	}
 
-	log.Printf("INFO: Database build complete - %d ranges, %d orgs indexed", len(inetnums), len(orgs))
+	logging.Library().Info("Database build complete", "ranges", len(inetnums), "orgs", len(orgs))
 
 	return &Database{
 		db:   db,
@@ -378,9 +378,84 @@ func (d *Database) lookupRange(queryStart, queryEnd uint32, prefix netip.Prefix)
 		}
 	}
 
+	return d.buildMatch(mostSpecific, prefix), nil
+}
+
+// Overlapping returns every inetnum that overlaps prefix: the ranges that
+// cover its first address and all ranges starting inside it. Callers resolve
+// them with MatchFor.
+func (d *Database) Overlapping(prefix netip.Prefix) ([]Inetnum, error) {
+	queryStart, queryEnd, err := PrefixToRange(prefix.Masked())
+	if err != nil {
+		return nil, err
+	}
+
+	iter := d.db.NewIterator(util.BytesPrefix([]byte(prefixRange)), nil)
+	defer iter.Release()
+
+	// Position on the first range starting after queryStart
+	positioned := false
+	if queryStart < 0xFFFFFFFF {
+		positioned = iter.Seek(makeRangeKey(queryStart+1, 0))
+	}
+
+	var ranges []Inetnum
+
+	// Scan backward for ranges covering queryStart, with the same /8 limit as lookups
+	var ok bool
+	if positioned {
+		ok = iter.Prev()
+	} else {
+		ok = iter.Last()
+	}
+	for ; ok; ok = iter.Prev() {
+		currentStart := extractStartFromKey(iter.Key())
+		if queryStart-currentStart > 16777216 {
+			break
+		}
+		if extractEndFromKey(iter.Key()) < queryStart {
+			continue
+		}
+
+		var inet Inetnum
+		if err := msgpack.Unmarshal(iter.Value(), &inet); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal inetnum: %w", err)
+		}
+		ranges = append(ranges, inet)
+	}
+
+	// Scan forward over ranges starting inside the prefix
+	if positioned {
+		for ok = iter.Seek(makeRangeKey(queryStart+1, 0)); ok; ok = iter.Next() {
+			if extractStartFromKey(iter.Key()) > queryEnd {
+				break
+			}
+
+			var inet Inetnum
+			if err := msgpack.Unmarshal(iter.Value(), &inet); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal inetnum: %w", err)
+			}
+			ranges = append(ranges, inet)
+		}
+	}
+
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("iterator error: %w", err)
+	}
+	return ranges, nil
+}
+
+// MatchFor resolves an inetnum (e.g. from IterateRanges) into a Match.
+// It returns nil for non-RIPE managed address blocks.
+func (d *Database) MatchFor(inet Inetnum) *Match {
+	return d.buildMatch(inet, netip.Prefix{})
+}
+
+// buildMatch resolves the organisation of an inetnum
+func (d *Database) buildMatch(mostSpecific Inetnum, prefix netip.Prefix) *Match {
 	// Skip non-RIPE managed address blocks (catch-all entries)
 	if mostSpecific.Netname == "NON-RIPE-NCC-MANAGED-ADDRESS-BLOCK" {
-		return nil, nil // No match - caller should try other sources
+		return nil // No match - caller should try other sources
 	}
 
 	// Resolve organisation with fallback hierarchy:
@@ -436,7 +511,7 @@ func (d *Database) lookupRange(queryStart, queryEnd uint32, prefix netip.Prefix)
 		Netname:      mostSpecific.Netname,
 		MatchedAt:    time.Now(),
 		FullyCovered: true, // We only return fully covering ranges
-	}, nil
+	}
 }
 
 // GetOrganisation retrieves an organisation by ID
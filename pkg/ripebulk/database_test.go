@@ -6,6 +6,7 @@ package ripebulk
 import (
 	"net/netip"
 	"path/filepath"
+	"slices"
 	"testing"
 )
 
@@ -448,3 +449,53 @@ func TestNonRIPEManagedBlock(t *testing.T) {
 		t.Errorf("Expected nil for NON-RIPE-NCC-MANAGED-ADDRESS-BLOCK, got org '%s'", match.OrgName)
 	}
 }
+
+func TestOverlapping(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.ldb")
+
+	inetnum := func(start, end, netname string) Inetnum {
+		return Inetnum{
+			Start:   AddrToUint32(netip.MustParseAddr(start)),
+			End:     AddrToUint32(netip.MustParseAddr(end)),
+			Netname: netname,
+		}
+	}
+	inetnums := []Inetnum{
+		inetnum("80.0.0.0", "80.255.255.255", "COVERING"),
+		inetnum("81.0.0.0", "81.0.3.255", "ALLOCATION"),
+		inetnum("81.0.2.0", "81.0.2.255", "ASSIGNMENT"),
+		inetnum("81.0.3.128", "81.0.4.255", "STRADDLING"),
+		inetnum("81.0.5.0", "81.0.5.255", "AFTER"),
+	}
+
+	db, err := BuildDatabase(dbPath, inetnums, map[string]Organisation{})
+	if err != nil {
+		t.Fatalf("BuildDatabase failed: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"81.0.0.0/22", []string{"ALLOCATION", "ASSIGNMENT", "STRADDLING"}},
+		{"81.0.2.0/24", []string{"ALLOCATION", "ASSIGNMENT"}},
+		{"80.1.0.0/16", []string{"COVERING"}},
+		{"82.0.0.0/16", nil},
+	}
+	for _, tt := range tests {
+		got, err := db.Overlapping(netip.MustParsePrefix(tt.prefix))
+		if err != nil {
+			t.Fatalf("Overlapping(%s) failed: %v", tt.prefix, err)
+		}
+		var names []string
+		for _, inet := range got {
+			names = append(names, inet.Netname)
+		}
+		slices.Sort(names)
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("Overlapping(%s): got %v, want %v", tt.prefix, names, tt.want)
+		}
+	}
+}
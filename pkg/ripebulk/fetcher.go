@@ -8,11 +8,12 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 const (
@@ -49,6 +50,11 @@ func NewFetcher(baseURL, cacheDir string) *Fetcher {
 	}
 }
 
+// SetTransport sets the transport used for downloads (default: http.DefaultTransport)
+func (f *Fetcher) SetTransport(rt http.RoundTripper) {
+	f.httpClient.Transport = rt
+}
+
 // FetchResult contains the result of a fetch operation
 type FetchResult struct {
 	FilePath     string    // Local path to the downloaded file
@@ -72,7 +78,7 @@ func (f *Fetcher) Fetch(ctx context.Context, filename string) (*FetchResult, err
 	var ifModifiedSince time.Time
 	if stat, err := os.Stat(cachePath); err == nil {
 		ifModifiedSince = stat.ModTime()
-		log.Printf("INFO: Found cached %s (modified: %s)", filename, ifModifiedSince.Format(time.RFC3339))
+		logging.Library().Info("Found cached file", "file", filename, "modified", ifModifiedSince.Format(time.RFC3339))
 	}
 
 	// Build request with conditional headers
@@ -86,7 +92,7 @@ func (f *Fetcher) Fetch(ctx context.Context, filename string) (*FetchResult, err
 	}
 
 	// Execute request
-	log.Printf("INFO: Fetching %s", url)
+	logging.Library().Info("Fetching", "url", url)
 	resp, err := f.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrFetchFailed, err)
@@ -96,7 +102,7 @@ func (f *Fetcher) Fetch(ctx context.Context, filename string) (*FetchResult, err
 	// Handle 304 Not Modified
 	if resp.StatusCode == http.StatusNotModified {
 		stat, _ := os.Stat(cachePath)
-		log.Printf("INFO: %s not modified (using cached version)", filename)
+		logging.Library().Info("Not modified; using cached version", "file", filename)
 		return &FetchResult{
 			FilePath:     cachePath,
 			LastModified: ifModifiedSince,
@@ -142,7 +148,7 @@ func (f *Fetcher) Fetch(ctx context.Context, filename string) (*FetchResult, err
 		return nil, fmt.Errorf("failed to rename file: %w", err)
 	}
 
-	log.Printf("INFO: Downloaded %s (%d bytes, last-modified: %s)", filename, size, lastModified.Format(time.RFC3339))
+	logging.Library().Info("Downloaded", "file", filename, "bytes", size, "last_modified", lastModified.Format(time.RFC3339))
 
 	return &FetchResult{
 		FilePath:     cachePath,
@@ -166,9 +172,9 @@ func (f *Fetcher) FetchAll(ctx context.Context) (inetnumPath, orgPath string, er
 		return "", "", fmt.Errorf("failed to fetch organisation: %w", err)
 	}
 
-	log.Printf("INFO: Fetch complete - inetnum: %s (cached: %v), org: %s (cached: %v)",
-		filepath.Base(inetnumResult.FilePath), inetnumResult.Cached,
-		filepath.Base(orgResult.FilePath), orgResult.Cached)
+	logging.Library().Info("Fetch complete",
+		"inetnum", filepath.Base(inetnumResult.FilePath), "inetnum_cached", inetnumResult.Cached,
+		"org", filepath.Base(orgResult.FilePath), "org_cached", orgResult.Cached)
 
 	return inetnumResult.FilePath, orgResult.FilePath, nil
 }
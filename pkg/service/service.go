@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package service answers lookups from an iporg database for network
+// servers, so that every API over the database behaves the same way.
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// Default limits
+const (
+	DefaultMaxCIDRResults = 10000
+	DefaultInfoTTL        = time.Minute
+)
+
+// Options configures a Service
+type Options struct {
+	MaxCIDRResults int           // Most results returned for a CIDR (default: DefaultMaxCIDRResults)
+	InfoTTL        time.Duration // How long Info is cached (default: DefaultInfoTTL)
+}
+
+// Info is the build metadata and record counts of a database
+type Info struct {
+	BuiltAt        time.Time
+	BuilderVersion string
+	Stats          *model.Stats
+}
+
+// Service answers lookups from a database handle
+type Service struct {
+	db   *iporgdb.DB
+	opts Options
+
+	mu       sync.Mutex
+	info     *Info
+	infoTime time.Time
+}
+
+// New creates a service over db
+func New(db *iporgdb.DB, opts Options) *Service {
+	if opts.MaxCIDRResults <= 0 {
+		opts.MaxCIDRResults = DefaultMaxCIDRResults
+	}
+	if opts.InfoTTL <= 0 {
+		opts.InfoTTL = DefaultInfoTTL
+	}
+	return &Service{db: db, opts: opts}
+}
+
+// Lookup returns the record containing ip. Errors wrap model.ErrInvalidIP
+// or model.ErrNotFound where they apply.
+func (s *Service) Lookup(ip string) (*model.LookupResult, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidIP, err)
+	}
+	rec, err := s.db.GetByIP(addr)
+	if err != nil {
+		return nil, err
+	}
+	return iporgdb.ToLookupResult(addr.String(), rec), nil
+}
+
+// LookupCIDR returns the records overlapping cidr, in address order, with
+// the IP of each result set to its first address. At most limit results
+// are returned (0 or more than the service maximum for the maximum), and
+// truncated reports whether there were more.
+func (s *Service) LookupCIDR(cidr string, limit int) (results []*model.LookupResult, truncated bool, err error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", model.ErrInvalidIP, err)
+	}
+	if limit <= 0 || limit > s.opts.MaxCIDRResults {
+		limit = s.opts.MaxCIDRResults
+	}
+	recs, truncated, err := s.db.LookupPrefix(prefix, limit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	results = make([]*model.LookupResult, len(recs))
+	for i, rec := range recs {
+		results[i] = iporgdb.ToLookupResult(rec.Start.String(), rec)
+	}
+	return results, truncated, nil
+}
+
+// Reload reopens the database, so that a rebuilt one moved into place is
+// served, and drops the cached Info
+func (s *Service) Reload() error {
+	if err := s.db.Reload(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = nil
+	return nil
+}
+
+// Info returns the build metadata and record counts. Counting scans the
+// whole database, so the answer is cached for the InfoTTL.
+func (s *Service) Info(ctx context.Context) (*Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.info != nil && time.Since(s.infoTime) < s.opts.InfoTTL {
+		return s.info, nil
+	}
+
+	builtAt, err := s.db.GetBuiltAt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get build time: %w", err)
+	}
+	version, err := s.db.GetBuilderVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get builder version: %w", err)
+	}
+	stats, err := s.db.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", err)
+	}
+
+	s.info = &Info{BuiltAt: builtAt, BuilderVersion: version, Stats: stats}
+	s.infoTime = time.Now()
+	return s.info, nil
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package service
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+func newTestService(t *testing.T, opts Options) (*Service, *iporgdb.DB) {
+	t.Helper()
+	db, err := iporgdb.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, rec := range []*model.Record{
+		{Start: netip.MustParseAddr("8.8.8.0"), End: netip.MustParseAddr("8.8.8.127"), ASN: 15169, ASNName: "GOOGLE",
+			OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.0/25", Schema: 1},
+		{Start: netip.MustParseAddr("8.8.8.128"), End: netip.MustParseAddr("8.8.8.255"), ASN: 15169, ASNName: "GOOGLE",
+			OrgName: "Google LLC", RIR: "ARIN", Country: "US", Prefix: "8.8.8.128/25", Schema: 1},
+	} {
+		if err := db.PutRange(rec); err != nil {
+			t.Fatalf("Failed to put range: %v", err)
+		}
+	}
+	return New(db, opts), db
+}
+
+func TestLookup(t *testing.T) {
+	s, _ := newTestService(t, Options{})
+
+	res, err := s.Lookup(" 8.8.8.8 ")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if res.IP != "8.8.8.8" || res.ASN != 15169 || res.Prefix != "8.8.8.0/25" {
+		t.Errorf("got %+v", res)
+	}
+
+	if _, err := s.Lookup("8.8.4.4"); !errors.Is(err, model.ErrNotFound) {
+		t.Errorf("unknown address: got %v, want %v", err, model.ErrNotFound)
+	}
+	if _, err := s.Lookup("not an ip"); !errors.Is(err, model.ErrInvalidIP) {
+		t.Errorf("invalid address: got %v, want %v", err, model.ErrInvalidIP)
+	}
+}
+
+func TestLookupCIDR(t *testing.T) {
+	s, _ := newTestService(t, Options{MaxCIDRResults: 1})
+
+	results, truncated, err := s.LookupCIDR("8.8.8.0/24", 0)
+	if err != nil {
+		t.Fatalf("LookupCIDR failed: %v", err)
+	}
+	if len(results) != 1 || !truncated || results[0].IP != "8.8.8.0" {
+		t.Errorf("got %d results, truncated %v; want 1, true", len(results), truncated)
+	}
+
+	s.opts.MaxCIDRResults = 10
+	results, truncated, err = s.LookupCIDR("8.8.8.0/24", 10)
+	if err != nil {
+		t.Fatalf("LookupCIDR failed: %v", err)
+	}
+	if len(results) != 2 || truncated || results[1].Prefix != "8.8.8.128/25" {
+		t.Errorf("got %d results, truncated %v; want 2, false", len(results), truncated)
+	}
+
+	if _, _, err := s.LookupCIDR("8.8.8.8", 0); !errors.Is(err, model.ErrInvalidIP) {
+		t.Errorf("invalid prefix: got %v, want %v", err, model.ErrInvalidIP)
+	}
+}
+
+func TestInfoCached(t *testing.T) {
+	s, db := newTestService(t, Options{InfoTTL: time.Hour})
+	builtAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := db.SetBuiltAt(builtAt); err != nil {
+		t.Fatalf("SetBuiltAt failed: %v", err)
+	}
+	if err := db.SetBuilderVersion("test"); err != nil {
+		t.Fatalf("SetBuilderVersion failed: %v", err)
+	}
+
+	info, err := s.Info(context.Background())
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if !info.BuiltAt.Equal(builtAt) || info.BuilderVersion != "test" || info.Stats.TotalRecords != 2 {
+		t.Errorf("got built %v, version %q, %d records", info.BuiltAt, info.BuilderVersion, info.Stats.TotalRecords)
+	}
+
+	// Within the TTL the cached answer is returned
+	if err := db.SetBuilderVersion("changed"); err != nil {
+		t.Fatalf("SetBuilderVersion failed: %v", err)
+	}
+	if again, _ := s.Info(context.Background()); again != info {
+		t.Errorf("Info within TTL: got a fresh answer, want the cached one")
+	}
+}
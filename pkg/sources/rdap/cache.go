@@ -5,35 +5,79 @@ package rdap
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/netip"
+	"sync/atomic"
 	"time"
 
 	"github.com/wingedpig/iporg/pkg/iporgdb"
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/ipcodec"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+	"github.com/wingedpig/iporg/pkg/util/ratelimit"
 )
 
+// CacheCategory is the iporgdb cache category holding RDAP results
+const CacheCategory = "rdap"
+
+// DefaultNegativeTTL is how long "no data" results are cached by default
+const DefaultNegativeTTL = 24 * time.Hour
+
 // CachedClient wraps an RDAP client with caching
 type CachedClient struct {
-	client   *Client
-	db       *iporgdb.DB
-	cacheTTL time.Duration
+	client      *Client
+	db          *iporgdb.DB
+	cacheTTL    time.Duration
+	negativeTTL time.Duration
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+	stale        atomic.Int64
 }
 
-// NewCachedClient creates a new cached RDAP client
+// NewCachedClient creates a new cached RDAP client. db may be the output
+// database or a separate store used only for the cache.
 func NewCachedClient(client *Client, db *iporgdb.DB, cacheTTL time.Duration) *CachedClient {
 	return &CachedClient{
-		client:   client,
-		db:       db,
-		cacheTTL: cacheTTL,
+		client:      client,
+		db:          db,
+		cacheTTL:    cacheTTL,
+		negativeTTL: DefaultNegativeTTL,
 	}
 }
 
+// SetNegativeTTL sets how long "no data" results are cached (0 disables
+// negative caching)
+func (c *CachedClient) SetNegativeTTL(ttl time.Duration) {
+	c.negativeTTL = ttl
+}
+
 // CacheEntry represents a cached RDAP result
 type CacheEntry struct {
 	Org       *model.RDAPOrg
 	FetchedAt time.Time
+	Negative  bool   `json:",omitempty"` // RDAP had no data for the key
+	Error     string `json:",omitempty"` // Why, for negative entries
+}
+
+// Expired reports whether the entry is older than its TTL at now
+func (e *CacheEntry) Expired(now time.Time, ttl, negativeTTL time.Duration) bool {
+	if e.Negative {
+		ttl = negativeTTL
+	}
+	return now.Sub(e.FetchedAt) >= ttl
+}
+
+// CacheStats counts cache outcomes since the client was created
+type CacheStats struct {
+	Hits         int64 // Fresh entries served, including negative ones
+	NegativeHits int64 // Fresh negative entries served
+	Misses       int64 // Lookups that went to RDAP
+	Stale        int64 // Expired entries served because RDAP rate limited us
 }
 
 // OrgForPrefix retrieves organization info for a prefix, using cache if available
@@ -45,39 +89,9 @@ func (c *CachedClient) OrgForPrefix(ctx context.Context, prefix string) (*model.
 	}
 	normalizedPrefix := parsedPrefix.Masked().String()
 
-	// Try cache first
-	var cached CacheEntry
-	if err := c.db.GetCache("rdap", normalizedPrefix, &cached); err == nil && cached.Org != nil {
-		// Check if cache is still valid
-		if time.Since(cached.FetchedAt) < c.cacheTTL {
-			log.Printf("INFO: Cache hit for prefix %s", normalizedPrefix)
-			return cached.Org, nil
-		}
-		log.Printf("INFO: Cache expired for prefix %s", normalizedPrefix)
-	}
-
-	// Cache miss or expired - fetch from RDAP
-	log.Printf("INFO: Fetching RDAP data for prefix %s", normalizedPrefix)
-	org, err := c.client.OrgForPrefix(ctx, normalizedPrefix)
-	if err != nil {
-		// If it's a rate limit error, try to use expired cache
-		if err == model.ErrRateLimited && cached.Org != nil {
-			log.Printf("WARN: Rate limited, using expired cache for %s", normalizedPrefix)
-			return cached.Org, nil
-		}
-		return nil, err
-	}
-
-	// Store in cache
-	entry := CacheEntry{
-		Org:       org,
-		FetchedAt: time.Now(),
-	}
-	if err := c.db.SetCache("rdap", normalizedPrefix, entry); err != nil {
-		log.Printf("WARN: Failed to cache RDAP result: %v", err)
-	}
-
-	return org, nil
+	return c.lookup(normalizedPrefix, slog.String("prefix", normalizedPrefix), func() (*model.RDAPOrg, error) {
+		return c.client.OrgForPrefix(ctx, normalizedPrefix)
+	})
 }
 
 // OrgForIP retrieves organization info for an IP, using cache if available
@@ -86,47 +100,112 @@ func (c *CachedClient) OrgForIP(ctx context.Context, ip netip.Addr) (*model.RDAP
 	// In practice, you might want to cache by prefix instead
 	ipStr := ip.String()
 
+	return c.lookup("ip:"+ipStr, slog.String("ip", ipStr), func() (*model.RDAPOrg, error) {
+		return c.client.OrgForIP(ctx, ip)
+	})
+}
+
+// lookup serves key from the cache or calls fetch and caches its result
+func (c *CachedClient) lookup(key string, what slog.Attr, fetch func() (*model.RDAPOrg, error)) (*model.RDAPOrg, error) {
 	var cached CacheEntry
-	if err := c.db.GetCache("rdap", "ip:"+ipStr, &cached); err == nil && cached.Org != nil {
-		if time.Since(cached.FetchedAt) < c.cacheTTL {
-			log.Printf("INFO: Cache hit for IP %s", ipStr)
+	found := c.db.GetCache(CacheCategory, key, &cached) == nil && !cached.FetchedAt.IsZero()
+
+	if found {
+		if !cached.Expired(time.Now(), c.cacheTTL, c.negativeTTL) {
+			c.hits.Add(1)
+			if cached.Negative {
+				c.negativeHits.Add(1)
+				return nil, fmt.Errorf("%s (cached): %w", cached.Error, ErrNoData)
+			}
+			logging.Library().Debug("RDAP cache hit", what)
 			return cached.Org, nil
 		}
+		logging.Library().Debug("RDAP cache entry expired", what)
 	}
 
-	log.Printf("INFO: Fetching RDAP data for IP %s", ipStr)
-	org, err := c.client.OrgForIP(ctx, ip)
+	// Cache miss or expired - fetch from RDAP
+	c.misses.Add(1)
+	logging.Library().Debug("Fetching RDAP data", what)
+	org, err := fetch()
 	if err != nil {
-		if err == model.ErrRateLimited && cached.Org != nil {
-			log.Printf("WARN: Rate limited, using expired cache for %s", ipStr)
+		// If it's a rate limit error, try to use expired cache
+		if errors.Is(err, model.ErrRateLimited) && found && cached.Org != nil {
+			c.stale.Add(1)
+			logging.Library().Warn("Rate limited; using expired cache entry", what)
 			return cached.Org, nil
 		}
+		if errors.Is(err, ErrNoData) && c.negativeTTL > 0 {
+			c.store(key, CacheEntry{FetchedAt: time.Now(), Negative: true, Error: err.Error()})
+		}
 		return nil, err
 	}
 
-	entry := CacheEntry{
-		Org:       org,
-		FetchedAt: time.Now(),
-	}
-	if err := c.db.SetCache("rdap", "ip:"+ipStr, entry); err != nil {
-		log.Printf("WARN: Failed to cache RDAP result: %v", err)
+	c.store(key, CacheEntry{Org: org, FetchedAt: time.Now()})
+	return org, nil
+}
+
+// store writes a cache entry, logging failures
+func (c *CachedClient) store(key string, entry CacheEntry) {
+	if err := c.db.SetCache(CacheCategory, key, entry); err != nil {
+		logging.Library().Warn("Failed to cache RDAP result", "key", key, "err", err)
 	}
+}
 
-	return org, nil
+// Rates returns the current request rate for each RDAP server contacted
+func (c *CachedClient) Rates() []ratelimit.HostRate {
+	return c.client.Rates()
 }
 
-// ClearExpiredCache removes expired cache entries
-func (c *CachedClient) ClearExpiredCache(ctx context.Context) error {
-	// This is a simplified implementation
-	// In a real implementation, you'd iterate through cache entries
-	// and delete expired ones
-	log.Printf("INFO: Cache cleanup not yet implemented")
-	return nil
+// ClearExpiredCache removes expired cache entries and returns how many were removed
+func (c *CachedClient) ClearExpiredCache(ctx context.Context) (int, error) {
+	return PruneCache(ctx, c.db, time.Now(), c.cacheTTL, c.negativeTTL)
 }
 
 // Stats returns cache statistics
-func (c *CachedClient) Stats() (hits, misses int64, err error) {
-	// This would require tracking cache hits/misses
-	// For now, return zeros
-	return 0, 0, nil
+func (c *CachedClient) Stats() CacheStats {
+	return CacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Stale:        c.stale.Load(),
+	}
+}
+
+// PruneCache deletes RDAP cache entries in db that are expired at now, as
+// well as entries that cannot be decoded. It returns the number removed.
+func PruneCache(ctx context.Context, db *iporgdb.DB, now time.Time, ttl, negativeTTL time.Duration) (int, error) {
+	const batchSize = 1000
+
+	var ops []iporgdb.BatchOp
+	removed := 0
+	flush := func() error {
+		if len(ops) == 0 {
+			return nil
+		}
+		if err := db.WriteBatch(ops); err != nil {
+			return fmt.Errorf("failed to delete cache entries: %w", err)
+		}
+		removed += len(ops)
+		ops = ops[:0]
+		return nil
+	}
+
+	err := db.IterateCache(CacheCategory, func(key string, value []byte) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(value, &entry); err == nil && !entry.Expired(now, ttl, negativeTTL) {
+			return nil
+		}
+		ops = append(ops, iporgdb.BatchOp{Key: ipcodec.CacheKey(CacheCategory, key), Delete: true})
+		if len(ops) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, err
+	}
+	return removed, flush()
 }
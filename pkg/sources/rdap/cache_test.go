@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package rdap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wingedpig/iporg/pkg/iporgdb"
+)
+
+const testRDAPResponse = `{
+  "objectClassName": "ip network",
+  "name": "EXAMPLE-NET",
+  "entities": [{
+    "handle": "ORG-EX1-RIPE",
+    "roles": ["registrant"],
+    "vcardArray": ["vcard", [["fn", {}, "text", "Example Ltd"]]]
+  }]
+}`
+
+func TestCachedClient(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if strings.HasSuffix(r.URL.Path, "/192.0.2.0") {
+			w.Write([]byte(testRDAPResponse))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	tmpDir, err := os.MkdirTemp("", "rdap-cache-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := iporgdb.Open(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	client := NewCachedClient(NewClient(server.URL, "test", 0), db, time.Hour)
+	client.SetNegativeTTL(time.Minute)
+	ctx := context.Background()
+
+	// Positive results are fetched once and then served from cache
+	for i := 0; i < 2; i++ {
+		org, err := client.OrgForPrefix(ctx, "192.0.2.0/24")
+		if err != nil {
+			t.Fatalf("OrgForPrefix failed: %v", err)
+		}
+		if org.OrgName != "Example Ltd" {
+			t.Errorf("got org %q, want Example Ltd", org.OrgName)
+		}
+	}
+
+	// 404s are cached as negative entries
+	for i := 0; i < 2; i++ {
+		_, err := client.OrgForIP(ctx, netip.MustParseAddr("198.51.100.1"))
+		if !errors.Is(err, ErrNoData) {
+			t.Fatalf("got %v, want ErrNoData", err)
+		}
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d RDAP requests, want 2", got)
+	}
+	want := CacheStats{Hits: 2, NegativeHits: 1, Misses: 2}
+	if got := client.Stats(); got != want {
+		t.Errorf("got stats %+v, want %+v", got, want)
+	}
+
+	var keys []string
+	err = db.IterateCache(CacheCategory, func(key string, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("IterateCache failed: %v", err)
+	}
+	if got := strings.Join(keys, ","); got != "192.0.2.0/24,ip:198.51.100.1" {
+		t.Errorf("got cache keys %s", got)
+	}
+
+	// Negative entries expire first
+	tests := []struct {
+		name    string
+		at      time.Duration
+		removed int
+	}{
+		{"fresh", 0, 0},
+		{"negative expired", 2 * time.Minute, 1},
+		{"all expired", 2 * time.Hour, 1},
+	}
+	for _, tt := range tests {
+		removed, err := PruneCache(ctx, db, time.Now().Add(tt.at), time.Hour, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: PruneCache failed: %v", tt.name, err)
+		}
+		if removed != tt.removed {
+			t.Errorf("%s: got %d removed, want %d", tt.name, removed, tt.removed)
+		}
+	}
+}
@@ -6,18 +6,18 @@ package rdap
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 	"net/netip"
 	"strings"
 	"time"
 
-	"golang.org/x/time/rate"
-
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+	"github.com/wingedpig/iporg/pkg/util/ratelimit"
 	"github.com/wingedpig/iporg/pkg/util/workers"
 )
 
@@ -26,29 +26,36 @@ const (
 	defaultTimeout      = 30 * time.Second
 )
 
+// ErrNoData is returned when RDAP has nothing usable for a query: the server
+// answered 404 or the response names no organization
+var ErrNoData = errors.New("no RDAP data")
+
 // Client is an RDAP client
 type Client struct {
 	bootstrapURL string
 	httpClient   *http.Client
-	limiter      *rate.Limiter
+	transport    *ratelimit.Transport
+	limiter      *ratelimit.Limiter
 	userAgent    string
 }
 
-// NewClient creates a new RDAP client
+// NewClient creates a new RDAP client. rateLimit applies per RDAP server
+// (redirects to other RIRs are paced separately) and adapts to throttling.
 func NewClient(bootstrapURL, userAgent string, rateLimit float64) *Client {
 	if bootstrapURL == "" {
 		bootstrapURL = defaultBootstrapURL
 	}
 
-	var limiter *rate.Limiter
-	if rateLimit > 0 {
-		limiter = rate.NewLimiter(rate.Limit(rateLimit), int(rateLimit)+1)
-	}
+	limiter := ratelimit.New(ratelimit.Config{Rate: rateLimit})
+	// The timeout starts once the limiter lets a request through, so waiting
+	// out a throttled host doesn't use it up
+	transport := &ratelimit.Transport{Limiter: limiter, Timeout: defaultTimeout}
 
 	return &Client{
 		bootstrapURL: bootstrapURL,
+		transport:    transport,
 		httpClient: &http.Client{
-			Timeout: defaultTimeout,
+			Transport: transport,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				// Follow redirects automatically
 				return nil
@@ -67,15 +74,9 @@ func (c *Client) QueryIP(ctx context.Context, ip netip.Addr) (*Response, error)
 	// Build URL - RDAP uses /ip/{address} path
 	url := fmt.Sprintf("%s/ip/%s", c.bootstrapURL, ipStr)
 
-	// Rate limit
-	if c.limiter != nil {
-		if err := c.limiter.Wait(ctx); err != nil {
-			return nil, fmt.Errorf("rate limit: %w", err)
-		}
-	}
-
-	// Retry with backoff
+	// Retry with backoff; the transport paces each attempt
 	var response Response
+	notFound := false
 	err := workers.Retry(ctx, workers.DefaultRetryConfig(), func() error {
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
@@ -93,13 +94,15 @@ func (c *Client) QueryIP(ctx context.Context, ip netip.Addr) (*Response, error)
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode == http.StatusTooManyRequests {
-			log.Printf("WARN: Rate limited by RDAP server for %s", ipStr)
-			return model.ErrRateLimited
+		if ratelimit.IsThrottled(resp) {
+			rerr := ratelimit.ErrorFromResponse(resp, model.ErrRateLimited)
+			logging.Library().Warn("Rate limited", "host", resp.Request.URL.Host, "ip", ipStr, "err", rerr)
+			return rerr
 		}
 
 		if resp.StatusCode == http.StatusNotFound {
-			// Not found is not an error for RDAP - it just means no data
+			// Not found is not a failure for RDAP - it just means no data
+			notFound = true
 			return nil
 		}
 
@@ -121,15 +124,29 @@ func (c *Client) QueryIP(ctx context.Context, ip netip.Addr) (*Response, error)
 	})
 
 	if err != nil {
-		if err == model.ErrRateLimited {
-			return nil, err
+		if errors.Is(err, model.ErrRateLimited) {
+			return nil, model.ErrRateLimited
 		}
 		return nil, fmt.Errorf("RDAP query failed for %s: %w", ipStr, err)
 	}
+	if notFound {
+		return nil, fmt.Errorf("%s: %w", ipStr, ErrNoData)
+	}
 
 	return &response, nil
 }
 
+// SetTransport sets the transport requests are sent through after rate
+// limiting (default: http.DefaultTransport)
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.transport.Base = rt
+}
+
+// Rates returns the current request rate for each RDAP server contacted
+func (c *Client) Rates() []ratelimit.HostRate {
+	return c.limiter.Rates()
+}
+
 // QueryPrefix performs an RDAP query for an IP prefix
 func (c *Client) QueryPrefix(ctx context.Context, prefix string) (*Response, error) {
 	// Normalize the prefix
@@ -5,10 +5,10 @@ package rdap
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/logging"
 )
 
 // ParseOrg extracts organization information from an RDAP response
@@ -97,13 +97,13 @@ func ParseOrg(response *Response) (*model.RDAPOrg, error) {
 		// Use detailed org from remarks (includes customer IDs)
 		org.OrgName = remarkOrg
 		org.SourceRole = "remark"
-		log.Printf("INFO: Using remark as organization: %s", remarkOrg)
+		logging.Library().Debug("Using remark as organization", "org", remarkOrg)
 		return org, nil
 	} else if hasGoodNetworkName {
 		// Use network name directly
 		org.OrgName = response.Name
 		org.SourceRole = "network_name"
-		log.Printf("INFO: Using network name as organization: %s", response.Name)
+		logging.Library().Debug("Using network name as organization", "org", response.Name)
 		return org, nil
 	} else if adminEntity != nil {
 		selectedEntity = adminEntity
@@ -138,7 +138,7 @@ func ParseOrg(response *Response) (*model.RDAPOrg, error) {
 			if name != "" {
 				org.OrgName = name
 				org.SourceRole = "entity"
-				log.Printf("WARN: Using entity name as fallback: %s", name)
+				logging.Library().Debug("Using entity name as fallback", "org", name)
 				break
 			}
 		}
@@ -150,7 +150,7 @@ func ParseOrg(response *Response) (*model.RDAPOrg, error) {
 		if response.Name != "" && !strings.HasSuffix(response.Name, "-MNT") {
 			org.OrgName = response.Name
 			org.SourceRole = "network_name"
-			log.Printf("INFO: Using network name: %s", response.Name)
+			logging.Library().Debug("Using network name", "org", response.Name)
 		}
 	}
 
@@ -163,7 +163,7 @@ func ParseOrg(response *Response) (*model.RDAPOrg, error) {
 	}
 
 	if org.OrgName == "" {
-		return nil, fmt.Errorf("no organization name found in RDAP response")
+		return nil, fmt.Errorf("no organization name found in RDAP response: %w", ErrNoData)
 	}
 
 	return org, nil
@@ -8,12 +8,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
-	"golang.org/x/time/rate"
-
+	"github.com/wingedpig/iporg/pkg/model"
+	"github.com/wingedpig/iporg/pkg/util/logging"
+	"github.com/wingedpig/iporg/pkg/util/ratelimit"
 	workers2 "github.com/wingedpig/iporg/pkg/util/workers"
 )
 
@@ -26,7 +26,8 @@ const (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
-	limiter    *rate.Limiter
+	transport  *ratelimit.Transport
+	limiter    *ratelimit.Limiter
 	userAgent  string
 }
 
@@ -36,15 +37,16 @@ func NewClient(baseURL, userAgent string, rateLimit float64) *Client {
 		baseURL = defaultBaseURL
 	}
 
-	var limiter *rate.Limiter
-	if rateLimit > 0 {
-		limiter = rate.NewLimiter(rate.Limit(rateLimit), int(rateLimit)+1)
-	}
+	limiter := ratelimit.New(ratelimit.Config{Rate: rateLimit})
+	// The timeout starts once the limiter lets a request through, so waiting
+	// out a throttled host doesn't use it up
+	transport := &ratelimit.Transport{Limiter: limiter, Timeout: defaultTimeout}
 
 	return &Client{
-		baseURL: baseURL,
+		baseURL:   baseURL,
+		transport: transport,
 		httpClient: &http.Client{
-			Timeout: defaultTimeout,
+			Transport: transport,
 		},
 		limiter:   limiter,
 		userAgent: userAgent,
@@ -55,14 +57,7 @@ func NewClient(baseURL, userAgent string, rateLimit float64) *Client {
 func (c *Client) AnnouncedPrefixes(ctx context.Context, asn int) ([]string, error) {
 	url := fmt.Sprintf("%s/data/announced-prefixes/data.json?resource=AS%d", c.baseURL, asn)
 
-	// Rate limit
-	if c.limiter != nil {
-		if err := c.limiter.Wait(ctx); err != nil {
-			return nil, fmt.Errorf("rate limit: %w", err)
-		}
-	}
-
-	// Retry with backoff
+	// Retry with backoff; the transport paces each attempt
 	var result announcedPrefixesResponse
 	err := workers2.Retry(ctx, workers2.DefaultRetryConfig(), func() error {
 		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -80,8 +75,8 @@ func (c *Client) AnnouncedPrefixes(ctx context.Context, asn int) ([]string, erro
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode == http.StatusTooManyRequests {
-			return fmt.Errorf("rate limited by server")
+		if ratelimit.IsThrottled(resp) {
+			return ratelimit.ErrorFromResponse(resp, model.ErrRateLimited)
 		}
 
 		if resp.StatusCode != http.StatusOK {
@@ -115,10 +110,21 @@ func (c *Client) AnnouncedPrefixes(ctx context.Context, asn int) ([]string, erro
 		}
 	}
 
-	log.Printf("INFO: Fetched %d prefixes for AS%d", len(prefixes), asn)
+	logging.Library().Info("Fetched announced prefixes", "asn", asn, "prefixes", len(prefixes))
 	return prefixes, nil
 }
 
+// SetTransport sets the transport requests are sent through after rate
+// limiting (default: http.DefaultTransport)
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.transport.Base = rt
+}
+
+// Rates returns the current request rate for each RIPEstat host contacted
+func (c *Client) Rates() []ratelimit.HostRate {
+	return c.limiter.Rates()
+}
+
 // FetchAnnouncedPrefixesForASNs fetches prefixes for multiple ASNs concurrently
 func (c *Client) FetchAnnouncedPrefixesForASNs(ctx context.Context, asns []int, workers int) (map[int][]string, error) {
 	if workers <= 0 {
@@ -141,7 +147,7 @@ func (c *Client) FetchAnnouncedPrefixesForASNs(ctx context.Context, asns []int,
 	for i, asn := range asns {
 		idx := i
 		currentASN := asn
-		pool.Submit(idx, func(ctx context.Context) error {
+		err := pool.Submit(idx, func(ctx context.Context) error {
 			prefixes, err := c.AnnouncedPrefixes(ctx, currentASN)
 			results[idx] = result{
 				asn:      currentASN,
@@ -150,10 +156,18 @@ func (c *Client) FetchAnnouncedPrefixesForASNs(ctx context.Context, asns []int,
 			}
 			return nil // Don't fail the pool on individual errors
 		})
+		if err != nil {
+			break
+		}
 	}
 
 	pool.Wait()
 
+	// Unsubmitted and skipped ASNs have no result after cancellation
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Collect results
 	asnPrefixes := make(map[int][]string)
 	var errors []error
@@ -161,14 +175,14 @@ func (c *Client) FetchAnnouncedPrefixesForASNs(ctx context.Context, asns []int,
 	for _, r := range results {
 		if r.err != nil {
 			errors = append(errors, fmt.Errorf("AS%d: %w", r.asn, r.err))
-			log.Printf("ERROR: Failed to fetch prefixes for AS%d: %v", r.asn, r.err)
+			logging.Library().Error("Failed to fetch prefixes", "asn", r.asn, "err", r.err)
 		} else {
 			asnPrefixes[r.asn] = r.prefixes
 		}
 	}
 
 	if len(errors) > 0 {
-		log.Printf("WARN: %d ASNs failed to fetch", len(errors))
+		logging.Library().Warn("Some ASNs failed to fetch", "failed", len(errors))
 	}
 
 	return asnPrefixes, nil
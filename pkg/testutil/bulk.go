@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package testutil
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/arinbulk"
+	"github.com/wingedpig/iporg/pkg/ripebulk"
+)
+
+// Registration is one registry object (RIPE inetnum or ARIN NetRange) in a
+// synthetic bulk database
+type Registration struct {
+	Range   string // CIDR or "start - end"
+	OrgID   string
+	OrgName string
+	Status  string // RIPE status or ARIN net type
+	Country string
+	NetName string
+}
+
+// WriteRIPEBulk builds a RIPE bulk database at path
+func WriteRIPEBulk(path string, regs []Registration) error {
+	var inetnums []ripebulk.Inetnum
+	orgs := make(map[string]ripebulk.Organisation)
+	for _, r := range regs {
+		start, end, err := parseRange(r.Range)
+		if err != nil {
+			return err
+		}
+		inetnums = append(inetnums, ripebulk.Inetnum{
+			Start:   start,
+			End:     end,
+			OrgID:   r.OrgID,
+			Status:  r.Status,
+			Country: r.Country,
+			Netname: r.NetName,
+		})
+		if r.OrgID != "" {
+			orgs[r.OrgID] = ripebulk.Organisation{OrgID: r.OrgID, OrgName: r.OrgName, OrgType: "OTHER"}
+		}
+	}
+
+	db, err := ripebulk.BuildDatabase(path, inetnums, orgs)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// WriteARINBulk builds an ARIN bulk database at path
+func WriteARINBulk(path string, regs []Registration) error {
+	var nets []arinbulk.NetBlock
+	orgs := make(map[string]arinbulk.Organization)
+	for i, r := range regs {
+		start, end, err := parseRange(r.Range)
+		if err != nil {
+			return err
+		}
+		nets = append(nets, arinbulk.NetBlock{
+			Start:     start,
+			End:       end,
+			NetName:   r.NetName,
+			NetHandle: fmt.Sprintf("NET-TEST-%d", i+1),
+			OrgID:     r.OrgID,
+			NetType:   r.Status,
+		})
+		if r.OrgID != "" {
+			orgs[r.OrgID] = arinbulk.Organization{OrgID: r.OrgID, OrgName: r.OrgName, Country: r.Country}
+		}
+	}
+
+	db, err := arinbulk.BuildDatabase(path, nets, orgs)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// parseRange parses an IPv4 CIDR or "start - end" range
+func parseRange(s string) (uint32, uint32, error) {
+	if startStr, endStr, ok := strings.Cut(s, "-"); ok {
+		start, err := netip.ParseAddr(strings.TrimSpace(startStr))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+		}
+		end, err := netip.ParseAddr(strings.TrimSpace(endStr))
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+		}
+		return ripebulk.AddrToUint32(start), ripebulk.AddrToUint32(end), nil
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %w", s, err)
+	}
+	return ripebulk.PrefixToRange(prefix)
+}
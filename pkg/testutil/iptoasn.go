@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/wingedpig/iporg/pkg/iptoasn"
+	"github.com/wingedpig/iporg/pkg/model"
+)
+
+// IPtoASNRow is one line of an iptoasn TSV file
+type IPtoASNRow struct {
+	Start    string
+	End      string
+	ASN      int
+	Country  string
+	Registry string
+	Name     string
+}
+
+// WriteIPtoASNTSV writes rows in the iptoasn TSV format
+func WriteIPtoASNTSV(path string, rows []IPtoASNRow) error {
+	var sb strings.Builder
+	for _, r := range rows {
+		fmt.Fprintf(&sb, "%s\t%s\t%d\t%s\t%s\t%s\n", r.Start, r.End, r.ASN, r.Country, r.Registry, r.Name)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
+
+// BuildIPtoASN writes rows as TSV next to dbPath and builds an iptoasn store
+// from it the way iptoasn-build does
+func BuildIPtoASN(dbPath string, rows []IPtoASNRow) error {
+	tsvPath := dbPath + ".tsv"
+	if err := WriteIPtoASNTSV(tsvPath, rows); err != nil {
+		return err
+	}
+	f, err := os.Open(tsvPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	raw, err := iptoasn.NewParser(f).ParseAll()
+	if err != nil {
+		return err
+	}
+
+	var prefixes []*model.CanonicalPrefix
+	asns := make(map[int]bool)
+	var v4 int64
+	for _, row := range raw {
+		if row.Start.Is4() {
+			v4++
+		}
+		prefixes = append(prefixes, &model.CanonicalPrefix{
+			CIDR:     row.Prefix.String(),
+			ASN:      row.ASN,
+			Country:  row.Country,
+			Registry: row.Registry,
+			ASName:   row.ASName,
+		})
+		asns[row.ASN] = true
+	}
+	aggregator := iptoasn.NewAggregator()
+	prefixes = aggregator.Deduplicate(prefixes)
+	aggregator.SortByStartIP(prefixes)
+
+	store, err := iptoasn.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.WriteBatch(prefixes, aggregator.CollapseByASN(prefixes)); err != nil {
+		return err
+	}
+	return store.SetStats(&model.IPToASNStats{
+		TotalPrefixes: int64(len(prefixes)),
+		IPv4Prefixes:  v4,
+		UniqueASNs:    len(asns),
+	})
+}
@@ -0,0 +1,327 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package testutil writes small synthetic data sets (MaxMind DBs, bulk
+// registry databases, iptoasn files) and serves stand-ins for RIPEstat and
+// RDAP, so builds can be tested end to end without real data or network.
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"sort"
+)
+
+// Network is one entry of a synthetic MaxMind DB
+type Network struct {
+	Prefix netip.Prefix
+	Data   map[string]any
+}
+
+// ASNBlock is one entry of a synthetic GeoLite2-ASN database
+type ASNBlock struct {
+	Prefix string
+	ASN    int
+	Org    string
+}
+
+// CityBlock is one entry of a synthetic GeoLite2-City database
+type CityBlock struct {
+	Prefix  string
+	Country string
+	Region  string
+	City    string
+	Lat     float64
+	Lon     float64
+}
+
+// WriteASNMMDB writes a GeoLite2-ASN database readable by geoip2
+func WriteASNMMDB(path string, blocks []ASNBlock) error {
+	networks := make([]Network, 0, len(blocks))
+	for _, b := range blocks {
+		prefix, err := netip.ParsePrefix(b.Prefix)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, Network{Prefix: prefix, Data: map[string]any{
+			"autonomous_system_number":       uint32(b.ASN),
+			"autonomous_system_organization": b.Org,
+		}})
+	}
+	return WriteMMDB(path, "GeoLite2-ASN", networks)
+}
+
+// WriteCityMMDB writes a GeoLite2-City database readable by geoip2
+func WriteCityMMDB(path string, blocks []CityBlock) error {
+	networks := make([]Network, 0, len(blocks))
+	for _, b := range blocks {
+		prefix, err := netip.ParsePrefix(b.Prefix)
+		if err != nil {
+			return err
+		}
+		data := map[string]any{
+			"country":  map[string]any{"iso_code": b.Country},
+			"location": map[string]any{"latitude": b.Lat, "longitude": b.Lon},
+		}
+		if b.City != "" {
+			data["city"] = map[string]any{"names": map[string]any{"en": b.City}}
+		}
+		if b.Region != "" {
+			data["subdivisions"] = []any{map[string]any{"names": map[string]any{"en": b.Region}}}
+		}
+		networks = append(networks, Network{Prefix: prefix, Data: data})
+	}
+	return WriteMMDB(path, "GeoLite2-City", networks)
+}
+
+// WriteMMDB writes an IPv6 MaxMind DB (format 2.0, 24-bit records) holding
+// networks. IPv4 networks are stored in ::/96 the way MaxMind does. When
+// networks overlap, the more specific one wins.
+func WriteMMDB(path, dbType string, networks []Network) error {
+	sorted := make([]Network, len(networks))
+	copy(sorted, networks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Prefix.Bits() < sorted[j].Prefix.Bits()
+	})
+
+	// Build the tree; leaves point into the data section
+	var data bytes.Buffer
+	offsets := make(map[string]int)
+	root := &mmdbNode{}
+	for _, n := range sorted {
+		encoded, err := encodeMMDB(n.Data)
+		if err != nil {
+			return fmt.Errorf("network %s: %w", n.Prefix, err)
+		}
+		offset, ok := offsets[string(encoded)]
+		if !ok {
+			offset = data.Len()
+			offsets[string(encoded)] = offset
+			data.Write(encoded)
+		}
+		root.insert(mmdbBits(n.Prefix), offset)
+	}
+
+	// Number the internal nodes breadth first; the root is node 0
+	var nodes []*mmdbNode
+	queue := []*mmdbNode{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		n.id = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil && !c.leaf {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	nodeCount := len(nodes)
+	if nodeCount+16+data.Len() >= 1<<24 {
+		return fmt.Errorf("database too large for 24-bit records")
+	}
+	record := func(c *mmdbNode) int {
+		switch {
+		case c == nil:
+			return nodeCount // No data
+		case c.leaf:
+			return nodeCount + 16 + c.offset
+		}
+		return c.id
+	}
+
+	var out bytes.Buffer
+	for _, n := range nodes {
+		for _, c := range n.children {
+			v := record(c)
+			out.Write([]byte{byte(v >> 16), byte(v >> 8), byte(v)})
+		}
+	}
+	out.Write(make([]byte, 16)) // Data section separator
+	out.Write(data.Bytes())
+
+	meta, err := encodeMMDB(map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(0),
+		"database_type":               dbType,
+		"description":                 map[string]any{"en": "iporg test fixture"},
+		"ip_version":                  uint16(6),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})
+	if err != nil {
+		return err
+	}
+	out.WriteString("\xAB\xCD\xEFMaxMind.com")
+	out.Write(meta)
+
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+// mmdbNode is a search tree node; leaves hold a data section offset
+type mmdbNode struct {
+	children [2]*mmdbNode
+	leaf     bool
+	offset   int
+	id       int
+}
+
+// insert stores offset for the network given by bits, replacing whatever the
+// tree held for that network
+func (n *mmdbNode) insert(bits []byte, offset int) {
+	for i, bit := range bits {
+		if i == len(bits)-1 {
+			n.children[bit] = &mmdbNode{leaf: true, offset: offset}
+			return
+		}
+		child := n.children[bit]
+		switch {
+		case child == nil:
+			child = &mmdbNode{}
+		case child.leaf:
+			// Push the covering network down a level
+			child = &mmdbNode{children: [2]*mmdbNode{
+				{leaf: true, offset: child.offset},
+				{leaf: true, offset: child.offset},
+			}}
+		}
+		n.children[bit] = child
+		n = child
+	}
+}
+
+// mmdbBits returns the network bits of prefix in the IPv6 tree
+func mmdbBits(prefix netip.Prefix) []byte {
+	addr := prefix.Masked().Addr()
+	bits := prefix.Bits()
+	if addr.Is4() {
+		bits += 96
+	}
+	raw := addr.As16()
+	if addr.Is4() {
+		raw = [16]byte{}
+		v4 := addr.As4()
+		copy(raw[12:], v4[:])
+	}
+	out := make([]byte, bits)
+	for i := range out {
+		out[i] = (raw[i/8] >> (7 - i%8)) & 1
+	}
+	return out
+}
+
+// MaxMind DB data section type numbers
+const (
+	mmdbString = 2
+	mmdbDouble = 3
+	mmdbUint16 = 5
+	mmdbUint32 = 6
+	mmdbMap    = 7
+	mmdbUint64 = 9
+	mmdbArray  = 11
+	mmdbBool   = 14
+)
+
+// encodeMMDB encodes v in the MaxMind DB data section format. Maps are
+// written with sorted keys so output is deterministic.
+func encodeMMDB(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeMMDBValue(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeMMDBValue(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case string:
+		writeMMDBControl(buf, mmdbString, len(v))
+		buf.WriteString(v)
+	case float64:
+		writeMMDBControl(buf, mmdbDouble, 8)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		writeMMDBUint(buf, mmdbUint16, uint64(v))
+	case uint32:
+		writeMMDBUint(buf, mmdbUint32, uint64(v))
+	case uint64:
+		writeMMDBUint(buf, mmdbUint64, v)
+	case bool:
+		size := 0
+		if v {
+			size = 1
+		}
+		writeMMDBControl(buf, mmdbBool, size)
+	case []any:
+		writeMMDBControl(buf, mmdbArray, len(v))
+		for _, item := range v {
+			if err := encodeMMDBValue(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeMMDBControl(buf, mmdbMap, len(v))
+		for _, k := range keys {
+			encodeMMDBValue(buf, k)
+			if err := encodeMMDBValue(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported MaxMind DB value type %T", v)
+	}
+	return nil
+}
+
+// writeMMDBUint writes an unsigned integer using as few bytes as possible
+func writeMMDBUint(buf *bytes.Buffer, typ int, v uint64) {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], v)
+	n := 0
+	for n < 8 && raw[n] == 0 {
+		n++
+	}
+	writeMMDBControl(buf, typ, 8-n)
+	buf.Write(raw[n:])
+}
+
+// writeMMDBControl writes the control byte(s) for a value of typ and size
+func writeMMDBControl(buf *bytes.Buffer, typ, size int) {
+	var sizeBits int
+	var sizeBytes []byte
+	switch {
+	case size < 29:
+		sizeBits = size
+	case size < 285:
+		sizeBits = 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		sizeBits = 30
+		s := size - 285
+		sizeBytes = []byte{byte(s >> 8), byte(s)}
+	default:
+		sizeBits = 31
+		s := size - 65821
+		sizeBytes = []byte{byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+
+	if typ <= 7 {
+		buf.WriteByte(byte(typ<<5 | sizeBits))
+	} else {
+		buf.WriteByte(byte(sizeBits))
+		buf.WriteByte(byte(typ - 7))
+	}
+	buf.Write(sizeBytes)
+}
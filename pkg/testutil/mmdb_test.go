@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package testutil
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wingedpig/iporg/pkg/sources/maxmind"
+)
+
+func TestWriteMMDB(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "testutil-mmdb-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	asnPath := filepath.Join(tmpDir, "asn.mmdb")
+	cityPath := filepath.Join(tmpDir, "city.mmdb")
+	err = WriteASNMMDB(asnPath, []ASNBlock{
+		{Prefix: "192.0.2.0/24", ASN: 64500, Org: "Example Net"},
+		{Prefix: "2001:db8::/32", ASN: 64501, Org: "Example V6"},
+	})
+	if err != nil {
+		t.Fatalf("WriteASNMMDB failed: %v", err)
+	}
+	err = WriteCityMMDB(cityPath, []CityBlock{
+		{Prefix: "192.0.2.0/24", Country: "GB", Region: "England", City: "London", Lat: 51.5, Lon: -0.1},
+		{Prefix: "192.0.2.128/25", Country: "FR", City: "Paris", Lat: 48.9, Lon: 2.3},
+	})
+	if err != nil {
+		t.Fatalf("WriteCityMMDB failed: %v", err)
+	}
+
+	readers, err := maxmind.Open(asnPath, cityPath)
+	if err != nil {
+		t.Fatalf("Failed to open synthetic databases: %v", err)
+	}
+	defer readers.Close()
+
+	asnTests := []struct {
+		ip   string
+		asn  int
+		name string
+	}{
+		{"192.0.2.1", 64500, "Example Net"},
+		{"2001:db8::1", 64501, "Example V6"},
+		{"198.51.100.1", 0, ""},
+	}
+	for _, tt := range asnTests {
+		asn, name, err := readers.ASNInfo(netip.MustParseAddr(tt.ip))
+		if err != nil {
+			t.Fatalf("ASNInfo(%s) failed: %v", tt.ip, err)
+		}
+		if asn != tt.asn || name != tt.name {
+			t.Errorf("%s: got AS%d %q, want AS%d %q", tt.ip, asn, name, tt.asn, tt.name)
+		}
+	}
+
+	// The more specific /25 wins over the /24
+	geoTests := []struct {
+		ip   string
+		want maxmind.GeoInfo
+	}{
+		{"192.0.2.1", maxmind.GeoInfo{Country: "GB", Region: "England", City: "London", Lat: 51.5, Lon: -0.1}},
+		{"192.0.2.200", maxmind.GeoInfo{Country: "FR", City: "Paris", Lat: 48.9, Lon: 2.3}},
+	}
+	for _, tt := range geoTests {
+		geo, err := readers.Geo(netip.MustParseAddr(tt.ip))
+		if err != nil {
+			t.Fatalf("Geo(%s) failed: %v", tt.ip, err)
+		}
+		if *geo != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.ip, *geo, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package testutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Server is an httptest server that counts the requests it answers
+type Server struct {
+	*httptest.Server
+	requests atomic.Int64
+}
+
+// Requests returns the number of requests served
+func (s *Server) Requests() int64 {
+	return s.requests.Load()
+}
+
+// newServer starts a counting server that is closed when tb finishes
+func newServer(tb testing.TB, handler http.HandlerFunc) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		handler(w, r)
+	}))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// NewRIPEstat starts a RIPEstat stand-in answering announced-prefixes
+// queries from announced (ASN to prefixes)
+func NewRIPEstat(tb testing.TB, announced map[int][]string) *Server {
+	return newServer(tb, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data/announced-prefixes/data.json" {
+			http.NotFound(w, r)
+			return
+		}
+		var asn int
+		if _, err := fmt.Sscanf(r.URL.Query().Get("resource"), "AS%d", &asn); err != nil {
+			http.Error(w, "invalid resource", http.StatusBadRequest)
+			return
+		}
+
+		type prefix struct {
+			Prefix string `json:"prefix"`
+		}
+		prefixes := []prefix{}
+		for _, p := range announced[asn] {
+			prefixes = append(prefixes, prefix{Prefix: p})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":      "ok",
+			"status_code": 200,
+			"data": map[string]any{
+				"resource": fmt.Sprintf("AS%d", asn),
+				"prefixes": prefixes,
+			},
+		})
+	})
+}
+
+// RDAPNetwork is one IP network served by an RDAP stand-in
+type RDAPNetwork struct {
+	CIDR    string
+	Handle  string
+	Name    string
+	OrgName string
+	Role    string // Entity role (default: registrant)
+	Country string
+	Status  string
+	Port43  string // Identifies the RIR, e.g. whois.ripe.net
+}
+
+// NewRDAP starts an RDAP stand-in. /ip/{addr} returns the most specific
+// network containing addr and 404 when there is none.
+func NewRDAP(tb testing.TB, networks []RDAPNetwork) *Server {
+	type parsedNetwork struct {
+		prefix netip.Prefix
+		net    RDAPNetwork
+	}
+	var parsed []parsedNetwork
+	for _, n := range networks {
+		parsed = append(parsed, parsedNetwork{prefix: netip.MustParsePrefix(n.CIDR), net: n})
+	}
+
+	return newServer(tb, func(w http.ResponseWriter, r *http.Request) {
+		addr, err := netip.ParseAddr(strings.TrimPrefix(r.URL.Path, "/ip/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		var best *parsedNetwork
+		for i := range parsed {
+			p := &parsed[i]
+			if p.prefix.Contains(addr) && (best == nil || p.prefix.Bits() > best.prefix.Bits()) {
+				best = p
+			}
+		}
+		if best == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		n := best.net
+		role := n.Role
+		if role == "" {
+			role = "registrant"
+		}
+		resp := map[string]any{
+			"objectClassName": "ip network",
+			"handle":          n.Handle,
+			"startAddress":    best.prefix.Masked().Addr().String(),
+			"name":            n.Name,
+			"country":         n.Country,
+			"port43":          n.Port43,
+			"entities": []any{map[string]any{
+				"objectClassName": "entity",
+				"handle":          "ORG-" + n.Handle,
+				"roles":           []string{role},
+				"vcardArray":      []any{"vcard", []any{[]any{"fn", map[string]any{}, "text", n.OrgName}}},
+			}},
+		}
+		if n.Status != "" {
+			resp["status"] = []string{n.Status}
+		}
+		w.Header().Set("Content-Type", "application/rdap+json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package httprecord records HTTP exchanges into a directory archive and
+// replays them later, so builds can be reproduced offline.
+//
+// Each exchange is keyed by the SHA-256 of the request method and URL and
+// stored as <key>.json (status and headers) plus <key>.body. Request headers
+// and bodies are not part of the key. When the same request is made more
+// than once while recording (retries, for example), the last response wins.
+package httprecord
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// exchange is the metadata stored for a recorded response
+type exchange struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+// Key returns the archive key for a request
+func Key(method, url string) string {
+	sum := sha256.Sum256([]byte(method + " " + url))
+	return hex.EncodeToString(sum[:])
+}
+
+// NotRecordedError is returned by a Replayer for requests missing from the archive
+type NotRecordedError struct {
+	Method string
+	URL    string
+}
+
+func (e *NotRecordedError) Error() string {
+	return fmt.Sprintf("httprecord: no recorded response for %s %s", e.Method, e.URL)
+}
+
+// Permanent tells retry loops that replaying again cannot succeed
+func (e *NotRecordedError) Permanent() bool {
+	return true
+}
+
+// NewTransport returns a Recorder for recordDir or a Replayer for replayDir,
+// wrapping base. It returns base unchanged if both are empty.
+func NewTransport(recordDir, replayDir string, base http.RoundTripper) (http.RoundTripper, error) {
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, fmt.Errorf("cannot both record and replay HTTP traffic")
+	case recordDir != "":
+		return NewRecorder(recordDir, base)
+	case replayDir != "":
+		return NewReplayer(replayDir)
+	}
+	return base, nil
+}
+
+// Recorder is an http.RoundTripper that saves every response it passes on
+type Recorder struct {
+	base http.RoundTripper
+	dir  string
+}
+
+// NewRecorder creates a recorder writing to dir. base defaults to
+// http.DefaultTransport.
+func NewRecorder(dir string, base http.RoundTripper) (*Recorder, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %w", err)
+	}
+	return &Recorder{base: base, dir: dir}, nil
+}
+
+// RoundTrip implements http.RoundTripper. The response body is archived as
+// it is read; the exchange is saved when the body is closed.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(r.dir, ".body-*")
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("httprecord: %w", err)
+	}
+
+	resp.Body = &recordingBody{
+		src: resp.Body,
+		tmp: tmp,
+		dir: r.dir,
+		exchange: exchange{
+			Method:     req.Method,
+			URL:        req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			RecordedAt: time.Now().UTC(),
+		},
+	}
+	return resp, nil
+}
+
+// recordingBody copies a response body to the archive as it is read
+type recordingBody struct {
+	src      io.ReadCloser
+	tmp      *os.File
+	dir      string
+	exchange exchange
+	failed   bool
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.src.Read(p)
+	if n > 0 {
+		if _, werr := b.tmp.Write(p[:n]); werr != nil {
+			b.failed = true
+		}
+	}
+	if err != nil && err != io.EOF {
+		b.failed = true
+	}
+	return n, err
+}
+
+// Close drains the rest of the body and saves the exchange
+func (b *recordingBody) Close() error {
+	if _, err := io.Copy(b.tmp, b.src); err != nil {
+		b.failed = true
+	}
+	b.src.Close()
+	tmpName := b.tmp.Name()
+	if err := b.tmp.Close(); err != nil {
+		b.failed = true
+	}
+	if b.failed {
+		os.Remove(tmpName)
+		return fmt.Errorf("httprecord: failed to record %s %s", b.exchange.Method, b.exchange.URL)
+	}
+
+	key := Key(b.exchange.Method, b.exchange.URL)
+	if err := os.Rename(tmpName, filepath.Join(b.dir, key+".body")); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("httprecord: %w", err)
+	}
+
+	data, err := json.MarshalIndent(b.exchange, "", "  ")
+	if err != nil {
+		return fmt.Errorf("httprecord: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(b.dir, key+".json"), data)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".meta-*")
+	if err != nil {
+		return fmt.Errorf("httprecord: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("httprecord: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("httprecord: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("httprecord: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper that serves responses from an archive and
+// never touches the network
+type Replayer struct {
+	dir    string
+	misses atomic.Int64
+}
+
+// NewReplayer creates a replayer reading from dir
+func NewReplayer(dir string) (*Replayer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("replay path %s is not a directory", dir)
+	}
+	return &Replayer{dir: dir}, nil
+}
+
+// Misses returns the number of requests that were not in the archive
+func (r *Replayer) Misses() int64 {
+	return r.misses.Load()
+}
+
+// RoundTrip implements http.RoundTripper. Requests missing from the archive
+// fail with a *NotRecordedError.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	key := Key(req.Method, req.URL.String())
+	data, err := os.ReadFile(filepath.Join(r.dir, key+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		r.misses.Add(1)
+		return nil, &NotRecordedError{Method: req.Method, URL: req.URL.String()}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("httprecord: %w", err)
+	}
+
+	var ex exchange
+	if err := json.Unmarshal(data, &ex); err != nil {
+		return nil, fmt.Errorf("httprecord: invalid archive entry %s: %w", key, err)
+	}
+
+	body, err := os.Open(filepath.Join(r.dir, key+".body"))
+	if err != nil {
+		return nil, fmt.Errorf("httprecord: %w", err)
+	}
+	info, err := body.Stat()
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("httprecord: %w", err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.StatusCode, http.StatusText(ex.StatusCode)),
+		StatusCode:    ex.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.Header,
+		Body:          body,
+		ContentLength: info.Size(),
+		Request:       req,
+	}, nil
+}
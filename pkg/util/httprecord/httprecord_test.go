@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package httprecord
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/data", http.StatusFound)
+		case "/data":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name":"example"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	tmpDir, err := os.MkdirTemp("", "httprecord-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	recorder, err := NewRecorder(tmpDir, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	paths := []string{"/old", "/missing"}
+	want := make(map[string]int)
+	for _, path := range paths {
+		status, _ := get(t, &http.Client{Transport: recorder}, server.URL+path)
+		want[path] = status
+	}
+	server.Close()
+
+	replayer, err := NewReplayer(tmpDir)
+	if err != nil {
+		t.Fatalf("NewReplayer failed: %v", err)
+	}
+	client := &http.Client{Transport: replayer}
+
+	// The redirect is followed from the archive, with the server gone
+	status, body := get(t, client, server.URL+"/old")
+	if status != http.StatusOK || body != `{"name":"example"}` {
+		t.Errorf("got %d %q, want the recorded response", status, body)
+	}
+	if status, _ := get(t, client, server.URL+"/missing"); status != want["/missing"] {
+		t.Errorf("got status %d, want %d", status, want["/missing"])
+	}
+
+	_, err = client.Get(server.URL + "/never")
+	var nre *NotRecordedError
+	if !errors.As(err, &nre) {
+		t.Fatalf("got %v, want NotRecordedError", err)
+	}
+	if !nre.Permanent() {
+		t.Error("NotRecordedError is not permanent")
+	}
+	if got := replayer.Misses(); got != 1 {
+		t.Errorf("got %d misses, want 1", got)
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	if _, err := NewTransport("a", "b", nil); err == nil {
+		t.Error("got nil error for record and replay together")
+	}
+	rt, err := NewTransport("", "", http.DefaultTransport)
+	if err != nil || rt != http.DefaultTransport {
+		t.Errorf("got %v, %v, want the base transport", rt, err)
+	}
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s failed: %v", url, err)
+	}
+	return resp.StatusCode, string(body)
+}
@@ -119,6 +119,44 @@ func CIDRToRange(cidr string) (start, end netip.Addr, err error) {
 	return start, end, nil
 }
 
+// RangeToPrefixes returns the smallest set of CIDR prefixes that exactly
+// covers the inclusive range start-end
+func RangeToPrefixes(start, end netip.Addr) ([]netip.Prefix, error) {
+	if !start.IsValid() || !end.IsValid() || start.Is4() != end.Is4() {
+		return nil, fmt.Errorf("invalid range %s-%s", start, end)
+	}
+	if end.Less(start) {
+		return nil, fmt.Errorf("range end %s is before start %s", end, start)
+	}
+
+	var prefixes []netip.Prefix
+	for {
+		// Find the largest aligned block starting at start that ends within the range
+		var prefix netip.Prefix
+		var last netip.Addr
+		for bits := 0; bits <= start.BitLen(); bits++ {
+			p := netip.PrefixFrom(start, bits)
+			if p.Masked().Addr() != start {
+				continue
+			}
+			_, blockEnd, err := CIDRToRange(p.String())
+			if err != nil {
+				return nil, err
+			}
+			if !end.Less(blockEnd) {
+				prefix, last = p, blockEnd
+				break
+			}
+		}
+		prefixes = append(prefixes, prefix)
+
+		if last == end {
+			return prefixes, nil
+		}
+		start = last.Next()
+	}
+}
+
 // IPToBytes converts an IP address to big-endian bytes
 func IPToBytes(ip netip.Addr) []byte {
 	return ip.AsSlice()
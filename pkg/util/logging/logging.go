@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package logging builds the slog loggers used by the commands. Library
+// packages log through Library, which discards everything until
+// SetLibraryLogger is called.
+package logging
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Discard returns a logger that drops every record
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// library receives the log output of the library packages
+var library atomic.Pointer[slog.Logger]
+
+func init() {
+	library.Store(Discard())
+}
+
+// Library returns the logger the library packages (iporgdb, the bulk
+// databases, the sources and enrich) write to
+func Library() *slog.Logger {
+	return library.Load()
+}
+
+// SetLibraryLogger directs the log output of the library packages to l. It
+// is safe to call while they are in use; nil restores the default of
+// discarding everything.
+func SetLibraryLogger(l *slog.Logger) {
+	if l == nil {
+		l = Discard()
+	}
+	library.Store(l)
+}
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// New creates a logger writing records at or above level to w. format is
+// "text" (key=value pairs) or "json" (one object per line).
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+}
+
+// Flags holds the --log-level and --log-format command-line options
+type Flags struct {
+	Level  string
+	Format string
+}
+
+// Register adds --log-level and --log-format to fs
+func (f *Flags) Register(fs *flag.FlagSet) {
+	fs.StringVar(&f.Level, "log-level", "info", "Log level: debug, info, warn or error")
+	fs.StringVar(&f.Format, "log-format", "text", "Log format: text or json")
+}
+
+// Setup creates a logger on stderr from the flags and installs it as the
+// slog default, which also receives output from the standard log package,
+// and as the library packages' logger.
+func (f *Flags) Setup() (*slog.Logger, error) {
+	logger, err := New(os.Stderr, f.Level, f.Format)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	SetLibraryLogger(logger)
+	return logger, nil
+}
+
+// Fatal logs msg at error level on the default logger and exits with status 1
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q): got error %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseLevel(%q): got %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	logger.Info("dropped")
+	logger.Warn("kept", "prefix", "192.0.2.0/24", "asn", 64500)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if rec["msg"] != "kept" || rec["prefix"] != "192.0.2.0/24" || rec["asn"] != float64(64500) {
+		t.Errorf("got %v, want msg=kept prefix=192.0.2.0/24 asn=64500", rec)
+	}
+
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Error("New with format xml: got nil error, want error")
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	if Discard().Enabled(context.Background(), slog.LevelError) {
+		t.Error("Discard logger is enabled, want disabled")
+	}
+}
+
+func TestSetLibraryLogger(t *testing.T) {
+	defer SetLibraryLogger(nil)
+	if Library().Enabled(context.Background(), slog.LevelError) {
+		t.Errorf("library logger enabled before SetLibraryLogger")
+	}
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, "info", "text")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	// Safe while the library packages are logging
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			Library().Debug("lookup")
+		}
+	}()
+	SetLibraryLogger(logger)
+	<-done
+
+	Library().Info("loaded")
+	if !strings.Contains(buf.String(), "msg=loaded") {
+		t.Errorf("got %q, want the library record", buf.String())
+	}
+	SetLibraryLogger(nil)
+	if Library().Enabled(context.Background(), slog.LevelError) {
+		t.Errorf("library logger enabled after SetLibraryLogger(nil)")
+	}
+}
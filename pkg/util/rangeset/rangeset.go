@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+// Package rangeset provides helpers for working with inclusive IPv4 ranges
+// held as uint32 values.
+package rangeset
+
+import (
+	"sort"
+)
+
+// Range is an inclusive range of IPv4 addresses
+type Range struct {
+	Start uint32
+	End   uint32
+}
+
+// Set is a sorted list of disjoint ranges
+type Set struct {
+	ranges []Range
+}
+
+// New builds a set from ranges in any order, merging overlapping and adjacent ranges
+func New(ranges []Range) *Set {
+	sorted := make([]Range, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	merged := make([]Range, 0, len(sorted))
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && uint64(r.Start) <= uint64(merged[n-1].End)+1 {
+			if r.End > merged[n-1].End {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return &Set{ranges: merged}
+}
+
+// Len returns the number of disjoint ranges in the set
+func (s *Set) Len() int {
+	return len(s.ranges)
+}
+
+// Ranges returns the disjoint ranges in ascending order
+func (s *Set) Ranges() []Range {
+	return s.ranges
+}
+
+// Gaps returns the parts of r that are not covered by the set
+func (s *Set) Gaps(r Range) []Range {
+	// First range that ends at or after r.Start
+	i := sort.Search(len(s.ranges), func(i int) bool { return s.ranges[i].End >= r.Start })
+
+	var gaps []Range
+	pos := uint64(r.Start)
+	for ; i < len(s.ranges) && s.ranges[i].Start <= r.End; i++ {
+		if uint64(s.ranges[i].Start) > pos {
+			gaps = append(gaps, Range{uint32(pos), s.ranges[i].Start - 1})
+		}
+		pos = uint64(s.ranges[i].End) + 1
+	}
+	if pos <= uint64(r.End) {
+		gaps = append(gaps, Range{uint32(pos), r.End})
+	}
+	return gaps
+}
+
+// Segment is a part of the address space owned by a single range
+type Segment[T any] struct {
+	Range
+	Value T // Value of the most specific range covering the segment
+}
+
+// Flattener turns nested ranges into non-overlapping segments, each owned
+// by the most specific range covering it. Ranges must be added in order of
+// start address; ranges sharing a start may arrive in any order. Memory use
+// is bounded by the nesting depth.
+type Flattener[T any] struct {
+	emit  func(Segment[T]) error
+	stack []Segment[T] // Open ranges, outermost first
+	group []Segment[T] // Pending ranges sharing the same start
+	pos   uint64       // Next address not yet emitted
+}
+
+// NewFlattener creates a flattener that calls emit for each segment in
+// ascending order
+func NewFlattener[T any](emit func(Segment[T]) error) *Flattener[T] {
+	return &Flattener[T]{emit: emit}
+}
+
+// Add adds a range with its value
+func (f *Flattener[T]) Add(start, end uint32, value T) error {
+	if end < start {
+		return nil
+	}
+	if len(f.group) > 0 && f.group[0].Start != start {
+		if err := f.flushGroup(); err != nil {
+			return err
+		}
+	}
+	f.group = append(f.group, Segment[T]{Range: Range{start, end}, Value: value})
+	return nil
+}
+
+// Close emits the remaining segments
+func (f *Flattener[T]) Close() error {
+	if err := f.flushGroup(); err != nil {
+		return err
+	}
+	for len(f.stack) > 0 {
+		if err := f.pop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flushGroup pushes the pending ranges, widest first
+func (f *Flattener[T]) flushGroup() error {
+	if len(f.group) == 0 {
+		return nil
+	}
+	sort.SliceStable(f.group, func(i, j int) bool { return f.group[i].End > f.group[j].End })
+
+	start := f.group[0].Start
+
+	// Close ranges that end before the group starts
+	for len(f.stack) > 0 && f.stack[len(f.stack)-1].End < start {
+		if err := f.pop(); err != nil {
+			return err
+		}
+	}
+
+	// The enclosing range owns the space up to the group
+	if n := len(f.stack); n > 0 && f.pos < uint64(start) {
+		if err := f.emitUpTo(f.stack[n-1], uint64(start)-1); err != nil {
+			return err
+		}
+	}
+	if len(f.stack) == 0 || f.pos < uint64(start) {
+		f.pos = uint64(start)
+	}
+
+	f.stack = append(f.stack, f.group...)
+	f.group = f.group[:0]
+	return nil
+}
+
+// pop closes the innermost open range
+func (f *Flattener[T]) pop() error {
+	top := f.stack[len(f.stack)-1]
+	f.stack = f.stack[:len(f.stack)-1]
+	return f.emitUpTo(top, uint64(top.End))
+}
+
+// emitUpTo emits the segment from pos to end (if any) owned by seg
+func (f *Flattener[T]) emitUpTo(seg Segment[T], end uint64) error {
+	if f.pos > end {
+		return nil
+	}
+	out := Segment[T]{Range: Range{uint32(f.pos), uint32(end)}, Value: seg.Value}
+	f.pos = end + 1
+	return f.emit(out)
+}
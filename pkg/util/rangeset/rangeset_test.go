@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package rangeset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFlattener(t *testing.T) {
+	type in struct {
+		start, end uint32
+		name       string
+	}
+	tests := []struct {
+		name   string
+		ranges []in
+		want   string
+	}{
+		{
+			name:   "single range",
+			ranges: []in{{10, 20, "a"}},
+			want:   "10-20:a",
+		},
+		{
+			name:   "nested child in the middle",
+			ranges: []in{{0, 99, "parent"}, {10, 19, "child"}},
+			want:   "0-9:parent 10-19:child 20-99:parent",
+		},
+		{
+			name:   "same start, child listed first",
+			ranges: []in{{0, 9, "child"}, {0, 99, "parent"}},
+			want:   "0-9:child 10-99:parent",
+		},
+		{
+			name:   "three levels and siblings",
+			ranges: []in{{0, 99, "a"}, {10, 49, "b"}, {20, 29, "c"}, {60, 69, "d"}},
+			want:   "0-9:a 10-19:b 20-29:c 30-49:b 50-59:a 60-69:d 70-99:a",
+		},
+		{
+			name:   "disjoint ranges leave gaps",
+			ranges: []in{{0, 9, "a"}, {20, 29, "b"}},
+			want:   "0-9:a 20-29:b",
+		},
+		{
+			name:   "partial overlap",
+			ranges: []in{{0, 19, "a"}, {10, 29, "b"}},
+			want:   "0-9:a 10-29:b",
+		},
+		{
+			name:   "end of address space",
+			ranges: []in{{0, 0xFFFFFFFF, "all"}, {0xFFFFFF00, 0xFFFFFFFF, "top"}},
+			want:   "0-4294967039:all 4294967040-4294967295:top",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			f := NewFlattener(func(s Segment[string]) error {
+				if got != "" {
+					got += " "
+				}
+				got += fmt.Sprintf("%d-%d:%s", s.Start, s.End, s.Value)
+				return nil
+			})
+			for _, r := range tt.ranges {
+				if err := f.Add(r.start, r.end, r.name); err != nil {
+					t.Fatalf("Add failed: %v", err)
+				}
+			}
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetGaps(t *testing.T) {
+	set := New([]Range{{50, 59}, {10, 19}, {20, 29}, {80, 0xFFFFFFFF}})
+	if set.Len() != 3 {
+		t.Errorf("got %d ranges, want 3 (adjacent ranges merged)", set.Len())
+	}
+
+	tests := []struct {
+		r    Range
+		want string
+	}{
+		{Range{0, 9}, "[{0 9}]"},
+		{Range{0, 100}, "[{0 9} {30 49} {60 79}]"},
+		{Range{12, 25}, "[]"},
+		{Range{25, 55}, "[{30 49}]"},
+		{Range{60, 79}, "[{60 79}]"},
+		{Range{90, 0xFFFFFFFF}, "[]"},
+	}
+
+	for _, tt := range tests {
+		got := fmt.Sprint(set.Gaps(tt.r))
+		if got != tt.want {
+			t.Errorf("Gaps(%v) = %s, want %s", tt.r, got, tt.want)
+		}
+	}
+}
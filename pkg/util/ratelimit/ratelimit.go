@@ -0,0 +1,295 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Config contains configuration for an adaptive limiter
+type Config struct {
+	Rate         float64       // Maximum requests per second per host (0 = no limit)
+	MinRate      float64       // Floor for the adaptive rate (default Rate/20)
+	Increase     float64       // Added to the rate after each success (default Rate/100)
+	Decrease     float64       // Rate multiplier after a throttled response (default 0.5)
+	DefaultPause time.Duration // Pause after a throttled response without Retry-After (default 1s)
+	MaxPause     time.Duration // Upper bound for a Retry-After pause (default 5m)
+}
+
+// Limiter is a per-host AIMD rate limiter: the rate is halved (by default)
+// when a host throttles us and restored additively as requests succeed.
+// Retry-After pauses a host for every caller sharing the limiter.
+type Limiter struct {
+	cfg   Config
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is the limiter state for one upstream host
+type hostState struct {
+	limiter     *rate.Limiter // nil when unlimited
+	pausedUntil time.Time
+	requests    int64
+	throttled   int64
+}
+
+// HostRate is a snapshot of the limiter state for one host
+type HostRate struct {
+	Host      string
+	Rate      float64 // Current effective rate (0 = unlimited)
+	MaxRate   float64
+	Requests  int64
+	Throttled int64
+}
+
+// New creates a limiter
+func New(cfg Config) *Limiter {
+	if cfg.Rate > 0 {
+		if cfg.MinRate <= 0 {
+			cfg.MinRate = cfg.Rate / 20
+		}
+		if cfg.Increase <= 0 {
+			cfg.Increase = cfg.Rate / 100
+		}
+	}
+	if cfg.Decrease <= 0 || cfg.Decrease >= 1 {
+		cfg.Decrease = 0.5
+	}
+	if cfg.DefaultPause <= 0 {
+		cfg.DefaultPause = time.Second
+	}
+	if cfg.MaxPause <= 0 {
+		cfg.MaxPause = 5 * time.Minute
+	}
+	return &Limiter{cfg: cfg, hosts: make(map[string]*hostState)}
+}
+
+// host returns the state for a host, creating it if needed. Callers hold l.mu.
+func (l *Limiter) host(name string) *hostState {
+	h, ok := l.hosts[name]
+	if !ok {
+		h = &hostState{}
+		if l.cfg.Rate > 0 {
+			h.limiter = rate.NewLimiter(rate.Limit(l.cfg.Rate), int(l.cfg.Rate)+1)
+		}
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// Wait blocks until a request to host is allowed
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	h := l.host(host)
+	l.mu.Unlock()
+
+	for {
+		l.mu.Lock()
+		wait := time.Until(h.pausedUntil)
+		l.mu.Unlock()
+		if wait <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if h.limiter != nil {
+		return h.limiter.Wait(ctx)
+	}
+	return nil
+}
+
+// Success records a successful request and raises the rate toward the maximum
+func (l *Limiter) Success(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.host(host)
+	h.requests++
+	if h.limiter != nil {
+		if r := float64(h.limiter.Limit()); r < l.cfg.Rate {
+			h.limiter.SetLimit(rate.Limit(min(l.cfg.Rate, r+l.cfg.Increase)))
+		}
+	}
+}
+
+// Throttled records a throttled request: the rate is reduced and the host is
+// paused for retryAfter (or the default pause if zero)
+func (l *Limiter) Throttled(host string, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h := l.host(host)
+	h.requests++
+	h.throttled++
+	if h.limiter != nil {
+		r := float64(h.limiter.Limit()) * l.cfg.Decrease
+		h.limiter.SetLimit(rate.Limit(max(l.cfg.MinRate, r)))
+	}
+
+	if retryAfter <= 0 {
+		retryAfter = l.cfg.DefaultPause
+	}
+	if until := time.Now().Add(min(retryAfter, l.cfg.MaxPause)); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+}
+
+// Rates returns the current state of every host seen, sorted by host
+func (l *Limiter) Rates() []HostRate {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rates := make([]HostRate, 0, len(l.hosts))
+	for name, h := range l.hosts {
+		hr := HostRate{
+			Host:      name,
+			MaxRate:   l.cfg.Rate,
+			Requests:  h.requests,
+			Throttled: h.throttled,
+		}
+		if h.limiter != nil {
+			hr.Rate = float64(h.limiter.Limit())
+		}
+		rates = append(rates, hr)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Host < rates[j].Host })
+	return rates
+}
+
+// Transport is an http.RoundTripper that paces requests through a Limiter
+// and feeds it the upstream responses. Use Timeout rather than
+// http.Client.Timeout: the client's timeout also runs while a request waits
+// for the limiter, which can be longer than any sensible request timeout.
+type Transport struct {
+	Base    http.RoundTripper // Defaults to http.DefaultTransport
+	Limiter *Limiter
+	Timeout time.Duration // Per request, including the body, from the end of the wait (0 = none)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	host := req.URL.Host
+	if err := t.Limiter.Wait(req.Context(), host); err != nil {
+		return nil, fmt.Errorf("rate limit: %w", err)
+	}
+
+	resp, err := t.roundTrip(base, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if IsThrottled(resp) {
+		t.Limiter.Throttled(host, ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+	} else {
+		t.Limiter.Success(host)
+	}
+	return resp, nil
+}
+
+// roundTrip sends req, bounded by the timeout if one is set
+func (t *Transport) roundTrip(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if t.Timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody ends a request's timeout when its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// IsThrottled reports whether resp asks the client to slow down: a 429, or a
+// 503 carrying Retry-After
+func IsThrottled(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") != "")
+}
+
+// ParseRetryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns zero if the header is absent or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// RetryAfterError reports a throttled response and the wait the server asked for
+type RetryAfterError struct {
+	Err   error
+	After time.Duration
+}
+
+// ErrorFromResponse returns a RetryAfterError wrapping err for a throttled response
+func ErrorFromResponse(resp *http.Response, err error) *RetryAfterError {
+	return &RetryAfterError{
+		Err:   err,
+		After: ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (e *RetryAfterError) Error() string {
+	if e.After > 0 {
+		return fmt.Sprintf("%v (retry after %s)", e.Err, e.After)
+	}
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns the requested wait, used by workers.Retry
+func (e *RetryAfterError) RetryAfter() time.Duration {
+	return e.After
+}
@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"-1", 0},
+		{"Wed, 01 Jan 2025 12:00:30 GMT", 30 * time.Second},
+		{"Wed, 01 Jan 2025 11:00:00 GMT", 0}, // In the past
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterAIMD(t *testing.T) {
+	l := New(Config{Rate: 10, Increase: 1, DefaultPause: time.Millisecond})
+
+	rateOf := func(host string) float64 {
+		for _, r := range l.Rates() {
+			if r.Host == host {
+				return r.Rate
+			}
+		}
+		return -1
+	}
+
+	l.Success("a")
+	if got := rateOf("a"); got != 10 {
+		t.Errorf("got rate %.1f, want 10 (capped at max)", got)
+	}
+
+	l.Throttled("a", 0)
+	l.Throttled("a", 0)
+	if got := rateOf("a"); got != 2.5 {
+		t.Errorf("got rate %.1f after two throttles, want 2.5", got)
+	}
+
+	// Throttles decrease down to MinRate (Rate/20) but no further
+	for i := 0; i < 10; i++ {
+		l.Throttled("a", 0)
+	}
+	if got := rateOf("a"); got != 0.5 {
+		t.Errorf("got rate %.2f, want floor 0.5", got)
+	}
+
+	l.Success("a")
+	l.Success("a")
+	if got := rateOf("a"); got != 2.5 {
+		t.Errorf("got rate %.1f after two successes, want 2.5", got)
+	}
+
+	// Hosts are independent
+	l.Success("b")
+	if got := rateOf("b"); got != 10 {
+		t.Errorf("got rate %.1f for b, want 10", got)
+	}
+
+	rates := l.Rates()
+	if len(rates) != 2 || rates[0].Host != "a" || rates[0].Throttled != 12 || rates[0].Requests != 15 {
+		t.Errorf("got %+v, want a with 15 requests and 12 throttled", rates)
+	}
+}
+
+func TestLimiterPause(t *testing.T) {
+	l := New(Config{})
+	l.Throttled("a", 100*time.Millisecond)
+
+	start := time.Now()
+	if err := l.Wait(context.Background(), "a"); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("waited %v, want the Retry-After pause", elapsed)
+	}
+
+	// Other hosts are not paused
+	start = time.Now()
+	l.Wait(context.Background(), "b")
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("waited %v for an unpaused host", elapsed)
+	}
+
+	// Cancelling the context ends the wait
+	l.Throttled("a", time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	l := New(Config{Rate: 100})
+	client := &http.Client{Transport: &Transport{Limiter: l}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if !IsThrottled(resp) {
+		t.Fatalf("got status %d, want throttled", resp.StatusCode)
+	}
+	rerr := ErrorFromResponse(resp, errors.New("rate limited"))
+	if rerr.RetryAfter() != time.Second {
+		t.Errorf("got retry after %v, want 1s", rerr.RetryAfter())
+	}
+
+	// The next request waits out the pause
+	start := time.Now()
+	resp, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("second request after %v, want the 1s Retry-After pause", elapsed)
+	}
+
+	u, _ := url.Parse(server.URL)
+	rates := l.Rates()
+	if len(rates) != 1 || rates[0].Host != u.Host || rates[0].Requests != 2 || rates[0].Throttled != 1 {
+		t.Errorf("got %+v, want 2 requests with 1 throttled", rates)
+	}
+}
+
+func TestTransportTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	l := New(Config{Rate: 100})
+	client := &http.Client{Transport: &Transport{Limiter: l, Timeout: 200 * time.Millisecond}}
+
+	// A pause longer than the timeout is waited out, not failed
+	u, _ := url.Parse(server.URL)
+	l.Throttled(u.Host, 400*time.Millisecond)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request after pause failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "ok" {
+		t.Errorf("got body %q, %v; want ok", body, err)
+	}
+
+	// The timeout still bounds the request itself
+	if resp, err := client.Get(server.URL + "/slow"); !errors.Is(err, context.DeadlineExceeded) {
+		if err == nil {
+			resp.Body.Close()
+		}
+		t.Errorf("slow request: got %v, want deadline exceeded", err)
+	}
+}
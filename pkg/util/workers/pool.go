@@ -5,7 +5,9 @@ package workers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"runtime/debug"
 	"sync"
 	"time"
 
@@ -21,32 +23,52 @@ type Result struct {
 	Error error // Error if task failed
 }
 
-// Pool represents a worker pool with rate limiting
+// PanicError is the error recorded for a task that panicked
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
+
+// Pool runs tasks on a fixed set of workers fed from a bounded queue
 type Pool struct {
-	workers   int
-	limiter   *rate.Limiter
-	semaphore chan struct{}
-	results   chan Result
-	wg        sync.WaitGroup
-	ctx       context.Context
-	cancel    context.CancelFunc
+	limiter     *rate.Limiter
+	tasks       chan job
+	results     chan Result
+	stream      bool
+	stopOnError bool
+	wg          sync.WaitGroup
+	ctx         context.Context
+	cancel      context.CancelFunc
+	closeOnce   sync.Once
+
+	mu       sync.Mutex
+	failed   []Result
+	firstErr error
+}
+
+// job is a queued task
+type job struct {
+	index int
+	task  Task
 }
 
 // Config contains configuration for a worker pool
 type Config struct {
-	Workers   int     // Number of concurrent workers
-	RateLimit float64 // Requests per second (0 = no limit)
-	BurstSize int     // Burst size for rate limiter
+	Workers     int     // Number of concurrent workers
+	RateLimit   float64 // Requests per second (0 = no limit)
+	BurstSize   int     // Burst size for rate limiter
+	QueueSize   int     // Tasks queued before Submit blocks (default 2x Workers)
+	StopOnError bool    // Cancel the remaining tasks after the first error
+	Stream      bool    // Deliver every result on Results instead of collecting failures
 }
 
-// NewPool creates a new worker pool
+// NewPool creates a new worker pool and starts its workers
 func NewPool(ctx context.Context, cfg Config) *Pool {
-	if cfg.Workers <= 0 {
-		cfg.Workers = 1
-	}
-	if cfg.BurstSize <= 0 {
-		cfg.BurstSize = cfg.Workers
-	}
+	cfg = cfg.withDefaults()
 
 	poolCtx, cancel := context.WithCancel(ctx)
 
@@ -55,60 +77,130 @@ func NewPool(ctx context.Context, cfg Config) *Pool {
 		limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), cfg.BurstSize)
 	}
 
-	return &Pool{
-		workers:   cfg.Workers,
-		limiter:   limiter,
-		semaphore: make(chan struct{}, cfg.Workers),
-		results:   make(chan Result, cfg.Workers*2), // Buffered to avoid blocking
-		ctx:       poolCtx,
-		cancel:    cancel,
+	p := &Pool{
+		limiter:     limiter,
+		tasks:       make(chan job, cfg.QueueSize),
+		stream:      cfg.Stream,
+		stopOnError: cfg.StopOnError,
+		ctx:         poolCtx,
+		cancel:      cancel,
+	}
+	if cfg.Stream {
+		p.results = make(chan Result, cfg.QueueSize)
 	}
+
+	p.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go p.worker()
+	}
+	return p
 }
 
-// Submit submits a task to the worker pool
-func (p *Pool) Submit(index int, task Task) {
-	p.wg.Add(1)
-	go func() {
-		defer p.wg.Done()
+// withDefaults fills in unset sizes
+func (cfg Config) withDefaults() Config {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.BurstSize <= 0 {
+		cfg.BurstSize = cfg.Workers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = cfg.Workers * 2
+	}
+	return cfg
+}
 
-		// Acquire semaphore (limit concurrency)
-		select {
-		case p.semaphore <- struct{}{}:
-			defer func() { <-p.semaphore }()
-		case <-p.ctx.Done():
-			p.results <- Result{Index: index, Error: p.ctx.Err()}
-			return
-		}
+// worker runs queued tasks until the queue is closed
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for j := range p.tasks {
+		p.record(Result{Index: j.index, Error: p.run(j.task)})
+	}
+}
 
-		// Wait for rate limiter
-		if p.limiter != nil {
-			if err := p.limiter.Wait(p.ctx); err != nil {
-				p.results <- Result{Index: index, Error: err}
-				return
-			}
+// run executes one task, turning a panic into a *PanicError
+func (p *Pool) run(task Task) (err error) {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	if p.limiter != nil {
+		if err := p.limiter.Wait(p.ctx); err != nil {
+			return err
 		}
+	}
 
-		// Execute task
-		err := task(p.ctx)
-		p.results <- Result{Index: index, Error: err}
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
 	}()
+	return task(p.ctx)
+}
+
+// record delivers or collects a result
+func (p *Pool) record(r Result) {
+	if r.Error != nil && p.stopOnError {
+		p.mu.Lock()
+		if p.firstErr == nil {
+			p.firstErr = r.Error
+			p.cancel()
+		}
+		p.mu.Unlock()
+	}
+
+	if p.stream {
+		p.results <- r
+		return
+	}
+	if r.Error != nil {
+		p.mu.Lock()
+		p.failed = append(p.failed, r)
+		p.mu.Unlock()
+	}
+}
+
+// Submit queues a task, blocking while the queue is full. It returns the
+// context error once the pool is stopped. Submit must not be called after Wait.
+func (p *Pool) Submit(index int, task Task) error {
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case p.tasks <- job{index: index, task: task}:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	}
 }
 
-// Wait waits for all tasks to complete and returns results
+// Results returns the channel results are delivered on in stream mode (nil
+// otherwise). It is closed by Wait and must be drained concurrently with
+// Submit and Wait, or the workers block.
+func (p *Pool) Results() <-chan Result {
+	return p.results
+}
+
+// Wait waits for all queued tasks to complete. It returns the failed results
+// (nil in stream mode, where every result is delivered on Results).
 func (p *Pool) Wait() []Result {
-	// Close results channel when all workers are done
-	go func() {
+	p.closeOnce.Do(func() {
+		close(p.tasks)
 		p.wg.Wait()
-		close(p.results)
-	}()
+		if p.stream {
+			close(p.results)
+		}
+	})
 
-	// Collect results
-	var results []Result
-	for result := range p.results {
-		results = append(results, result)
-	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failed
+}
 
-	return results
+// Err returns the error that stopped the pool when StopOnError is set
+func (p *Pool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.firstErr
 }
 
 // Stop cancels all pending tasks
@@ -134,7 +226,19 @@ func DefaultRetryConfig() RetryConfig {
 	}
 }
 
-// Retry executes a function with exponential backoff
+// retryAfter is implemented by errors that carry a server-requested wait
+type retryAfter interface {
+	RetryAfter() time.Duration
+}
+
+// permanent is implemented by errors that retrying cannot fix
+type permanent interface {
+	Permanent() bool
+}
+
+// Retry executes a function with exponential backoff. If the error carries a
+// RetryAfter wait longer than the backoff, that wait (up to MaxDelay) is used.
+// Errors reporting Permanent() are returned without retrying.
 func Retry(ctx context.Context, cfg RetryConfig, fn func() error) error {
 	var lastErr error
 	delay := cfg.InitialDelay
@@ -149,10 +253,20 @@ func Retry(ctx context.Context, cfg RetryConfig, fn func() error) error {
 		if attempt == cfg.MaxAttempts {
 			break
 		}
+		var perm permanent
+		if errors.As(lastErr, &perm) && perm.Permanent() {
+			return lastErr
+		}
+
+		wait := delay
+		var ra retryAfter
+		if errors.As(lastErr, &ra) && ra.RetryAfter() > wait {
+			wait = min(ra.RetryAfter(), cfg.MaxDelay)
+		}
 
 		// Exponential backoff with jitter
 		select {
-		case <-time.After(delay):
+		case <-time.After(wait):
 			delay = time.Duration(float64(delay) * cfg.Multiplier)
 			if delay > cfg.MaxDelay {
 				delay = cfg.MaxDelay
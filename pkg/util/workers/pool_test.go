@@ -0,0 +1,260 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolBoundedQueue(t *testing.T) {
+	pool := NewPool(context.Background(), Config{Workers: 2, QueueSize: 3})
+
+	release := make(chan struct{})
+	var running, maxRunning atomic.Int32
+	submitted := make(chan int, 100)
+
+	go func() {
+		for i := 0; i < 20; i++ {
+			pool.Submit(i, func(ctx context.Context) error {
+				n := running.Add(1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				<-release
+				running.Add(-1)
+				return nil
+			})
+			submitted <- i
+		}
+		close(submitted)
+	}()
+
+	// Two running plus three queued; the sixth Submit must block
+	time.Sleep(50 * time.Millisecond)
+	if got := len(submitted); got != 5 {
+		t.Errorf("got %d tasks accepted while blocked, want 5", got)
+	}
+
+	close(release)
+	for range submitted {
+	}
+	if results := pool.Wait(); len(results) != 0 {
+		t.Errorf("got %d failed results, want 0", len(results))
+	}
+	if got := maxRunning.Load(); got > 2 {
+		t.Errorf("got %d concurrent tasks, want at most 2", got)
+	}
+}
+
+func TestPoolFailuresAndPanics(t *testing.T) {
+	pool := NewPool(context.Background(), Config{Workers: 4})
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(i, func(ctx context.Context) error {
+			switch i {
+			case 3:
+				return fmt.Errorf("task %d failed", i)
+			case 7:
+				panic("boom")
+			}
+			return nil
+		})
+	}
+
+	results := pool.Wait()
+	slices.SortFunc(results, func(a, b Result) int { return a.Index - b.Index })
+	if len(results) != 2 || results[0].Index != 3 || results[1].Index != 7 {
+		t.Fatalf("got %+v, want failures for tasks 3 and 7", results)
+	}
+	var pe *PanicError
+	if !errors.As(results[1].Error, &pe) || pe.Value != "boom" {
+		t.Errorf("got %v, want a PanicError for boom", results[1].Error)
+	}
+}
+
+func TestPoolStopOnError(t *testing.T) {
+	pool := NewPool(context.Background(), Config{Workers: 1, StopOnError: true})
+	boom := errors.New("boom")
+
+	var ran atomic.Int32
+	var submitErr error
+	for i := 0; i < 100; i++ {
+		submitErr = pool.Submit(i, func(ctx context.Context) error {
+			ran.Add(1)
+			if i == 5 {
+				return boom
+			}
+			return nil
+		})
+		if submitErr != nil {
+			break
+		}
+	}
+	pool.Wait()
+
+	if !errors.Is(pool.Err(), boom) {
+		t.Errorf("got Err() = %v, want boom", pool.Err())
+	}
+	if submitErr == nil {
+		t.Error("got nil Submit error after stop, want context error")
+	}
+	if got := ran.Load(); got > 6 {
+		t.Errorf("got %d tasks run, want none after the failure", got)
+	}
+}
+
+func TestPoolStream(t *testing.T) {
+	pool := NewPool(context.Background(), Config{Workers: 3, QueueSize: 1, Stream: true})
+
+	go func() {
+		for i := 0; i < 50; i++ {
+			pool.Submit(i, func(ctx context.Context) error { return nil })
+		}
+		pool.Wait()
+	}()
+
+	seen := make(map[int]bool)
+	for r := range pool.Results() {
+		if r.Error != nil {
+			t.Errorf("task %d: %v", r.Index, r.Error)
+		}
+		seen[r.Index] = true
+	}
+	if len(seen) != 50 {
+		t.Errorf("got %d results, want 50", len(seen))
+	}
+}
+
+func TestStreamOrdered(t *testing.T) {
+	cfg := Config{Workers: 4, QueueSize: 2}
+	square := func(ctx context.Context, n int) (int, error) {
+		// Later inputs finish first
+		time.Sleep(time.Duration(20-n) * time.Millisecond)
+		if n == 13 {
+			panic("unlucky")
+		}
+		return n * n, nil
+	}
+
+	var got []int
+	var failed []int
+	err := Stream(context.Background(), cfg, slices.Values(rangeInts(20)), true, square,
+		func(it Item[int, int]) error {
+			if it.Index != it.In {
+				t.Errorf("got index %d for input %d", it.Index, it.In)
+			}
+			if it.Err != nil {
+				failed = append(failed, it.In)
+				return nil
+			}
+			got = append(got, it.Out)
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+
+	var want []int
+	for _, n := range rangeInts(20) {
+		if n != 13 {
+			want = append(want, n*n)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !slices.Equal(failed, []int{13}) {
+		t.Errorf("got failures %v, want [13]", failed)
+	}
+}
+
+func TestStreamStops(t *testing.T) {
+	tests := []struct {
+		name        string
+		stopOnError bool
+		emitErr     bool
+	}{
+		{"stop on task error", true, false},
+		{"emit error", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			boom := errors.New("boom")
+			var produced atomic.Int32
+			inputs := func(yield func(int) bool) {
+				for i := 0; ; i++ {
+					produced.Add(1)
+					if !yield(i) {
+						return
+					}
+				}
+			}
+			fn := func(ctx context.Context, n int) (int, error) {
+				if n == 10 && tt.stopOnError {
+					return 0, boom
+				}
+				return n, nil
+			}
+			emit := func(it Item[int, int]) error {
+				if it.In == 10 && tt.emitErr {
+					return boom
+				}
+				return nil
+			}
+
+			cfg := Config{Workers: 2, QueueSize: 2, StopOnError: tt.stopOnError}
+			if err := Stream(context.Background(), cfg, inputs, false, fn, emit); !errors.Is(err, boom) {
+				t.Errorf("got %v, want boom", err)
+			}
+			// The unbounded input is only read a window ahead
+			if got := produced.Load(); got > 100 {
+				t.Errorf("got %d inputs read, want the stream to stop", got)
+			}
+		})
+	}
+}
+
+func rangeInts(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+// retryAfterErr requests a wait before the next attempt
+type retryAfterErr time.Duration
+
+func (e retryAfterErr) Error() string             { return "throttled" }
+func (e retryAfterErr) RetryAfter() time.Duration { return time.Duration(e) }
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	cfg := RetryConfig{MaxAttempts: 2, InitialDelay: time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+
+	attempts := 0
+	start := time.Now()
+	err := Retry(context.Background(), cfg, func() error {
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("wrapped: %w", retryAfterErr(100*time.Millisecond))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("retried after %v, want the 100ms Retry-After", elapsed)
+	}
+}
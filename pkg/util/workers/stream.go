@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: MIT
+// Copyright (c) 2025 Mark Feghali
+
+package workers
+
+import (
+	"context"
+	"iter"
+	"runtime/debug"
+	"sync"
+)
+
+// Item is one input and its result in a Stream
+type Item[In, Out any] struct {
+	Index int // Position of the input in the sequence
+	In    In
+	Out   Out
+	Err   error
+}
+
+// Stream runs fn over the inputs with cfg.Workers goroutines and calls emit
+// for each result from the calling goroutine. At most QueueSize+Workers
+// inputs are in flight, so the producer is paced by the consumer. With
+// ordered set, results are emitted in input order.
+//
+// Task errors are passed to emit; with StopOnError the first one stops the
+// stream and is returned instead. An error from emit also stops the stream,
+// as does cancelling ctx.
+// RateLimit is ignored.
+func Stream[In, Out any](ctx context.Context, cfg Config, inputs iter.Seq[In], ordered bool,
+	fn func(context.Context, In) (Out, error), emit func(Item[In, Out]) error) error {
+	cfg = cfg.withDefaults()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	window := cfg.QueueSize + cfg.Workers
+	slots := make(chan struct{}, window)
+	jobs := make(chan Item[In, Out], cfg.QueueSize)
+	done := make(chan Item[In, Out], window)
+
+	// Producer: one slot per input, released when its result is emitted
+	go func() {
+		defer close(jobs)
+		i := 0
+		for in := range inputs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- Item[In, Out]{Index: i, In: in}:
+			case <-ctx.Done():
+				return
+			}
+			i++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(cfg.Workers)
+	for w := 0; w < cfg.Workers; w++ {
+		go func() {
+			defer wg.Done()
+			for it := range jobs {
+				if err := ctx.Err(); err != nil {
+					it.Err = err
+				} else {
+					it.Out, it.Err = callSafe(ctx, fn, it.In)
+				}
+				done <- it // Never blocks: done holds a full window
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var firstErr error
+	deliver := func(it Item[In, Out]) {
+		<-slots
+		if firstErr != nil {
+			return // Draining after a stop
+		}
+		if it.Err != nil && cfg.StopOnError {
+			firstErr = it.Err
+		} else if err := emit(it); err != nil {
+			firstErr = err
+		}
+		if firstErr != nil {
+			cancel()
+		}
+	}
+
+	pending := make(map[int]Item[In, Out])
+	next := 0
+	for it := range done {
+		if !ordered {
+			deliver(it)
+			continue
+		}
+		pending[it.Index] = it
+		for {
+			p, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			deliver(p)
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err() // Set only if the parent context was cancelled
+}
+
+// callSafe calls fn, turning a panic into a *PanicError
+func callSafe[In, Out any](ctx context.Context, fn func(context.Context, In) (Out, error), in In) (out Out, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx, in)
+}